@@ -1,12 +1,15 @@
 package main
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/sst/sst/v3/cmd/sst/cli"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/internal/util"
 	"github.com/sst/sst/v3/pkg/bus"
 	"github.com/sst/sst/v3/pkg/project"
+	"github.com/sst/sst/v3/pkg/project/provider"
 	"github.com/sst/sst/v3/pkg/server"
 	"golang.org/x/sync/errgroup"
 )
@@ -69,7 +72,8 @@ var CmdDeploy = &cli.Command{
 			"",
 			"This is useful when deploying a new stage with a lot of resources. You want",
 			"to be able to deploy as many resources as possible and then come back and",
-			"fix the errors.",
+			"fix the errors. Resources that depend on the failed ones are skipped and listed at",
+			"the end, while the state and outputs of everything else are still saved.",
 			"",
 			"The `sst dev` command deploys your resources a little differently. It skips",
 			"deploying resources that are going to be run locally. Sometimes you want to",
@@ -79,6 +83,41 @@ var CmdDeploy = &cli.Command{
 			"sst deploy --dev",
 			"```",
 			"The `--dev` flag will deploy your resources as if you were running `sst dev`.",
+			"",
+			"Large stages can run into the rate limits of your provider. You can limit how many",
+			"resources are deployed at the same time with `--parallel`, or with the `parallel`",
+			"setting in your `sst.config.ts`.",
+			"",
+			"```bash frame=\"none\"",
+			"sst deploy --parallel 8",
+			"```",
+			"",
+			"To deploy a plan that was saved with `sst diff --out`, pass in `--plan`.",
+			"",
+			"```bash frame=\"none\"",
+			"sst deploy --stage production --plan plan.json",
+			"```",
+			"",
+			"It only makes the changes in the plan. And fails if your `sst.config.ts`, the state",
+			"of the stage, the secrets, or the providers have changed since the plan was saved.",
+			"",
+			"If you deploy the same app to multiple stages, regions, or accounts, you can define",
+			"a group of them in the `groups` of your `sst.config.ts` and deploy them together.",
+			"",
+			"```bash frame=\"none\"",
+			"sst deploy --group prod-all",
+			"```",
+			"",
+			"Each stage in the group is deployed with its own provider overrides, one at a time",
+			"or concurrently based on the `concurrency` of the group. A summary of all of them is",
+			"printed at the end.",
+			"",
+			"For short lived stages, like the ones for pull requests, set a `--ttl`. The stage then",
+			"expires if it hasn't been deployed for that long, and is removed by `sst stage gc`.",
+			"",
+			"```bash frame=\"none\"",
+			"sst deploy --stage pr-123 --ttl 3d --labels pr=123",
+			"```",
 		}, "\n"),
 	},
 	Flags: []cli.Flag{
@@ -105,6 +144,46 @@ var CmdDeploy = &cli.Command{
 				Long:  "Deploy resources like `sst dev` would.",
 			},
 		},
+		{
+			Name: "ttl",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Remove the stage after a while",
+				Long:  "How long to keep the stage after it was last deployed, like `72h` or `3d`. Expired stages are removed with `sst stage gc`.",
+			},
+		},
+		{
+			Name: "labels",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Label the stage",
+				Long:  "A comma separated list of `key=value` labels to save with the stage.",
+			},
+		},
+		{
+			Name: "group",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Deploy a group of stages",
+				Long:  "Deploy all the stages in the given group from your `sst.config.ts`.",
+			},
+		},
+		{
+			Name: "plan",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Deploy a saved plan",
+				Long:  "Deploy the plan saved by `sst diff --out`.",
+			},
+		},
+		{
+			Name: "parallel",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Limit concurrent operations",
+				Long:  "The maximum number of resource operations to run at the same time.",
+			},
+		},
 	},
 	Examples: []cli.Example{
 		{
@@ -126,6 +205,48 @@ var CmdDeploy = &cli.Command{
 			target = strings.Split(c.String("target"), ",")
 		}
 
+		parallel, err := parseParallel(c)
+		if err != nil {
+			return err
+		}
+
+		if group := c.String("group"); group != "" {
+			if len(target) > 0 || c.Bool("dev") || c.String("plan") != "" {
+				return util.NewReadableError(nil, "The --target, --dev, and --plan flags cannot be used with --group")
+			}
+			return deployGroup(c, p, group)
+		}
+
+		if ttl := c.String("ttl"); ttl != "" {
+			if _, err := provider.ParseTTL(ttl); err != nil {
+				return util.NewReadableError(err, "The --ttl flag needs to be a duration like 72h or 3d")
+			}
+		}
+		labels := map[string]string{}
+		if c.String("labels") != "" {
+			for _, item := range strings.Split(c.String("labels"), ",") {
+				key, value, ok := strings.Cut(item, "=")
+				if !ok || strings.TrimSpace(key) == "" {
+					return util.NewReadableError(nil, "The --labels flag needs to be a list of key=value pairs")
+				}
+				labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+
+		dev := c.Bool("dev")
+		var plan *project.Plan
+		if c.String("plan") != "" {
+			if len(target) > 0 || dev {
+				return util.NewReadableError(nil, "The --target and --dev flags cannot be used with --plan, they are read from the plan")
+			}
+			plan, err = project.ReadPlan(c.String("plan"))
+			if err != nil {
+				return err
+			}
+			target = plan.Target
+			dev = plan.Dev
+		}
+
 		var wg errgroup.Group
 		defer wg.Wait()
 		out := make(chan interface{})
@@ -152,10 +273,14 @@ var CmdDeploy = &cli.Command{
 		err = p.Run(c.Context, &project.StackInput{
 			Command:    "deploy",
 			Target:     target,
-			Dev:        c.Bool("dev"),
+			Dev:        dev,
 			ServerPort: s.Port,
 			Verbose:    c.Bool("verbose"),
 			Continue:   c.Bool("continue"),
+			Parallel:   parallel,
+			Plan:       plan,
+			TTL:        c.String("ttl"),
+			Labels:     labels,
 		})
 		if err != nil {
 			return err
@@ -163,3 +288,15 @@ var CmdDeploy = &cli.Command{
 		return nil
 	},
 }
+
+func parseParallel(c *cli.Cli) (int, error) {
+	value := c.String("parallel")
+	if value == "" {
+		return 0, nil
+	}
+	parallel, err := strconv.Atoi(value)
+	if err != nil || parallel < 1 {
+		return 0, util.NewReadableError(nil, "The --parallel flag needs to be a positive number")
+	}
+	return parallel, nil
+}
@@ -48,6 +48,16 @@ var CmdDiff = &cli.Command{
 			"```",
 			"",
 			"This is useful because in dev mode, you app is deployed a little differently.",
+			"",
+			"If you need to approve a change before it goes out, save the plan with `--out`.",
+			"",
+			"```bash frame=\"none\"",
+			"sst diff --stage production --out plan.json",
+			"```",
+			"",
+			"And then deploy exactly that plan with `sst deploy --plan plan.json`. The deploy",
+			"fails if your `sst.config.ts`, the state of the stage, the secrets, or the providers",
+			"have changed since the plan was saved.",
 		}, "\n"),
 	},
 	Flags: []cli.Flag{
@@ -68,6 +78,22 @@ var CmdDiff = &cli.Command{
 				}, "\n"),
 			},
 		},
+		{
+			Name: "out",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Save the plan to a file",
+				Long:  "Save the plan to the given file so it can be deployed with `sst deploy --plan`.",
+			},
+		},
+		{
+			Name: "parallel",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Limit concurrent operations",
+				Long:  "The maximum number of resource operations to run at the same time.",
+			},
+		},
 	},
 	Examples: []cli.Example{
 		{
@@ -89,6 +115,11 @@ var CmdDiff = &cli.Command{
 			target = strings.Split(c.String("target"), ",")
 		}
 
+		parallel, err := parseParallel(c)
+		if err != nil {
+			return err
+		}
+
 		var wg errgroup.Group
 		defer wg.Wait()
 		outputs := []*apitype.ResOutputsEvent{}
@@ -122,10 +153,15 @@ var CmdDiff = &cli.Command{
 			Dev:        c.Bool("dev"),
 			Target:     target,
 			Verbose:    c.Bool("verbose"),
+			Parallel:   parallel,
+			SavePlan:   c.String("out"),
 		})
 		if err != nil {
 			return err
 		}
+		if c.String("out") != "" {
+			defer ui.Success("Saved the plan to " + c.String("out"))
+		}
 		if len(outputs) == 0 {
 			fmt.Println(
 				ui.TEXT_HIGHLIGHT_BOLD.Render("➜"),
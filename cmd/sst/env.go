@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sst/sst/v3/cmd/sst/cli"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/internal/util"
+)
+
+var CmdEnv = &cli.Command{
+	Name: "env",
+	Description: cli.Description{
+		Short: "Export the environment of linked resources",
+		Long: strings.Join([]string{
+			"Writes the environment variables of your linked resources to a file or to stdout.",
+			"This is useful for tools that read the environment from a file, like IDE run",
+			"configurations, `docker compose`, or `direnv`.",
+			"",
+			"```bash frame=\"none\"",
+			"sst env --out .env",
+			"```",
+			"",
+			"By default, it includes all the links in your app. You can pick specific ones with",
+			"the `--links` flag.",
+			"",
+			"```bash frame=\"none\"",
+			"sst env --links MyBucket,MyTable",
+			"```",
+			"",
+			"Or use the same environment as a `dev` component, like `sst shell --target`.",
+			"",
+			"```bash frame=\"none\"",
+			"sst env --target MyApp",
+			"```",
+			"",
+			"The `--format` flag supports the following formats:",
+			"",
+			"- `dotenv`: The default, `KEY='value'` lines.",
+			"- `json`: A JSON object of the variables.",
+			"- `shell`: `export KEY='value'` lines that can be passed to `eval`.",
+			"- `docker`: Unquoted `KEY=value` lines for `docker run --env-file`.",
+			"",
+			"Pass in `--aws` to include the AWS credentials of your app, or `--role` to include",
+			"temporary credentials for a role. When the credentials expire, their expiry is added",
+			"as `AWS_CREDENTIAL_EXPIRATION` and as a comment in the formats that support it.",
+		}, "\n"),
+	},
+	Flags: []cli.Flag{
+		{
+			Name: "target",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Use the environment of a dev component",
+				Long:  "Use the environment of the given `dev` component.",
+			},
+		},
+		{
+			Name: "links",
+			Type: "string",
+			Description: cli.Description{
+				Short: "The links to include",
+				Long:  "A comma separated list of the links to include.",
+			},
+		},
+		{
+			Name: "format",
+			Type: "string",
+			Description: cli.Description{
+				Short: "The output format",
+				Long:  "The output format. One of `dotenv`, `json`, `shell`, or `docker`.",
+			},
+		},
+		{
+			Name: "out",
+			Type: "string",
+			Description: cli.Description{
+				Short: "The file to write to",
+				Long:  "The file to write to. Defaults to stdout.",
+			},
+		},
+		{
+			Name: "aws",
+			Type: "bool",
+			Description: cli.Description{
+				Short: "Include AWS credentials",
+				Long:  "Include the AWS credentials of your app.",
+			},
+		},
+		{
+			Name: "role",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Include credentials for a role",
+				Long:  "Assume the given IAM role and include its temporary credentials.",
+			},
+		},
+	},
+	Examples: []cli.Example{
+		{
+			Content: "sst env --format docker --out app.env",
+			Description: cli.Description{
+				Short: "Write an env file for docker",
+			},
+		},
+		{
+			Content: "eval \"$(sst env --format shell)\"",
+			Description: cli.Description{
+				Short: "Load the environment in your shell",
+			},
+		},
+	},
+	Run: func(c *cli.Cli) error {
+		format := c.String("format")
+		if format == "" {
+			format = "dotenv"
+		}
+		if format != "dotenv" && format != "json" && format != "shell" && format != "docker" {
+			return util.NewReadableError(nil, "Invalid format \""+format+"\". Use one of dotenv, json, shell, or docker")
+		}
+
+		p, err := c.InitProject()
+		if err != nil {
+			return err
+		}
+		defer p.Cleanup()
+
+		complete, err := p.GetCompleted(c.Context)
+		if err != nil {
+			return err
+		}
+
+		var env map[string]string
+		target := c.String("target")
+		if target != "" {
+			if _, ok := complete.Devs[target]; !ok {
+				return util.NewReadableError(nil, "Could not find a dev component named "+target)
+			}
+			env, err = p.EnvFor(c.Context, complete, target)
+		} else {
+			var links []string
+			if value := c.String("links"); value != "" {
+				for _, link := range strings.Split(value, ",") {
+					links = append(links, strings.TrimSpace(link))
+				}
+			}
+			env, err = p.EnvForLinks(complete, links)
+		}
+		if err != nil {
+			return util.NewReadableError(err, err.Error())
+		}
+
+		if c.Bool("aws") || c.String("role") != "" {
+			creds, err := p.AwsEnv(c.Context, c.String("role"))
+			if err != nil {
+				return util.NewReadableError(err, "Could not load AWS credentials: "+err.Error())
+			}
+			for key, value := range creds {
+				env[key] = value
+			}
+		}
+
+		var out io.Writer = os.Stdout
+		if path := c.String("out"); path != "" {
+			file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			out = file
+		}
+		err = writeEnv(out, format, env)
+		if err != nil {
+			return util.NewReadableError(err, err.Error())
+		}
+		if c.String("out") != "" {
+			ui.Success(fmt.Sprintf("Wrote %d variables to %s", len(env), c.String("out")))
+		}
+		return nil
+	},
+}
+
+func writeEnv(out io.Writer, format string, env map[string]string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(env)
+	}
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if expires, ok := env["AWS_CREDENTIAL_EXPIRATION"]; ok {
+		fmt.Fprintf(out, "# AWS credentials expire at %s\n", expires)
+	}
+	for _, key := range keys {
+		value := env[key]
+		switch format {
+		case "dotenv":
+			fmt.Fprintf(out, "%s=%s\n", key, quoteDotenv(value))
+		case "shell":
+			fmt.Fprintf(out, "export %s=%s\n", key, quoteShell(value))
+		case "docker":
+			// env files for docker don't support quoting or multiline values
+			if strings.ContainsAny(value, "\r\n") {
+				return fmt.Errorf("The value of %s has a newline which is not supported by the docker format", key)
+			}
+			fmt.Fprintf(out, "%s=%s\n", key, value)
+		}
+	}
+	return nil
+}
+
+func quoteShell(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// dotenv parsers don't support escapes in single quotes so fall back to
+// double quotes when the value has one
+func quoteDotenv(value string) string {
+	if !strings.Contains(value, "'") {
+		return "'" + value + "'"
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`)
+	return `"` + replacer.Replace(value) + `"`
+}
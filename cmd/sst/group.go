@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sst/sst/v3/cmd/sst/cli"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/process"
+	"github.com/sst/sst/v3/pkg/project"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	groupStatusDeployed  = "deployed"
+	groupStatusFailed    = "failed"
+	groupStatusSkipped   = "skipped"
+	groupStatusCancelled = "cancelled"
+)
+
+type groupResult struct {
+	label    string
+	stage    string
+	status   string
+	duration time.Duration
+}
+
+// Deploys every stage in a group by running `sst deploy` for each of them.
+// Each one runs in its own process since a deploy assumes it's the only one
+// running.
+func deployGroup(c *cli.Cli, p *project.Project, name string) error {
+	group, ok := p.App().Groups[name]
+	if !ok || len(group.Stages) == 0 {
+		return util.NewReadableError(nil, "Could not find a group named \""+name+"\" in your sst.config.ts")
+	}
+	mode := group.Mode
+	if mode == "" {
+		mode = "fail-fast"
+	}
+	if mode != "fail-fast" && mode != "best-effort" {
+		return util.NewReadableError(nil, "The mode of the \""+name+"\" group needs to be fail-fast or best-effort")
+	}
+	concurrency := group.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	labels := map[string]bool{}
+	stages := map[string]bool{}
+	results := make([]*groupResult, len(group.Stages))
+	for index, item := range group.Stages {
+		if item.Stage == "" {
+			return util.NewReadableError(nil, fmt.Sprintf("The stage at index %d of the \"%s\" group is missing a stage", index, name))
+		}
+		label := item.Name
+		if label == "" {
+			label = item.Stage
+		}
+		if labels[label] {
+			return util.NewReadableError(nil, fmt.Sprintf("The \"%s\" group deploys %s more than once, give each of them a unique name", name, label))
+		}
+		labels[label] = true
+		// deploys of the same stage would run against the same state
+		if stages[item.Stage] && concurrency > 1 {
+			return util.NewReadableError(nil, fmt.Sprintf("The \"%s\" group deploys the %s stage more than once, which can't be done at the same time. Set its concurrency to 1 or use a different stage for each of them", name, item.Stage))
+		}
+		stages[item.Stage] = true
+		// a provider that isn't installed would make every stage try to
+		// install it at the same time
+		for provider := range item.Providers {
+			if _, ok := p.App().Providers[provider]; !ok {
+				return util.NewReadableError(nil, fmt.Sprintf("The \"%s\" provider used by %s is not in the providers of your sst.config.ts", provider, label))
+			}
+		}
+		results[index] = &groupResult{
+			label:  label,
+			stage:  item.Stage,
+			status: groupStatusSkipped,
+		}
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := []string{"deploy"}
+	if c.Bool("continue") {
+		args = append(args, "--continue")
+	}
+	if c.String("parallel") != "" {
+		args = append(args, "--parallel", c.String("parallel"))
+	}
+	if c.Bool("verbose") {
+		args = append(args, "--verbose")
+	}
+
+	fmt.Println(ui.TEXT_HIGHLIGHT_BOLD.Render("➜"), ui.TEXT_NORMAL_BOLD.Render(fmt.Sprintf(" Deploying %d stages in %s", len(group.Stages), name)))
+	fmt.Println()
+
+	var output sync.Mutex
+	var failed bool
+	var wg errgroup.Group
+	wg.SetLimit(concurrency)
+	for index, item := range group.Stages {
+		result := results[index]
+		output.Lock()
+		stop := failed && mode == "fail-fast"
+		output.Unlock()
+		if stop || c.Context.Err() != nil {
+			break
+		}
+		overrides, err := json.Marshal(item.Providers)
+		if err != nil {
+			return err
+		}
+		// each stage gets its own logs and outputs so concurrent deploys
+		// don't write over each other
+		dir := filepath.Join(p.PathWorkingDir(), "group", name, result.label)
+		err = os.MkdirAll(filepath.Join(dir, "log"), 0755)
+		if err != nil {
+			return err
+		}
+		wg.Go(func() error {
+			if c.Context.Err() != nil {
+				return nil
+			}
+			output.Lock()
+			if failed && mode == "fail-fast" {
+				output.Unlock()
+				return nil
+			}
+			if concurrency == 1 {
+				fmt.Println(ui.TEXT_INFO_BOLD.Render(fmt.Sprintf("[%d/%d]", index+1, len(group.Stages))), ui.TEXT_NORMAL_BOLD.Render(result.label))
+			}
+			output.Unlock()
+
+			cmd := process.Command(executable, append(append([]string{}, args...), "--stage", item.Stage)...)
+			cmd.Env = append(os.Environ(),
+				"SST_PROVIDER_OVERRIDES="+string(overrides),
+				"SST_LOG_DIR="+filepath.Join(dir, "log"),
+				"SST_OUTPUTS_PATH="+filepath.Join(dir, "outputs.json"),
+			)
+			started := time.Now()
+			var err error
+			if concurrency == 1 {
+				cmd.Stdin = os.Stdin
+				cmd.Stdout = os.Stdout
+				cmd.Stderr = os.Stderr
+				err = cmd.Run()
+			} else {
+				err = runPrefixed(cmd, result.label, &output)
+			}
+			result.duration = time.Since(started)
+
+			output.Lock()
+			defer output.Unlock()
+			switch {
+			case c.Context.Err() != nil:
+				result.status = groupStatusCancelled
+			case err != nil:
+				result.status = groupStatusFailed
+				failed = true
+			default:
+				result.status = groupStatusDeployed
+			}
+			if concurrency == 1 {
+				fmt.Println()
+			}
+			return nil
+		})
+	}
+	wg.Wait()
+
+	printGroupSummary(results, filepath.Join(p.PathWorkingDir(), "group", name))
+	count := 0
+	for _, result := range results {
+		if result.status == groupStatusFailed {
+			count++
+		}
+	}
+	if count > 0 {
+		return util.NewReadableError(nil, fmt.Sprintf("%d of %d stages in %s failed to deploy", count, len(results), name))
+	}
+	if c.Context.Err() != nil {
+		return util.NewReadableError(nil, "Cancelled deploying "+name)
+	}
+	return nil
+}
+
+// Runs the command with each line of its output prefixed with the label so
+// the output of concurrent deploys can be told apart
+func runPrefixed(cmd *exec.Cmd, label string, output *sync.Mutex) error {
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	prefix := ui.TEXT_INFO_BOLD.Render(label) + ui.TEXT_DIM.Render(" | ")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			output.Lock()
+			fmt.Println(prefix + scanner.Text())
+			output.Unlock()
+		}
+	}()
+	err := cmd.Run()
+	writer.Close()
+	<-done
+	return err
+}
+
+func printGroupSummary(results []*groupResult, dir string) {
+	width := 0
+	for _, result := range results {
+		width = max(width, len(result.label))
+	}
+	fmt.Println(ui.TEXT_HIGHLIGHT_BOLD.Render("➜"), ui.TEXT_NORMAL_BOLD.Render(" Summary"))
+	for _, result := range results {
+		var status string
+		switch result.status {
+		case groupStatusDeployed:
+			status = ui.TEXT_SUCCESS_BOLD.Render(ui.IconCheck) + "  " + ui.TEXT_NORMAL.Render("Deployed")
+		case groupStatusFailed:
+			status = ui.TEXT_DANGER_BOLD.Render(ui.IconX) + "  " + ui.TEXT_NORMAL.Render("Failed")
+		case groupStatusCancelled:
+			status = ui.TEXT_WARNING_BOLD.Render("!") + "  " + ui.TEXT_NORMAL.Render("Cancelled")
+		default:
+			status = ui.TEXT_DIM.Render("-") + "  " + ui.TEXT_DIM.Render("Skipped")
+		}
+		line := "   " + ui.TEXT_NORMAL_BOLD.Render(result.label+strings.Repeat(" ", width-len(result.label))) + "  " + status
+		if result.label != result.stage {
+			line += ui.TEXT_DIM.Render(" " + result.stage)
+		}
+		if result.duration > 0 {
+			line += ui.TEXT_DIM.Render(" " + strconv.FormatFloat(result.duration.Seconds(), 'f', 0, 64) + "s")
+		}
+		fmt.Println(line)
+	}
+	fmt.Println(ui.TEXT_DIM.Render("   Logs and outputs of each stage are in " + dir))
+	fmt.Println()
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/sst/sst/v3/cmd/sst/cli"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/bus"
+	"github.com/sst/sst/v3/pkg/project"
+	"github.com/sst/sst/v3/pkg/server"
+	"golang.org/x/sync/errgroup"
+)
+
+var CmdImport = &cli.Command{
+	Name: "import",
+	Description: cli.Description{
+		Short: "Import existing resources",
+		Long: strings.Join([]string{
+			"Helps import resources that were created outside of your app.",
+			"",
+			"First generate the code for the resources, either from their IDs or by finding",
+			"the AWS resources with a tag.",
+			"",
+			"```bash frame=\"none\"",
+			"sst import generate --type aws:s3/bucketV2:BucketV2 --id my-bucket,my-other-bucket",
+			"sst import generate --tag team=payments --out imports.ts",
+			"```",
+			"",
+			"The generated code sets the inputs to the current values of the resources, so",
+			"once it's added to your `sst.config.ts` the import has nothing to change.",
+			"",
+			"Then check that every import matches.",
+			"",
+			"```bash frame=\"none\"",
+			"sst import check",
+			"```",
+			"",
+			"This lists the inputs that are still different in one table. Once it passes, run",
+			"`sst deploy` to import them.",
+		}, "\n"),
+	},
+	Children: []*cli.Command{
+		{
+			Name: "generate",
+			Description: cli.Description{
+				Short: "Generate the code to import resources",
+				Long: strings.Join([]string{
+					"Reads the given resources and generates the code to import them.",
+					"",
+					"Pass in the type of the resources and a comma separated list of their IDs.",
+					"",
+					"```bash frame=\"none\"",
+					"sst import generate --type aws:dynamodb/table:Table --id users,orders",
+					"```",
+					"",
+					"Or find the AWS resources that have all of the given tags.",
+					"",
+					"```bash frame=\"none\"",
+					"sst import generate --tag team=payments,env=production",
+					"```",
+					"",
+					"Finding resources by tag supports S3 buckets, DynamoDB tables, Lambda functions,",
+					"SQS queues, SNS topics, ECR repositories, log groups, Kinesis streams, and Step",
+					"Functions state machines. Other resources that are found are listed so they can",
+					"be imported by type.",
+					"",
+					"The code is printed out, or saved with `--out`. Nothing is deployed.",
+				}, "\n"),
+			},
+			Flags: []cli.Flag{
+				{
+					Name: "type",
+					Type: "string",
+					Description: cli.Description{
+						Short: "The type of the resources",
+						Long:  "The type of the resources to import, like `aws:s3/bucketV2:BucketV2`.",
+					},
+				},
+				{
+					Name: "id",
+					Type: "string",
+					Description: cli.Description{
+						Short: "The IDs of the resources",
+						Long:  "A comma separated list of the IDs of the resources to import.",
+					},
+				},
+				{
+					Name: "tag",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Find resources by tag",
+						Long:  "A comma separated list of `key=value` tags to find the AWS resources to import.",
+					},
+				},
+				{
+					Name: "out",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Save the code to a file",
+						Long:  "Save the generated code to the given file.",
+					},
+				},
+			},
+			Examples: []cli.Example{
+				{
+					Content: "sst import generate --type aws:s3/bucketV2:BucketV2 --id my-bucket",
+					Description: cli.Description{
+						Short: "Generate the code to import a bucket",
+					},
+				},
+			},
+			Run: func(c *cli.Cli) error {
+				if (c.String("type") == "") != (c.String("id") == "") {
+					return util.NewReadableError(nil, "Pass in both --type and --id")
+				}
+				if c.String("id") == "" && c.String("tag") == "" {
+					return util.NewReadableError(nil, "Pass in the resources to import with --type and --id, or --tag")
+				}
+				tags := map[string]string{}
+				for _, item := range splitList(c.String("tag")) {
+					key, value, ok := strings.Cut(item, "=")
+					if !ok || key == "" {
+						return util.NewReadableError(nil, "The --tag flag needs to be a list of key=value pairs")
+					}
+					tags[key] = value
+				}
+
+				p, err := c.InitProject()
+				if err != nil {
+					return err
+				}
+				defer p.Cleanup()
+
+				spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+				defer spin.Stop()
+				resources := []project.ImportResource{}
+				for _, id := range splitList(c.String("id")) {
+					resources = append(resources, project.ImportResource{
+						Type: c.String("type"),
+						ID:   id,
+					})
+				}
+				unsupported := []string{}
+				if len(tags) > 0 {
+					spin.Suffix = "  Finding resources..."
+					spin.Start()
+					found, skipped, err := p.DiscoverImports(c.Context, tags)
+					if err != nil {
+						return util.NewReadableError(err, "Could not find the resources with the given tags")
+					}
+					resources = append(resources, found...)
+					unsupported = skipped
+				}
+				if len(resources) == 0 {
+					spin.Stop()
+					printUnsupportedImports(unsupported)
+					return util.NewReadableError(nil, "No resources to import")
+				}
+				project.NameImports(resources)
+
+				spin.Suffix = fmt.Sprintf("  Reading %d resources...", len(resources))
+				spin.Start()
+				code, err := p.GenerateImports(c.Context, resources)
+				spin.Stop()
+				if err != nil {
+					return err
+				}
+				printUnsupportedImports(unsupported)
+				if c.String("out") != "" {
+					err := os.WriteFile(c.String("out"), []byte(code), 0644)
+					if err != nil {
+						return util.NewReadableError(err, "Could not write "+c.String("out"))
+					}
+					ui.Success(fmt.Sprintf("Saved the code for %d resources to %s. Add it to the run function of your sst.config.ts and then run `sst import check`.", len(resources), c.String("out")))
+					return nil
+				}
+				fmt.Println(code)
+				return nil
+			},
+		},
+		{
+			Name: "check",
+			Description: cli.Description{
+				Short: "Check the imports in your app",
+				Long: strings.Join([]string{
+					"Runs a diff of your app and lists every input of the imported resources that",
+					"doesn't match the resource in the cloud.",
+					"",
+					"```bash frame=\"none\"",
+					"sst import check --stage production",
+					"```",
+					"",
+					"Update the inputs in your `sst.config.ts` to the values in the Cloud column and",
+					"run it again until nothing is listed.",
+				}, "\n"),
+			},
+			Run: func(c *cli.Cli) error {
+				p, err := c.InitProject()
+				if err != nil {
+					return err
+				}
+				defer p.Cleanup()
+
+				var wg errgroup.Group
+				defer wg.Wait()
+				s, err := server.New()
+				if err != nil {
+					return err
+				}
+				wg.Go(func() error {
+					defer c.Cancel()
+					return s.Start(c.Context, p)
+				})
+				defer c.Cancel()
+
+				events := bus.Subscribe(&project.CompleteEvent{})
+				spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+				spin.Suffix = "  Checking imports..."
+				spin.Start()
+				err = p.Run(c.Context, &project.StackInput{
+					Command:    "diff",
+					ServerPort: s.Port,
+					Verbose:    c.Bool("verbose"),
+				})
+				spin.Stop()
+				var complete *project.CompleteEvent
+			drain:
+				for {
+					select {
+					case evt := <-events:
+						if evt, ok := evt.(*project.CompleteEvent); ok && !evt.Old {
+							complete = evt
+						}
+					default:
+						break drain
+					}
+				}
+				if complete == nil {
+					if err != nil {
+						return err
+					}
+					return util.NewReadableError(nil, "Could not check the imports")
+				}
+
+				count := printImportDiffs(complete.ImportDiffs)
+				others := []project.Error{}
+				for _, item := range complete.Errors {
+					if _, ok := complete.ImportDiffs[item.URN]; !ok {
+						others = append(others, item)
+					}
+				}
+				for _, item := range others {
+					if item.URN != "" {
+						fmt.Println(ui.TEXT_DANGER_BOLD.Render(item.URN))
+					}
+					fmt.Println(ui.TEXT_NORMAL.Render(item.Message))
+					fmt.Println()
+				}
+				if count > 0 {
+					return util.NewReadableError(nil, fmt.Sprintf("%d imported resources don't match the cloud", count))
+				}
+				if err != nil {
+					return err
+				}
+				ui.Success("All imports match")
+				return nil
+			},
+		},
+	},
+}
+
+func printUnsupportedImports(arns []string) {
+	if len(arns) == 0 {
+		return
+	}
+	fmt.Println(ui.TEXT_WARNING_BOLD.Render("!"), "", ui.TEXT_NORMAL_BOLD.Render("These resources need to be imported with --type and --id"))
+	for _, item := range arns {
+		fmt.Println("   " + ui.TEXT_DIM.Render(item))
+	}
+	fmt.Println()
+}
+
+// Prints every import diff as a row of one table and returns the number of
+// resources with diffs
+func printImportDiffs(diffs map[string][]project.ImportDiff) int {
+	if len(diffs) == 0 {
+		return 0
+	}
+	urns := []string{}
+	for urn := range diffs {
+		urns = append(urns, urn)
+	}
+	sort.Strings(urns)
+	rows := [][]string{{"Resource", "Input", "Cloud", "Config"}}
+	for _, urn := range urns {
+		name := urn
+		if index := strings.LastIndex(urn, "::"); index != -1 {
+			name = urn[index+2:]
+		}
+		for _, diff := range diffs[urn] {
+			rows = append(rows, []string{name, diff.Input, formatImportValue(diff.Old), formatImportValue(diff.New)})
+		}
+	}
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for index, cell := range row {
+			widths[index] = max(widths[index], len(cell))
+		}
+	}
+	for index, row := range rows {
+		cells := []string{}
+		for column, cell := range row {
+			if column < len(row)-1 {
+				cell += strings.Repeat(" ", widths[column]-len(cell))
+			}
+			cells = append(cells, cell)
+		}
+		line := strings.Join(cells, "  ")
+		if index == 0 {
+			fmt.Println("   " + ui.TEXT_NORMAL_BOLD.Render(line))
+			continue
+		}
+		fmt.Println("   " + ui.TEXT_NORMAL.Render(line))
+	}
+	fmt.Println()
+	return len(urns)
+}
+
+func formatImportValue(value interface{}) string {
+	if value == nil {
+		return "undefined"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
@@ -105,7 +105,10 @@ func run() error {
 		return err
 	}
 
-	if !flag.SST_SKIP_DEPENDENCY_CHECK {
+	// installing from a bundle is done offline and includes the dependencies
+	path := c.Path()
+	fromBundle := len(path) == 2 && path[1].Name == "install" && c.String("from") != ""
+	if !flag.SST_SKIP_DEPENDENCY_CHECK && !fromBundle {
 		spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
 		spin.Suffix = "  Download dependencies..."
 		if global.NeedsPulumi() {
@@ -624,8 +627,64 @@ var root = &cli.Command{
 					"Behind the scenes, it installs the packages for your providers and adds the providers to your globals.",
 					"",
 					"If you don't have a version specified for your providers in your `sst.config.ts`, it'll install their latest versions.",
+					"",
+					"If you need to install without internet access, create a bundle on a machine that has it.",
+					"",
+					"```bash frame=\"none\"",
+					"sst install --bundle sst-bundle.tar",
+					"```",
+					"",
+					"This includes the provider packages from your provider lock, their Pulumi plugins, the",
+					"Pulumi and bun binaries, and the platform. Then install the bundle offline on a machine",
+					"with the same OS, architecture, and version of SST.",
+					"",
+					"```bash frame=\"none\"",
+					"sst install --from sst-bundle.tar",
+					"```",
+					"",
+					"The providers in the bundle need to match your `.sst/provider-lock.json`, and their",
+					"packages are checked against the integrity in it. Every other file is checked against",
+					"the checksums in the bundle. Nothing is replaced unless the whole bundle checks out.",
+					"",
+					"The resolved providers are saved to `.sst/provider-lock.json`, along with the integrity",
+					"hash of their package and the version of their Pulumi plugin. Providers in the lock are",
+					"always looked up by their locked package, and the package that's downloaded is checked",
+					"against their integrity on every install.",
+					"",
+					"In CI, use `--frozen` to install exactly what's in the lock. It fails if the lock would",
+					"need to change, for example if a provider was added or its version changed.",
+					"",
+					"```bash frame=\"none\"",
+					"sst install --frozen",
+					"```",
 				}, "\n"),
 			},
+			Flags: []cli.Flag{
+				{
+					Name: "bundle",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Create an offline bundle",
+						Long:  "Install the providers and write them to the given file along with everything else needed to install offline.",
+					},
+				},
+				{
+					Name: "frozen",
+					Type: "bool",
+					Description: cli.Description{
+						Short: "Fail if the provider lock changes",
+						Long:  "Install the providers in the provider lock and fail if the lock would need to change.",
+					},
+				},
+				{
+					Name: "from",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Install from an offline bundle",
+						Long:  "Install from a bundle created with `--bundle`, without using the network.",
+					},
+				},
+			},
 			Run: func(cli *cli.Cli) error {
 				cfgPath, err := cli.Discover()
 				if err != nil {
@@ -648,6 +707,18 @@ var root = &cli.Command{
 
 				spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
 				defer spin.Stop()
+				if from := cli.String("from"); from != "" {
+					spin.Suffix = "  Installing from bundle..."
+					spin.Start()
+					err = p.InstallBundle(from)
+					if err != nil {
+						return err
+					}
+					spin.Stop()
+					ui.Success("Installed providers from " + from)
+					return nil
+				}
+
 				spin.Suffix = "  Installing providers..."
 				spin.Start()
 				if !p.CheckPlatform(version) {
@@ -657,10 +728,24 @@ var root = &cli.Command{
 					}
 				}
 
-				err = p.Install()
+				if cli.Bool("frozen") {
+					err = p.InstallFrozen()
+				} else {
+					err = p.Install()
+				}
 				if err != nil {
 					return err
 				}
+				if out := cli.String("bundle"); out != "" {
+					spin.Suffix = "  Creating bundle..."
+					err = p.Bundle(cli.Context, out)
+					if err != nil {
+						return err
+					}
+					spin.Stop()
+					ui.Success("Installed providers and created a bundle in " + out)
+					return nil
+				}
 				spin.Stop()
 				ui.Success("Installed providers")
 				return nil
@@ -782,6 +867,7 @@ var root = &cli.Command{
 			},
 			Run: CmdShell,
 		},
+		CmdEnv,
 		{
 			Name: "remove",
 			Description: cli.Description{
@@ -816,6 +902,14 @@ var root = &cli.Command{
 						Long:  "Only run it for the given component.",
 					},
 				},
+				{
+					Name: "parallel",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Limit concurrent operations",
+						Long:  "The maximum number of resource operations to run at the same time.",
+					},
+				},
 			},
 			Run: CmdRemove,
 		},
@@ -980,10 +1074,20 @@ var root = &cli.Command{
 						Long:  "Only run it for the given component.",
 					},
 				},
+				{
+					Name: "parallel",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Limit concurrent operations",
+						Long:  "The maximum number of resource operations to run at the same time.",
+					},
+				},
 			},
 			Run: CmdRefresh,
 		},
 		CmdState,
+		CmdStage,
+		CmdImport,
 		CmdCert,
 		CmdTunnel,
 		CmdDiagnostic,
@@ -286,6 +286,12 @@ func CmdMosaic(c *cli.Cli) error {
 								"SST_LOG="+p.PathLog("tunnel_"+name),
 							)...)
 						}
+						if len(evt.Tunnels) > 0 && p.App().Tunnel != nil && len(p.App().Tunnel.Forwards) > 0 {
+							multi.AddProcess("forward", []string{currentExecutable, "tunnel", "forward", "--stage", p.App().Stage}, "⇄", "Forwards", "", true, true, append(
+								multiEnv,
+								"SST_LOG="+p.PathLog("tunnel_forward"),
+							)...)
+						}
 						if len(evt.Tasks) > 0 {
 							multi.AddProcess("task", []string{currentExecutable, "ui", "--filter=task"}, "⧉", "Tasks", "", false, true, append(multiEnv, "SST_LOG="+p.PathLog("ui-task"))...)
 						}
@@ -342,6 +348,9 @@ func CmdMosaic(c *cli.Cli) error {
 						for range evt.Tunnels {
 							mono.AddProcess("tunnel", []string{currentExecutable, "tunnel", "--stage", p.App().Stage}, "", "Tunnel")
 						}
+						if len(evt.Tunnels) > 0 && p.App().Tunnel != nil && len(p.App().Tunnel.Forwards) > 0 {
+							mono.AddProcess("forward", []string{currentExecutable, "tunnel", "forward", "--stage", p.App().Stage}, "", "Forwards")
+						}
 						break
 					}
 				}
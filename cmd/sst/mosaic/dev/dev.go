@@ -3,6 +3,7 @@ package dev
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -10,7 +11,9 @@ import (
 	"reflect"
 
 	"github.com/sst/sst/v3/cmd/sst/mosaic/deployer"
+	"github.com/sst/sst/v3/internal/util"
 	"github.com/sst/sst/v3/pkg/bus"
+	"github.com/sst/sst/v3/pkg/emulator"
 	"github.com/sst/sst/v3/pkg/project"
 	"github.com/sst/sst/v3/pkg/server"
 	"golang.org/x/sync/errgroup"
@@ -23,12 +26,19 @@ type Message struct {
 
 func Start(ctx context.Context, p *project.Project, server *server.Server) error {
 	var complete *project.CompleteEvent
-	var wg errgroup.Group
+	wg, ctx := errgroup.WithContext(ctx)
 
 	log := slog.Default().With("service", "dev")
 	log.Info("starting")
 	defer log.Info("done")
 
+	// hold env requests until the emulators are up, this has to happen before
+	// any of the handlers are registered
+	emulate := len(p.App().Emulate) > 0
+	if emulate {
+		p.StartEmulator()
+	}
+
 	wg.Go(func() error {
 		evts := bus.Subscribe(&project.CompleteEvent{})
 		for {
@@ -119,6 +129,29 @@ func Start(ctx context.Context, p *project.Project, server *server.Server) error
 		return
 	})
 
+	if emulate {
+		wg.Go(func() error {
+			m, err := emulator.New(
+				filepath.Join(p.PathWorkingDir(), "emulator"),
+				fmt.Sprintf("sst-%s-%s", p.App().Name, p.App().Stage),
+				p.App().Emulate,
+			)
+			if err != nil {
+				p.SetEmulator(nil)
+				return util.NewReadableError(err, err.Error())
+			}
+			err = m.Start(ctx)
+			if err != nil {
+				p.SetEmulator(nil)
+				return util.NewReadableError(err, "Could not start the local emulators: "+err.Error())
+			}
+			defer m.Stop()
+			p.SetEmulator(m)
+			<-ctx.Done()
+			return nil
+		})
+	}
+
 	return wg.Wait()
 }
 
@@ -31,6 +31,8 @@ var transformers = []ErrorTransformer{
 	exact(provider.ErrCloudflareMissingAccount, "The Cloudflare Account ID was not able to be determined from this token. Make sure it has permissions to fetch account information or you can set the CLOUDFLARE_DEFAULT_ACCOUNT_ID environment variable to the account id you want to use."),
 	exact(server.ErrServerNotFound, "Could not find an `sst dev` session to connect to. Since you are running a command outside of the multiplexer be sure to start `sst dev` first."),
 	exact(provider.ErrBucketMissing, "The state bucket is missing, it may have been accidentally deleted. Go to https://console.aws.amazon.com/systems-manager/parameters/%252Fsst%252Fbootstrap/description?tab=Table and check if the state bucket mentioned there exists. If it doesn't you can recreate it or delete the `/sst/bootstrap` key to force recreation."),
+	exact(project.ErrImportNoAws, "Finding resources by tag needs the aws provider in your sst.config.ts. Import other resources with --type and --id instead."),
+	exact(project.ErrStageNotExpired, "The stage was deployed again and is no longer expired"),
 	exact(project.ErrProtectedStage, "Cannot remove protected stage. To remove a protected stage edit your sst.config.ts and remove the `protect` property."),
 	exact(provider.ErrLockNotFound, "This app / stage is not locked"),
 	exact(aws.ErrAppsyncNotReady, "SST creates an appsync event api to power live lambda. After 10 seconds of waiting this cli could not connect to it."),
@@ -49,6 +51,21 @@ var transformers = []ErrorTransformer{
 	match(func(err *project.ErrProviderVersionTooLow) string {
 		return fmt.Sprintf("You specified version %s of the \"%s\" provider. SST needs %s or higher.", err.Version, err.Name, err.Needed)
 	}),
+	match(func(err *project.ErrPlanInvalid) string {
+		return fmt.Sprintf("Cannot apply the plan because %s. Run `sst diff --out` to create a new plan.", err.Reason)
+	}),
+	match(func(err *project.ErrProviderIntegrity) string {
+		return fmt.Sprintf("Could not verify the \"%s\" provider, %s.", err.Name, err.Reason)
+	}),
+	match(func(err *project.ErrProviderLockChanged) string {
+		return "The provider lock is out of date. Run `sst install` without `--frozen` to update it.\n   - " + strings.Join(err.Changes, "\n   - ")
+	}),
+	match(func(err *project.ErrBundleInvalid) string {
+		return fmt.Sprintf("Cannot install the bundle because %s.", err.Reason)
+	}),
+	match(func(err *project.ErrImportFailed) string {
+		return "Could not read the resources to import:\n" + err.Output
+	}),
 	match(func(err *project.ErrVersionMismatch) string {
 		return fmt.Sprintf("You are using v%s which does not match v%s in your \"sst.config.ts\".", err.Needed, err.Received)
 	}),
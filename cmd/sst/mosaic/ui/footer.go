@@ -17,6 +17,7 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/deployer"
 	"github.com/sst/sst/v3/pkg/project"
+	sstresource "github.com/sst/sst/v3/pkg/server/resource"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
@@ -28,6 +29,7 @@ type footer struct {
 	summary     bool
 	pending     []*apitype.ResourcePreEvent
 	downloading map[string]*apitype.ProgressEvent
+	uploading   map[string]*sstresource.UploadProgressEvent
 	skipped     int
 	cancelled   bool
 
@@ -147,6 +149,7 @@ func (m *footer) Reset() {
 	m.parents = map[string]string{}
 	m.pending = []*apitype.ResourcePreEvent{}
 	m.downloading = map[string]*apitype.ProgressEvent{}
+	m.uploading = map[string]*sstresource.UploadProgressEvent{}
 	m.complete = nil
 	m.summary = false
 	m.cancelled = false
@@ -207,6 +210,8 @@ func (m *footer) Update(msg any) {
 		if msg.Type == apitype.PluginDownload {
 			m.downloading[msg.ID] = msg
 		}
+	case *sstresource.UploadProgressEvent:
+		m.uploading[msg.Bucket] = msg
 	case *apitype.SummaryEvent:
 		m.summary = true
 	case *apitype.ResOutputsEvent:
@@ -264,6 +269,22 @@ func (m *footer) View(width int) string {
 		percentage := int(float64(progress.Completed) / float64(progress.Total) * 100)
 		result = append(result, fmt.Sprintf("%s  %-11s %s %d%%", spinner, "Downloading", splits[1], percentage))
 	}
+	keys = make([]string, 0, len(m.uploading))
+	for k := range m.uploading {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		progress := m.uploading[key]
+		if progress.Done {
+			continue
+		}
+		percentage := 100
+		if progress.Total > 0 {
+			percentage = int(float64(progress.Completed) / float64(progress.Total) * 100)
+		}
+		result = append(result, fmt.Sprintf("%s  %-11s %s %d/%d files %d%%", spinner, "Uploading", key, progress.Uploaded, progress.Files, percentage))
+	}
 	for _, r := range m.pending {
 		label := "Creating"
 		if r.Metadata.Op == apitype.OpUpdate {
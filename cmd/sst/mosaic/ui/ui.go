@@ -22,6 +22,7 @@ import (
 	"github.com/sst/sst/v3/cmd/sst/mosaic/ui/common"
 	"github.com/sst/sst/v3/pkg/flag"
 	"github.com/sst/sst/v3/pkg/project"
+	sstresource "github.com/sst/sst/v3/pkg/server/resource"
 
 	"golang.org/x/crypto/ssh/terminal"
 )
@@ -375,6 +376,39 @@ func (u *UI) Event(unknown interface{}) {
 			u.printEvent(TEXT_INFO, "Info", "Downloaded provider "+splits[1])
 		}
 
+	case *sstresource.InvalidationEstimateEvent:
+		message := fmt.Sprintf("%s: %d billable paths in %d batches", evt.DistributionId, evt.Billable, evt.Batches)
+		if evt.Billable != evt.Requested {
+			message += fmt.Sprintf(", collapsed from %d", evt.Requested)
+		}
+		if evt.Billable > sstresource.INVALIDATION_FREE_PATHS {
+			u.printEvent(TEXT_WARNING, "Invalidate", message, fmt.Sprintf("CloudFront charges for every path over %d a month", sstresource.INVALIDATION_FREE_PATHS))
+			break
+		}
+		u.printEvent(TEXT_INFO, "Invalidate", message)
+
+	case *sstresource.FunctionDeploymentEvent:
+		name := evt.FunctionName + ":" + evt.Alias
+		switch evt.Status {
+		case sstresource.FunctionDeploymentStatusShifting:
+			u.printEvent(TEXT_INFO, "Shift", fmt.Sprintf("%s: %d%% of traffic to version %s", name, evt.Weight, evt.Version))
+		case sstresource.FunctionDeploymentStatusComplete:
+			u.printEvent(TEXT_SUCCESS, "Shift", fmt.Sprintf("%s: all traffic to version %s", name, evt.Version))
+		case sstresource.FunctionDeploymentStatusRolledBack:
+			reason := "the deployment failed"
+			if evt.Alarm != "" {
+				reason = "the " + evt.Alarm + " alarm went off"
+			}
+			u.printEvent(TEXT_DANGER, "Rollback", fmt.Sprintf("%s: back to version %s, %s", name, evt.Previous, reason))
+		}
+
+	case *sstresource.SqlMigrationEvent:
+		if evt.Status == sstresource.SqlMigrationStatusPending {
+			u.printEvent(TEXT_WARNING, "Migrate", fmt.Sprintf("%s: %s would be applied", evt.DatabaseName, evt.Name))
+			break
+		}
+		u.printEvent(TEXT_SUCCESS, "Migrate", fmt.Sprintf("%s: applied %s", evt.DatabaseName, evt.Name))
+
 	case *project.CompleteEvent:
 		u.complete = evt
 		if evt.Old {
@@ -495,6 +529,17 @@ func (u *UI) Event(unknown interface{}) {
 				}
 			}
 
+			if len(evt.Skipped) > 0 {
+				u.println(
+					TEXT_WARNING_BOLD.Render("Skipped"),
+					TEXT_NORMAL.Render(fmt.Sprintf(" %d resources that depend on the failed ones:", len(evt.Skipped))),
+				)
+				for _, urn := range evt.Skipped {
+					u.println(TEXT_DIM.Render("   " + u.FormatURN(urn)))
+				}
+				u.blank()
+			}
+
 			if evt.UpdateID != "" {
 				u.blank()
 				u.println(
@@ -23,6 +23,11 @@ func CmdRefresh(c *cli.Cli) error {
 		target = strings.Split(c.String("target"), ",")
 	}
 
+	parallel, err := parseParallel(c)
+	if err != nil {
+		return err
+	}
+
 	var wg errgroup.Group
 	defer wg.Wait()
 	ui := ui.New(c.Context)
@@ -49,6 +54,7 @@ func CmdRefresh(c *cli.Cli) error {
 		Target:     target,
 		ServerPort: s.Port,
 		Verbose:    c.Bool("verbose"),
+		Parallel:   parallel,
 	})
 	if err != nil {
 		return err
@@ -6,6 +6,7 @@ import (
 	"github.com/sst/sst/v3/cmd/sst/cli"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
 	"github.com/sst/sst/v3/pkg/bus"
+	"github.com/sst/sst/v3/pkg/flag"
 	"github.com/sst/sst/v3/pkg/project"
 	"github.com/sst/sst/v3/pkg/server"
 	"golang.org/x/sync/errgroup"
@@ -23,6 +24,11 @@ func CmdRemove(c *cli.Cli) error {
 		target = strings.Split(c.String("target"), ",")
 	}
 
+	parallel, err := parseParallel(c)
+	if err != nil {
+		return err
+	}
+
 	var wg errgroup.Group
 	defer wg.Wait()
 	ui := ui.New(c.Context)
@@ -49,6 +55,9 @@ func CmdRemove(c *cli.Cli) error {
 		Target:     target,
 		ServerPort: s.Port,
 		Verbose:    c.Bool("verbose"),
+		Parallel:   parallel,
+		// set by `sst stage gc` so the expiry is checked again under the lock
+		Expired: flag.SST_REMOVE_EXPIRED,
 	})
 	if err != nil {
 		return err
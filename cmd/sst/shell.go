@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,7 +9,6 @@ import (
 	"github.com/sst/sst/v3/cmd/sst/cli"
 	"github.com/sst/sst/v3/internal/util"
 	"github.com/sst/sst/v3/pkg/process"
-	"github.com/sst/sst/v3/pkg/project/provider"
 )
 
 func CmdShell(c *cli.Cli) error {
@@ -67,30 +65,23 @@ func CmdShell(c *cli.Cli) error {
 			key, value, _ := strings.Cut(item, "=")
 			env[key] = value
 		}
-		for resource, value := range complete.Links {
-			jsonValue, err := json.Marshal(value.Properties)
-			if err != nil {
-				return err
-			}
-			env[fmt.Sprintf("SST_RESOURCE_%s", resource)] = string(jsonValue)
+		links, err := p.EnvForLinks(complete, nil)
+		if err != nil {
+			return err
+		}
+		for key, value := range links {
+			env[key] = value
 		}
-		env["SST_RESOURCE_App"] = fmt.Sprintf(`{"name": "%s", "stage": "%s" }`, p.App().Name, p.App().Stage)
 
-		aws, ok := p.Provider("aws")
-		if ok {
+		if _, ok := p.Provider("aws"); ok {
 			// newer versions of aws-sdk do not like it when you specify both profile and credentials
 			delete(env, "AWS_PROFILE")
-			provider := aws.(*provider.AwsProvider)
-			cfg := provider.Config()
-			creds, err := cfg.Credentials.Retrieve(c.Context)
+			creds, err := p.AwsEnv(c.Context, "")
 			if err != nil {
 				return err
 			}
-			cmd.Env = append(cmd.Env, fmt.Sprintf("AWS_ACCESS_KEY_ID=%s", creds.AccessKeyID))
-			cmd.Env = append(cmd.Env, fmt.Sprintf("AWS_SECRET_ACCESS_KEY=%s", creds.SecretAccessKey))
-			cmd.Env = append(cmd.Env, fmt.Sprintf("AWS_SESSION_TOKEN=%s", creds.SessionToken))
-			if cfg.Region != "" {
-				cmd.Env = append(cmd.Env, fmt.Sprintf("AWS_REGION=%s", cfg.Region))
+			for key, value := range creds {
+				cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
 			}
 		}
 
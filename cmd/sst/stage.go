@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/sst/v3/cmd/sst/cli"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/process"
+	"github.com/sst/sst/v3/pkg/project"
+	"github.com/sst/sst/v3/pkg/project/provider"
+)
+
+var CmdStage = &cli.Command{
+	Name: "stage",
+	Description: cli.Description{
+		Short: "Manage the stages of your app",
+	},
+	Children: []*cli.Command{
+		{
+			Name: "clone",
+			Description: cli.Description{
+				Short: "Copy the secrets of a stage to another",
+				Long: strings.Join([]string{
+					"Copies the secrets of one stage to another, so a new stage can be deployed",
+					"without setting each of them again.",
+					"",
+					"```bash frame=\"none\"",
+					"sst stage clone --from staging --to feature-x",
+					"```",
+					"",
+					"The secrets are encrypted again for the new stage. Secrets that the new stage",
+					"already has are kept, unless they are cloned over. The state and the lock of",
+					"the stage are never copied.",
+					"",
+					"Only clone some of the secrets with `--include` or `--exclude`. These take a",
+					"comma separated list of names, where `*` matches any part of a name.",
+					"",
+					"```bash frame=\"none\"",
+					"sst stage clone --from staging --to feature-x --exclude \"Stripe*\"",
+					"```",
+					"",
+					"And change the values as they are cloned with `--replace`, a comma separated",
+					"list of `old=new` pairs.",
+					"",
+					"```bash frame=\"none\"",
+					"sst stage clone --from staging --to feature-x --replace staging.example.com=feature-x.example.com",
+					"```",
+					"",
+					"To see which secrets would be cloned, use `--dry-run`.",
+				}, "\n"),
+			},
+			Flags: []cli.Flag{
+				{
+					Name: "from",
+					Type: "string",
+					Description: cli.Description{
+						Short: "The stage to copy from",
+						Long:  "The stage to copy the secrets from.",
+					},
+				},
+				{
+					Name: "to",
+					Type: "string",
+					Description: cli.Description{
+						Short: "The stage to copy to",
+						Long:  "The stage to copy the secrets to.",
+					},
+				},
+				{
+					Name: "include",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Only clone these secrets",
+						Long:  "A comma separated list of the secrets to clone.",
+					},
+				},
+				{
+					Name: "exclude",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Skip these secrets",
+						Long:  "A comma separated list of the secrets to skip.",
+					},
+				},
+				{
+					Name: "replace",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Change the cloned values",
+						Long:  "A comma separated list of `old=new` pairs to replace in the cloned values.",
+					},
+				},
+				{
+					Name: "dry-run",
+					Type: "bool",
+					Description: cli.Description{
+						Short: "List the secrets to clone",
+						Long:  "List the secrets that would be cloned without cloning them.",
+					},
+				},
+			},
+			Examples: []cli.Example{
+				{
+					Content: "sst stage clone --from staging --to feature-x",
+					Description: cli.Description{
+						Short: "Copy the secrets of staging to feature-x",
+					},
+				},
+			},
+			Run: func(c *cli.Cli) error {
+				from := c.String("from")
+				to := c.String("to")
+				if from == "" || to == "" {
+					return util.NewReadableError(nil, "Pass in the stages to clone with --from and --to")
+				}
+				if from == to {
+					return util.NewReadableError(nil, "The --from and --to stages need to be different")
+				}
+				replace, err := project.ParseCloneReplace(c.String("replace"))
+				if err != nil {
+					return util.NewReadableError(err, "The --replace flag needs to be a list of old=new pairs")
+				}
+				rules := &project.CloneRules{
+					Include: splitList(c.String("include")),
+					Exclude: splitList(c.String("exclude")),
+					Replace: replace,
+				}
+
+				p, err := c.InitProject()
+				if err != nil {
+					return err
+				}
+				defer p.Cleanup()
+
+				cloned, err := p.CloneSecrets(from, to, rules, c.Bool("dry-run"))
+				if err != nil {
+					return util.NewReadableError(err, fmt.Sprintf("Could not clone the secrets of %s to %s", from, to))
+				}
+				if len(cloned) == 0 {
+					return util.NewReadableError(nil, "No secrets to clone from "+from)
+				}
+				names := []string{}
+				for name := range cloned {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				if c.Bool("dry-run") {
+					fmt.Println(ui.TEXT_HIGHLIGHT_BOLD.Render("➜"), ui.TEXT_NORMAL_BOLD.Render(fmt.Sprintf(" %d secrets would be cloned to %s", len(names), to)))
+					for _, name := range names {
+						fmt.Println("   " + ui.TEXT_NORMAL_BOLD.Render(name))
+					}
+					fmt.Println()
+					return nil
+				}
+				ui.Success(fmt.Sprintf("Cloned %d secrets from %s to %s: %s", len(names), from, to, strings.Join(names, ", ")))
+				return nil
+			},
+		},
+		{
+			Name: "gc",
+			Description: cli.Description{
+				Short: "Remove expired stages",
+				Long: strings.Join([]string{
+					"Removes the stages of your app that were deployed with a `--ttl` and haven't",
+					"been deployed since.",
+					"",
+					"```bash frame=\"none\"",
+					"sst stage gc",
+					"```",
+					"",
+					"Each stage is removed like it would be with `sst remove`. Stages that are",
+					"protected in your `sst.config.ts` are skipped.",
+					"",
+					"To see which stages would be removed, along with roughly how many resources",
+					"they have, use `--dry-run`.",
+					"",
+					"```bash frame=\"none\"",
+					"sst stage gc --dry-run",
+					"```",
+				}, "\n"),
+			},
+			Flags: []cli.Flag{
+				{
+					Name: "dry-run",
+					Type: "bool",
+					Description: cli.Description{
+						Short: "List the expired stages",
+						Long:  "List the expired stages without removing them.",
+					},
+				},
+			},
+			Run: func(c *cli.Cli) error {
+				p, err := c.InitProject()
+				if err != nil {
+					return err
+				}
+				defer p.Cleanup()
+
+				expired, err := expiredStages(p)
+				if err != nil {
+					return err
+				}
+				if len(expired) == 0 {
+					ui.Success("No expired stages")
+					return nil
+				}
+
+				if c.Bool("dry-run") {
+					fmt.Println(ui.TEXT_HIGHLIGHT_BOLD.Render("➜"), ui.TEXT_NORMAL_BOLD.Render(fmt.Sprintf(" %d expired stages", len(expired))))
+					for _, stage := range expired {
+						metadata := stage.metadata
+						fmt.Println("   " + ui.TEXT_NORMAL_BOLD.Render(stage.name))
+						fmt.Println(ui.TEXT_DIM.Render("     Creator   ") + metadata.Creator)
+						fmt.Println(ui.TEXT_DIM.Render("     Created   ") + metadata.Created)
+						fmt.Println(ui.TEXT_DIM.Render("     Deployed  ") + metadata.Deployed)
+						fmt.Println(ui.TEXT_DIM.Render("     TTL       ") + metadata.TTL)
+						if len(metadata.Labels) > 0 {
+							labels := []string{}
+							for key, value := range metadata.Labels {
+								labels = append(labels, key+"="+value)
+							}
+							sort.Strings(labels)
+							fmt.Println(ui.TEXT_DIM.Render("     Labels    ") + strings.Join(labels, ", "))
+						}
+						count, err := countResources(p, stage.name)
+						if err != nil {
+							fmt.Println(ui.TEXT_DIM.Render("     Resources ") + "unknown")
+							continue
+						}
+						fmt.Println(ui.TEXT_DIM.Render("     Resources ") + fmt.Sprintf("%d", count))
+					}
+					fmt.Println()
+					return nil
+				}
+
+				cfgPath, err := c.Discover()
+				if err != nil {
+					return err
+				}
+				executable, err := os.Executable()
+				if err != nil {
+					return err
+				}
+				removed := 0
+				failed := 0
+				for _, stage := range expired {
+					if c.Context.Err() != nil {
+						break
+					}
+					// protect is usually based on the stage so the config needs to
+					// be evaluated for each of them
+					sp, err := project.New(&project.ProjectConfig{
+						Version: version,
+						Config:  cfgPath,
+						Stage:   stage.name,
+					})
+					if err != nil {
+						return err
+					}
+					protected := sp.App().Protect
+					sp.Cleanup()
+					if protected {
+						fmt.Println(ui.TEXT_WARNING_BOLD.Render("!"), "", ui.TEXT_NORMAL_BOLD.Render(stage.name), ui.TEXT_DIM.Render("is protected, skipping"))
+						continue
+					}
+					fmt.Println(ui.TEXT_HIGHLIGHT_BOLD.Render("➜"), ui.TEXT_NORMAL_BOLD.Render(" Removing "+stage.name))
+					fmt.Println()
+					cmd := process.Command(executable, "remove", "--stage", stage.name)
+					cmd.Stdin = os.Stdin
+					cmd.Stdout = os.Stdout
+					cmd.Stderr = os.Stderr
+					cmd.Env = append(os.Environ(), "SST_REMOVE_EXPIRED=true")
+					if err := cmd.Run(); err != nil {
+						// the stage might have been deployed since it was listed
+						if !stillExpired(p, stage.name) {
+							continue
+						}
+						failed++
+						continue
+					}
+					removed++
+				}
+				if failed > 0 {
+					return util.NewReadableError(nil, fmt.Sprintf("Removed %d expired stages, %d failed to be removed", removed, failed))
+				}
+				ui.Success(fmt.Sprintf("Removed %d expired stages", removed))
+				return nil
+			},
+		},
+	},
+}
+
+type expiredStage struct {
+	name     string
+	metadata *provider.StageMetadata
+}
+
+func expiredStages(p *project.Project) ([]expiredStage, error) {
+	stages, err := provider.ListStages(p.Backend(), p.App().Name)
+	if err != nil {
+		return nil, util.NewReadableError(err, "Could not list stages")
+	}
+	sort.Strings(stages)
+	now := time.Now()
+	result := []expiredStage{}
+	for _, stage := range stages {
+		metadata, err := provider.GetStageMetadata(p.Backend(), p.App().Name, stage)
+		if err != nil {
+			return nil, util.NewReadableError(err, "Could not read the metadata of "+stage)
+		}
+		if metadata == nil {
+			continue
+		}
+		expires, ok := metadata.Expires()
+		if !ok || expires.After(now) {
+			continue
+		}
+		result = append(result, expiredStage{stage, metadata})
+	}
+	return result, nil
+}
+
+func stillExpired(p *project.Project, stage string) bool {
+	metadata, err := provider.GetStageMetadata(p.Backend(), p.App().Name, stage)
+	if err != nil || metadata == nil {
+		return err != nil
+	}
+	expires, ok := metadata.Expires()
+	return ok && !expires.After(time.Now())
+}
+
+// An estimate of the resources in the stage from its last state, without the
+// stack and the providers
+func countResources(p *project.Project, stage string) (int, error) {
+	dir, err := os.MkdirTemp("", "sst-stage-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	err = provider.PullState(p.Backend(), p.App().Name, stage, path)
+	if err != nil {
+		return 0, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var untyped apitype.VersionedCheckpoint
+	err = json.Unmarshal(data, &untyped)
+	if err != nil {
+		return 0, err
+	}
+	var checkpoint apitype.CheckpointV3
+	err = json.Unmarshal(untyped.Checkpoint, &checkpoint)
+	if err != nil {
+		return 0, err
+	}
+	if checkpoint.Latest == nil {
+		return 0, nil
+	}
+	count := 0
+	for _, resource := range checkpoint.Latest.Resources {
+		if resource.Type == "pulumi:pulumi:Stack" || strings.HasPrefix(string(resource.Type), "pulumi:providers:") {
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+func splitList(value string) []string {
+	result := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
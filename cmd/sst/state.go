@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/sst/sst/v3/cmd/sst/cli"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
 	"github.com/sst/sst/v3/internal/util"
@@ -139,6 +140,126 @@ var CmdState = &cli.Command{
 				return err
 			},
 		},
+		{
+			Name: "graph",
+			Flags: []cli.Flag{
+				{
+					Name: "format",
+					Type: "string",
+					Description: cli.Description{
+						Short: "The format of the graph",
+						Long:  "The format of the graph, one of `dot`, `mermaid`, or `json`. Defaults to `dot`.",
+					},
+				},
+				{
+					Name: "type",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Only graph these types",
+						Long:  "A comma separated list of the types of resources to graph, along with their children. A type ending in `*` matches every type that starts with it.",
+					},
+				},
+				{
+					Name: "root",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Only graph this resource",
+						Long:  "The name of the resource to graph, along with its children.",
+					},
+				},
+			},
+			Description: cli.Description{
+				Short: "Prints a graph of the resources in your app",
+				Long: strings.Join([]string{
+					"Prints the components in your app along with the resources in them, and the",
+					"dependencies between the resources.",
+					"",
+					"```bash frame=\"none\"",
+					"sst state graph --stage production | dot -Tsvg > graph.svg",
+					"```",
+					"",
+					"It can also be printed as a Mermaid flowchart or as JSON.",
+					"",
+					"```bash frame=\"none\"",
+					"sst state graph --format mermaid",
+					"```",
+					"",
+					"Graph only some of the resources by their type or by a component. Either of",
+					"these include everything in the resources that match.",
+					"",
+					"```bash frame=\"none\"",
+					"sst state graph --type \"sst:aws:Function\"",
+					"sst state graph --root MyApi",
+					"```",
+					"",
+					"Resources that failed in the last deploy are highlighted.",
+				}, "\n"),
+			},
+			Run: func(c *cli.Cli) error {
+				format := c.String("format")
+				if format == "" {
+					format = "dot"
+				}
+				if format != "dot" && format != "mermaid" && format != "json" {
+					return util.NewReadableError(nil, "The --format flag needs to be dot, mermaid, or json")
+				}
+				p, err := c.InitProject()
+				if err != nil {
+					return err
+				}
+				defer p.Cleanup()
+				workdir, err := p.NewWorkdir(id.Descending())
+				if err != nil {
+					return err
+				}
+				defer workdir.Cleanup()
+
+				_, err = workdir.Pull()
+				if err != nil {
+					return util.NewReadableError(err, "Could not pull state")
+				}
+				checkpoint, err := workdir.Export()
+				if err != nil {
+					return util.NewReadableError(err, "Could not export state")
+				}
+				if checkpoint.Latest == nil {
+					return util.NewReadableError(nil, "There are no resources in this stage")
+				}
+
+				failed := map[resource.URN]bool{}
+				update, err := provider.GetLastUpdate(p.Backend(), p.App().Name, p.App().Stage)
+				if err != nil {
+					return err
+				}
+				if update != nil {
+					for _, item := range update.Errors {
+						failed[resource.URN(item.URN)] = true
+					}
+				}
+				opts := state.GraphOptions{
+					Root:   c.String("root"),
+					Failed: failed,
+				}
+				if c.String("type") != "" {
+					opts.Types = strings.Split(c.String("type"), ",")
+				}
+				graph := state.NewGraph(checkpoint.Latest.Resources, opts)
+				if len(graph.Nodes) == 0 {
+					return util.NewReadableError(nil, "No resources match")
+				}
+				switch format {
+				case "mermaid":
+					fmt.Print(graph.Mermaid())
+				case "json":
+					encoder := json.NewEncoder(os.Stdout)
+					encoder.SetIndent("", "  ")
+					return encoder.Encode(graph)
+				default:
+					fmt.Print(graph.DOT())
+				}
+				return nil
+			},
+		},
 		{
 			Name: "list",
 			// TODO: Fix https://github.com/sst/sst/issues/5566 before enabling
@@ -1,21 +1,27 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"os"
 	"os/user"
+	"sort"
 	"strings"
 
 	"github.com/sst/sst/v3/cmd/sst/cli"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/dev"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
 	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/bus"
 	"github.com/sst/sst/v3/pkg/process"
 	"github.com/sst/sst/v3/pkg/project"
 	"github.com/sst/sst/v3/pkg/server"
 	"github.com/sst/sst/v3/pkg/tunnel"
+	"golang.org/x/sync/errgroup"
 )
 
 var CmdTunnel = &cli.Command{
@@ -57,6 +63,18 @@ var CmdTunnel = &cli.Command{
 			"",
 			"This needs a network interface on your local machine. You can create this",
 			"with the `sst tunnel install` command.",
+			"",
+			"If you can't use `sudo`, run a single command through the tunnel instead.",
+			"",
+			"```bash frame=\"none\"",
+			"sst tunnel exec -- curl http://10.0.4.12:3000",
+			"```",
+			"",
+			"Or forward a local port to a host in the VPC, like a database.",
+			"",
+			"```bash frame=\"none\"",
+			"sst tunnel forward 5432:mydb.cluster-xyz.us-east-1.rds.amazonaws.com:5432",
+			"```",
 		}, "\n"),
 	},
 	Run: func(c *cli.Cli) error {
@@ -64,72 +82,39 @@ var CmdTunnel = &cli.Command{
 			return util.NewReadableError(nil, "The sst tunnel needs to be installed or upgraded. Run `sudo sst tunnel install`")
 		}
 
-		cfgPath, err := c.Discover()
-		if err != nil {
-			return err
-		}
-
 		slog.Info("starting tunnel")
-
-		var completed *project.CompleteEvent
-
-		stage, err := c.Stage(cfgPath)
+		bastions, err := resolveBastions(c)
 		if err != nil {
 			return err
 		}
-
-		if url, err := server.Discover(cfgPath, stage); err == nil {
-			completed, err = dev.Completed(c.Context, url)
-			if err != nil {
-				return err
-			}
-		} else {
-			proj, err := c.InitProject()
-			if err != nil {
-				return err
-			}
-			completed, err = proj.GetCompleted(c.Context)
-			if err != nil {
-				return err
-			}
-		}
-
+		encoded, err := json.Marshal(bastions)
 		if err != nil {
 			return err
 		}
-		if len(completed.Tunnels) == 0 {
-			return util.NewReadableError(nil, "No tunnels found for stage "+stage)
-		}
-		var tun project.Tunnel
-		for _, item := range completed.Tunnels {
-			tun = item
-		}
-		subnets := strings.Join(tun.Subnets, ",")
 		// run as root
 		tunnelCmd := process.CommandContext(
 			c.Context,
 			"sudo", "-n", "-E",
 			tunnel.BINARY_PATH, "tunnel", "start",
-			"--subnets", subnets,
-			"--host", tun.IP,
-			"--user", tun.Username,
 			"--print-logs",
 		)
 		tunnelCmd.Env = append(
 			os.Environ(),
 			"SST_SKIP_LOCAL=true",
 			"SST_SKIP_DEPENDENCY_CHECK=true",
-			"SSH_PRIVATE_KEY="+tun.PrivateKey,
+			"SST_TUNNELS="+string(encoded),
 			"SST_LOG="+strings.ReplaceAll(os.Getenv("SST_LOG"), ".log", "_sudo.log"),
 		)
 		tunnelCmd.Stdout = os.Stdout
 		slog.Info("starting tunnel", "cmd", tunnelCmd.Args)
 		fmt.Println(ui.TEXT_HIGHLIGHT_BOLD.Render("Tunnel"))
-		fmt.Println()
-		fmt.Print(ui.TEXT_HIGHLIGHT_BOLD.Render("➜"))
-		fmt.Println(ui.TEXT_NORMAL.Render("  Forwarding ranges"))
-		for _, subnet := range tun.Subnets {
-			fmt.Println(ui.TEXT_DIM.Render("   " + subnet))
+		for _, bastion := range bastions {
+			fmt.Println()
+			fmt.Print(ui.TEXT_HIGHLIGHT_BOLD.Render("➜"))
+			fmt.Println(ui.TEXT_NORMAL.Render("  Forwarding ranges via " + bastion.Name))
+			for _, subnet := range bastion.Subnets {
+				fmt.Println(ui.TEXT_DIM.Render("   " + subnet))
+			}
 		}
 		fmt.Println()
 		fmt.Println(ui.TEXT_DIM.Render("Waiting for connections..."))
@@ -176,6 +161,188 @@ var CmdTunnel = &cli.Command{
 				return nil
 			},
 		},
+		{
+			Name: "exec",
+			Description: cli.Description{
+				Short: "Run a command through the tunnel",
+				Long: strings.Join([]string{
+					"Run a command with its traffic forwarded through the tunnel.",
+					"",
+					"```bash frame=\"none\"",
+					"sst tunnel exec -- curl http://10.0.4.12:3000",
+					"```",
+					"",
+					"Unlike `sst tunnel`, this does not need a network interface so it doesn't",
+					"require `sudo` or `sst tunnel install`. Instead, it starts a local SOCKS5 proxy",
+					"and points the command at it through the `ALL_PROXY`, `HTTP_PROXY`, and",
+					"`HTTPS_PROXY` environment variables.",
+					"",
+					":::note",
+					"Only tools that respect these proxy environment variables will use the tunnel.",
+					"Database clients like `psql` and `mysql` don't, use `sst tunnel forward` for those.",
+					":::",
+					"",
+					"The proxy URL is also available as `SST_TUNNEL_PROXY` for tools that need it",
+					"passed in explicitly.",
+				}, "\n"),
+			},
+			Args: []cli.Argument{
+				{
+					Name:     "command",
+					Required: true,
+					Description: cli.Description{
+						Short: "The command to run",
+						Long:  "The command to run.",
+					},
+				},
+			},
+			Examples: []cli.Example{
+				{
+					Content: "sst tunnel exec -- curl http://10.0.4.12:3000",
+					Description: cli.Description{
+						Short: "Make a request to a service in the VPC",
+					},
+				},
+			},
+			Run: func(c *cli.Cli) error {
+				args := c.Arguments()
+				if len(args) == 0 {
+					return util.NewReadableError(nil, "No command specified. Run `sst tunnel exec -- <command>`")
+				}
+				bastions, err := resolveBastions(c)
+				if err != nil {
+					return err
+				}
+				router, err := tunnel.NewRouter(bastions...)
+				if err != nil {
+					return err
+				}
+				ctx, cancel := context.WithCancel(c.Context)
+				defer cancel()
+				err = router.Connect(ctx)
+				if err != nil {
+					return util.NewReadableError(err, "Could not connect to the bastion host: "+err.Error())
+				}
+				defer router.Close()
+				listener, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					return err
+				}
+				go tunnel.ServeProxy(ctx, listener, router.Dial, func(network, addr string) {
+					slog.Info("tunneling", "network", network, "addr", addr)
+				})
+				slog.Info("running command through tunnel", "proxy", listener.Addr().String(), "args", args)
+				cmd := process.Command(args[0], args[1:]...)
+				cmd.Env = append(os.Environ(), tunnel.ProxyEnv(listener.Addr().String())...)
+				cmd.Stdout = os.Stdout
+				cmd.Stderr = os.Stderr
+				cmd.Stdin = os.Stdin
+				err = cmd.Run()
+				if err != nil {
+					return util.NewReadableError(err, err.Error())
+				}
+				return nil
+			},
+		},
+		{
+			Name: "forward",
+			Description: cli.Description{
+				Short: "Forward local ports to hosts in the VPC",
+				Long: strings.Join([]string{
+					"Forward a local port to a single host in the VPC.",
+					"",
+					"```bash frame=\"none\"",
+					"sst tunnel forward 5432:mydb.cluster-xyz.us-east-1.rds.amazonaws.com:5432",
+					"```",
+					"",
+					"This opens a TCP listener on `localhost:5432` and connects it to the host",
+					"over SSH through the bastion. It doesn't need a network interface, so there's",
+					"no need for `sudo` or `sst tunnel install`.",
+					"",
+					"If no forwards are passed in, the ones in the `tunnel.forwards` of your",
+					"`sst.config.ts` are started.",
+					"",
+					"```ts title=\"sst.config.ts\"",
+					"tunnel: {",
+					"  forwards: {",
+					"    database: \"5432:mydb.cluster-xyz.us-east-1.rds.amazonaws.com:5432\"",
+					"  }",
+					"}",
+					"```",
+					"",
+					"If you are running `sst dev`, these are started automatically under the",
+					"_Forwards_ tab in the sidebar.",
+				}, "\n"),
+			},
+			Args: []cli.Argument{
+				{
+					Name: "forwards",
+					Description: cli.Description{
+						Short: "The ports to forward",
+						Long:  "The ports to forward in the form of `<local-port>:<host>:<port>`.",
+					},
+				},
+			},
+			Examples: []cli.Example{
+				{
+					Content: "sst tunnel forward 5432:mydb.cluster-xyz.us-east-1.rds.amazonaws.com:5432",
+					Description: cli.Description{
+						Short: "Forward a local port to a database",
+					},
+				},
+			},
+			Run: func(c *cli.Cli) error {
+				forwards, err := resolveForwards(c)
+				if err != nil {
+					return err
+				}
+				bastions, err := resolveBastions(c)
+				if err != nil {
+					return err
+				}
+				router, err := tunnel.NewRouter(bastions...)
+				if err != nil {
+					return err
+				}
+				// cancelled before the router is closed so it stops reconnecting
+				ctx, cancel := context.WithCancel(c.Context)
+				// subscribe before connecting so the first status isn't missed
+				go printTunnelStatus(ctx, bus.Subscribe(&tunnel.StatusEvent{}))
+				err = router.Connect(ctx)
+				if err != nil {
+					cancel()
+					return util.NewReadableError(err, "Could not connect to the bastion host: "+err.Error())
+				}
+				defer func() {
+					cancel()
+					router.Close()
+				}()
+				fmt.Println(ui.TEXT_HIGHLIGHT_BOLD.Render("Forwards"))
+				fmt.Println()
+				for _, forward := range forwards {
+					fmt.Print(ui.TEXT_HIGHLIGHT_BOLD.Render("➜"))
+					fmt.Println(ui.TEXT_NORMAL.Render("  " + forward.Name))
+					fmt.Println(ui.TEXT_DIM.Render("   " + forward.LocalAddress() + " → " + forward.RemoteAddress()))
+				}
+				fmt.Println()
+				fmt.Println(ui.TEXT_DIM.Render("Waiting for connections..."))
+				fmt.Println()
+				wg, ctx := errgroup.WithContext(ctx)
+				for _, forward := range forwards {
+					forward := forward
+					wg.Go(func() error {
+						err := tunnel.ServeForward(ctx, forward, router.Dial, func(forward tunnel.Forward, addr string) {
+							fmt.Println(ui.TEXT_INFO_BOLD.Render(("| "), ui.TEXT_NORMAL.Render("Forwarding", forward.Name, addr)))
+						})
+						if err != nil {
+							return util.NewReadableError(err, fmt.Sprintf("Could not listen on %s for %s", forward.LocalAddress(), forward.Name))
+						}
+						return nil
+					})
+				}
+				return wg.Wait()
+			},
+		},
 		{
 			Name: "start",
 			Description: cli.Description{
@@ -224,26 +391,55 @@ var CmdTunnel = &cli.Command{
 				},
 			},
 			Run: func(c *cli.Cli) error {
-				subnets := strings.Split(c.String("subnets"), ",")
-				host := c.String("host")
-				port := c.String("port")
-				user := c.String("user")
-				if port == "" {
-					port = "22"
-				}
-				slog.Info("starting tunnel", "subnet", subnets, "host", host, "port", port)
+				var bastions []tunnel.Bastion
+				if encoded := os.Getenv("SST_TUNNELS"); encoded != "" {
+					err := json.Unmarshal([]byte(encoded), &bastions)
+					if err != nil {
+						return err
+					}
+				} else {
+					port := c.String("port")
+					if port == "" {
+						port = "22"
+					}
+					bastions = append(bastions, tunnel.Bastion{
+						Name:       "default",
+						Host:       c.String("host") + ":" + port,
+						Username:   c.String("user"),
+						PrivateKey: os.Getenv("SSH_PRIVATE_KEY"),
+						Subnets:    strings.Split(c.String("subnets"), ","),
+					})
+				}
+				var subnets []string
+				for _, bastion := range bastions {
+					subnets = append(subnets, bastion.Subnets...)
+				}
+				slog.Info("starting tunnel", "subnets", subnets, "bastions", len(bastions))
 				err := tunnel.Start(subnets...)
 				if err != nil {
 					return err
 				}
 				defer tunnel.Stop()
 				slog.Info("tunnel started")
-				err = tunnel.StartProxy(
-					c.Context,
-					user,
-					host+":"+port,
-					[]byte(os.Getenv("SSH_PRIVATE_KEY")),
-				)
+				go printTunnelStatus(c.Context, bus.Subscribe(&tunnel.StatusEvent{}))
+				router, err := tunnel.NewRouter(bastions...)
+				if err != nil {
+					return err
+				}
+				err = router.Connect(c.Context)
+				if err != nil {
+					slog.Error("failed to start tunnel", "error", err)
+					fmt.Println(ui.TEXT_DANGER_BOLD.Render("| ") + ui.TEXT_NORMAL.Render(err.Error()))
+					return nil
+				}
+				defer router.Close()
+				listener, err := net.Listen("tcp", tunnel.PROXY_ADDRESS)
+				if err != nil {
+					return err
+				}
+				err = tunnel.ServeProxy(c.Context, listener, router.Dial, func(network, addr string) {
+					fmt.Println(ui.TEXT_INFO_BOLD.Render(("| "), ui.TEXT_NORMAL.Render("Tunneling", network, addr)))
+				})
 				if err != nil {
 					slog.Error("failed to start tunnel", "error", err)
 				}
@@ -252,3 +448,114 @@ var CmdTunnel = &cli.Command{
 		},
 	},
 }
+
+func resolveBastions(c *cli.Cli) ([]tunnel.Bastion, error) {
+	cfgPath, err := c.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	var completed *project.CompleteEvent
+
+	stage, err := c.Stage(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if url, err := server.Discover(cfgPath, stage); err == nil {
+		completed, err = dev.Completed(c.Context, url)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		proj, err := c.InitProject()
+		if err != nil {
+			return nil, err
+		}
+		completed, err = proj.GetCompleted(c.Context)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(completed.Tunnels) == 0 {
+		return nil, util.NewReadableError(nil, "No tunnels found for stage "+stage)
+	}
+	bastions := []tunnel.Bastion{}
+	for name, item := range completed.Tunnels {
+		bastions = append(bastions, tunnel.Bastion{
+			Name:       name,
+			Host:       item.IP + ":22",
+			Username:   item.Username,
+			PrivateKey: item.PrivateKey,
+			Subnets:    item.Subnets,
+		})
+	}
+	sort.Slice(bastions, func(i, j int) bool {
+		return bastions[i].Name < bastions[j].Name
+	})
+	return bastions, nil
+}
+
+func printTunnelStatus(ctx context.Context, evts <-chan interface{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case unknown := <-evts:
+			evt := unknown.(*tunnel.StatusEvent)
+			switch evt.Status {
+			case tunnel.StatusConnected:
+				fmt.Println(ui.TEXT_SUCCESS_BOLD.Render("| ") + ui.TEXT_NORMAL.Render("Connected to "+evt.Name+" ("+evt.Host+")"))
+			case tunnel.StatusDisconnected:
+				fmt.Println(ui.TEXT_WARNING_BOLD.Render("| ") + ui.TEXT_NORMAL.Render("Lost connection to "+evt.Name+", reconnecting..."))
+			}
+		}
+	}
+}
+
+func resolveForwards(c *cli.Cli) ([]tunnel.Forward, error) {
+	forwards := []tunnel.Forward{}
+	for _, arg := range c.Arguments() {
+		forward, err := tunnel.ParseForward("", arg)
+		if err != nil {
+			return nil, util.NewReadableError(err, err.Error())
+		}
+		forwards = append(forwards, forward)
+	}
+	if len(forwards) > 0 {
+		return forwards, nil
+	}
+	cfgPath, err := c.Discover()
+	if err != nil {
+		return nil, err
+	}
+	stage, err := c.Stage(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	p, err := project.New(&project.ProjectConfig{
+		Version: version,
+		Config:  cfgPath,
+		Stage:   stage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p.App().Tunnel != nil {
+		for name, spec := range p.App().Tunnel.Forwards {
+			forward, err := tunnel.ParseForward(name, spec)
+			if err != nil {
+				return nil, util.NewReadableError(err, err.Error())
+			}
+			forwards = append(forwards, forward)
+		}
+	}
+	if len(forwards) == 0 {
+		return nil, util.NewReadableError(nil, "No forwards specified. Run `sst tunnel forward <local-port>:<host>:<port>` or add them to `tunnel.forwards` in your sst.config.ts")
+	}
+	sort.Slice(forwards, func(i, j int) bool {
+		return forwards[i].Name < forwards[j].Name
+	})
+	return forwards, nil
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/sst/sst/v3/cmd/sst/mosaic/ui/common"
 	"github.com/sst/sst/v3/pkg/project"
 	"github.com/sst/sst/v3/pkg/server"
+	"github.com/sst/sst/v3/pkg/server/resource"
 )
 
 func CmdUI(c *cli.Cli) error {
@@ -65,6 +66,10 @@ func CmdUI(c *cli.Cli) error {
 		u = ui.New(c.Context, ui.WithDev)
 		types = append(types,
 			common.StdoutEvent{},
+			resource.UploadProgressEvent{},
+			resource.InvalidationEstimateEvent{},
+			resource.FunctionDeploymentEvent{},
+			resource.SqlMigrationEvent{},
 			deployer.DeployFailedEvent{},
 			project.StackCommandEvent{},
 			project.CancelledEvent{},
@@ -0,0 +1,424 @@
+package emulator
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// bucket is a minimal S3 compatible server that keeps objects on disk. It
+// only supports path style requests, which the AWS SDKs use automatically
+// when the endpoint is an IP address.
+type bucket struct {
+	dir      string
+	server   *http.Server
+	listener net.Listener
+}
+
+type objectMeta struct {
+	ContentType string            `json:"contentType"`
+	ETag        string            `json:"etag"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+func newBucket(dir string) *bucket {
+	return &bucket{dir: filepath.Join(dir, "bucket")}
+}
+
+func (b *bucket) start(ctx context.Context) error {
+	err := os.MkdirAll(b.dir, 0755)
+	if err != nil {
+		return err
+	}
+	b.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	b.server = &http.Server{Handler: b}
+	go b.server.Serve(b.listener)
+	return nil
+}
+
+func (b *bucket) stop() error {
+	if b.server == nil {
+		return nil
+	}
+	return b.server.Close()
+}
+
+func (b *bucket) provision(ctx context.Context, name string, properties map[string]interface{}, resources []apitype.ResourceV3) (map[string]interface{}, map[string]string, error) {
+	bucketName, _ := properties["name"].(string)
+	if bucketName == "" {
+		return nil, nil, fmt.Errorf("bucket link has no name")
+	}
+	err := os.MkdirAll(filepath.Join(b.dir, bucketName), 0755)
+	if err != nil {
+		return nil, nil, err
+	}
+	return properties, map[string]string{
+		"AWS_ENDPOINT_URL_S3": "http://" + b.listener.Addr().String(),
+	}, nil
+}
+
+func (b *bucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	slog.Info("bucket emulator", "method", r.Method, "bucket", bucketName, "key", key)
+	if bucketName == "" || strings.Contains(bucketName, "..") || strings.Contains(key, "..") {
+		s3Error(w, http.StatusBadRequest, "InvalidRequest", "Invalid bucket or key")
+		return
+	}
+	root := filepath.Join(b.dir, bucketName)
+	if key == "" {
+		switch r.Method {
+		case http.MethodPut:
+			os.MkdirAll(root, 0755)
+			w.WriteHeader(http.StatusOK)
+		case http.MethodHead:
+			if _, err := os.Stat(root); err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case http.MethodDelete:
+			os.RemoveAll(filepath.Join(b.dir, ".meta", bucketName))
+			err := os.Remove(root)
+			if err != nil {
+				s3Error(w, http.StatusConflict, "BucketNotEmpty", "The bucket you tried to delete is not empty")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			b.list(w, r, bucketName, root)
+		case http.MethodPost:
+			if _, ok := r.URL.Query()["delete"]; ok {
+				b.deleteObjects(w, r, bucketName, root)
+				return
+			}
+			s3Error(w, http.StatusNotImplemented, "NotImplemented", "Not implemented")
+		default:
+			s3Error(w, http.StatusNotImplemented, "NotImplemented", "Not implemented")
+		}
+		return
+	}
+	if _, err := os.Stat(root); err != nil {
+		s3Error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+	path := filepath.Join(root, filepath.FromSlash(key))
+	metaPath := filepath.Join(b.dir, ".meta", bucketName, filepath.FromSlash(key)+".json")
+	switch r.Method {
+	case http.MethodPut:
+		b.put(w, r, path, metaPath)
+	case http.MethodGet, http.MethodHead:
+		file, err := os.Open(path)
+		if err != nil {
+			s3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+			return
+		}
+		defer file.Close()
+		stat, _ := file.Stat()
+		meta := readMeta(metaPath)
+		for key, value := range meta.Metadata {
+			w.Header().Set("x-amz-meta-"+key, value)
+		}
+		if meta.ContentType != "" {
+			w.Header().Set("Content-Type", meta.ContentType)
+		}
+		w.Header().Set("ETag", meta.ETag)
+		http.ServeContent(w, r, "", stat.ModTime(), file)
+	case http.MethodDelete:
+		os.Remove(path)
+		os.Remove(metaPath)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s3Error(w, http.StatusNotImplemented, "NotImplemented", "Not implemented")
+	}
+}
+
+func (b *bucket) put(w http.ResponseWriter, r *http.Request, path string, metaPath string) {
+	var body io.Reader = r.Body
+	if source := r.Header.Get("x-amz-copy-source"); source != "" {
+		source = strings.TrimPrefix(source, "/")
+		if strings.Contains(source, "..") {
+			s3Error(w, http.StatusBadRequest, "InvalidRequest", "Invalid copy source")
+			return
+		}
+		sourceBucket, sourceKey, _ := strings.Cut(source, "/")
+		file, err := os.Open(filepath.Join(b.dir, sourceBucket, filepath.FromSlash(sourceKey)))
+		if err != nil {
+			s3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+			return
+		}
+		defer file.Close()
+		body = file
+	} else if strings.HasPrefix(r.Header.Get("x-amz-content-sha256"), "STREAMING-") || strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked") {
+		body = newChunkedReader(r.Body)
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		s3Error(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		s3Error(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	defer file.Close()
+	hash := md5.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), body)
+	if err != nil {
+		s3Error(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	meta := objectMeta{
+		ContentType: r.Header.Get("Content-Type"),
+		ETag:        `"` + hex.EncodeToString(hash.Sum(nil)) + `"`,
+		Metadata:    map[string]string{},
+	}
+	for key, values := range r.Header {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "x-amz-meta-") {
+			meta.Metadata[strings.TrimPrefix(lower, "x-amz-meta-")] = values[0]
+		}
+	}
+	os.MkdirAll(filepath.Dir(metaPath), 0755)
+	data, _ := json.Marshal(meta)
+	os.WriteFile(metaPath, data, 0644)
+	w.Header().Set("ETag", meta.ETag)
+	if r.Header.Get("x-amz-copy-source") != "" {
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyObjectResult"`
+			ETag         string
+			LastModified string
+		}{ETag: meta.ETag, LastModified: time.Now().UTC().Format(time.RFC3339)})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+type listEntry struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int64
+	StorageClass string
+}
+
+type listPrefix struct {
+	Prefix string
+}
+
+func (b *bucket) list(w http.ResponseWriter, r *http.Request, bucketName string, root string) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	after := query.Get("start-after")
+	if token := query.Get("continuation-token"); token != "" {
+		after = token
+	}
+	if marker := query.Get("marker"); marker != "" {
+		after = marker
+	}
+	maxKeys := 1000
+	if value, err := strconv.Atoi(query.Get("max-keys")); err == nil && value > 0 && value < maxKeys {
+		maxKeys = value
+	}
+	keys := []string{}
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		keys = append(keys, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(keys)
+	contents := []listEntry{}
+	prefixes := []listPrefix{}
+	seen := map[string]bool{}
+	truncated := false
+	next := ""
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) || key <= after {
+			continue
+		}
+		if len(contents)+len(prefixes) >= maxKeys {
+			truncated = true
+			break
+		}
+		if delimiter != "" {
+			if index := strings.Index(key[len(prefix):], delimiter); index >= 0 {
+				common := key[:len(prefix)+index+len(delimiter)]
+				if !seen[common] {
+					seen[common] = true
+					prefixes = append(prefixes, listPrefix{common})
+				}
+				next = key
+				continue
+			}
+		}
+		stat, err := os.Stat(filepath.Join(root, filepath.FromSlash(key)))
+		if err != nil {
+			continue
+		}
+		meta := readMeta(filepath.Join(b.dir, ".meta", bucketName, filepath.FromSlash(key)+".json"))
+		contents = append(contents, listEntry{
+			Key:          key,
+			LastModified: stat.ModTime().UTC().Format(time.RFC3339),
+			ETag:         meta.ETag,
+			Size:         stat.Size(),
+			StorageClass: "STANDARD",
+		})
+		next = key
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Xmlns                 string   `xml:"xmlns,attr"`
+		Name                  string
+		Prefix                string
+		Delimiter             string `xml:",omitempty"`
+		MaxKeys               int
+		KeyCount              int
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+		NextMarker            string `xml:",omitempty"`
+		Contents              []listEntry
+		CommonPrefixes        []listPrefix
+	}{
+		Xmlns:          "http://s3.amazonaws.com/doc/2006-03-01/",
+		Name:           bucketName,
+		Prefix:         prefix,
+		Delimiter:      delimiter,
+		MaxKeys:        maxKeys,
+		KeyCount:       len(contents) + len(prefixes),
+		IsTruncated:    truncated,
+		Contents:       contents,
+		CommonPrefixes: prefixes,
+	}
+	if truncated {
+		result.NextContinuationToken = next
+		result.NextMarker = next
+	}
+	writeXML(w, result)
+}
+
+func (b *bucket) deleteObjects(w http.ResponseWriter, r *http.Request, bucketName string, root string) {
+	var input struct {
+		Objects []struct {
+			Key string
+		} `xml:"Object"`
+	}
+	err := xml.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		s3Error(w, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+	type deleted struct {
+		Key string
+	}
+	result := struct {
+		XMLName xml.Name  `xml:"DeleteResult"`
+		Deleted []deleted `xml:"Deleted"`
+	}{}
+	for _, object := range input.Objects {
+		if strings.Contains(object.Key, "..") {
+			continue
+		}
+		os.Remove(filepath.Join(root, filepath.FromSlash(object.Key)))
+		os.Remove(filepath.Join(b.dir, ".meta", bucketName, filepath.FromSlash(object.Key)+".json"))
+		result.Deleted = append(result.Deleted, deleted{object.Key})
+	}
+	writeXML(w, result)
+}
+
+func readMeta(path string) objectMeta {
+	var meta objectMeta
+	data, err := os.ReadFile(path)
+	if err == nil {
+		json.Unmarshal(data, &meta)
+	}
+	return meta
+}
+
+func writeXML(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(value)
+}
+
+func s3Error(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: message})
+}
+
+// chunkedReader decodes the aws-chunked encoding the SDKs use for streaming
+// uploads, dropping chunk signatures and trailing checksums.
+type chunkedReader struct {
+	reader    *bufio.Reader
+	remaining int64
+	done      bool
+}
+
+func newChunkedReader(r io.Reader) *chunkedReader {
+	return &chunkedReader{reader: bufio.NewReader(r)}
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.done {
+		return 0, io.EOF
+	}
+	if c.remaining == 0 {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return 0, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return c.Read(p)
+		}
+		size, _, _ := strings.Cut(line, ";")
+		c.remaining, err = strconv.ParseInt(size, 16, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid chunk size %q", size)
+		}
+		if c.remaining == 0 {
+			c.done = true
+			return 0, io.EOF
+		}
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.reader.Read(p)
+	c.remaining -= int64(n)
+	if c.remaining == 0 {
+		// consume the CRLF that ends the chunk
+		c.reader.ReadString('\n')
+	}
+	return n, err
+}
@@ -0,0 +1,63 @@
+package emulator
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func request(t *testing.T, method string, url string, body string, headers map[string]string) (int, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func TestBucket(t *testing.T) {
+	b := newBucket(t.TempDir())
+	err := b.start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.stop()
+	_, env, err := b.provision(context.Background(), "MyBucket", map[string]interface{}{"name": "my-bucket"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint := env["AWS_ENDPOINT_URL_S3"] + "/my-bucket"
+
+	status, _ := request(t, "PUT", endpoint+"/a/one.txt", "one", map[string]string{"Content-Type": "text/plain"})
+	if status != http.StatusOK {
+		t.Fatalf("put returned %d", status)
+	}
+	chunked := "3;chunk-signature=abc\r\ntwo\r\n0;chunk-signature=def\r\n\r\n"
+	request(t, "PUT", endpoint+"/a/b/two.txt", chunked, map[string]string{"x-amz-content-sha256": "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"})
+
+	status, body := request(t, "GET", endpoint+"/a/b/two.txt", "", nil)
+	if status != http.StatusOK || body != "two" {
+		t.Errorf("Expected two, got %d %q", status, body)
+	}
+
+	_, body = request(t, "GET", endpoint+"?list-type=2&prefix=a/&delimiter=/", "", nil)
+	if !strings.Contains(body, "<Key>a/one.txt</Key>") || !strings.Contains(body, "<Prefix>a/b/</Prefix>") {
+		t.Errorf("Unexpected list result %s", body)
+	}
+
+	request(t, "DELETE", endpoint+"/a/one.txt", "", nil)
+	status, body = request(t, "GET", endpoint+"/a/one.txt", "", nil)
+	if status != http.StatusNotFound || !strings.Contains(body, "NoSuchKey") {
+		t.Errorf("Expected NoSuchKey, got %d %s", status, body)
+	}
+}
@@ -0,0 +1,80 @@
+package emulator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sst/sst/v3/pkg/process"
+)
+
+type container struct {
+	name    string
+	image   string
+	port    int
+	env     []string
+	volumes []string
+	args    []string
+	// address is the host address the container port is published on
+	address string
+}
+
+func (c *container) start(ctx context.Context) error {
+	out, err := process.CommandContext(ctx, "docker", "inspect", "-f", "{{.State.Running}}", c.name).Output()
+	if err != nil || strings.TrimSpace(string(out)) != "true" {
+		process.CommandContext(ctx, "docker", "rm", "-f", c.name).Run()
+		args := []string{"run", "-d", "--name", c.name, "-p", fmt.Sprintf("127.0.0.1::%d", c.port)}
+		for _, env := range c.env {
+			args = append(args, "-e", env)
+		}
+		for _, volume := range c.volumes {
+			args = append(args, "-v", volume)
+		}
+		args = append(args, c.image)
+		args = append(args, c.args...)
+		slog.Info("starting container", "args", args)
+		out, err := process.CommandContext(ctx, "docker", args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("docker run %s: %w\n%s", c.image, err, out)
+		}
+	}
+	out, err = process.CommandContext(ctx, "docker", "port", c.name, fmt.Sprintf("%d/tcp", c.port)).Output()
+	if err != nil {
+		return fmt.Errorf("docker port %s: %w", c.name, err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	c.address = strings.TrimSpace(lines[0])
+	return c.wait(ctx)
+}
+
+func (c *container) wait(ctx context.Context) error {
+	deadline := time.Now().Add(time.Minute)
+	for time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", c.address, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	return fmt.Errorf("timed out waiting for %s on %s", c.name, c.address)
+}
+
+func (c *container) stop() error {
+	return process.Command("docker", "rm", "-f", c.name).Run()
+}
+
+func (c *container) exec(ctx context.Context, args ...string) ([]byte, error) {
+	return process.CommandContext(ctx, "docker", append([]string{"exec", c.name}, args...)...).CombinedOutput()
+}
+
+func (c *container) endpoint() string {
+	return "http://" + c.address
+}
@@ -0,0 +1,129 @@
+package emulator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+type dynamo struct {
+	container
+}
+
+func newDynamo(prefix string) *dynamo {
+	return &dynamo{container{
+		name:    prefix + "-dynamo",
+		image:   "amazon/dynamodb-local:2.5.2",
+		port:    8000,
+		volumes: []string{prefix + "-dynamo:/home/dynamodblocal/data"},
+		args:    []string{"-jar", "DynamoDBLocal.jar", "-sharedDb", "-dbPath", "/home/dynamodblocal/data"},
+	}}
+}
+
+func (d *dynamo) provision(ctx context.Context, name string, properties map[string]interface{}, resources []apitype.ResourceV3) (map[string]interface{}, map[string]string, error) {
+	tableName, _ := properties["name"].(string)
+	if tableName == "" {
+		return nil, nil, fmt.Errorf("dynamo link has no name")
+	}
+	table := findResource(resources, "aws:dynamodb/table:Table", tableName)
+	if table == nil {
+		return nil, nil, fmt.Errorf("could not find the table %s in the state", tableName)
+	}
+	input := map[string]interface{}{
+		"TableName":            tableName,
+		"BillingMode":          "PAY_PER_REQUEST",
+		"KeySchema":            keySchema(table["hashKey"], table["rangeKey"]),
+		"AttributeDefinitions": []map[string]interface{}{},
+	}
+	if attributes, ok := table["attributes"].([]interface{}); ok {
+		definitions := []map[string]interface{}{}
+		for _, item := range attributes {
+			attribute := item.(map[string]interface{})
+			definitions = append(definitions, map[string]interface{}{
+				"AttributeName": attribute["name"],
+				"AttributeType": attribute["type"],
+			})
+		}
+		input["AttributeDefinitions"] = definitions
+	}
+	for _, field := range []string{"globalSecondaryIndexes", "localSecondaryIndexes"} {
+		indexes, ok := table[field].([]interface{})
+		if !ok || len(indexes) == 0 {
+			continue
+		}
+		result := []map[string]interface{}{}
+		for _, item := range indexes {
+			index := item.(map[string]interface{})
+			hashKey := index["hashKey"]
+			if field == "localSecondaryIndexes" {
+				hashKey = table["hashKey"]
+			}
+			projection := map[string]interface{}{
+				"ProjectionType": index["projectionType"],
+			}
+			if attrs, ok := index["nonKeyAttributes"].([]interface{}); ok && len(attrs) > 0 {
+				projection["NonKeyAttributes"] = attrs
+			}
+			result = append(result, map[string]interface{}{
+				"IndexName":  index["name"],
+				"KeySchema":  keySchema(hashKey, index["rangeKey"]),
+				"Projection": projection,
+			})
+		}
+		input[strings.ToUpper(field[:1])+field[1:]] = result
+	}
+	if stream, ok := table["streamEnabled"].(bool); ok && stream {
+		input["StreamSpecification"] = map[string]interface{}{
+			"StreamEnabled":  true,
+			"StreamViewType": table["streamViewType"],
+		}
+	}
+	err := d.call(ctx, "CreateTable", input)
+	if err != nil && !strings.Contains(err.Error(), "ResourceInUseException") {
+		return nil, nil, err
+	}
+	return properties, map[string]string{
+		"AWS_ENDPOINT_URL_DYNAMODB": d.endpoint(),
+	}, nil
+}
+
+func keySchema(hashKey interface{}, rangeKey interface{}) []map[string]interface{} {
+	result := []map[string]interface{}{
+		{"AttributeName": hashKey, "KeyType": "HASH"},
+	}
+	if value, ok := rangeKey.(string); ok && value != "" {
+		result = append(result, map[string]interface{}{"AttributeName": value, "KeyType": "RANGE"})
+	}
+	return result
+}
+
+func (d *dynamo) call(ctx context.Context, action string, input interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.endpoint(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "DynamoDB_20120810."+action)
+	// signatures aren't verified but the header needs to be present
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=local/20240101/us-east-1/dynamodb/aws4_request, SignedHeaders=host, Signature=0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s failed: %s", action, data)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package emulator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/sst/v3/pkg/project/common"
+)
+
+const (
+	KindBucket   = "bucket"
+	KindDynamo   = "dynamo"
+	KindQueue    = "queue"
+	KindPostgres = "postgres"
+)
+
+// linkTypes maps the link type of a component to the emulator backing it.
+var linkTypes = map[string]string{
+	"sst.aws.Bucket":   KindBucket,
+	"sst.aws.Dynamo":   KindDynamo,
+	"sst.aws.Queue":    KindQueue,
+	"sst.aws.Postgres": KindPostgres,
+}
+
+type service interface {
+	start(ctx context.Context) error
+	stop() error
+	// provision creates the named resource in the emulator and returns the
+	// link properties and environment variables that point at it.
+	provision(ctx context.Context, name string, properties map[string]interface{}, resources []apitype.ResourceV3) (map[string]interface{}, map[string]string, error)
+}
+
+// Manager runs local stand-ins for linked resources during `sst dev` and
+// rewrites their links to point at them.
+type Manager struct {
+	dir         string
+	prefix      string
+	services    map[string]service
+	mu          sync.Mutex
+	provisioned map[string]result
+}
+
+type result struct {
+	properties map[string]interface{}
+	env        map[string]string
+}
+
+// New creates a manager for the given kinds. The dir is where data that lives
+// on disk is kept and the prefix is used to name containers.
+func New(dir string, prefix string, kinds []string) (*Manager, error) {
+	m := &Manager{
+		dir:         dir,
+		prefix:      prefix,
+		services:    map[string]service{},
+		provisioned: map[string]result{},
+	}
+	for _, kind := range kinds {
+		switch kind {
+		case KindBucket:
+			m.services[kind] = newBucket(dir)
+		case KindDynamo:
+			m.services[kind] = newDynamo(prefix)
+		case KindQueue:
+			m.services[kind] = newQueue(prefix)
+		case KindPostgres:
+			m.services[kind] = newPostgres(prefix)
+		default:
+			return nil, fmt.Errorf("unknown emulator %q, expected one of bucket, dynamo, queue, postgres", kind)
+		}
+	}
+	return m, nil
+}
+
+func (m *Manager) Start(ctx context.Context) error {
+	for kind, svc := range m.services {
+		slog.Info("starting emulator", "kind", kind)
+		err := svc.start(ctx)
+		if err != nil {
+			m.Stop()
+			return fmt.Errorf("failed to start %s emulator: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) Stop() {
+	for kind, svc := range m.services {
+		slog.Info("stopping emulator", "kind", kind)
+		if err := svc.stop(); err != nil {
+			slog.Error("failed to stop emulator", "kind", kind, "err", err)
+		}
+	}
+}
+
+// Link returns the properties and extra environment for a linked resource.
+// Links that are not emulated are returned untouched.
+func (m *Manager) Link(ctx context.Context, name string, link common.Link, resources []apitype.ResourceV3) (map[string]interface{}, map[string]string, error) {
+	linkType, _ := link.Properties["type"].(string)
+	kind, ok := linkTypes[linkType]
+	if !ok {
+		return link.Properties, nil, nil
+	}
+	svc, ok := m.services[kind]
+	if !ok {
+		return link.Properties, nil, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if match, ok := m.provisioned[name]; ok {
+		return match.properties, match.env, nil
+	}
+	properties, env, err := svc.provision(ctx, name, link.Properties, resources)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to emulate %s: %w", name, err)
+	}
+	slog.Info("emulating link", "name", name, "kind", kind)
+	m.provisioned[name] = result{properties, env}
+	return properties, env, nil
+}
+
+func copyProperties(input map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, value := range input {
+		result[key] = value
+	}
+	return result
+}
+
+// findResource returns the outputs of the resource of the given type whose
+// name output matches.
+func findResource(resources []apitype.ResourceV3, resourceType string, name string) map[string]interface{} {
+	for _, resource := range resources {
+		if string(resource.Type) != resourceType {
+			continue
+		}
+		if resource.Outputs["name"] == name {
+			return resource.Outputs
+		}
+	}
+	return nil
+}
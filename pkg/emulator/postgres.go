@@ -0,0 +1,75 @@
+package emulator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+const postgresUser = "postgres"
+const postgresPassword = "password"
+
+type postgres struct {
+	container
+}
+
+func newPostgres(prefix string) *postgres {
+	return &postgres{container{
+		name:    prefix + "-postgres",
+		image:   "postgres:16-alpine",
+		port:    5432,
+		env:     []string{"POSTGRES_USER=" + postgresUser, "POSTGRES_PASSWORD=" + postgresPassword},
+		volumes: []string{prefix + "-postgres:/var/lib/postgresql/data"},
+	}}
+}
+
+func (p *postgres) start(ctx context.Context) error {
+	err := p.container.start(ctx)
+	if err != nil {
+		return err
+	}
+	// the port opens before the server accepts connections
+	deadline := time.Now().Add(time.Minute)
+	for time.Now().Before(deadline) {
+		_, err := p.exec(ctx, "pg_isready", "-U", postgresUser)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	return fmt.Errorf("timed out waiting for postgres to be ready")
+}
+
+func (p *postgres) provision(ctx context.Context, name string, properties map[string]interface{}, resources []apitype.ResourceV3) (map[string]interface{}, map[string]string, error) {
+	database, _ := properties["database"].(string)
+	if database == "" {
+		database = "postgres"
+	}
+	if database != "postgres" {
+		out, err := p.exec(ctx, "createdb", "-U", postgresUser, database)
+		if err != nil && !strings.Contains(string(out), "already exists") {
+			return nil, nil, fmt.Errorf("createdb %s: %s", database, out)
+		}
+	}
+	host, port, err := net.SplitHostPort(p.address)
+	if err != nil {
+		return nil, nil, err
+	}
+	portNumber, _ := strconv.Atoi(port)
+	next := copyProperties(properties)
+	next["host"] = host
+	next["port"] = portNumber
+	next["username"] = postgresUser
+	next["password"] = postgresPassword
+	next["database"] = database
+	return next, nil, nil
+}
@@ -0,0 +1,75 @@
+package emulator
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+type queue struct {
+	container
+}
+
+func newQueue(prefix string) *queue {
+	return &queue{container{
+		name:  prefix + "-queue",
+		image: "softwaremill/elasticmq-native:1.6.5",
+		port:  9324,
+	}}
+}
+
+func (q *queue) provision(ctx context.Context, name string, properties map[string]interface{}, resources []apitype.ResourceV3) (map[string]interface{}, map[string]string, error) {
+	queueURL, _ := properties["url"].(string)
+	queueName := path.Base(queueURL)
+	if queueURL == "" || queueName == "" {
+		return nil, nil, fmt.Errorf("queue link has no url")
+	}
+	form := url.Values{}
+	form.Set("Action", "CreateQueue")
+	form.Set("QueueName", queueName)
+	if strings.HasSuffix(queueName, ".fifo") {
+		form.Set("Attribute.1.Name", "FifoQueue")
+		form.Set("Attribute.1.Value", "true")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, q.endpoint(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("CreateQueue failed: %s", data)
+	}
+	var result struct {
+		QueueUrl string `xml:"CreateQueueResult>QueueUrl"`
+	}
+	err = xml.Unmarshal(data, &result)
+	if err != nil {
+		return nil, nil, err
+	}
+	// elasticmq reports its address inside the container so swap in the
+	// published one
+	parsed, err := url.Parse(result.QueueUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+	parsed.Scheme = "http"
+	parsed.Host = q.address
+	next := copyProperties(properties)
+	next["url"] = parsed.String()
+	return next, map[string]string{
+		"AWS_ENDPOINT_URL_SQS": q.endpoint(),
+	}, nil
+}
@@ -15,6 +15,14 @@ var SST_PULUMI_PATH = os.Getenv("SST_PULUMI_PATH")
 var SST_BUILD_CONCURRENCY = os.Getenv("SST_BUILD_CONCURRENCY")
 var SST_BUILD_CONCURRENCY_FUNCTION = os.Getenv("SST_BUILD_CONCURRENCY_FUNCTION")
 var SST_BUILD_CONCURRENCY_SITE = os.Getenv("SST_BUILD_CONCURRENCY_SITE")
+var SST_RESOURCE_CONCURRENCY_AWS = os.Getenv("SST_RESOURCE_CONCURRENCY_AWS")
+var SST_RESOURCE_CONCURRENCY_CLOUDFLARE = os.Getenv("SST_RESOURCE_CONCURRENCY_CLOUDFLARE")
+var SST_RESOURCE_CONCURRENCY_VERCEL = os.Getenv("SST_RESOURCE_CONCURRENCY_VERCEL")
+var SST_RESOURCE_CONCURRENCY_UPLOAD = os.Getenv("SST_RESOURCE_CONCURRENCY_UPLOAD")
+var SST_PROVIDER_OVERRIDES = os.Getenv("SST_PROVIDER_OVERRIDES")
+var SST_LOG_DIR = os.Getenv("SST_LOG_DIR")
+var SST_OUTPUTS_PATH = os.Getenv("SST_OUTPUTS_PATH")
+var SST_REMOVE_EXPIRED = isTrue("SST_REMOVE_EXPIRED")
 var SST_SKIP_DEPENDENCY_CHECK = isTrue("SST_SKIP_DEPENDENCY_CHECK")
 var SST_TELEMETRY_DISABLED = isTrue("SST_TELEMETRY_DISABLED") || isTrue("DO_NOT_TRACK")
 var SST_BUN_VERSION = os.Getenv("SST_BUN_VERSION")
@@ -1,11 +1,19 @@
 package npm
 
 import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/sst/sst/v3/internal/fs"
 )
@@ -17,6 +25,10 @@ type Package struct {
 		Name    string `json:"name"`
 		Version string `json:"version"`
 	}
+	Dist *struct {
+		Integrity string `json:"integrity"`
+		Tarball   string `json:"tarball"`
+	} `json:"dist"`
 }
 
 func Get(name string, version string) (*Package, error) {
@@ -42,6 +54,60 @@ func Get(name string, version string) (*Package, error) {
 	return &data, nil
 }
 
+var ErrIntegrityMismatch = errors.New("does not match the integrity")
+
+// Download fetches a package tarball and checks it against the integrity it
+// was locked with
+func Download(tarball string, integrity string) ([]byte, error) {
+	slog.Info("downloading package", "tarball", tarball)
+	resp, err := http.Get(tarball)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download package: %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	err = VerifyIntegrity(data, integrity)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", tarball, err)
+	}
+	return data, nil
+}
+
+// VerifyIntegrity checks data against a subresource integrity string like the
+// dist.integrity of a package, passing if any of its hashes match
+func VerifyIntegrity(data []byte, integrity string) error {
+	for _, item := range strings.Fields(integrity) {
+		algorithm, expected, ok := strings.Cut(item, "-")
+		if !ok {
+			continue
+		}
+		var hasher hash.Hash
+		switch algorithm {
+		case "sha512":
+			hasher = sha512.New()
+		case "sha384":
+			hasher = sha512.New384()
+		case "sha256":
+			hasher = sha256.New()
+		case "sha1":
+			hasher = sha1.New()
+		default:
+			continue
+		}
+		hasher.Write(data)
+		if base64.StdEncoding.EncodeToString(hasher.Sum(nil)) == expected {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q", ErrIntegrityMismatch, integrity)
+}
+
 func DetectPackageManager(dir string) (string, string) {
 	options := []struct {
 		search string
@@ -0,0 +1,25 @@
+package npm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVerifyIntegrity(t *testing.T) {
+	data := []byte("hello")
+	valid := []string{
+		"sha512-m3HSJL1i83hdltRq0+o9czGb+8KJDKra4t/3JRlnPKcjI8PZm6XBHXx6zG4UuMXaDEZjR1wuXDre9G9zvN7AQw==",
+		"sha1-qvTGHdzF6KLavt4PO0gs2a6pQ00= sha512-invalid",
+		"sha256-LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=",
+	}
+	for _, integrity := range valid {
+		if err := VerifyIntegrity(data, integrity); err != nil {
+			t.Errorf("expected %s to match: %v", integrity, err)
+		}
+	}
+	for _, integrity := range []string{"", "sha512-AAAA", "md5-XUFAKrxLKna5cZ2REBfFkg=="} {
+		if err := VerifyIntegrity(data, integrity); !errors.Is(err, ErrIntegrityMismatch) {
+			t.Errorf("expected %q to not match", integrity)
+		}
+	}
+}
@@ -0,0 +1,555 @@
+package project
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/sst/sst/v3/pkg/flag"
+	"github.com/sst/sst/v3/pkg/global"
+	"github.com/sst/sst/v3/pkg/npm"
+	"github.com/sst/sst/v3/pkg/process"
+	"golang.org/x/exp/slices"
+)
+
+const BUNDLE_MANIFEST = "sst.bundle.json"
+
+// The first entry in a bundle. It records the checksum of every other entry
+// so an offline install can verify the bundle while extracting it.
+type BundleManifest struct {
+	Version   string            `json:"version"`
+	OS        string            `json:"os"`
+	Arch      string            `json:"arch"`
+	Providers ProviderLock      `json:"providers"`
+	Files     map[string]string `json:"files"`
+}
+
+type ErrBundleInvalid struct {
+	Reason string
+}
+
+func (e *ErrBundleInvalid) Error() string {
+	return "bundle is invalid: " + e.Reason
+}
+
+type bundleEntry struct {
+	name string
+	path string
+}
+
+// Bundle writes everything an install needs to a tar file; the platform with
+// the provider packages, the pulumi plugins for the providers, and the pulumi
+// and bun binaries.
+func (p *Project) Bundle(ctx context.Context, out string) error {
+	slog.Info("bundling", "out", out)
+	if global.NeedsPulumi() {
+		err := global.InstallPulumi(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	if global.NeedsBun() {
+		err := global.InstallBun(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	err := global.EnsureMkcert()
+	if err != nil {
+		return err
+	}
+	err = p.installPlugins()
+	if err != nil {
+		return err
+	}
+
+	entries := []bundleEntry{}
+	binaries, err := os.ReadDir(global.BinPath())
+	if err != nil {
+		return err
+	}
+	for _, item := range binaries {
+		name := item.Name()
+		if item.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(name, "pulumi") || strings.HasPrefix(name, "mkcert") || (name == filepath.Base(global.BunPath()) && !flag.SST_NO_BUN) {
+			entries = append(entries, bundleEntry{"bin/" + name, filepath.Join(global.BinPath(), name)})
+		}
+	}
+	for prefix, dir := range map[string]string{
+		"plugins":  pluginsPath(),
+		"platform": p.PathPlatformDir(),
+	} {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, bundleEntry{prefix + "/" + filepath.ToSlash(rel), path})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// the verified provider tarballs are part of the platform, an install
+	// checks them against the integrity in the lock
+	for _, entry := range p.lock {
+		name := "platform/" + PROVIDER_PACKAGES + "/" + providerTarballName(entry)
+		if entry.Integrity == "" || !slices.ContainsFunc(entries, func(item bundleEntry) bool { return item.name == name }) {
+			return fmt.Errorf("%s was not installed from a verified tarball, run `sst install` first", entry.Name)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+
+	manifest := BundleManifest{
+		Version:   p.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Providers: p.lock,
+		Files:     map[string]string{},
+	}
+	for _, entry := range entries {
+		sum, err := checksumEntry(entry.path)
+		if err != nil {
+			return err
+		}
+		manifest.Files[entry.name] = sum
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := tar.NewWriter(file)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = writer.WriteHeader(&tar.Header{
+		Name: BUNDLE_MANIFEST,
+		Mode: 0644,
+		Size: int64(len(data)),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err := writeBundleEntry(writer, entry)
+		if err != nil {
+			return err
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// InstallBundle installs a bundle created by Bundle without using the
+// network. The bundle has to match the provider lock of the project and every
+// file is checked against its manifest while it's extracted to a staging
+// directory. Nothing is replaced until all of it has been verified.
+func (p *Project) InstallBundle(path string) error {
+	slog.Info("installing bundle", "path", path)
+	file, err := os.Open(path)
+	if err != nil {
+		return &ErrBundleInvalid{Reason: fmt.Sprintf("%s could not be read", path)}
+	}
+	defer file.Close()
+	reader := tar.NewReader(file)
+	manifest, err := p.readBundleManifest(path, reader)
+	if err != nil {
+		return err
+	}
+
+	// staged next to where things end up so they can be moved into place
+	local, err := os.MkdirTemp(p.PathWorkingDir(), "bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(local)
+	shared, err := os.MkdirTemp(global.ConfigDir(), "bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(shared)
+	roots := map[string]string{
+		"bin":      filepath.Join(shared, "bin"),
+		"plugins":  filepath.Join(shared, "plugins"),
+		"platform": filepath.Join(local, "platform"),
+	}
+	err = extractBundle(reader, manifest, roots)
+	if err != nil {
+		return err
+	}
+
+	// the installed providers are replaced with the contents of the tarballs
+	// that match the integrity in the lock
+	for _, entry := range p.lock {
+		name := providerTarballName(entry)
+		data, err := os.ReadFile(filepath.Join(roots["platform"], PROVIDER_PACKAGES, name))
+		if err != nil {
+			return &ErrBundleInvalid{Reason: fmt.Sprintf("%s is missing", name)}
+		}
+		if err := npm.VerifyIntegrity(data, entry.Integrity); err != nil {
+			return &ErrBundleInvalid{Reason: fmt.Sprintf("%s@%s does not match the integrity in your provider lock", entry.Package, entry.Version)}
+		}
+		dest := filepath.Join(roots["platform"], "node_modules", filepath.FromSlash(entry.Package))
+		os.RemoveAll(dest)
+		err = extractPackage(data, dest)
+		if err != nil {
+			return &ErrBundleInvalid{Reason: fmt.Sprintf("%s@%s could not be extracted: %v", entry.Package, entry.Version, err)}
+		}
+	}
+
+	err = replaceDir(roots["platform"], p.PathPlatformDir())
+	if err != nil {
+		return err
+	}
+	err = moveEntries(roots["bin"], global.BinPath())
+	if err != nil {
+		return err
+	}
+	return moveEntries(roots["plugins"], pluginsPath())
+}
+
+func (p *Project) readBundleManifest(path string, reader *tar.Reader) (*BundleManifest, error) {
+	header, err := reader.Next()
+	if err != nil || header.Name != BUNDLE_MANIFEST {
+		return nil, &ErrBundleInvalid{Reason: fmt.Sprintf("%s is not an sst bundle", path)}
+	}
+	var manifest BundleManifest
+	err = json.NewDecoder(reader).Decode(&manifest)
+	if err != nil {
+		return nil, &ErrBundleInvalid{Reason: fmt.Sprintf("%s is not an sst bundle", path)}
+	}
+	if manifest.Version != p.Version() {
+		return nil, &ErrBundleInvalid{Reason: fmt.Sprintf("it was created with sst %s but this is sst %s", manifest.Version, p.Version())}
+	}
+	if manifest.OS != runtime.GOOS || manifest.Arch != runtime.GOARCH {
+		return nil, &ErrBundleInvalid{Reason: fmt.Sprintf("it was created for %s/%s", manifest.OS, manifest.Arch)}
+	}
+	if p.NeedsInstall() {
+		return nil, &ErrBundleInvalid{Reason: "your provider lock does not match the providers in your sst.config.ts, run `sst install` with network access first"}
+	}
+	err = checkBundleProviders(manifest.Providers, p.lock)
+	if err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// The bundle has to be made for the providers in the project's lock, and the
+// lock has to have their integrity to check the bundled tarballs against
+func checkBundleProviders(bundled ProviderLock, lock ProviderLock) error {
+	if len(bundled) != len(lock) {
+		return &ErrBundleInvalid{Reason: "its providers do not match your provider lock"}
+	}
+	match := map[string]*ProviderLockEntry{}
+	for _, entry := range bundled {
+		match[entry.Name] = entry
+	}
+	for _, entry := range lock {
+		if entry.Integrity == "" {
+			return &ErrBundleInvalid{Reason: fmt.Sprintf("your provider lock has no integrity for %s, run `sst install` with network access to record it", entry.Name)}
+		}
+		item, ok := match[entry.Name]
+		if !ok || item.Package != entry.Package || item.Version != entry.Version || item.Integrity != entry.Integrity {
+			return &ErrBundleInvalid{Reason: fmt.Sprintf("its %s provider does not match your provider lock", entry.Name)}
+		}
+	}
+	return nil
+}
+
+// Extracts every entry after the manifest into the root for its prefix,
+// checking each against the manifest. Links can only point inside of their
+// root and nothing can be written through them.
+func extractBundle(reader *tar.Reader, manifest *BundleManifest, roots map[string]string) error {
+	seen := map[string]bool{}
+	links := map[string]bool{}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		expected, ok := manifest.Files[header.Name]
+		prefix, rel, _ := strings.Cut(header.Name, "/")
+		root, known := roots[prefix]
+		rel = filepath.FromSlash(rel)
+		if !ok || !known || !filepath.IsLocal(rel) {
+			return &ErrBundleInvalid{Reason: fmt.Sprintf("%s is not in the manifest", header.Name)}
+		}
+		if seen[header.Name] {
+			return &ErrBundleInvalid{Reason: fmt.Sprintf("%s is in the bundle more than once", header.Name)}
+		}
+		seen[header.Name] = true
+		for parent := path.Dir(header.Name); parent != "."; parent = path.Dir(parent) {
+			if links[parent] {
+				return &ErrBundleInvalid{Reason: fmt.Sprintf("%s is inside of a link", header.Name)}
+			}
+		}
+		dest := filepath.Join(root, rel)
+		err = os.MkdirAll(filepath.Dir(dest), 0755)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			target := filepath.Join(filepath.Dir(rel), filepath.FromSlash(header.Linkname))
+			if filepath.IsAbs(header.Linkname) || !filepath.IsLocal(target) {
+				return &ErrBundleInvalid{Reason: fmt.Sprintf("%s links outside of the bundle", header.Name)}
+			}
+			if "symlink:"+header.Linkname != expected {
+				return &ErrBundleInvalid{Reason: fmt.Sprintf("the checksum of %s does not match", header.Name)}
+			}
+			err = os.Symlink(header.Linkname, dest)
+			if err != nil {
+				return err
+			}
+			links[header.Name] = true
+		case tar.TypeReg:
+			out, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			hasher := sha256.New()
+			_, err = io.Copy(io.MultiWriter(out, hasher), reader)
+			out.Close()
+			if err != nil {
+				return err
+			}
+			if hex.EncodeToString(hasher.Sum(nil)) != expected {
+				return &ErrBundleInvalid{Reason: fmt.Sprintf("the checksum of %s does not match", header.Name)}
+			}
+		default:
+			return &ErrBundleInvalid{Reason: fmt.Sprintf("%s is not a file", header.Name)}
+		}
+	}
+	for name := range manifest.Files {
+		if !seen[name] {
+			return &ErrBundleInvalid{Reason: fmt.Sprintf("%s is missing", name)}
+		}
+	}
+	return nil
+}
+
+// Extracts an npm tarball, which has everything under a single directory
+func extractPackage(data []byte, dest string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		_, rel, _ := strings.Cut(header.Name, "/")
+		rel = filepath.FromSlash(rel)
+		if !filepath.IsLocal(rel) {
+			return fmt.Errorf("%s is outside of the package", header.Name)
+		}
+		path := filepath.Join(dest, rel)
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return err
+		}
+		out, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(header.Mode).Perm()|0644)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, reader)
+		out.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Swaps a directory into place, putting the old one back if that fails
+func replaceDir(src string, dest string) error {
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+	backup := dest + ".old"
+	os.RemoveAll(backup)
+	if _, err := os.Stat(dest); err == nil {
+		err = os.Rename(dest, backup)
+		if err != nil {
+			return err
+		}
+	}
+	err := os.Rename(src, dest)
+	if err != nil {
+		os.Rename(backup, dest)
+		return err
+	}
+	return os.RemoveAll(backup)
+}
+
+// Moves everything in src into dest, replacing what's there with the same name
+func moveEntries(src string, dest string) error {
+	items, err := os.ReadDir(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dest, 0755)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		target := filepath.Join(dest, item.Name())
+		os.RemoveAll(target)
+		err = os.Rename(filepath.Join(src, item.Name()), target)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pulumi downloads plugins on first use, make sure the ones for the providers
+// are there before bundling them
+func (p *Project) installPlugins() error {
+	for _, entry := range p.lock {
+		data, err := os.ReadFile(filepath.Join(p.PathPlatformDir(), "node_modules", entry.Package, "package.json"))
+		if err != nil {
+			return err
+		}
+		var pkg struct {
+			Pulumi struct {
+				Resource bool   `json:"resource"`
+				Name     string `json:"name"`
+				Version  string `json:"version"`
+				Server   string `json:"server"`
+			} `json:"pulumi"`
+		}
+		err = json.Unmarshal(data, &pkg)
+		if err != nil {
+			return err
+		}
+		if !pkg.Pulumi.Resource || pkg.Pulumi.Name == "" || pkg.Pulumi.Version == "" {
+			continue
+		}
+		args := []string{"plugin", "install", "resource", pkg.Pulumi.Name, pkg.Pulumi.Version}
+		if pkg.Pulumi.Server != "" {
+			args = append(args, "--server", pkg.Pulumi.Server)
+		}
+		cmd := process.Command(global.PulumiPath(), args...)
+		cmd.Env = append(os.Environ(), "PULUMI_HOME="+global.ConfigDir())
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to install the %s plugin: %s", pkg.Pulumi.Name, output)
+		}
+	}
+	return nil
+}
+
+func pluginsPath() string {
+	return filepath.Join(global.ConfigDir(), "plugins")
+}
+
+func checksumEntry(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		return "symlink:" + target, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func writeBundleEntry(writer *tar.Writer, entry bundleEntry) error {
+	info, err := os.Lstat(entry.path)
+	if err != nil {
+		return err
+	}
+	link := ""
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err = os.Readlink(entry.path)
+		if err != nil {
+			return err
+		}
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = entry.name
+	err = writer.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	if link != "" {
+		return nil
+	}
+	file, err := os.Open(entry.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(writer, file)
+	return err
+}
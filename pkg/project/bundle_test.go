@@ -0,0 +1,116 @@
+package project
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testBundleEntry struct {
+	name string
+	body string
+	link string
+}
+
+// Builds the entries of a bundle after the manifest along with their checksums
+func testBundle(t *testing.T, entries ...testBundleEntry) (*tar.Reader, *BundleManifest) {
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	manifest := &BundleManifest{Files: map[string]string{}}
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.body)), Typeflag: tar.TypeReg}
+		sum := sha256.Sum256([]byte(entry.body))
+		manifest.Files[entry.name] = hex.EncodeToString(sum[:])
+		if entry.link != "" {
+			header = &tar.Header{Name: entry.name, Typeflag: tar.TypeSymlink, Linkname: entry.link}
+			manifest.Files[entry.name] = "symlink:" + entry.link
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		writer.Write([]byte(entry.body))
+	}
+	writer.Close()
+	return tar.NewReader(&buf), manifest
+}
+
+func TestExtractBundle(t *testing.T) {
+	invalid := map[string][]testBundleEntry{
+		"link outside": {
+			{name: "platform/x", link: "../../.."},
+		},
+		"write through link": {
+			{name: "platform/x", link: "node_modules"},
+			{name: "platform/x/../../evil", body: "evil"},
+		},
+		"file under link": {
+			{name: "platform/node_modules/a", body: "a"},
+			{name: "platform/x", link: "node_modules"},
+			{name: "platform/x/b", body: "b"},
+		},
+		"unknown prefix": {
+			{name: "other/x", body: "x"},
+		},
+	}
+	for name, entries := range invalid {
+		root := t.TempDir()
+		reader, manifest := testBundle(t, entries...)
+		err := extractBundle(reader, manifest, map[string]string{"platform": filepath.Join(root, "platform")})
+		var invalidErr *ErrBundleInvalid
+		if !errors.As(err, &invalidErr) {
+			t.Errorf("%s: expected the bundle to be invalid, got %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(root), "evil")); err == nil {
+			t.Fatalf("%s: wrote outside of the root", name)
+		}
+	}
+
+	root := t.TempDir()
+	reader, manifest := testBundle(t,
+		testBundleEntry{name: "platform/node_modules/a/index.js", body: "a"},
+		testBundleEntry{name: "platform/node_modules/.bin/a", link: "../a/index.js"},
+	)
+	manifest.Files["platform/node_modules/a/index.js"] = "tampered"
+	err := extractBundle(reader, manifest, map[string]string{"platform": root})
+	var invalidErr *ErrBundleInvalid
+	if !errors.As(err, &invalidErr) {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+
+	root = t.TempDir()
+	reader, manifest = testBundle(t,
+		testBundleEntry{name: "platform/node_modules/a/index.js", body: "a"},
+		testBundleEntry{name: "platform/node_modules/.bin/a", link: "../a/index.js"},
+	)
+	if err := extractBundle(reader, manifest, map[string]string{"platform": root}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "node_modules", ".bin", "a")); string(data) != "a" {
+		t.Fatalf("expected the link to resolve, got %q", data)
+	}
+}
+
+func TestCheckBundleProviders(t *testing.T) {
+	lock := ProviderLock{{Name: "aws", Package: "@pulumi/aws", Version: "6.0.0", Integrity: "sha512-a"}}
+	if err := checkBundleProviders(ProviderLock{{Name: "aws", Package: "@pulumi/aws", Version: "6.0.0", Integrity: "sha512-a"}}, lock); err != nil {
+		t.Fatal(err)
+	}
+	for _, bundled := range []ProviderLock{
+		{},
+		{{Name: "aws", Package: "@pulumi/aws", Version: "6.0.1", Integrity: "sha512-a"}},
+		{{Name: "aws", Package: "@pulumi/aws", Version: "6.0.0", Integrity: "sha512-b"}},
+		{{Name: "aws", Package: "aws-evil", Version: "6.0.0", Integrity: "sha512-a"}},
+	} {
+		if err := checkBundleProviders(bundled, lock); err == nil {
+			t.Errorf("expected %+v to not match the lock", bundled)
+		}
+	}
+	if err := checkBundleProviders(lock, ProviderLock{{Name: "aws", Package: "@pulumi/aws", Version: "6.0.0"}}); err == nil {
+		t.Error("expected a lock without integrity to be rejected")
+	}
+}
@@ -0,0 +1,101 @@
+package project
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/sst/sst/v3/pkg/project/provider"
+)
+
+// Rules for which secrets are cloned from one stage to another and how their
+// values are changed. Include and exclude are glob patterns matched against
+// the secret names.
+type CloneRules struct {
+	Include []string
+	Exclude []string
+	Replace []CloneReplace
+}
+
+// Replaces every occurrence of Old with New in the cloned values, like the
+// hostname of the source stage
+type CloneReplace struct {
+	Old string
+	New string
+}
+
+// ParseCloneReplace parses a comma separated list of old=new pairs
+func ParseCloneReplace(value string) ([]CloneReplace, error) {
+	result := []CloneReplace{}
+	if value == "" {
+		return result, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		old, next, ok := strings.Cut(item, "=")
+		if !ok || old == "" {
+			return nil, fmt.Errorf("invalid replace rule %q", item)
+		}
+		result = append(result, CloneReplace{Old: old, New: next})
+	}
+	return result, nil
+}
+
+func (r *CloneRules) matches(name string) bool {
+	if len(r.Include) > 0 && !matchAny(r.Include, name) {
+		return false
+	}
+	return !matchAny(r.Exclude, name)
+}
+
+func (r *CloneRules) Apply(secrets map[string]string) map[string]string {
+	result := map[string]string{}
+	for name, value := range secrets {
+		if !r.matches(name) {
+			continue
+		}
+		for _, replace := range r.Replace {
+			value = strings.ReplaceAll(value, replace.Old, replace.New)
+		}
+		result[name] = value
+	}
+	return result
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CloneSecrets copies the secrets of one stage to another. They are decrypted
+// with the passphrase of the source stage and encrypted again with the one of
+// the target. Secrets that already exist in the target are kept unless they
+// are cloned over. Only secrets are copied, never the state or the lock.
+func (p *Project) CloneSecrets(from, to string, rules *CloneRules, dryRun bool) (map[string]string, error) {
+	if InvalidStageRegex.MatchString(to) {
+		return nil, ErrInvalidStageName
+	}
+	source, err := provider.GetSecrets(p.home, p.app.Name, from)
+	if err != nil {
+		return nil, err
+	}
+	cloned := rules.Apply(source)
+	if dryRun || len(cloned) == 0 {
+		return cloned, nil
+	}
+	target, err := provider.GetSecrets(p.home, p.app.Name, to)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range cloned {
+		target[name] = value
+	}
+	err = provider.PutSecrets(p.home, p.app.Name, to, target)
+	if err != nil {
+		return nil, err
+	}
+	return cloned, nil
+}
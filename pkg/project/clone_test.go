@@ -0,0 +1,54 @@
+package project
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCloneRules(t *testing.T) {
+	secrets := map[string]string{
+		"StripeKey":      "sk_test",
+		"StripeWebhook":  "https://dev.example.com/stripe",
+		"DatabaseUrl":    "postgres://dev.example.com/app",
+		"GithubToken":    "ghp_token",
+		"GithubClientId": "client",
+	}
+
+	result := (&CloneRules{}).Apply(secrets)
+	if !reflect.DeepEqual(result, secrets) {
+		t.Fatalf("expected every secret without rules, got %v", result)
+	}
+
+	result = (&CloneRules{
+		Include: []string{"Stripe*", "Database*", "GithubToken"},
+		Exclude: []string{"StripeKey"},
+		Replace: []CloneReplace{{Old: "dev.example.com", New: "pr-12.example.com"}},
+	}).Apply(secrets)
+	expected := map[string]string{
+		"StripeWebhook": "https://pr-12.example.com/stripe",
+		"DatabaseUrl":   "postgres://pr-12.example.com/app",
+		"GithubToken":   "ghp_token",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+
+	result = (&CloneRules{Exclude: []string{"Github*"}}).Apply(secrets)
+	if len(result) != 3 || result["GithubToken"] != "" {
+		t.Fatalf("expected the excluded secrets to be dropped, got %v", result)
+	}
+}
+
+func TestParseCloneReplace(t *testing.T) {
+	result, err := ParseCloneReplace("dev.example.com=pr.example.com,_dev=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []CloneReplace{{Old: "dev.example.com", New: "pr.example.com"}, {Old: "_dev", New: ""}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+	if _, err := ParseCloneReplace("=value"); err == nil {
+		t.Fatal("expected a rule without an old value to fail")
+	}
+}
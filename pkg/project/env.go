@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"time"
 
 	awssdk "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
@@ -17,29 +18,34 @@ func (p *Project) EnvFor(ctx context.Context, complete *CompleteEvent, name stri
 	env := map[string]string{}
 	if dev.Aws != nil && dev.Aws.Role != "" {
 		log.Info("loading aws credentials", "role", dev.Aws.Role)
-		prov, _ := p.Provider("aws")
-		awsProvider := prov.(*provider.AwsProvider)
-		stsClient := sts.NewFromConfig(awsProvider.Config())
-		sessionName := "sst-dev"
-		result, err := stsClient.AssumeRole(ctx, &sts.AssumeRoleInput{
-			RoleArn:         &dev.Aws.Role,
-			RoleSessionName: &sessionName,
-			DurationSeconds: awssdk.Int32(3600),
-		})
+		creds, err := p.AwsEnv(ctx, dev.Aws.Role)
 		if err == nil {
-			env["AWS_ACCESS_KEY_ID"] = *result.Credentials.AccessKeyId
-			env["AWS_SECRET_ACCESS_KEY"] = *result.Credentials.SecretAccessKey
-			env["AWS_SESSION_TOKEN"] = *result.Credentials.SessionToken
-			env["AWS_REGION"] = awsProvider.Config().Region
+			for key, value := range creds {
+				env[key] = value
+			}
 		}
 
 		if err != nil {
 			log.Error("failed to load aws credentials", "err", err)
 		}
 	}
+	emulators, err := p.waitForEmulator(ctx)
+	if err != nil {
+		return nil, err
+	}
 	log.Info("dev", "links", dev.Links)
 	for _, resource := range dev.Links {
 		value := complete.Links[resource].Properties
+		if emulators != nil {
+			properties, extra, err := emulators.Link(ctx, resource, complete.Links[resource], complete.Resources)
+			if err != nil {
+				return nil, err
+			}
+			value = properties
+			for key, val := range extra {
+				env[key] = val
+			}
+		}
 		jsonValue, _ := json.Marshal(value)
 		env["SST_RESOURCE_"+resource] = string(jsonValue)
 	}
@@ -49,3 +55,71 @@ func (p *Project) EnvFor(ctx context.Context, complete *CompleteEvent, name stri
 	}
 	return env, nil
 }
+
+// EnvForLinks returns the SST_RESOURCE_ variables for the given links, or for
+// every link in the app when none are passed in.
+func (p *Project) EnvForLinks(complete *CompleteEvent, links []string) (map[string]string, error) {
+	env := map[string]string{}
+	if len(links) == 0 {
+		for name := range complete.Links {
+			links = append(links, name)
+		}
+	}
+	for _, name := range links {
+		link, ok := complete.Links[name]
+		if !ok {
+			return nil, fmt.Errorf("link %s not found", name)
+		}
+		jsonValue, err := json.Marshal(link.Properties)
+		if err != nil {
+			return nil, err
+		}
+		env["SST_RESOURCE_"+name] = string(jsonValue)
+	}
+	env["SST_RESOURCE_App"] = fmt.Sprintf(`{"name": "%s", "stage": "%s" }`, p.App().Name, p.App().Stage)
+	return env, nil
+}
+
+// AwsEnv returns the AWS credentials of the app as environment variables. If
+// a role is passed in, it is assumed and its temporary credentials are used.
+// AWS_CREDENTIAL_EXPIRATION is set when the credentials expire.
+func (p *Project) AwsEnv(ctx context.Context, role string) (map[string]string, error) {
+	prov, ok := p.Provider("aws")
+	if !ok {
+		return nil, fmt.Errorf("aws provider not found")
+	}
+	awsProvider := prov.(*provider.AwsProvider)
+	env := map[string]string{}
+	if role != "" {
+		stsClient := sts.NewFromConfig(awsProvider.Config())
+		sessionName := "sst-dev"
+		result, err := stsClient.AssumeRole(ctx, &sts.AssumeRoleInput{
+			RoleArn:         &role,
+			RoleSessionName: &sessionName,
+			DurationSeconds: awssdk.Int32(3600),
+		})
+		if err != nil {
+			return nil, err
+		}
+		env["AWS_ACCESS_KEY_ID"] = *result.Credentials.AccessKeyId
+		env["AWS_SECRET_ACCESS_KEY"] = *result.Credentials.SecretAccessKey
+		env["AWS_SESSION_TOKEN"] = *result.Credentials.SessionToken
+		env["AWS_CREDENTIAL_EXPIRATION"] = result.Credentials.Expiration.UTC().Format(time.RFC3339)
+		env["AWS_REGION"] = awsProvider.Config().Region
+		return env, nil
+	}
+	creds, err := awsProvider.Config().Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	env["AWS_ACCESS_KEY_ID"] = creds.AccessKeyID
+	env["AWS_SECRET_ACCESS_KEY"] = creds.SecretAccessKey
+	env["AWS_SESSION_TOKEN"] = creds.SessionToken
+	if creds.CanExpire {
+		env["AWS_CREDENTIAL_EXPIRATION"] = creds.Expires.UTC().Format(time.RFC3339)
+	}
+	if region := awsProvider.Config().Region; region != "" {
+		env["AWS_REGION"] = region
+	}
+	return env, nil
+}
@@ -0,0 +1,26 @@
+package project
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitForEmulator(t *testing.T) {
+	p := &Project{}
+	if m, err := p.waitForEmulator(context.Background()); m != nil || err != nil {
+		t.Fatal("expected no wait when the emulators aren't started")
+	}
+
+	p.StartEmulator()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.waitForEmulator(ctx); err == nil {
+		t.Fatal("expected to wait until the emulators are set")
+	}
+
+	go p.SetEmulator(nil)
+	if _, err := p.waitForEmulator(context.Background()); err == nil {
+		t.Fatal("expected an error when the emulators failed to start")
+	}
+}
@@ -0,0 +1,290 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+	"github.com/sst/sst/v3/pkg/global"
+	"github.com/sst/sst/v3/pkg/id"
+	"github.com/sst/sst/v3/pkg/process"
+	"github.com/sst/sst/v3/pkg/project/provider"
+)
+
+// A cloud resource to import, identified by its pulumi type and the ID the
+// provider uses to look it up
+type ImportResource struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+var ErrImportNoAws = fmt.Errorf("the aws provider is needed to discover resources by tag")
+
+type ErrImportFailed struct {
+	Output string
+}
+
+func (e *ErrImportFailed) Error() string {
+	return "failed to read the resources to import: " + e.Output
+}
+
+// The AWS resources that can be discovered by tag, keyed by the service and
+// the resource type in their ARN
+var awsImportTypes = map[string]struct {
+	Type string
+	ID   func(parsed arn.ARN, name string) string
+}{
+	"s3:": {"aws:s3/bucketV2:BucketV2", func(parsed arn.ARN, name string) string {
+		return parsed.Resource
+	}},
+	"dynamodb:table": {"aws:dynamodb/table:Table", func(parsed arn.ARN, name string) string {
+		return name
+	}},
+	"lambda:function": {"aws:lambda/function:Function", func(parsed arn.ARN, name string) string {
+		return name
+	}},
+	"sqs:": {"aws:sqs/queue:Queue", func(parsed arn.ARN, name string) string {
+		return fmt.Sprintf("https://sqs.%s.amazonaws.com/%s/%s", parsed.Region, parsed.AccountID, parsed.Resource)
+	}},
+	"sns:": {"aws:sns/topic:Topic", func(parsed arn.ARN, name string) string {
+		return parsed.String()
+	}},
+	"ecr:repository": {"aws:ecr/repository:Repository", func(parsed arn.ARN, name string) string {
+		return name
+	}},
+	"logs:log-group": {"aws:cloudwatch/logGroup:LogGroup", func(parsed arn.ARN, name string) string {
+		return strings.TrimSuffix(name, ":*")
+	}},
+	"kinesis:stream": {"aws:kinesis/stream:Stream", func(parsed arn.ARN, name string) string {
+		return name
+	}},
+	"states:stateMachine": {"aws:sfn/stateMachine:StateMachine", func(parsed arn.ARN, name string) string {
+		return parsed.String()
+	}},
+}
+
+// DiscoverImports finds the AWS resources with all of the given tags. The
+// ARNs of resources that can't be imported this way are returned separately.
+func (p *Project) DiscoverImports(ctx context.Context, tags map[string]string) ([]ImportResource, []string, error) {
+	match, ok := p.Provider("aws")
+	if !ok {
+		return nil, nil, ErrImportNoAws
+	}
+	client := resourcegroupstaggingapi.NewFromConfig(match.(*provider.AwsProvider).Config())
+
+	filters := []types.TagFilter{}
+	for key, value := range tags {
+		filters = append(filters, types.TagFilter{
+			Key:    aws.String(key),
+			Values: []string{value},
+		})
+	}
+	arns := []string{}
+	pages := resourcegroupstaggingapi.NewGetResourcesPaginator(client, &resourcegroupstaggingapi.GetResourcesInput{
+		TagFilters: filters,
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, item := range page.ResourceTagMappingList {
+			arns = append(arns, aws.ToString(item.ResourceARN))
+		}
+	}
+
+	resources := []ImportResource{}
+	unsupported := []string{}
+	for _, value := range arns {
+		parsed, err := arn.Parse(value)
+		if err != nil {
+			unsupported = append(unsupported, value)
+			continue
+		}
+		kind, name := "", parsed.Resource
+		if index := strings.IndexAny(parsed.Resource, ":/"); index != -1 {
+			kind, name = parsed.Resource[:index], parsed.Resource[index+1:]
+		}
+		match, ok := awsImportTypes[parsed.Service+":"+kind]
+		if !ok {
+			unsupported = append(unsupported, value)
+			continue
+		}
+		resources = append(resources, ImportResource{
+			Type: match.Type,
+			ID:   match.ID(parsed, name),
+		})
+	}
+	return resources, unsupported, nil
+}
+
+var importNameRegex = regexp.MustCompile(`[a-zA-Z0-9]+`)
+
+// NameImports gives the resources without a name one based on their ID, like
+// MyBucket for my-bucket
+func NameImports(resources []ImportResource) {
+	seen := map[string]bool{}
+	for index := range resources {
+		name := resources[index].Name
+		if name == "" {
+			id := resources[index].ID
+			if slash := strings.LastIndexAny(id, "/:"); slash != -1 && slash < len(id)-1 {
+				id = id[slash+1:]
+			}
+			for _, part := range importNameRegex.FindAllString(id, -1) {
+				name += strings.ToUpper(part[:1]) + part[1:]
+			}
+			if name == "" || !unicode.IsLetter(rune(name[0])) {
+				name = "Imported" + name
+			}
+		}
+		next := name
+		for count := 2; seen[next]; count++ {
+			next = name + strconv.Itoa(count)
+		}
+		seen[next] = true
+		resources[index].Name = next
+	}
+}
+
+// GenerateImports reads the given resources from the cloud and returns the
+// code to add to the sst.config.ts to import them. The inputs in the code
+// match the current values of the resources so the import has no diffs.
+// Nothing is written to the state.
+func (p *Project) GenerateImports(ctx context.Context, resources []ImportResource) (string, error) {
+	log := slog.Default().With("service", "project.import")
+	workdir, err := p.NewWorkdir(id.Descending())
+	if err != nil {
+		return "", err
+	}
+	defer workdir.Cleanup()
+	passphrase, err := provider.Passphrase(p.home, p.app.Name, p.app.Stage)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(
+		filepath.Join(workdir.path, "Pulumi.yaml"),
+		[]byte("name: "+p.app.Name+"\nruntime: nodejs\n"),
+		0644,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	env := os.Environ()
+	for key, value := range p.Env() {
+		env = append(env, fmt.Sprintf("%v=%v", key, value))
+	}
+	if match, ok := p.Provider("aws"); ok {
+		cfg := match.(*provider.AwsProvider).Config()
+		credentials, err := cfg.Credentials.Retrieve(ctx)
+		if err != nil {
+			return "", err
+		}
+		env = append(env,
+			"AWS_REGION="+cfg.Region,
+			"AWS_ACCESS_KEY_ID="+credentials.AccessKeyID,
+			"AWS_SECRET_ACCESS_KEY="+credentials.SecretAccessKey,
+			"AWS_SESSION_TOKEN="+credentials.SessionToken,
+		)
+	}
+	env = append(env,
+		"PULUMI_CONFIG_PASSPHRASE="+passphrase,
+		"PULUMI_SKIP_UPDATE_CHECK=true",
+		"PULUMI_BACKEND_URL=file://"+filepath.ToSlash(workdir.Backend()),
+		"PULUMI_HOME="+global.ConfigDir(),
+	)
+	stack := fmt.Sprintf("organization/%v/%v", p.app.Name, p.app.Stage)
+
+	// the import only needs the stack to exist, a new stage gets an empty one
+	// that is thrown away
+	_, err = workdir.Pull()
+	if err != nil {
+		if !errors.Is(err, provider.ErrStateNotFound) {
+			return "", err
+		}
+		cmd := process.Command(global.PulumiPath(), "stack", "init", stack)
+		cmd.Dir = workdir.path
+		cmd.Env = env
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return "", &ErrImportFailed{Output: string(output)}
+		}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"resources": resources,
+	})
+	if err != nil {
+		return "", err
+	}
+	importPath := filepath.Join(workdir.path, "import.json")
+	err = os.WriteFile(importPath, data, 0644)
+	if err != nil {
+		return "", err
+	}
+	outPath := filepath.Join(workdir.path, "import.ts")
+	cmd := process.Command(global.PulumiPath(),
+		"import",
+		"--file", importPath,
+		"--preview-only",
+		"--generate-code",
+		"--out", outPath,
+		"--stack", stack,
+		"--non-interactive",
+	)
+	cmd.Dir = workdir.Backend()
+	cmd.Env = env
+	log.Info("generating imports", "args", cmd.Args)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", &ErrImportFailed{Output: strings.TrimSpace(string(output))}
+	}
+	code, err := os.ReadFile(outPath)
+	if err != nil {
+		return "", err
+	}
+	return formatImports(string(code), resources), nil
+}
+
+var importResourceRegex = regexp.MustCompile(`new [\w.]+\("([^"]+)"`)
+
+// The generated code imports the providers and protects the resources. In
+// sst.config.ts the providers are globals and the resources need the import
+// option instead. The protect option is matched to the resource it belongs to
+// by the name the resource is declared with.
+func formatImports(code string, resources []ImportResource) string {
+	ids := map[string]string{}
+	for _, resource := range resources {
+		ids[resource.Name] = resource.ID
+	}
+	lines := []string{}
+	current := ""
+	for _, line := range strings.Split(code, "\n") {
+		if strings.HasPrefix(line, "import ") {
+			continue
+		}
+		if match := importResourceRegex.FindStringSubmatch(line); match != nil {
+			current = match[1]
+		}
+		if id, ok := ids[current]; ok && strings.TrimSpace(line) == "protect: true," {
+			line = strings.Replace(line, "protect: true,", "import: "+strconv.Quote(id)+",", 1)
+			current = ""
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")) + "\n"
+}
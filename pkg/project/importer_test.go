@@ -0,0 +1,58 @@
+package project
+
+import (
+	"testing"
+)
+
+func TestNameImports(t *testing.T) {
+	resources := []ImportResource{
+		{ID: "my-bucket"},
+		{ID: "my_bucket"},
+		{ID: "arn:aws:sns:us-east-1:123456789012:orders"},
+		{ID: "123-queue"},
+		{ID: "custom", Name: "Custom"},
+	}
+	NameImports(resources)
+	expected := []string{"MyBucket", "MyBucket2", "Orders", "Imported123Queue", "Custom"}
+	for index, resource := range resources {
+		if resource.Name != expected[index] {
+			t.Errorf("Expected %s, got %s", expected[index], resource.Name)
+		}
+	}
+}
+
+func TestFormatImports(t *testing.T) {
+	code := `import * as pulumi from "@pulumi/pulumi";
+import * as aws from "@pulumi/aws";
+
+const orders = new aws.sqs.Queue("Orders", {
+    name: "orders",
+}, {
+    protect: true,
+});
+const myBucket = new aws.s3.BucketV2("MyBucket", {
+    bucket: "my-bucket",
+}, {
+    protect: true,
+});
+`
+	expected := `const orders = new aws.sqs.Queue("Orders", {
+    name: "orders",
+}, {
+    import: "https://sqs.us-east-1.amazonaws.com/123456789012/orders",
+});
+const myBucket = new aws.s3.BucketV2("MyBucket", {
+    bucket: "my-bucket",
+}, {
+    import: "my-bucket",
+});
+`
+	// the generated code doesn't follow the order of the input
+	result := formatImports(code, []ImportResource{
+		{Name: "MyBucket", ID: "my-bucket"},
+		{Name: "Orders", ID: "https://sqs.us-east-1.amazonaws.com/123456789012/orders"},
+	})
+	if result != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, result)
+	}
+}
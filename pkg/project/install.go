@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
@@ -30,6 +31,23 @@ func (err *ErrProviderVersionTooLow) Error() string {
 	return "provider version too low"
 }
 
+type ErrProviderIntegrity struct {
+	Name   string
+	Reason string
+}
+
+func (err *ErrProviderIntegrity) Error() string {
+	return "provider integrity check failed"
+}
+
+type ErrProviderLockChanged struct {
+	Changes []string
+}
+
+func (err *ErrProviderLockChanged) Error() string {
+	return "provider lock changed"
+}
+
 func (p *Project) NeedsInstall() bool {
 	if len(p.app.Providers) != len(p.lock) {
 		return true
@@ -53,9 +71,31 @@ func (p *Project) NeedsInstall() bool {
 }
 
 func (p *Project) Install() error {
-	slog.Info("installing deps")
+	return p.install(false)
+}
+
+// InstallFrozen installs the providers exactly as they are in the provider
+// lock and fails if the lock would have to change.
+func (p *Project) InstallFrozen() error {
+	return p.install(true)
+}
 
-	err := p.generateProviderLock()
+func (p *Project) install(frozen bool) error {
+	slog.Info("installing deps", "frozen", frozen)
+
+	previous := readProviderLock(p.PathConfig())
+	err := p.generateProviderLock(previous, frozen)
+	if err != nil {
+		return err
+	}
+	if frozen {
+		changes := diffProviderLock(previous, p.lock)
+		if len(changes) > 0 {
+			return &ErrProviderLockChanged{Changes: changes}
+		}
+	}
+
+	err = p.fetchProviders()
 	if err != nil {
 		return err
 	}
@@ -70,11 +110,19 @@ func (p *Project) Install() error {
 		return err
 	}
 
+	err = p.verifyProviders()
+	if err != nil {
+		return err
+	}
+
 	err = p.writeTypes()
 	if err != nil {
 		return err
 	}
 
+	if frozen {
+		return nil
+	}
 	err = p.writeProviderLock()
 	if err != nil {
 		return err
@@ -106,7 +154,7 @@ func (p *Project) writePackageJson() error {
 	dependencies := result["dependencies"].(map[string]interface{})
 	for _, entry := range p.lock {
 		slog.Info("adding dependency", "name", entry.Name)
-		dependencies[entry.Package] = entry.Version
+		dependencies[entry.Package] = "file:./" + PROVIDER_PACKAGES + "/" + providerTarballName(entry)
 	}
 	dependencies["@pulumi/pulumi"] = global.PULUMI_VERSION
 
@@ -185,15 +233,103 @@ func (p *Project) fetchDeps() error {
 	return nil
 }
 
+// Where the verified provider tarballs are kept in the platform directory
+const PROVIDER_PACKAGES = "packages"
+
+// Downloads the tarball of every provider and checks it against the integrity
+// in the lock. The package manager installs these files instead of resolving
+// the versions again, so what's installed is what was checked.
+func (p *Project) fetchProviders() error {
+	slog.Info("fetching providers")
+	dir := filepath.Join(p.PathPlatformDir(), PROVIDER_PACKAGES)
+	os.RemoveAll(dir)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	var wg errgroup.Group
+	for _, entry := range p.lock {
+		entry := entry
+		wg.Go(func() error {
+			if entry.Integrity == "" {
+				return &ErrProviderIntegrity{Name: entry.Name, Reason: fmt.Sprintf("there is no integrity to check %s@%s against", entry.Package, entry.Version)}
+			}
+			pkg, err := npm.Get(entry.Package, entry.Version)
+			if err != nil {
+				return err
+			}
+			if pkg.Dist == nil || pkg.Dist.Tarball == "" {
+				return &ErrProviderIntegrity{Name: entry.Name, Reason: fmt.Sprintf("the registry did not return a tarball for %s@%s", entry.Package, entry.Version)}
+			}
+			data, err := npm.Download(pkg.Dist.Tarball, entry.Integrity)
+			if errors.Is(err, npm.ErrIntegrityMismatch) {
+				return &ErrProviderIntegrity{Name: entry.Name, Reason: fmt.Sprintf("the downloaded %s@%s does not match the integrity %s", entry.Package, entry.Version, entry.Integrity)}
+			}
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(dir, providerTarballName(entry)), data, 0644)
+		})
+	}
+	return wg.Wait()
+}
+
+func providerTarballName(entry *ProviderLockEntry) string {
+	return strings.ReplaceAll(entry.Package, "/", "+") + "@" + entry.Version + ".tgz"
+}
+
 type ProviderLockEntry struct {
 	Name    string `json:"name"`
 	Package string `json:"package"`
 	Version string `json:"version"`
 	Alias   string `json:"alias"`
+	// Integrity is the hash of the package tarball from the registry
+	Integrity string `json:"integrity,omitempty"`
+	// PluginVersion is the version of the pulumi plugin the package runs
+	PluginVersion string `json:"pluginVersion,omitempty"`
 }
 
 type ProviderLock = []*ProviderLockEntry
 
+func readProviderLock(cfgPath string) ProviderLock {
+	lock := ProviderLock{}
+	data, err := os.ReadFile(path.ResolveProviderLock(cfgPath))
+	if err != nil {
+		return lock
+	}
+	json.Unmarshal(data, &lock)
+	return lock
+}
+
+// Lists what would change in the provider lock, compared by provider name
+func diffProviderLock(previous ProviderLock, next ProviderLock) []string {
+	changes := []string{}
+	old := map[string]*ProviderLockEntry{}
+	for _, entry := range previous {
+		old[entry.Name] = entry
+	}
+	for _, entry := range next {
+		match, ok := old[entry.Name]
+		delete(old, entry.Name)
+		if !ok {
+			changes = append(changes, fmt.Sprintf("%s would be added at %s", entry.Name, entry.Version))
+			continue
+		}
+		if *match != *entry && match.Package == entry.Package && match.Version == entry.Version {
+			changes = append(changes, fmt.Sprintf("%s@%s would record a different integrity or plugin version", entry.Package, entry.Version))
+			continue
+		}
+		if *match != *entry {
+			changes = append(changes, fmt.Sprintf("%s would change from %s@%s to %s@%s", entry.Name, match.Package, match.Version, entry.Package, entry.Version))
+		}
+	}
+	for name := range old {
+		changes = append(changes, fmt.Sprintf("%s would be removed", name))
+	}
+	sort.Strings(changes)
+	return changes
+}
+
 func (p *Project) loadProviderLock() error {
 	lockPath := path.ResolveProviderLock(p.PathConfig())
 	data, err := os.ReadFile(lockPath)
@@ -208,7 +344,7 @@ func (p *Project) loadProviderLock() error {
 	return nil
 }
 
-func (p *Project) generateProviderLock() error {
+func (p *Project) generateProviderLock(previous ProviderLock, frozen bool) error {
 	var wg errgroup.Group
 	out := ProviderLock{}
 	results := make(chan ProviderLockEntry, 1000)
@@ -216,6 +352,10 @@ func (p *Project) generateProviderLock() error {
 	if err != nil {
 		return err
 	}
+	locked := map[string]*ProviderLockEntry{}
+	for _, entry := range previous {
+		locked[entry.Name] = entry
+	}
 	for name, config := range p.app.Providers {
 		n := name
 		version := config.(map[string]interface{})["version"]
@@ -223,12 +363,12 @@ func (p *Project) generateProviderLock() error {
 			version = "latest"
 		}
 		wg.Go(func() error {
-			result, err := FindProvider(n, version.(string))
+			result, err := resolveProvider(n, version.(string), locked[n], frozen)
 			if err != nil {
 				return err
 			}
 			if match, ok := pkg.Dependencies[result.Package]; ok {
-				if version == "latest" {
+				if version == "latest" && !frozen {
 					result.Version = match
 				}
 				if semver.MustParse(result.Version).Compare(semver.MustParse(match)) < 0 {
@@ -253,10 +393,43 @@ func (p *Project) generateProviderLock() error {
 		out = append(out, &r)
 	}
 	close(results)
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
 	p.lock = out
 	return nil
 }
 
+// Providers that are already locked are looked up by their locked package
+// instead of searching the registry again, so a similarly named package can't
+// replace them. When the locked version is used its integrity has to match.
+func resolveProvider(name string, version string, locked *ProviderLockEntry, frozen bool) (*ProviderLockEntry, error) {
+	if locked == nil {
+		if frozen {
+			return &ProviderLockEntry{Name: name, Version: version}, nil
+		}
+		return FindProvider(name, version)
+	}
+	if version == "latest" && frozen {
+		version = locked.Version
+	}
+	pkg, err := npm.Get(locked.Package, version)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.Pulumi == nil {
+		return nil, &ErrProviderIntegrity{Name: name, Reason: fmt.Sprintf("%s is not a pulumi provider", pkg.Name)}
+	}
+	result := newProviderLockEntry(name, pkg)
+	if result.Version == locked.Version && locked.Integrity != "" && result.Integrity != locked.Integrity {
+		return nil, &ErrProviderIntegrity{
+			Name:   name,
+			Reason: fmt.Sprintf("the registry returned %s for %s@%s but the lock has %s", result.Integrity, result.Package, result.Version, locked.Integrity),
+		}
+	}
+	return result, nil
+}
+
 func FindProvider(name string, version string) (*ProviderLockEntry, error) {
 	for _, prefix := range []string{"@sst-provider/", "@pulumi/", "@pulumiverse/", "pulumi-", "@", ""} {
 		pkg, err := npm.Get(prefix+name, version)
@@ -266,25 +439,56 @@ func FindProvider(name string, version string) (*ProviderLockEntry, error) {
 		if pkg.Pulumi == nil {
 			continue
 		}
-		alias := pkg.Pulumi.Name
-		if alias == "" || alias == "terraform-provider" {
-			alias = pkg.Name
-			alias = strings.ReplaceAll(alias, "@sst-provider", "")
-			alias = strings.ReplaceAll(alias, "/", "")
-			alias = strings.ReplaceAll(alias, "@", "")
-			alias = strings.ReplaceAll(alias, "pulumi", "")
-		}
-		alias = strings.ReplaceAll(alias, "-", "")
-		return &ProviderLockEntry{
-			Name:    name,
-			Package: pkg.Name,
-			Version: pkg.Version,
-			Alias:   alias,
-		}, nil
+		return newProviderLockEntry(name, pkg), nil
 	}
 	return nil, fmt.Errorf("provider %s not found", name)
 }
 
+func newProviderLockEntry(name string, pkg *npm.Package) *ProviderLockEntry {
+	alias := pkg.Pulumi.Name
+	if alias == "" || alias == "terraform-provider" {
+		alias = pkg.Name
+		alias = strings.ReplaceAll(alias, "@sst-provider", "")
+		alias = strings.ReplaceAll(alias, "/", "")
+		alias = strings.ReplaceAll(alias, "@", "")
+		alias = strings.ReplaceAll(alias, "pulumi", "")
+	}
+	alias = strings.ReplaceAll(alias, "-", "")
+	entry := &ProviderLockEntry{
+		Name:          name,
+		Package:       pkg.Name,
+		Version:       pkg.Version,
+		Alias:         alias,
+		PluginVersion: pkg.Pulumi.Version,
+	}
+	if pkg.Dist != nil {
+		entry.Integrity = pkg.Dist.Integrity
+	}
+	return entry
+}
+
+// Checks that the packages that were installed are the ones in the lock
+func (p *Project) verifyProviders() error {
+	for _, entry := range p.lock {
+		data, err := os.ReadFile(filepath.Join(p.PathPlatformDir(), "node_modules", entry.Package, "package.json"))
+		if err != nil {
+			return &ErrProviderIntegrity{Name: entry.Name, Reason: fmt.Sprintf("%s was not installed", entry.Package)}
+		}
+		var installed npm.Package
+		err = json.Unmarshal(data, &installed)
+		if err != nil {
+			return err
+		}
+		if installed.Version != entry.Version {
+			return &ErrProviderIntegrity{Name: entry.Name, Reason: fmt.Sprintf("%s@%s was installed instead of %s", entry.Package, installed.Version, entry.Version)}
+		}
+		if entry.PluginVersion != "" && (installed.Pulumi == nil || installed.Pulumi.Version != entry.PluginVersion) {
+			return &ErrProviderIntegrity{Name: entry.Name, Reason: fmt.Sprintf("%s does not use version %s of the pulumi plugin", entry.Package, entry.PluginVersion)}
+		}
+	}
+	return nil
+}
+
 func (p *Project) writeProviderLock() error {
 	lockPath := path.ResolveProviderLock(p.PathConfig())
 	data, err := json.MarshalIndent(p.lock, "", "  ")
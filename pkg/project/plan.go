@@ -0,0 +1,148 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/zeebo/xxh3"
+)
+
+const PLAN_VERSION = 1
+
+// A plan saved by `sst diff --out` that `sst deploy --plan` applies. Along
+// with the engine's update plan it records what the diff was computed from so
+// the deploy can refuse to run if any of it has changed.
+type Plan struct {
+	Version   int             `json:"version"`
+	App       string          `json:"app"`
+	Stage     string          `json:"stage"`
+	Created   string          `json:"created"`
+	Target    []string        `json:"target,omitempty"`
+	Dev       bool            `json:"dev,omitempty"`
+	Config    string          `json:"config"`
+	State     string          `json:"state"`
+	Secrets   []string        `json:"secrets"`
+	Providers ProviderLock    `json:"providers"`
+	Engine    json.RawMessage `json:"engine"`
+}
+
+type ErrPlanInvalid struct {
+	Reason string
+}
+
+func (e *ErrPlanInvalid) Error() string {
+	return "plan is invalid: " + e.Reason
+}
+
+func ReadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &ErrPlanInvalid{Reason: fmt.Sprintf("%s could not be read", path)}
+	}
+	var plan Plan
+	err = json.Unmarshal(data, &plan)
+	if err != nil || len(plan.Engine) == 0 {
+		return nil, &ErrPlanInvalid{Reason: fmt.Sprintf("%s is not a plan file", path)}
+	}
+	if plan.Version != PLAN_VERSION {
+		return nil, &ErrPlanInvalid{Reason: fmt.Sprintf("%s was created by an unsupported version of sst", path)}
+	}
+	return &plan, nil
+}
+
+func (plan *Plan) Write(path string) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (plan *Plan) checkApp(app *App, lock ProviderLock) error {
+	if plan.App != app.Name || plan.Stage != app.Stage {
+		return &ErrPlanInvalid{Reason: fmt.Sprintf("it was made for the %s app on the %s stage", plan.App, plan.Stage)}
+	}
+	if hashJSON(plan.Providers) != hashJSON(lock) {
+		return &ErrPlanInvalid{Reason: "the providers have changed since it was made"}
+	}
+	return nil
+}
+
+func (plan *Plan) checkState(resources []apitype.ResourceV3) error {
+	if plan.State != hashJSON(resources) {
+		return &ErrPlanInvalid{Reason: "the state of this stage has changed since it was made"}
+	}
+	return nil
+}
+
+func (plan *Plan) checkConfig(hash string) error {
+	if plan.Config != hash {
+		return &ErrPlanInvalid{Reason: "the sst.config.ts or the files it imports have changed since it was made"}
+	}
+	return nil
+}
+
+func (plan *Plan) checkSecrets(names []string) error {
+	if hashJSON(plan.Secrets) != hashJSON(names) {
+		return &ErrPlanInvalid{Reason: "the secrets have changed since it was made"}
+	}
+	return nil
+}
+
+func hashJSON(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return strconv.FormatUint(xxh3.Hash(data), 16)
+}
+
+// The output of the build includes the command being run so it can't be
+// compared across diff and deploy, hash the inputs instead
+func hashConfig(files []string, app *App) (string, error) {
+	sorted := append([]string{}, files...)
+	sort.Strings(sorted)
+	hasher := xxh3.New()
+	appBytes, err := json.Marshal(app)
+	if err != nil {
+		return "", err
+	}
+	hasher.Write(appBytes)
+	for _, file := range sorted {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		hasher.WriteString(file)
+		hasher.Write(data)
+	}
+	return strconv.FormatUint(hasher.Sum64(), 16), nil
+}
+
+func secretNames(secrets ...map[string]string) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, group := range secrets {
+		for key := range group {
+			if !seen[key] {
+				seen[key] = true
+				names = append(names, key)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newPlan(app *App, input *StackInput) *Plan {
+	return &Plan{
+		Version: PLAN_VERSION,
+		App:     app.Name,
+		Stage:   app.Stage,
+		Created: time.Now().Format(time.RFC3339),
+		Target:  input.Target,
+		Dev:     input.Dev,
+	}
+}
@@ -3,6 +3,7 @@ package project
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -15,6 +16,7 @@ import (
 	"github.com/evanw/esbuild/pkg/api"
 	"github.com/sst/sst/v3/internal/fs"
 	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/emulator"
 	"github.com/sst/sst/v3/pkg/flag"
 	"github.com/sst/sst/v3/pkg/js"
 	"github.com/sst/sst/v3/pkg/process"
@@ -35,12 +37,32 @@ type App struct {
 	Home      string                 `json:"home"`
 	Version   string                 `json:"version"`
 	Protect   bool                   `json:"protect"`
+	Tunnel    *AppTunnel             `json:"tunnel"`
+	Emulate   []string               `json:"emulate"`
+	Parallel  int                    `json:"parallel"`
+	Groups    map[string]*AppGroup   `json:"groups"`
 	// Deprecated: Backend is now Home
 	Backend string `json:"backend"`
 	// Deprecated: RemovalPolicy is now Removal
 	RemovalPolicy string `json:"removalPolicy"`
 }
 
+type AppTunnel struct {
+	Forwards map[string]string `json:"forwards"`
+}
+
+type AppGroup struct {
+	Stages      []*AppGroupStage `json:"stages"`
+	Concurrency int              `json:"concurrency"`
+	Mode        string           `json:"mode"`
+}
+
+type AppGroupStage struct {
+	Name      string                            `json:"name"`
+	Stage     string                            `json:"stage"`
+	Providers map[string]map[string]interface{} `json:"providers"`
+}
+
 type Project struct {
 	version         string
 	lock            ProviderLock
@@ -50,6 +72,7 @@ type Project struct {
 	home            provider.Home
 	env             map[string]string
 	loadedProviders map[string]provider.Provider
+	emulator        *emulatorState
 	Runtime         *runtime.Collection
 }
 
@@ -216,6 +239,25 @@ console.log("~j" + JSON.stringify(await mod.app({
 				}
 			}
 
+			// set when deploying a stage that's part of a group
+			if flag.SST_PROVIDER_OVERRIDES != "" {
+				var overrides map[string]map[string]interface{}
+				err := json.Unmarshal([]byte(flag.SST_PROVIDER_OVERRIDES), &overrides)
+				if err != nil {
+					return nil, fmt.Errorf("invalid SST_PROVIDER_OVERRIDES: %w", err)
+				}
+				for name, args := range overrides {
+					existing, ok := proj.app.Providers[name].(map[string]interface{})
+					if !ok {
+						existing = map[string]interface{}{}
+						proj.app.Providers[name] = existing
+					}
+					for key, value := range args {
+						existing[key] = value
+					}
+				}
+			}
+
 			if proj.app.Name == "" {
 				return nil, fmt.Errorf("Project name is required")
 			}
@@ -340,6 +382,19 @@ func (proj *Project) LoadHome() error {
 	return nil
 }
 
+// NewWith creates a project for an app that's already known, without
+// evaluating the config or loading its providers
+func NewWith(root string, app *App, home provider.Home) *Project {
+	return &Project{
+		root:            root,
+		config:          filepath.Join(root, "sst.config.ts"),
+		app:             app,
+		home:            home,
+		env:             map[string]string{},
+		loadedProviders: map[string]provider.Provider{},
+	}
+}
+
 func (p Project) getPath(path ...string) string {
 	paths := append([]string{p.PathWorkingDir()}, path...)
 	return filepath.Join(paths...)
@@ -377,6 +432,43 @@ func (p Project) Env() map[string]string {
 	return p.env
 }
 
+type emulatorState struct {
+	ready   chan struct{}
+	manager *emulator.Manager
+}
+
+// StartEmulator makes EnvFor wait until SetEmulator is called, so processes
+// that start early don't get the deployed resources instead. It needs to be
+// called before anything calls EnvFor.
+func (p *Project) StartEmulator() {
+	p.emulator = &emulatorState{ready: make(chan struct{})}
+}
+
+// SetEmulator routes the links of emulated resources in EnvFor to the local
+// stand-ins of the given manager. A nil manager means they failed to start.
+func (p *Project) SetEmulator(m *emulator.Manager) {
+	if p.emulator == nil {
+		p.StartEmulator()
+	}
+	p.emulator.manager = m
+	close(p.emulator.ready)
+}
+
+func (p *Project) waitForEmulator(ctx context.Context) (*emulator.Manager, error) {
+	if p.emulator == nil {
+		return nil, nil
+	}
+	select {
+	case <-p.emulator.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if p.emulator.manager == nil {
+		return nil, fmt.Errorf("the local emulators failed to start")
+	}
+	return p.emulator.manager, nil
+}
+
 func (p *Project) Provider(name string) (provider.Provider, bool) {
 	result, ok := p.loadedProviders[name]
 	return result, ok
@@ -390,8 +482,19 @@ func (p *Project) Cleanup() error {
 }
 
 func (p *Project) PathLog(name string) string {
+	dir := filepath.Join(p.PathWorkingDir(), "log")
+	if flag.SST_LOG_DIR != "" {
+		dir = flag.SST_LOG_DIR
+	}
 	if name == "" {
-		return filepath.Join(p.PathWorkingDir(), "log")
+		return dir
+	}
+	return filepath.Join(dir, name+".log")
+}
+
+func (p *Project) PathOutputs() string {
+	if flag.SST_OUTPUTS_PATH != "" {
+		return flag.SST_OUTPUTS_PATH
 	}
-	return filepath.Join(p.PathWorkingDir(), "log", name+".log")
+	return filepath.Join(p.PathWorkingDir(), "outputs.json")
 }
@@ -13,10 +13,16 @@ import (
 )
 
 type LocalHome struct {
+	dir string
 }
 
 func NewLocalHome() *LocalHome {
-	return &LocalHome{}
+	return &LocalHome{dir: global.ConfigDir()}
+}
+
+// NewLocalHomeIn keeps the state in dir instead of the global config directory
+func NewLocalHomeIn(dir string) *LocalHome {
+	return &LocalHome{dir: dir}
 }
 
 func (l *LocalHome) Bootstrap() error {
@@ -86,11 +92,11 @@ func (c *LocalHome) getPassphrase(app, stage string) (string, error) {
 }
 
 func (l *LocalHome) pathForData(key, app, stage string) string {
-	return filepath.Join(global.ConfigDir(), "state", key, app, fmt.Sprintf("%v.json", stage))
+	return filepath.Join(l.dir, "state", key, app, fmt.Sprintf("%v.json", stage))
 }
 
 func (a *LocalHome) listStages(app string) ([]string, error) {
-	path := filepath.Join(global.ConfigDir(), "state", "app", app)
+	path := filepath.Join(a.dir, "state", "app", app)
 
 	entries, err := os.ReadDir(path)
 	if err != nil {
@@ -114,6 +120,6 @@ func (a *LocalHome) listStages(app string) ([]string, error) {
 func (c *LocalHome) info() (util.KeyValuePairs[string], error) {
 	return util.KeyValuePairs[string]{
 		{Key: "Provider", Value: "Local"},
-		{Key: "Path", Value: global.ConfigDir()},
+		{Key: "Path", Value: c.dir},
 	}, nil
 }
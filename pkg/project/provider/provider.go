@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sst/sst/v3/internal/util"
@@ -153,7 +155,27 @@ func PutSummary(backend Home, app, stage, updateID string, summary Summary) erro
 func PutUpdate(backend Home, app, stage string, update *Update) error {
 	slog.Info("putting update", "app", app, "stage", stage)
 	update.RunID = flag.SST_RUN_ID
-	return putData(backend, "update", app, stage+"/"+update.ID, false, update)
+	err := putData(backend, "update", app, stage+"/"+update.ID, false, update)
+	if err != nil {
+		return err
+	}
+	// keep track of the last update that ran against the resources, diffs
+	// and state edits don't count. It's kept outside of the updates so they
+	// can be listed without it.
+	if update.Command == "" || update.Command == "edit" {
+		return nil
+	}
+	return putData(backend, "latest", app, stage, false, update)
+}
+
+// GetLastUpdate returns nil if the stage has not been updated
+func GetLastUpdate(backend Home, app, stage string) (*Update, error) {
+	var result *Update
+	err := getData(backend, "latest", app, stage, false, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 func Cleanup(backend Home, app, stage string) error {
@@ -163,9 +185,102 @@ func Cleanup(backend Home, app, stage string) error {
 	if err := backend.cleanup("snapshot", app, stage); err != nil {
 		return err
 	}
+	if err := removeData(backend, "stage", app, stage); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := removeData(backend, "latest", app, stage); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	return nil
 }
 
+// Metadata about who created a stage and how long it should live after it was
+// last deployed. Stages without a TTL are never removed by `sst stage gc`.
+type StageMetadata struct {
+	Creator  string            `json:"creator"`
+	Created  string            `json:"created"`
+	Deployed string            `json:"deployed"`
+	TTL      string            `json:"ttl,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+func (m *StageMetadata) Expires() (time.Time, bool) {
+	if m.TTL == "" {
+		return time.Time{}, false
+	}
+	last := m.Deployed
+	if last == "" {
+		last = m.Created
+	}
+	created, err := time.Parse(time.RFC3339, last)
+	if err != nil {
+		return time.Time{}, false
+	}
+	ttl, err := ParseTTL(m.TTL)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return created.Add(ttl), true
+}
+
+// ParseTTL parses a duration like 72h with support for days, like 3d
+func ParseTTL(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil || count <= 0 {
+			return 0, fmt.Errorf("invalid ttl %q", value)
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid ttl %q", value)
+	}
+	return ttl, nil
+}
+
+// GetStageMetadata returns nil if the stage has no metadata
+func GetStageMetadata(backend Home, app, stage string) (*StageMetadata, error) {
+	var result *StageMetadata
+	err := getData(backend, "stage", app, stage, false, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func PutStageMetadata(backend Home, app, stage string, metadata *StageMetadata) error {
+	slog.Info("putting stage metadata", "app", app, "stage", stage)
+	return putData(backend, "stage", app, stage, false, metadata)
+}
+
+// The invalidation batches of a distribution that were submitted for a
+// version, so a deploy that fails part way only submits the rest when it's
+// retried. Batches are stored by their hash.
+type InvalidationProgress struct {
+	Version       string   `json:"version"`
+	Batches       []string `json:"batches"`
+	Invalidations []string `json:"invalidations"`
+}
+
+func GetInvalidationProgress(backend Home, app, stage, distributionId string) (*InvalidationProgress, error) {
+	var result *InvalidationProgress
+	err := getData(backend, "invalidation/"+distributionId, app, stage, false, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func PutInvalidationProgress(backend Home, app, stage, distributionId string, progress *InvalidationProgress) error {
+	slog.Info("putting invalidation progress", "distributionId", distributionId, "batches", len(progress.Batches))
+	return putData(backend, "invalidation/"+distributionId, app, stage, false, progress)
+}
+
+func RemoveInvalidationProgress(backend Home, app, stage, distributionId string) error {
+	return backend.removeData("invalidation/"+distributionId, app, stage)
+}
+
 func GetSecrets(backend Home, app, stage string) (map[string]string, error) {
 	if stage == "" {
 		stage = "_fallback"
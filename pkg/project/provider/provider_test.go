@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTTL(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		err      bool
+	}{
+		{"72h", 72 * time.Hour, false},
+		{"30m", 30 * time.Minute, false},
+		{"3d", 72 * time.Hour, false},
+		{"0d", 0, true},
+		{"-1h", 0, true},
+		{"d", 0, true},
+		{"soon", 0, true},
+	}
+	for _, test := range tests {
+		result, err := ParseTTL(test.value)
+		if (err != nil) != test.err {
+			t.Errorf("ParseTTL(%q) error = %v", test.value, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("ParseTTL(%q) = %v, expected %v", test.value, result, test.expected)
+		}
+	}
+}
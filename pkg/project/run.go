@@ -12,6 +12,7 @@ import (
 	"os/signal"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -47,6 +48,18 @@ func (p *Project) RunNext(ctx context.Context, input *StackInput) error {
 		return ErrProtectedStage
 	}
 
+	var plan *Plan
+	if input.Plan != nil {
+		err := input.Plan.checkApp(p.app, p.lock)
+		if err != nil {
+			return err
+		}
+	}
+	if input.SavePlan != "" {
+		plan = newPlan(p.app, input)
+		plan.Providers = p.lock
+	}
+
 	bus.Publish(&StackCommandEvent{
 		App:     p.app.Name,
 		Stage:   p.app.Stage,
@@ -71,6 +84,27 @@ func (p *Project) RunNext(ctx context.Context, input *StackInput) error {
 		defer p.Unlock()
 	}
 
+	if input.Command == "remove" && input.Expired {
+		metadata, err := provider.GetStageMetadata(p.home, p.app.Name, p.app.Stage)
+		if err != nil {
+			return err
+		}
+		if metadata == nil {
+			return ErrStageNotExpired
+		}
+		expires, ok := metadata.Expires()
+		if !ok || expires.After(time.Now()) {
+			return ErrStageNotExpired
+		}
+	}
+
+	if input.Command == "deploy" {
+		err = p.putStageMetadata(input)
+		if err != nil {
+			return err
+		}
+	}
+
 	workdir, err := p.NewWorkdir(update.ID)
 	if err != nil {
 		return err
@@ -82,7 +116,7 @@ func (p *Project) RunNext(ctx context.Context, input *StackInput) error {
 		return err
 	}
 
-	outfile := filepath.Join(p.PathPlatformDir(), fmt.Sprintf("sst.config.%v.mjs", time.Now().UnixMilli()))
+	outfile := filepath.Join(p.PathPlatformDir(), fmt.Sprintf("sst.config.%v.mjs", update.ID))
 	os.WriteFile(
 		filepath.Join(workdir.path, "Pulumi.yaml"),
 		[]byte("name: "+p.app.Name+"\nruntime: nodejs\nmain: "+outfile+"\n"),
@@ -131,6 +165,15 @@ func (p *Project) RunNext(ctx context.Context, input *StackInput) error {
 		log.Info("state file might be corrupted", "err", err)
 		return err
 	}
+	if input.Plan != nil {
+		err := input.Plan.checkState(completed.Resources)
+		if err != nil {
+			return err
+		}
+	}
+	if plan != nil {
+		plan.State = hashJSON(completed.Resources)
+	}
 	completed.Finished = true
 	completed.Old = true
 	bus.Publish(completed)
@@ -222,6 +265,22 @@ func (p *Project) RunNext(ctx context.Context, input *StackInput) error {
 	})
 	log.Info("tracked files")
 
+	if input.Plan != nil || plan != nil {
+		hash, err := hashConfig(files, p.app)
+		if err != nil {
+			return err
+		}
+		if input.Plan != nil {
+			err := input.Plan.checkConfig(hash)
+			if err != nil {
+				return err
+			}
+		}
+		if plan != nil {
+			plan.Config = hash
+		}
+	}
+
 	secrets := map[string]string{}
 	fallback := map[string]string{}
 
@@ -247,6 +306,16 @@ func (p *Project) RunNext(ctx context.Context, input *StackInput) error {
 		return err
 	}
 
+	if input.Plan != nil {
+		err := input.Plan.checkSecrets(secretNames(fallback, secrets))
+		if err != nil {
+			return err
+		}
+	}
+	if plan != nil {
+		plan.Secrets = secretNames(fallback, secrets)
+	}
+
 	env := os.Environ()
 	for key, value := range p.Env() {
 		env = append(env, fmt.Sprintf("%v=%v", key, value))
@@ -316,7 +385,11 @@ func (p *Project) RunNext(ctx context.Context, input *StackInput) error {
 	case "refresh":
 		args = append([]string{"refresh", "--yes"}, args...)
 	case "deploy":
-		args = append([]string{"up", "--yes", "-f"}, args...)
+		if input.Continue {
+			args = append([]string{"up", "--yes", "-f", "--continue-on-error"}, args...)
+		} else {
+			args = append([]string{"up", "--yes", "-f"}, args...)
+		}
 	case "remove":
 		args = append([]string{"destroy", "--yes", "-f"}, args...)
 	}
@@ -336,6 +409,26 @@ func (p *Project) RunNext(ctx context.Context, input *StackInput) error {
 		}
 	}
 
+	parallel := input.Parallel
+	if parallel == 0 {
+		parallel = p.app.Parallel
+	}
+	if parallel > 0 {
+		args = append(args, "--parallel", strconv.Itoa(parallel))
+	}
+
+	enginePlanPath := filepath.Join(workdir.path, "plan.json")
+	if input.Plan != nil {
+		err := os.WriteFile(enginePlanPath, input.Plan.Engine, 0644)
+		if err != nil {
+			return err
+		}
+		args = append(args, "--plan", enginePlanPath)
+	}
+	if plan != nil {
+		args = append(args, "--save-plan", enginePlanPath)
+	}
+
 	cmd := process.Command(pulumiPath, args...)
 	process.Detach(cmd)
 	cmd.Env = env
@@ -347,6 +440,9 @@ func (p *Project) RunNext(ctx context.Context, input *StackInput) error {
 	errors := []Error{}
 	finished := false
 	importDiffs := map[string][]ImportDiff{}
+	// track the failed steps so their skipped dependents can be reported when
+	// continuing on error
+	failed := map[string]bool{}
 
 	partial := make(chan int, 1000)
 	partialContext, partialCancel := context.WithCancel(ctx)
@@ -487,6 +583,7 @@ loop:
 		}
 
 		if event.ResOpFailedEvent != nil {
+			failed[event.ResOpFailedEvent.Metadata.URN] = true
 			if event.ResOpFailedEvent.Metadata.Op == apitype.OpImport {
 				for _, name := range event.ResOpFailedEvent.Metadata.Diffs {
 					old := event.ResOpFailedEvent.Metadata.Old.Inputs[name]
@@ -527,6 +624,14 @@ loop:
 	complete.Finished = finished
 	complete.Errors = errors
 	complete.ImportDiffs = importDiffs
+	if input.Continue && len(errors) > 0 {
+		for _, item := range errors {
+			if item.URN != "" {
+				failed[item.URN] = true
+			}
+		}
+		complete.Skipped = skippedResources(complete.Resources, failed)
+	}
 	types.Generate(p.PathConfig(), complete.Links)
 	defer bus.Publish(complete)
 
@@ -542,7 +647,7 @@ loop:
 		}
 	}
 
-	outputsFilePath := filepath.Join(p.PathWorkingDir(), "outputs.json")
+	outputsFilePath := p.PathOutputs()
 	outputsFile, _ := os.Create(outputsFilePath)
 	defer outputsFile.Close()
 	json.NewEncoder(outputsFile).Encode(complete.Outputs)
@@ -570,5 +675,65 @@ loop:
 	if cmd.ProcessState.ExitCode() > 0 {
 		return ErrStackRunFailed
 	}
+
+	if plan != nil {
+		plan.Engine, err = os.ReadFile(enginePlanPath)
+		if err != nil {
+			return err
+		}
+		err = plan.Write(input.SavePlan)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// The engine skips every step that depends on a failed one without emitting
+// an event for it, so the skipped resources are worked out from the
+// dependency graph in the state instead. Resources that would have been
+// created for the first time aren't in the state and can't be listed.
+func skippedResources(resources []apitype.ResourceV3, failed map[string]bool) []string {
+	dependents := map[string][]string{}
+	for _, resource := range resources {
+		urn := string(resource.URN)
+		dependencies := []string{string(resource.Parent), string(resource.DeletedWith)}
+		for _, dependency := range resource.Dependencies {
+			dependencies = append(dependencies, string(dependency))
+		}
+		for _, urns := range resource.PropertyDependencies {
+			for _, dependency := range urns {
+				dependencies = append(dependencies, string(dependency))
+			}
+		}
+		for _, dependency := range dependencies {
+			if dependency != "" {
+				dependents[dependency] = append(dependents[dependency], urn)
+			}
+		}
+	}
+
+	visited := map[string]bool{}
+	queue := []string{}
+	for urn := range failed {
+		queue = append(queue, urn)
+	}
+	for len(queue) > 0 {
+		urn := queue[0]
+		queue = queue[1:]
+		for _, dependent := range dependents[urn] {
+			if visited[dependent] || failed[dependent] {
+				continue
+			}
+			visited[dependent] = true
+			queue = append(queue, dependent)
+		}
+	}
+
+	result := []string{}
+	for urn := range visited {
+		result = append(result, urn)
+	}
+	slices.Sort(result)
+	return result
+}
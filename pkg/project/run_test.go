@@ -0,0 +1,34 @@
+package project
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestSkippedResources(t *testing.T) {
+	resources := []apitype.ResourceV3{
+		{URN: "urn:table"},
+		{URN: "urn:queue"},
+		{URN: "urn:api", Dependencies: []resource.URN{"urn:table"}},
+		{URN: "urn:route", Parent: "urn:api"},
+		{URN: "urn:site", PropertyDependencies: map[resource.PropertyKey][]resource.URN{"url": {"urn:route"}}},
+		{URN: "urn:worker", Dependencies: []resource.URN{"urn:queue"}},
+		{URN: "urn:cleanup", DeletedWith: "urn:worker"},
+	}
+
+	result := skippedResources(resources, map[string]bool{"urn:table": true})
+	expected := []string{"urn:api", "urn:route", "urn:site"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+
+	// a failed dependent isn't also reported as skipped
+	result = skippedResources(resources, map[string]bool{"urn:table": true, "urn:api": true, "urn:queue": true})
+	expected = []string{"urn:cleanup", "urn:route", "urn:site", "urn:worker"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+}
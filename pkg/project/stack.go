@@ -21,6 +21,16 @@ type StackInput struct {
 	Verbose    bool
 	Continue   bool
 	SkipHash   string
+	Parallel   int
+	// Plan is applied instead of computing a new one
+	Plan *Plan
+	// SavePlan is the path a diff saves its plan to
+	SavePlan string
+	// TTL and Labels are saved to the stage metadata on deploy
+	TTL    string
+	Labels map[string]string
+	// Expired only removes the stage if it's still expired once it's locked
+	Expired bool
 }
 
 type ConcurrentUpdateEvent struct{}
@@ -69,6 +79,9 @@ type CompleteEvent struct {
 	Resources   []apitype.ResourceV3
 	ImportDiffs map[string][]ImportDiff
 	Tunnels     map[string]Tunnel
+	// Skipped are the resources that were not updated because they depend
+	// on a resource that failed, when continuing on error.
+	Skipped []string
 }
 
 type Tunnel struct {
@@ -145,6 +158,7 @@ var ErrStackRunFailed = fmt.Errorf("stack run had errors")
 var ErrStageNotFound = fmt.Errorf("stage not found")
 var ErrPassphraseInvalid = fmt.Errorf("passphrase invalid")
 var ErrProtectedStage = fmt.Errorf("cannot remove protected stage")
+var ErrStageNotExpired = fmt.Errorf("stage is no longer expired")
 
 func (p *Project) Lock(command string) (*provider.Update, error) {
 	return provider.Lock(p.home, p.Version(), command, p.app.Name, p.app.Stage)
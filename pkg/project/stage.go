@@ -1,9 +1,14 @@
 package project
 
 import (
+	"maps"
 	"os"
+	"os/user"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/sst/sst/v3/pkg/project/provider"
 )
 
 func resolveStageFile(cfgPath string) string {
@@ -28,3 +33,64 @@ func SetPersonalStage(cfgPath string, stage string) error {
 	}
 	return nil
 }
+
+// Records who created the stage the first time it's deployed, along with the
+// TTL and labels when they are passed in. The backend is only written to when
+// something changed, so most deploys, including the ones in `sst dev`, only
+// read the metadata.
+func (p *Project) putStageMetadata(input *StackInput) error {
+	metadata, err := provider.GetStageMetadata(p.home, p.app.Name, p.app.Stage)
+	if err != nil {
+		return err
+	}
+	next, changed := nextStageMetadata(metadata, input, time.Now().UTC())
+	if !changed {
+		return nil
+	}
+	return provider.PutStageMetadata(p.home, p.app.Name, p.app.Stage, next)
+}
+
+// How often the last deploy of a stage with a TTL is refreshed. The TTL
+// counts from the last deploy so it has to move forward, but not on every one.
+const stageDeployedInterval = time.Hour
+
+func nextStageMetadata(current *provider.StageMetadata, input *StackInput, now time.Time) (*provider.StageMetadata, bool) {
+	changed := false
+	next := &provider.StageMetadata{}
+	if current != nil {
+		*next = *current
+		next.Labels = maps.Clone(current.Labels)
+	} else {
+		creator := "unknown"
+		if account, err := user.Current(); err == nil {
+			creator = account.Username
+		}
+		next.Creator = creator
+		next.Created = now.Format(time.RFC3339)
+		changed = true
+	}
+	if input.TTL != "" && input.TTL != next.TTL {
+		next.TTL = input.TTL
+		changed = true
+	}
+	for key, value := range input.Labels {
+		if next.Labels == nil {
+			next.Labels = map[string]string{}
+		}
+		if existing, ok := next.Labels[key]; !ok || existing != value {
+			next.Labels[key] = value
+			changed = true
+		}
+	}
+	if next.TTL != "" {
+		last, err := time.Parse(time.RFC3339, next.Deployed)
+		if changed || err != nil || now.Sub(last) >= stageDeployedInterval {
+			next.Deployed = now.Format(time.RFC3339)
+			changed = true
+		}
+	}
+	if changed && next.Deployed == "" {
+		next.Deployed = now.Format(time.RFC3339)
+	}
+	return next, changed
+}
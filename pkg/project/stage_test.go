@@ -0,0 +1,42 @@
+package project
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sst/sst/v3/pkg/project/provider"
+)
+
+func TestNextStageMetadata(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	next, changed := nextStageMetadata(nil, &StackInput{}, now)
+	if !changed || next.Created == "" || next.Deployed == "" {
+		t.Fatalf("expected a new stage to be written, got %+v", next)
+	}
+
+	current := &provider.StageMetadata{
+		Creator:  "frank",
+		Created:  "2024-04-01T00:00:00Z",
+		Deployed: "2024-04-01T00:00:00Z",
+		Labels:   map[string]string{"team": "web"},
+	}
+	if _, changed := nextStageMetadata(current, &StackInput{Labels: map[string]string{"team": "web"}}, now); changed {
+		t.Fatal("expected an unchanged stage without a TTL to be skipped")
+	}
+	next, changed = nextStageMetadata(current, &StackInput{Labels: map[string]string{"team": "api"}}, now)
+	if !changed || next.Labels["team"] != "api" || current.Labels["team"] != "web" {
+		t.Fatalf("expected a changed label to be written, got %+v", next)
+	}
+
+	current.TTL = "7d"
+	current.Deployed = now.Add(-10 * time.Minute).Format(time.RFC3339)
+	if _, changed := nextStageMetadata(current, &StackInput{Dev: true}, now); changed {
+		t.Fatal("expected a recent deploy to be skipped")
+	}
+	current.Deployed = now.Add(-2 * time.Hour).Format(time.RFC3339)
+	next, changed = nextStageMetadata(current, &StackInput{Dev: true}, now)
+	if !changed || next.Deployed != now.Format(time.RFC3339) {
+		t.Fatalf("expected the last deploy of a stage with a TTL to be refreshed, got %+v", next)
+	}
+}
@@ -1,16 +1,39 @@
 package resource
 
 import (
-	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
 	"os"
+	"reflect"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sst/sst/v3/pkg/bus"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	BUCKET_FILES_MULTIPART_THRESHOLD = 64 * 1024 * 1024
+	BUCKET_FILES_PART_SIZE           = 16 * 1024 * 1024
+	BUCKET_FILES_MAX_PARTS           = 10000
+	BUCKET_FILES_PART_CONCURRENCY    = 4
+	BUCKET_FILES_READ_CONCURRENCY    = 16
+	BUCKET_FILES_DELETE_BATCH        = 1000
+	BUCKET_FILES_ATTEMPTS            = 3
 )
 
 type BucketFiles struct {
 	*AwsResource
+	// Uploads are limited separately from the other calls to AWS
+	uploads *throttle
 }
 
 type BucketFile struct {
@@ -19,6 +42,61 @@ type BucketFile struct {
 	CacheControl *string `json:"cacheControl,omitempty"`
 	ContentType  string  `json:"contentType"`
 	Hash         *string `json:"hash,omitempty"`
+	// Set when the source is already compressed, like a gzip or brotli
+	// variant of another file
+	ContentEncoding *string           `json:"contentEncoding,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	StorageClass    *string           `json:"storageClass,omitempty"`
+	// Encrypts the object with this KMS key instead of the bucket default
+	KmsKeyId *string           `json:"kmsKeyId,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+// The S3 attributes of the object, shared by single and multipart uploads
+type bucketFileAttributes struct {
+	CacheControl         *string
+	ContentType          *string
+	ContentEncoding      *string
+	Metadata             map[string]string
+	StorageClass         types.StorageClass
+	ServerSideEncryption types.ServerSideEncryption
+	SSEKMSKeyId          *string
+	Tagging              *string
+}
+
+func (f *BucketFile) attributes() bucketFileAttributes {
+	result := bucketFileAttributes{
+		CacheControl:    f.CacheControl,
+		ContentType:     aws.String(f.ContentType),
+		ContentEncoding: f.ContentEncoding,
+		Metadata:        f.Metadata,
+	}
+	if f.StorageClass != nil {
+		result.StorageClass = types.StorageClass(*f.StorageClass)
+	}
+	if f.KmsKeyId != nil {
+		result.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		result.SSEKMSKeyId = f.KmsKeyId
+	}
+	if len(f.Tags) > 0 {
+		tags := url.Values{}
+		for key, value := range f.Tags {
+			tags.Set(key, value)
+		}
+		result.Tagging = aws.String(tags.Encode())
+	}
+	return result
+}
+
+// unchanged is true if the file was uploaded with the same content and
+// attributes before, wherever its source is now
+func (f *BucketFile) unchanged(old *BucketFile) bool {
+	if f.Hash == nil || old.Hash == nil {
+		return false
+	}
+	a, b := *f, *old
+	a.Source, b.Source = "", ""
+	return reflect.DeepEqual(a, b)
 }
 
 type BucketFilesInputs struct {
@@ -36,12 +114,12 @@ type BucketFilesOutputs struct {
 }
 
 func (r *BucketFiles) Create(input *BucketFilesInputs, output *CreateResult[BucketFilesOutputs]) error {
-	cfg, err := r.config()
+	cfg, err := r.configWith(r.uploads)
 	if err != nil {
 		return err
 	}
 	cfg.Region = input.Region
-	s3Client := s3.NewFromConfig(cfg)
+	s3Client := r.clients.S3(cfg)
 
 	if err := r.upload(s3Client, input.BucketName, input.Files, nil); err != nil {
 		return err
@@ -60,12 +138,12 @@ func (r *BucketFiles) Create(input *BucketFilesInputs, output *CreateResult[Buck
 }
 
 func (r *BucketFiles) Update(input *UpdateInput[BucketFilesInputs, BucketFilesOutputs], output *UpdateResult[BucketFilesOutputs]) error {
-	cfg, err := r.config()
+	cfg, err := r.configWith(r.uploads)
 	if err != nil {
 		return err
 	}
 	cfg.Region = input.News.Region
-	s3Client := s3.NewFromConfig(cfg)
+	s3Client := r.clients.S3(cfg)
 
 	oldFiles := input.Olds.Files
 	if input.News.BucketName != input.Olds.BucketName {
@@ -106,98 +184,386 @@ func (r *BucketFiles) Delete(input *DeleteInput[BucketFilesOutputs], output *int
 	if input.Outs.Region != "" {
 		cfg.Region = input.Outs.Region
 	}
-	s3Client := s3.NewFromConfig(cfg)
+	s3Client := r.clients.S3(cfg)
 
 	return r.purge(s3Client, input.Outs.BucketName, nil, input.Outs.Files)
 }
 
-func (r *BucketFiles) upload(client *s3.Client, bucketName string, files []BucketFile, oldFiles []BucketFile) error {
+func (r *BucketFiles) Check(input *CheckInput[BucketFilesInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("bucketName", input.News.BucketName)
+	keys := map[string]bool{}
+	for index, file := range input.News.Files {
+		property := fmt.Sprintf("files[%d]", index)
+		if file.Key == "" {
+			checks.fail(property, "key is required")
+			continue
+		}
+		if file.Source == "" {
+			checks.fail(property, fmt.Sprintf("source of %q is required", file.Key))
+		}
+		if file.StorageClass != nil && !slices.Contains(types.StorageClass("").Values(), types.StorageClass(*file.StorageClass)) {
+			checks.fail(property, fmt.Sprintf("%q is not a valid storage class", *file.StorageClass))
+		}
+		if len(file.Tags) > 10 {
+			checks.fail(property, fmt.Sprintf("%q has %d tags, S3 allows up to 10", file.Key, len(file.Tags)))
+		}
+		if keys[file.Key] {
+			checks.fail(property, fmt.Sprintf("%q is uploaded more than once", file.Key))
+		}
+		keys[file.Key] = true
+	}
+	*output = checks.result()
+	return nil
+}
+
+func (r *BucketFiles) Diff(input *DiffInput[BucketFilesInputs, BucketFilesOutputs], output *DiffResult) error {
+	var changed diffBuilder
+	changed.compare("bucketName", input.Olds.BucketName, input.News.BucketName)
+	changed.compare("files", input.Olds.Files, input.News.Files)
+	changed.compare("purge", input.Olds.Purge, input.News.Purge)
+	changed.compare("region", input.Olds.Region, input.News.Region)
+	*output = newDiff(changed)
+	return nil
+}
+
+// Read drops the files that were removed from the bucket outside of a deploy
+// so the next one uploads them again
+func (r *BucketFiles) Read(input *ReadInput[BucketFilesOutputs], output *ReadResult[BucketFilesOutputs]) error {
+	props := input.Props
+	if props.BucketName == "" {
+		*output = ReadResult[BucketFilesOutputs]{ID: input.ID, Props: props}
+		return nil
+	}
+	cfg, err := r.config()
+	if err != nil {
+		return err
+	}
+	if props.Region != "" {
+		cfg.Region = props.Region
+	}
+	client := r.clients.S3(cfg)
+
+	_, err = client.HeadBucket(r.context, &s3.HeadBucketInput{
+		Bucket: aws.String(props.BucketName),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			*output = ReadResult[BucketFilesOutputs]{}
+			return nil
+		}
+		return err
+	}
+
+	// only the tracked files are checked, the bucket can hold far more
+	existing := make([]bool, len(props.Files))
+	group, ctx := errgroup.WithContext(r.context)
+	group.SetLimit(BUCKET_FILES_READ_CONCURRENCY)
+	for index, file := range props.Files {
+		group.Go(func() error {
+			_, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+				Bucket: aws.String(props.BucketName),
+				Key:    aws.String(file.Key),
+			})
+			if err != nil {
+				var notFound *types.NotFound
+				if errors.As(err, &notFound) {
+					return nil
+				}
+				return err
+			}
+			existing[index] = true
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return err
+	}
+	files := []BucketFile{}
+	for index, file := range props.Files {
+		if existing[index] {
+			files = append(files, file)
+		}
+	}
+	props.Files = files
+	*output = ReadResult[BucketFilesOutputs]{ID: input.ID, Props: props}
+	return nil
+}
+
+// Uploads the files that changed, each one retried on its own. Files above
+// the multipart threshold are streamed in parts so they are never read into
+// memory at once.
+func (r *BucketFiles) upload(client S3Client, bucketName string, files []BucketFile, oldFiles []BucketFile) error {
 	// Create map of existing files
 	oldFilesMap := make(map[string]BucketFile)
 	for _, f := range oldFiles {
 		oldFilesMap[f.Key] = f
 	}
 
-	// Create channels for work distribution and error collection
-	filesChan := make(chan BucketFile)
-	errChan := make(chan error, len(files))
-	var wg sync.WaitGroup
-
-	// Start worker pool (10 workers)
-	numWorkers := 10
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			// Each worker processes files from the channel
-			for file := range filesChan {
-				// write start timestamp with nanoseconds to file
-				content, err := os.ReadFile(file.Source)
-				if err != nil {
-					errChan <- err
-					return
-				}
+	changed := []BucketFile{}
+	for _, file := range files {
+		oldFile, exists := oldFilesMap[file.Key]
+		if exists && file.unchanged(&oldFile) {
+			continue
+		}
+		changed = append(changed, file)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
 
-				_, err = client.PutObject(r.context, &s3.PutObjectInput{
-					Bucket:       aws.String(bucketName),
-					Key:          aws.String(file.Key),
-					Body:         bytes.NewReader(content),
-					CacheControl: file.CacheControl,
-					ContentType:  aws.String(file.ContentType),
+	progress := newUploadProgress(bucketName, len(changed))
+	for _, file := range changed {
+		info, err := os.Stat(file.Source)
+		if err != nil {
+			return err
+		}
+		progress.event.Total += info.Size()
+	}
+	progress.publish(true)
+
+	group, ctx := errgroup.WithContext(r.context)
+	group.SetLimit(r.uploads.size)
+	for _, file := range changed {
+		group.Go(func() error {
+			var err error
+			for attempt := 0; attempt < BUCKET_FILES_ATTEMPTS; attempt++ {
+				if attempt > 0 {
+					slog.Info("retrying upload", "key", file.Key, "attempt", attempt, "err", err)
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case <-time.After(backoff(attempt)):
+					}
+				}
+				sent := int64(0)
+				err = r.uploadFile(ctx, client, bucketName, file, func(n int64) {
+					sent += n
+					progress.add(n)
 				})
-				if err != nil {
-					errChan <- err
+				if err == nil {
+					progress.complete()
+					return nil
 				}
+				// start over on the next attempt
+				progress.add(-sent)
 			}
-		}()
+			return fmt.Errorf("failed to upload %s: %w", file.Key, err)
+		})
 	}
+	err := group.Wait()
+	progress.event.Done = true
+	progress.publish(true)
+	return err
+}
 
-	// Send files that need uploading to the channel
-	go func() {
-		for _, file := range files {
-			oldFile, exists := oldFilesMap[file.Key]
-			if exists && oldFile.Hash != nil && *oldFile.Hash == *file.Hash &&
-				oldFile.CacheControl == file.CacheControl &&
-				oldFile.ContentType == file.ContentType {
-				continue
-			}
-			filesChan <- file
-		}
-		close(filesChan)
-	}()
+func (r *BucketFiles) uploadFile(ctx context.Context, client S3Client, bucketName string, file BucketFile, progress func(int64)) error {
+	f, err := os.Open(file.Source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() >= BUCKET_FILES_MULTIPART_THRESHOLD {
+		return r.uploadMultipart(ctx, client, bucketName, file, f, info.Size(), progress)
+	}
 
-	// Wait for all workers to finish
-	wg.Wait()
-	close(errChan)
+	attributes := file.attributes()
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(file.Key),
+		Body:                 f,
+		ContentLength:        aws.Int64(info.Size()),
+		CacheControl:         attributes.CacheControl,
+		ContentType:          attributes.ContentType,
+		ContentEncoding:      attributes.ContentEncoding,
+		Metadata:             attributes.Metadata,
+		StorageClass:         attributes.StorageClass,
+		ServerSideEncryption: attributes.ServerSideEncryption,
+		SSEKMSKeyId:          attributes.SSEKMSKeyId,
+		Tagging:              attributes.Tagging,
+		ChecksumAlgorithm:    types.ChecksumAlgorithmCrc32,
+	})
+	if err != nil {
+		return err
+	}
+	progress(info.Size())
+	return nil
+}
 
-	// Check for any errors
-	for err := range errChan {
-		if err != nil {
-			return err
-		}
+func (r *BucketFiles) uploadMultipart(ctx context.Context, client S3Client, bucketName string, file BucketFile, f *os.File, size int64, progress func(int64)) error {
+	attributes := file.attributes()
+	created, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(file.Key),
+		CacheControl:         attributes.CacheControl,
+		ContentType:          attributes.ContentType,
+		ContentEncoding:      attributes.ContentEncoding,
+		Metadata:             attributes.Metadata,
+		StorageClass:         attributes.StorageClass,
+		ServerSideEncryption: attributes.ServerSideEncryption,
+		SSEKMSKeyId:          attributes.SSEKMSKeyId,
+		Tagging:              attributes.Tagging,
+		ChecksumAlgorithm:    types.ChecksumAlgorithmCrc32,
+	})
+	if err != nil {
+		return err
 	}
 
+	partSize := multipartPartSize(size)
+	count := int((size + partSize - 1) / partSize)
+	parts := make([]types.CompletedPart, count)
+	var lock sync.Mutex
+	group, partCtx := errgroup.WithContext(ctx)
+	group.SetLimit(BUCKET_FILES_PART_CONCURRENCY)
+	for index := 0; index < count; index++ {
+		offset := int64(index) * partSize
+		length := min(partSize, size-offset)
+		group.Go(func() error {
+			result, err := client.UploadPart(partCtx, &s3.UploadPartInput{
+				Bucket:            aws.String(bucketName),
+				Key:               aws.String(file.Key),
+				UploadId:          created.UploadId,
+				PartNumber:        aws.Int32(int32(index + 1)),
+				Body:              io.NewSectionReader(f, offset, length),
+				ContentLength:     aws.Int64(length),
+				ChecksumAlgorithm: types.ChecksumAlgorithmCrc32,
+			})
+			if err != nil {
+				return err
+			}
+			parts[index] = types.CompletedPart{
+				ETag:          result.ETag,
+				ChecksumCRC32: result.ChecksumCRC32,
+				PartNumber:    aws.Int32(int32(index + 1)),
+			}
+			lock.Lock()
+			progress(length)
+			lock.Unlock()
+			return nil
+		})
+	}
+	err = group.Wait()
+	if err == nil {
+		_, err = client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:   aws.String(bucketName),
+			Key:      aws.String(file.Key),
+			UploadId: created.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: parts,
+			},
+		})
+	}
+	if err != nil {
+		// the parts of an unfinished upload are billed until it's aborted
+		_, abortErr := client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucketName),
+			Key:      aws.String(file.Key),
+			UploadId: created.UploadId,
+		})
+		if abortErr != nil {
+			slog.Error("failed to abort multipart upload", "key", file.Key, "err", abortErr)
+		}
+		return err
+	}
 	return nil
 }
 
-func (r *BucketFiles) purge(client *s3.Client, bucketName string, files []BucketFile, oldFiles []BucketFile) error {
+// The parts are grown for large files so they fit in the 10,000 parts S3
+// allows for an upload
+func multipartPartSize(size int64) int64 {
+	partSize := int64(BUCKET_FILES_PART_SIZE)
+	for (size+partSize-1)/partSize > BUCKET_FILES_MAX_PARTS {
+		partSize *= 2
+	}
+	return partSize
+}
+
+// Deletes the old files that are no longer in files, in batches of the 1,000
+// keys that DeleteObjects allows
+func (r *BucketFiles) purge(client S3Client, bucketName string, files []BucketFile, oldFiles []BucketFile) error {
 	newFileKeys := make(map[string]bool)
 	for _, f := range files {
 		newFileKeys[f.Key] = true
 	}
 
+	objects := []types.ObjectIdentifier{}
 	for _, oldFile := range oldFiles {
 		if !newFileKeys[oldFile.Key] {
-			_, err := client.DeleteObject(r.context, &s3.DeleteObjectInput{
-				Bucket: aws.String(bucketName),
-				Key:    aws.String(oldFile.Key),
-			})
-			if err != nil {
-				return err
-			}
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(oldFile.Key)})
+		}
+	}
+
+	for start := 0; start < len(objects); start += BUCKET_FILES_DELETE_BATCH {
+		end := min(start+BUCKET_FILES_DELETE_BATCH, len(objects))
+		result, err := client.DeleteObjects(r.context, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucketName),
+			Delete: &types.Delete{
+				Objects: objects[start:end],
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if len(result.Errors) > 0 {
+			failed := result.Errors[0]
+			return fmt.Errorf("failed to delete %d files, %s: %s", len(result.Errors), aws.ToString(failed.Key), aws.ToString(failed.Message))
 		}
 	}
 
 	return nil
 }
 
+// Published as the files of a bucket are uploaded
+type UploadProgressEvent struct {
+	Bucket    string
+	Files     int
+	Uploaded  int
+	Total     int64
+	Completed int64
+	Done      bool
+}
+
+type uploadProgress struct {
+	lock      sync.Mutex
+	event     UploadProgressEvent
+	published time.Time
+}
+
+func newUploadProgress(bucketName string, files int) *uploadProgress {
+	return &uploadProgress{
+		event: UploadProgressEvent{
+			Bucket: bucketName,
+			Files:  files,
+		},
+	}
+}
+
+func (p *uploadProgress) add(n int64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.event.Completed += n
+	p.publish(false)
+}
+
+func (p *uploadProgress) complete() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.event.Uploaded++
+	p.publish(false)
+}
+
+// Publishes a copy of the progress, at most every 100ms unless forced
+func (p *uploadProgress) publish(force bool) {
+	if !force && time.Since(p.published) < 100*time.Millisecond {
+		return
+	}
+	p.published = time.Now()
+	event := p.event
+	bus.Publish(&event)
+}
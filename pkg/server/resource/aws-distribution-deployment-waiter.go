@@ -19,7 +19,10 @@ type DistributionDeploymentWaiterInputs struct {
 }
 
 type DistributionDeploymentWaiterOutputs struct {
-	IsDone bool `json:"isDone"`
+	IsDone         bool   `json:"isDone"`
+	DistributionId string `json:"distributionId,omitempty"`
+	Etag           string `json:"etag,omitempty"`
+	Wait           bool   `json:"wait,omitempty"`
 }
 
 func (r *DistributionDeploymentWaiter) Create(input *DistributionDeploymentWaiterInputs, output *CreateResult[DistributionDeploymentWaiterOutputs]) error {
@@ -28,7 +31,7 @@ func (r *DistributionDeploymentWaiter) Create(input *DistributionDeploymentWaite
 	}
 	*output = CreateResult[DistributionDeploymentWaiterOutputs]{
 		ID:   "waiter",
-		Outs: DistributionDeploymentWaiterOutputs{IsDone: true},
+		Outs: r.outputs(input),
 	}
 	return nil
 }
@@ -38,11 +41,46 @@ func (r *DistributionDeploymentWaiter) Update(input *UpdateInput[DistributionDep
 		return err
 	}
 	*output = UpdateResult[DistributionDeploymentWaiterOutputs]{
-		Outs: DistributionDeploymentWaiterOutputs{IsDone: true},
+		Outs: r.outputs(&input.News),
 	}
 	return nil
 }
 
+func (r *DistributionDeploymentWaiter) Check(input *CheckInput[DistributionDeploymentWaiterInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("distributionId", input.News.DistributionId)
+	*output = checks.result()
+	return nil
+}
+
+func (r *DistributionDeploymentWaiter) Diff(input *DiffInput[DistributionDeploymentWaiterInputs, DistributionDeploymentWaiterOutputs], output *DiffResult) error {
+	if input.Olds.DistributionId == "" {
+		*output = DiffResult{}
+		return nil
+	}
+	var changed diffBuilder
+	changed.compare("distributionId", input.Olds.DistributionId, input.News.DistributionId)
+	changed.compare("etag", input.Olds.Etag, input.News.Etag)
+	changed.compare("wait", input.Olds.Wait, input.News.Wait)
+	*output = newDiff(changed)
+	return nil
+}
+
+// There is nothing in the cloud for the waiter, so it reads its own outputs
+func (r *DistributionDeploymentWaiter) Read(input *ReadInput[DistributionDeploymentWaiterOutputs], output *ReadResult[DistributionDeploymentWaiterOutputs]) error {
+	*output = ReadResult[DistributionDeploymentWaiterOutputs]{ID: input.ID, Props: input.Props}
+	return nil
+}
+
+func (r *DistributionDeploymentWaiter) outputs(input *DistributionDeploymentWaiterInputs) DistributionDeploymentWaiterOutputs {
+	return DistributionDeploymentWaiterOutputs{
+		IsDone:         true,
+		DistributionId: input.DistributionId,
+		Etag:           input.Etag,
+		Wait:           input.Wait,
+	}
+}
+
 func (r *DistributionDeploymentWaiter) handle(input *DistributionDeploymentWaiterInputs) error {
 	if !input.Wait {
 		return nil
@@ -52,7 +90,7 @@ func (r *DistributionDeploymentWaiter) handle(input *DistributionDeploymentWaite
 	if err != nil {
 		return err
 	}
-	client := cloudfront.NewFromConfig(cfg)
+	client := r.clients.CloudFront(cfg)
 
 	start := time.Now()
 	timeout := 5 * time.Minute
@@ -1,8 +1,13 @@
 package resource
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"log/slog"
 	"math"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -10,11 +15,17 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
 	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+	"github.com/sst/sst/v3/pkg/bus"
+	"github.com/sst/sst/v3/pkg/project/provider"
 )
 
 const (
 	FILE_LIMIT     = 3000
 	WILDCARD_LIMIT = 15
+	// Directories with at least this many files are invalidated with a wildcard
+	INVALIDATION_COLLAPSE_THRESHOLD = 20
+	// The paths CloudFront invalidates for free each month
+	INVALIDATION_FREE_PATHS = 1000
 )
 
 type DistributionInvalidation struct {
@@ -28,35 +39,257 @@ type DistributionInvalidationInputs struct {
 	Version        string   `json:"version"`
 }
 
-func (r *DistributionInvalidation) Create(input *DistributionInvalidationInputs, output *CreateResult[struct{}]) error {
-	if err := r.handle(input); err != nil {
+type DistributionInvalidationOutputs struct {
+	DistributionId string   `json:"distributionId,omitempty"`
+	Paths          []string `json:"paths,omitempty"`
+	Wait           bool     `json:"wait,omitempty"`
+	Version        string   `json:"version,omitempty"`
+	// The IDs of the invalidations that were submitted
+	Invalidations []string `json:"invalidations,omitempty"`
+}
+
+func (r *DistributionInvalidation) Create(input *DistributionInvalidationInputs, output *CreateResult[DistributionInvalidationOutputs]) error {
+	outs, err := r.handle(input)
+	if err != nil {
 		return err
 	}
-	*output = CreateResult[struct{}]{
-		ID: "invalidation",
+	*output = CreateResult[DistributionInvalidationOutputs]{
+		ID:   "invalidation",
+		Outs: *outs,
 	}
 	return nil
 }
 
-func (r *DistributionInvalidation) Update(input *UpdateInput[DistributionInvalidationInputs, struct{}], output *UpdateResult[struct{}]) error {
-	if err := r.handle(&input.News); err != nil {
+func (r *DistributionInvalidation) Update(input *UpdateInput[DistributionInvalidationInputs, DistributionInvalidationOutputs], output *UpdateResult[DistributionInvalidationOutputs]) error {
+	outs, err := r.handle(&input.News)
+	if err != nil {
 		return err
 	}
-	*output = UpdateResult[struct{}]{}
+	*output = UpdateResult[DistributionInvalidationOutputs]{
+		Outs: *outs,
+	}
+	return nil
+}
+
+func (r *DistributionInvalidation) Check(input *CheckInput[DistributionInvalidationInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("distributionId", input.News.DistributionId)
+	if len(input.News.Paths) == 0 {
+		checks.fail("paths", "paths needs at least one path")
+	}
+	for index, path := range input.News.Paths {
+		if !strings.HasPrefix(strings.TrimSpace(path), "/") {
+			checks.fail(fmt.Sprintf("paths[%d]", index), fmt.Sprintf("%q needs to start with /", path))
+		}
+	}
+	*output = checks.result()
 	return nil
 }
 
-func (r *DistributionInvalidation) handle(input *DistributionInvalidationInputs) error {
+func (r *DistributionInvalidation) Diff(input *DiffInput[DistributionInvalidationInputs, DistributionInvalidationOutputs], output *DiffResult) error {
+	if input.Olds.DistributionId == "" {
+		*output = DiffResult{}
+		return nil
+	}
+	var changed diffBuilder
+	changed.compare("distributionId", input.Olds.DistributionId, input.News.DistributionId)
+	changed.compare("paths", input.Olds.Paths, input.News.Paths)
+	changed.compare("wait", input.Olds.Wait, input.News.Wait)
+	changed.compare("version", input.Olds.Version, input.News.Version)
+	*output = newDiff(changed)
+	return nil
+}
+
+// An invalidation can't be undone, so it reads its own outputs
+func (r *DistributionInvalidation) Read(input *ReadInput[DistributionInvalidationOutputs], output *ReadResult[DistributionInvalidationOutputs]) error {
+	*output = ReadResult[DistributionInvalidationOutputs]{ID: input.ID, Props: input.Props}
+	return nil
+}
+
+type InvalidationEstimateEvent struct {
+	DistributionId string
+	// The number of paths that were asked for and the number that will be
+	// submitted after the redundant ones are collapsed
+	Requested int
+	Billable  int
+	Batches   int
+}
+
+func (r *DistributionInvalidation) handle(input *DistributionInvalidationInputs) (*DistributionInvalidationOutputs, error) {
 	cfg, err := r.config()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	client := r.clients.CloudFront(cfg)
+
+	outs := DistributionInvalidationOutputs{
+		DistributionId: input.DistributionId,
+		Paths:          input.Paths,
+		Wait:           input.Wait,
+		Version:        input.Version,
+		Invalidations:  []string{},
+	}
+	// wildcards fit fewer to a batch and every batch after the first waits
+	// for the one before it, so directories are only collapsed into wildcards
+	// when that doesn't take more batches
+	batches := invalidationBatches(collapseInvalidationPaths(input.Paths, true))
+	if uncollapsed := invalidationBatches(collapseInvalidationPaths(input.Paths, false)); len(uncollapsed) < len(batches) {
+		batches = uncollapsed
+	}
+
+	billable := 0
+	for _, batch := range batches {
+		billable += len(batch)
+	}
+	bus.Publish(&InvalidationEstimateEvent{
+		DistributionId: input.DistributionId,
+		Requested:      len(input.Paths),
+		Billable:       billable,
+		Batches:        len(batches),
+	})
+
+	// the outputs aren't saved when this fails, so the batches that were
+	// submitted are kept in the home backend until every one of them is
+	home := r.project.Backend()
+	app := r.project.App()
+	saved, err := provider.GetInvalidationProgress(home, app.Name, app.Stage, input.DistributionId)
+	if err != nil {
+		return nil, err
+	}
+	progress := &provider.InvalidationProgress{Version: input.Version}
+	if saved != nil && saved.Version == input.Version {
+		progress = saved
+	}
+
+	for i, batch := range batches {
+		hash := invalidationBatchHash(batch)
+		if index := slices.Index(progress.Batches, hash); index >= 0 {
+			slog.Info("skipping submitted invalidation batch", "distributionId", input.DistributionId, "id", progress.Invalidations[index])
+			outs.Invalidations = append(outs.Invalidations, progress.Invalidations[index])
+			continue
+		}
+		id, err := r.invalidate(client, input.DistributionId, batch, input.Wait || len(batches) > 1)
+		if err != nil {
+			if len(outs.Invalidations) > 0 {
+				slog.Warn("invalidation failed part way", "distributionId", input.DistributionId, "submitted", outs.Invalidations)
+				return nil, fmt.Errorf("failed to invalidate %d of %d batches for %s, the next deploy only submits those: %w", len(batches)-i, len(batches), input.DistributionId, err)
+			}
+			return nil, err
+		}
+		outs.Invalidations = append(outs.Invalidations, id)
+		if len(batches) > 1 && i < len(batches)-1 {
+			progress.Batches = append(progress.Batches, hash)
+			progress.Invalidations = append(progress.Invalidations, id)
+			err := provider.PutInvalidationProgress(home, app.Name, app.Stage, input.DistributionId, progress)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
-	client := cloudfront.NewFromConfig(cfg)
 
-	// Split paths into chunks
+	if saved != nil || len(progress.Batches) > 0 {
+		err := provider.RemoveInvalidationProgress(home, app.Name, app.Stage, input.DistributionId)
+		if err != nil {
+			slog.Warn("failed to remove invalidation progress", "distributionId", input.DistributionId, "err", err)
+		}
+	}
+	return &outs, nil
+}
+
+func invalidationBatchHash(batch []string) string {
+	hash := sha256.Sum256([]byte(strings.Join(batch, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+func (r *DistributionInvalidation) invalidate(client CloudFrontClient, distributionId string, paths []string, wait bool) (string, error) {
+	result, err := client.CreateInvalidation(r.context, &cloudfront.CreateInvalidationInput{
+		DistributionId: aws.String(distributionId),
+		InvalidationBatch: &types.InvalidationBatch{
+			CallerReference: aws.String(strconv.FormatInt(time.Now().UnixNano(), 10)),
+			Paths: &types.Paths{
+				Quantity: aws.Int32(int32(len(paths))),
+				Items:    paths,
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if result.Invalidation == nil || result.Invalidation.Id == nil {
+		return "", fmt.Errorf("Invalidation ID not found")
+	}
+
+	// Have to wait for invalidation if there are multiple steps
+	if wait {
+		waiter := cloudfront.NewInvalidationCompletedWaiter(client)
+		err := waiter.Wait(r.context, &cloudfront.GetInvalidationInput{
+			DistributionId: aws.String(distributionId),
+			Id:             aws.String(*result.Invalidation.Id),
+		}, 10*time.Minute)
+		if err != nil {
+			// Suppress errors
+			// log.Printf("Error waiting for invalidation: %v", err)
+		}
+	}
+
+	return *result.Invalidation.Id, nil
+}
+
+// Removes the paths that are already covered by a wildcard and, if
+// directories is set, replaces directories with many files in them with a
+// wildcard. CloudFront bills a wildcard as a single path.
+func collapseInvalidationPaths(paths []string, directories bool) []string {
+	unique := map[string]bool{}
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if path == "/*" {
+			return []string{"/*"}
+		}
+		unique[path] = true
+	}
+
+	// files in the same directory are invalidated with a wildcard once there
+	// are enough of them
+	counts := map[string]int{}
+	for path := range unique {
+		if directories && !strings.HasSuffix(path, "*") {
+			counts[path[:strings.LastIndex(path, "/")+1]]++
+		}
+	}
+	for directory, count := range counts {
+		if count >= INVALIDATION_COLLAPSE_THRESHOLD {
+			unique[directory+"*"] = true
+		}
+	}
+
+	wildcards := []string{}
+	for path := range unique {
+		if strings.HasSuffix(path, "*") {
+			wildcards = append(wildcards, strings.TrimSuffix(path, "*"))
+		}
+	}
+	result := []string{}
+	for path := range unique {
+		covered := slices.ContainsFunc(wildcards, func(prefix string) bool {
+			return path != prefix+"*" && strings.HasPrefix(path, prefix)
+		})
+		if !covered {
+			result = append(result, path)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Splits the paths into batches that fit in a single invalidation
+func invalidationBatches(paths []string) [][]string {
 	var pathsFile, pathsWildcard []string
-	for _, path := range input.Paths {
-		if strings.TrimSpace(path)[len(path)-1:] == "*" {
+	for _, path := range paths {
+		if strings.HasSuffix(path, "*") {
 			pathsWildcard = append(pathsWildcard, path)
 		} else {
 			pathsFile = append(pathsFile, path)
@@ -70,45 +303,14 @@ func (r *DistributionInvalidation) handle(input *DistributionInvalidationInputs)
 		math.Ceil(float64(wildcardCount)/WILDCARD_LIMIT),
 	))
 
-	// Invalidate each chunk
+	batches := [][]string{}
 	for i := 0; i < stepsCount; i++ {
 		fileStart := int(math.Min(float64(i*FILE_LIMIT), float64(fileCount)))
 		fileEnd := int(math.Min(float64((i+1)*FILE_LIMIT), float64(fileCount)))
 		wildcardStart := int(math.Min(float64(i*WILDCARD_LIMIT), float64(wildcardCount)))
 		wildcardEnd := int(math.Min(float64((i+1)*WILDCARD_LIMIT), float64(wildcardCount)))
-		stepPaths := append(pathsFile[fileStart:fileEnd], pathsWildcard[wildcardStart:wildcardEnd]...)
-
-		result, err := client.CreateInvalidation(r.context, &cloudfront.CreateInvalidationInput{
-			DistributionId: aws.String(input.DistributionId),
-			InvalidationBatch: &types.InvalidationBatch{
-				CallerReference: aws.String(strconv.FormatInt(time.Now().UnixNano(), 10)),
-				Paths: &types.Paths{
-					Quantity: aws.Int32(int32(len(stepPaths))),
-					Items:    stepPaths,
-				},
-			},
-		})
-		if err != nil {
-			return err
-		}
-
-		if result.Invalidation == nil || result.Invalidation.Id == nil {
-			return fmt.Errorf("Invalidation ID not found")
-		}
-
-		// Have to wait for invalidation if there are multiple steps
-		if input.Wait || stepsCount > 1 {
-			waiter := cloudfront.NewInvalidationCompletedWaiter(client)
-			err := waiter.Wait(r.context, &cloudfront.GetInvalidationInput{
-				DistributionId: aws.String(input.DistributionId),
-				Id:             aws.String(*result.Invalidation.Id),
-			}, 10*time.Minute)
-			if err != nil {
-				// Suppress errors
-				// log.Printf("Error waiting for invalidation: %v", err)
-			}
-		}
+		batch := append([]string{}, pathsFile[fileStart:fileEnd]...)
+		batches = append(batches, append(batch, pathsWildcard[wildcardStart:wildcardEnd]...))
 	}
-
-	return nil
-}
\ No newline at end of file
+	return batches
+}
@@ -0,0 +1,48 @@
+package resource
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestCollapseInvalidationPaths(t *testing.T) {
+	result := collapseInvalidationPaths([]string{"/index.html", "/assets/*", "/assets/app.js", "/assets/css/*", "/index.html", " /about"}, true)
+	expected := []string{"/about", "/assets/*", "/index.html"}
+	if !slices.Equal(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+
+	if result := collapseInvalidationPaths([]string{"/a", "/*", "/b/*"}, true); !slices.Equal(result, []string{"/*"}) {
+		t.Fatalf("expected everything to collapse into /*, got %v", result)
+	}
+
+	paths := []string{"/index.html"}
+	for i := 0; i < INVALIDATION_COLLAPSE_THRESHOLD; i++ {
+		paths = append(paths, fmt.Sprintf("/images/%d.png", i))
+	}
+	expected = []string{"/images/*", "/index.html"}
+	if result := collapseInvalidationPaths(paths, true); !slices.Equal(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+	if result := collapseInvalidationPaths(paths, false); len(result) != len(paths) {
+		t.Fatalf("expected the directories to be kept, got %v", result)
+	}
+}
+
+func TestInvalidationBatches(t *testing.T) {
+	paths := []string{}
+	for i := 0; i < FILE_LIMIT+1; i++ {
+		paths = append(paths, fmt.Sprintf("/%d.html", i))
+	}
+	for i := 0; i < WILDCARD_LIMIT*2+1; i++ {
+		paths = append(paths, fmt.Sprintf("/%d/*", i))
+	}
+	batches := invalidationBatches(paths)
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 batches, got %d", len(batches))
+	}
+	if len(batches[0]) != FILE_LIMIT+WILDCARD_LIMIT || len(batches[1]) != 1+WILDCARD_LIMIT || len(batches[2]) != 1 {
+		t.Fatalf("unexpected batch sizes %d, %d, %d", len(batches[0]), len(batches[1]), len(batches[2]))
+	}
+}
@@ -1,6 +1,8 @@
 package resource
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -21,57 +23,174 @@ type FunctionCodeUpdaterInputs struct {
 	FunctionLastModified string `json:"functionLastModified"`
 	Region               string `json:"region"`
 	ImageUri             string `json:"imageUri"`
+	// When set, the new code is published as a version and the alias is moved
+	// to it
+	Alias      string              `json:"alias,omitempty"`
+	Deployment *FunctionDeployment `json:"deployment,omitempty"`
 }
 
 type FunctionCodeUpdaterOutputs struct {
-	Version string `json:"version"`
+	Version              string              `json:"version"`
+	S3Bucket             string              `json:"s3Bucket,omitempty"`
+	S3Key                string              `json:"s3Key,omitempty"`
+	FunctionName         string              `json:"functionName,omitempty"`
+	FunctionLastModified string              `json:"functionLastModified,omitempty"`
+	Region               string              `json:"region,omitempty"`
+	ImageUri             string              `json:"imageUri,omitempty"`
+	Alias                string              `json:"alias,omitempty"`
+	AliasArn             string              `json:"aliasArn,omitempty"`
+	Deployment           *FunctionDeployment `json:"deployment,omitempty"`
 }
 
 func (r *FunctionCodeUpdater) Create(input *FunctionCodeUpdaterInputs, output *CreateResult[FunctionCodeUpdaterOutputs]) error {
-	version, err := r.updateCode(input)
+	version, err := r.updateCode(r.context, input)
 	if err != nil {
 		return err
 	}
 
-	if err := r.waitForUpdate(input); err != nil {
+	if err := r.waitForUpdate(r.context, input); err != nil {
 		return err
 	}
 
+	aliasArn := ""
+	if input.Alias != "" {
+		version, aliasArn, err = r.deploy(input, nil)
+		if err != nil {
+			return err
+		}
+	}
+
 	*output = CreateResult[FunctionCodeUpdaterOutputs]{
 		ID:   input.FunctionName,
-		Outs: FunctionCodeUpdaterOutputs{Version: version},
+		Outs: r.outputs(input, version, aliasArn),
 	}
 	return nil
 }
 
 func (r *FunctionCodeUpdater) Update(input *UpdateInput[FunctionCodeUpdaterInputs, FunctionCodeUpdaterOutputs], output *UpdateResult[FunctionCodeUpdaterOutputs]) error {
-	version, err := r.updateCode(&input.News)
+	version, err := r.updateCode(r.context, &input.News)
 	if err != nil {
 		return err
 	}
 
-	if err := r.waitForUpdate(&input.News); err != nil {
+	if err := r.waitForUpdate(r.context, &input.News); err != nil {
 		return err
 	}
 
+	aliasArn := ""
+	if input.News.Alias != "" {
+		version, aliasArn, err = r.deploy(&input.News, &input.Olds)
+		if err != nil {
+			return err
+		}
+	}
+
 	*output = UpdateResult[FunctionCodeUpdaterOutputs]{
-		Outs: FunctionCodeUpdaterOutputs{Version: version},
+		Outs: r.outputs(&input.News, version, aliasArn),
+	}
+	return nil
+}
+
+func (r *FunctionCodeUpdater) Check(input *CheckInput[FunctionCodeUpdaterInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("functionName", input.News.FunctionName)
+	if input.News.ImageUri == "" {
+		checks.require("s3Bucket", input.News.S3Bucket)
+		checks.require("s3Key", input.News.S3Key)
+	}
+	if deployment := input.News.Deployment; deployment != nil {
+		checks.require("alias", input.News.Alias)
+		switch deployment.Strategy {
+		case FunctionDeploymentAllAtOnce:
+		case FunctionDeploymentCanary, FunctionDeploymentLinear:
+			if deployment.Percent <= 0 || deployment.Percent >= 100 {
+				checks.fail("deployment.percent", "percent needs to be between 0 and 100")
+			}
+		default:
+			checks.fail("deployment.strategy", "strategy needs to be one of allAtOnce, canary, or linear")
+		}
+		if deployment.Interval < 0 {
+			checks.fail("deployment.interval", "interval can't be negative")
+		}
+	}
+	*output = checks.result()
+	return nil
+}
+
+func (r *FunctionCodeUpdater) Diff(input *DiffInput[FunctionCodeUpdaterInputs, FunctionCodeUpdaterOutputs], output *DiffResult) error {
+	if input.Olds.FunctionName == "" {
+		*output = DiffResult{}
+		return nil
+	}
+	var changed diffBuilder
+	changed.compare("functionName", input.Olds.FunctionName, input.News.FunctionName)
+	changed.compare("region", input.Olds.Region, input.News.Region)
+	changed.compare("s3Bucket", input.Olds.S3Bucket, input.News.S3Bucket)
+	changed.compare("s3Key", input.Olds.S3Key, input.News.S3Key)
+	changed.compare("imageUri", input.Olds.ImageUri, input.News.ImageUri)
+	changed.compare("functionLastModified", input.Olds.FunctionLastModified, input.News.FunctionLastModified)
+	changed.compare("alias", input.Olds.Alias, input.News.Alias)
+	changed.compare("deployment", input.Olds.Deployment, input.News.Deployment)
+	// the ID is the function name
+	*output = newDiff(changed, "functionName", "region")
+	return nil
+}
+
+func (r *FunctionCodeUpdater) Read(input *ReadInput[FunctionCodeUpdaterOutputs], output *ReadResult[FunctionCodeUpdaterOutputs]) error {
+	if input.Props.FunctionName == "" {
+		*output = ReadResult[FunctionCodeUpdaterOutputs]{ID: input.ID, Props: input.Props}
+		return nil
+	}
+	cfg, err := r.config()
+	if err != nil {
+		return err
 	}
+	if input.Props.Region != "" {
+		cfg.Region = input.Props.Region
+	}
+	client := r.clients.Lambda(cfg)
+	_, err = client.GetFunction(r.context, &lambda.GetFunctionInput{
+		FunctionName: aws.String(input.Props.FunctionName),
+	})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			*output = ReadResult[FunctionCodeUpdaterOutputs]{}
+			return nil
+		}
+		return err
+	}
+	*output = ReadResult[FunctionCodeUpdaterOutputs]{ID: input.ID, Props: input.Props}
 	return nil
 }
 
-func (r *FunctionCodeUpdater) updateCode(input *FunctionCodeUpdaterInputs) (string, error) {
+func (r *FunctionCodeUpdater) outputs(input *FunctionCodeUpdaterInputs, version string, aliasArn string) FunctionCodeUpdaterOutputs {
+	return FunctionCodeUpdaterOutputs{
+		Version:              version,
+		S3Bucket:             input.S3Bucket,
+		S3Key:                input.S3Key,
+		FunctionName:         input.FunctionName,
+		FunctionLastModified: input.FunctionLastModified,
+		Region:               input.Region,
+		ImageUri:             input.ImageUri,
+		Alias:                input.Alias,
+		AliasArn:             aliasArn,
+		Deployment:           input.Deployment,
+	}
+}
+
+func (r *FunctionCodeUpdater) updateCode(ctx context.Context, input *FunctionCodeUpdaterInputs) (string, error) {
 	cfg, err := r.config()
 	if err != nil {
 		return "", err
 	}
 
 	cfg.Region = input.Region
-	client := lambda.NewFromConfig(cfg)
+	client := r.clients.Lambda(cfg)
 
 	// Handle the case where the function is deployed in a container
 	if input.ImageUri != "" {
-		ret, err := client.UpdateFunctionCode(r.context, &lambda.UpdateFunctionCodeInput{
+		ret, err := client.UpdateFunctionCode(ctx, &lambda.UpdateFunctionCodeInput{
 			FunctionName: aws.String(input.FunctionName),
 			ImageUri:     aws.String(input.ImageUri),
 		})
@@ -86,7 +205,7 @@ func (r *FunctionCodeUpdater) updateCode(input *FunctionCodeUpdaterInputs) (stri
 		return "unknown", nil
 	}
 
-	ret, err := client.UpdateFunctionCode(r.context, &lambda.UpdateFunctionCodeInput{
+	ret, err := client.UpdateFunctionCode(ctx, &lambda.UpdateFunctionCodeInput{
 		FunctionName: aws.String(input.FunctionName),
 		S3Bucket:     aws.String(input.S3Bucket),
 		S3Key:        aws.String(input.S3Key),
@@ -101,17 +220,17 @@ func (r *FunctionCodeUpdater) updateCode(input *FunctionCodeUpdaterInputs) (stri
 	return "unknown", nil
 }
 
-func (r *FunctionCodeUpdater) waitForUpdate(input *FunctionCodeUpdaterInputs) error {
+func (r *FunctionCodeUpdater) waitForUpdate(ctx context.Context, input *FunctionCodeUpdaterInputs) error {
 	cfg, err := r.config()
 	if err != nil {
 		return err
 	}
 
 	cfg.Region = input.Region
-	client := lambda.NewFromConfig(cfg)
+	client := r.clients.Lambda(cfg)
 
 	for {
-		ret, err := client.GetFunction(r.context, &lambda.GetFunctionInput{
+		ret, err := client.GetFunction(ctx, &lambda.GetFunctionInput{
 			FunctionName: aws.String(input.FunctionName),
 		})
 		if err != nil {
@@ -0,0 +1,238 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/sst/sst/v3/pkg/bus"
+)
+
+const (
+	FunctionDeploymentAllAtOnce = "allAtOnce"
+	FunctionDeploymentCanary    = "canary"
+	FunctionDeploymentLinear    = "linear"
+)
+
+// How often the alarms are checked while a version is baking
+const FUNCTION_DEPLOYMENT_POLL = 10 * time.Second
+
+// How long moving the alias and the code back gets, it doesn't use the
+// request's context since that may be what got cancelled
+const FUNCTION_DEPLOYMENT_ROLLBACK_TIMEOUT = time.Minute
+
+// How traffic is moved from the version the alias points to over to the new
+// one. A canary sends Percent of the traffic to the new version and the rest
+// after Interval. Linear adds Percent every Interval. If any of the alarms go
+// off in the meantime the alias is moved back.
+type FunctionDeployment struct {
+	Strategy string `json:"strategy"`
+	Percent  int    `json:"percent,omitempty"`
+	// In seconds
+	Interval int      `json:"interval,omitempty"`
+	Alarms   []string `json:"alarms,omitempty"`
+}
+
+const (
+	FunctionDeploymentStatusShifting   = "shifting"
+	FunctionDeploymentStatusComplete   = "complete"
+	FunctionDeploymentStatusRolledBack = "rolledBack"
+)
+
+type FunctionDeploymentEvent struct {
+	FunctionName string
+	Alias        string
+	Version      string
+	Previous     string
+	// The percent of traffic going to the new version
+	Weight int
+	Status string
+	Alarm  string
+}
+
+// Publishes the current code as a version and moves the alias to it, returns
+// the version and the alias ARN. When the alias is moved back, the unqualified
+// function is also set back to the previous code so nothing keeps running the
+// new version.
+func (r *FunctionCodeUpdater) deploy(input *FunctionCodeUpdaterInputs, previousCode *FunctionCodeUpdaterOutputs) (string, string, error) {
+	cfg, err := r.config()
+	if err != nil {
+		return "", "", err
+	}
+	cfg.Region = input.Region
+	client := r.clients.Lambda(cfg)
+
+	published, err := client.PublishVersion(r.context, &lambda.PublishVersionInput{
+		FunctionName: aws.String(input.FunctionName),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	version := aws.ToString(published.Version)
+
+	alias, err := client.GetAlias(r.context, &lambda.GetAliasInput{
+		FunctionName: aws.String(input.FunctionName),
+		Name:         aws.String(input.Alias),
+	})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if !errors.As(err, &notFound) {
+			return "", "", err
+		}
+		// nothing is using the alias yet so there is no traffic to shift
+		created, err := client.CreateAlias(r.context, &lambda.CreateAliasInput{
+			FunctionName:    aws.String(input.FunctionName),
+			Name:            aws.String(input.Alias),
+			FunctionVersion: aws.String(version),
+		})
+		if err != nil {
+			return "", "", err
+		}
+		r.publishDeployment(input, version, "", 100, FunctionDeploymentStatusComplete, "")
+		return version, aws.ToString(created.AliasArn), nil
+	}
+	aliasArn := aws.ToString(alias.AliasArn)
+
+	previous := aws.ToString(alias.FunctionVersion)
+	if previous == version {
+		return version, aliasArn, nil
+	}
+
+	deployment := input.Deployment
+	if deployment == nil {
+		deployment = &FunctionDeployment{Strategy: FunctionDeploymentAllAtOnce}
+	}
+	alarms := r.clients.CloudWatch(cfg)
+	for _, weight := range functionDeploymentSteps(deployment) {
+		if weight == 100 {
+			break
+		}
+		if err := r.route(r.context, client, input, previous, version, weight); err != nil {
+			return "", "", err
+		}
+		r.publishDeployment(input, version, previous, weight, FunctionDeploymentStatusShifting, "")
+		alarm, err := r.bake(alarms, deployment)
+		if err != nil || alarm != "" {
+			if rollback := r.rollback(client, input, previous, version, previousCode); rollback != nil {
+				return "", "", rollback
+			}
+			r.publishDeployment(input, version, previous, 0, FunctionDeploymentStatusRolledBack, alarm)
+			if err != nil {
+				return "", "", err
+			}
+			return "", "", fmt.Errorf("rolled back %s to version %s because the %s alarm went off", input.FunctionName, previous, alarm)
+		}
+	}
+
+	if err := r.route(r.context, client, input, version, version, 0); err != nil {
+		return "", "", err
+	}
+	r.publishDeployment(input, version, previous, 100, FunctionDeploymentStatusComplete, "")
+	return version, aliasArn, nil
+}
+
+// Moves all the traffic back to the previous version and puts the previous
+// code back on the unqualified function
+func (r *FunctionCodeUpdater) rollback(client LambdaClient, input *FunctionCodeUpdaterInputs, previous string, version string, previousCode *FunctionCodeUpdaterOutputs) error {
+	ctx, cancel := context.WithTimeout(context.Background(), FUNCTION_DEPLOYMENT_ROLLBACK_TIMEOUT)
+	defer cancel()
+	if err := r.route(ctx, client, input, previous, version, 0); err != nil {
+		return err
+	}
+	if previousCode == nil {
+		return nil
+	}
+	restore := *input
+	restore.S3Bucket = previousCode.S3Bucket
+	restore.S3Key = previousCode.S3Key
+	restore.ImageUri = previousCode.ImageUri
+	if _, err := r.updateCode(ctx, &restore); err != nil {
+		return fmt.Errorf("failed to restore the previous code of %s: %w", input.FunctionName, err)
+	}
+	return r.waitForUpdate(ctx, &restore)
+}
+
+// Points the alias at the primary version and sends the weight percent of the
+// traffic to the next one
+func (r *FunctionCodeUpdater) route(ctx context.Context, client LambdaClient, input *FunctionCodeUpdaterInputs, primary string, next string, weight int) error {
+	weights := map[string]float64{}
+	if weight > 0 && primary != next {
+		weights[next] = float64(weight) / 100
+	}
+	_, err := client.UpdateAlias(ctx, &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(input.FunctionName),
+		Name:            aws.String(input.Alias),
+		FunctionVersion: aws.String(primary),
+		RoutingConfig: &types.AliasRoutingConfiguration{
+			AdditionalVersionWeights: weights,
+		},
+	})
+	return err
+}
+
+// Waits out the interval and returns the first alarm that goes off
+func (r *FunctionCodeUpdater) bake(client CloudWatchClient, deployment *FunctionDeployment) (string, error) {
+	deadline := time.Now().Add(time.Duration(deployment.Interval) * time.Second)
+	for {
+		if len(deployment.Alarms) > 0 {
+			result, err := client.DescribeAlarms(r.context, &cloudwatch.DescribeAlarmsInput{
+				AlarmNames: deployment.Alarms,
+				StateValue: cwtypes.StateValueAlarm,
+			})
+			if err != nil {
+				return "", err
+			}
+			if len(result.MetricAlarms) > 0 {
+				return aws.ToString(result.MetricAlarms[0].AlarmName), nil
+			}
+			if len(result.CompositeAlarms) > 0 {
+				return aws.ToString(result.CompositeAlarms[0].AlarmName), nil
+			}
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return "", nil
+		}
+		select {
+		case <-r.context.Done():
+			return "", r.context.Err()
+		case <-time.After(min(remaining, FUNCTION_DEPLOYMENT_POLL)):
+		}
+	}
+}
+
+func (r *FunctionCodeUpdater) publishDeployment(input *FunctionCodeUpdaterInputs, version string, previous string, weight int, status string, alarm string) {
+	bus.Publish(&FunctionDeploymentEvent{
+		FunctionName: input.FunctionName,
+		Alias:        input.Alias,
+		Version:      version,
+		Previous:     previous,
+		Weight:       weight,
+		Status:       status,
+		Alarm:        alarm,
+	})
+}
+
+// The percent of traffic the new version gets at each step, always ending
+// at 100
+func functionDeploymentSteps(deployment *FunctionDeployment) []int {
+	switch deployment.Strategy {
+	case FunctionDeploymentCanary:
+		return []int{deployment.Percent, 100}
+	case FunctionDeploymentLinear:
+		steps := []int{}
+		count := int(math.Ceil(100 / float64(deployment.Percent)))
+		for i := 1; i < count; i++ {
+			steps = append(steps, i*deployment.Percent)
+		}
+		return append(steps, 100)
+	}
+	return []int{100}
+}
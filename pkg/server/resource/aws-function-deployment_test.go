@@ -0,0 +1,23 @@
+package resource
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFunctionDeploymentSteps(t *testing.T) {
+	cases := []struct {
+		deployment FunctionDeployment
+		expected   []int
+	}{
+		{FunctionDeployment{Strategy: FunctionDeploymentAllAtOnce}, []int{100}},
+		{FunctionDeployment{Strategy: FunctionDeploymentCanary, Percent: 10}, []int{10, 100}},
+		{FunctionDeployment{Strategy: FunctionDeploymentLinear, Percent: 25}, []int{25, 50, 75, 100}},
+		{FunctionDeployment{Strategy: FunctionDeploymentLinear, Percent: 30}, []int{30, 60, 90, 100}},
+	}
+	for _, item := range cases {
+		if steps := functionDeploymentSteps(&item.deployment); !slices.Equal(steps, item.expected) {
+			t.Errorf("%+v: expected %v, got %v", item.deployment, item.expected, steps)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package resource
 
 import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
@@ -16,23 +21,109 @@ type FunctionEnvironmentUpdateInputs struct {
 	Region       string            `json:"region"`
 }
 
-func (r *FunctionEnvironmentUpdate) Create(input *FunctionEnvironmentUpdateInputs, output *CreateResult[struct{}]) error {
+type FunctionEnvironmentUpdateOutputs struct {
+	FunctionName string            `json:"functionName,omitempty"`
+	Environment  map[string]string `json:"environment,omitempty"`
+	Region       string            `json:"region,omitempty"`
+}
+
+func (r *FunctionEnvironmentUpdate) Create(input *FunctionEnvironmentUpdateInputs, output *CreateResult[FunctionEnvironmentUpdateOutputs]) error {
 	if err := r.updateEnvironment(input); err != nil {
 		return err
 	}
 
-	*output = CreateResult[struct{}]{
-		ID: input.FunctionName,
+	*output = CreateResult[FunctionEnvironmentUpdateOutputs]{
+		ID:   input.FunctionName,
+		Outs: FunctionEnvironmentUpdateOutputs(*input),
 	}
 	return nil
 }
 
-func (r *FunctionEnvironmentUpdate) Update(input *UpdateInput[FunctionEnvironmentUpdateInputs, struct{}], output *UpdateResult[struct{}]) error {
+func (r *FunctionEnvironmentUpdate) Update(input *UpdateInput[FunctionEnvironmentUpdateInputs, FunctionEnvironmentUpdateOutputs], output *UpdateResult[FunctionEnvironmentUpdateOutputs]) error {
 	if err := r.updateEnvironment(&input.News); err != nil {
 		return err
 	}
 
-	*output = UpdateResult[struct{}]{}
+	*output = UpdateResult[FunctionEnvironmentUpdateOutputs]{
+		Outs: FunctionEnvironmentUpdateOutputs(input.News),
+	}
+	return nil
+}
+
+var environmentKeyRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+func (r *FunctionEnvironmentUpdate) Check(input *CheckInput[FunctionEnvironmentUpdateInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("functionName", input.News.FunctionName)
+	keys := []string{}
+	for key := range input.News.Environment {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !environmentKeyRegex.MatchString(key) {
+			checks.fail("environment", fmt.Sprintf("%q needs to start with a letter and only contain letters, numbers, and underscores", key))
+		}
+	}
+	*output = checks.result()
+	return nil
+}
+
+func (r *FunctionEnvironmentUpdate) Diff(input *DiffInput[FunctionEnvironmentUpdateInputs, FunctionEnvironmentUpdateOutputs], output *DiffResult) error {
+	if input.Olds.FunctionName == "" {
+		*output = DiffResult{}
+		return nil
+	}
+	var changed diffBuilder
+	changed.compare("functionName", input.Olds.FunctionName, input.News.FunctionName)
+	changed.compare("region", input.Olds.Region, input.News.Region)
+	if len(input.Olds.Environment) != 0 || len(input.News.Environment) != 0 {
+		changed.compare("environment", input.Olds.Environment, input.News.Environment)
+	}
+	// the ID is the function name
+	*output = newDiff(changed, "functionName", "region")
+	return nil
+}
+
+// Read picks up the variables that were changed or removed outside of a
+// deploy, so the next one sets them again
+func (r *FunctionEnvironmentUpdate) Read(input *ReadInput[FunctionEnvironmentUpdateOutputs], output *ReadResult[FunctionEnvironmentUpdateOutputs]) error {
+	props := input.Props
+	if props.FunctionName == "" {
+		*output = ReadResult[FunctionEnvironmentUpdateOutputs]{ID: input.ID, Props: props}
+		return nil
+	}
+	cfg, err := r.config()
+	if err != nil {
+		return err
+	}
+	if props.Region != "" {
+		cfg.Region = props.Region
+	}
+	client := r.clients.Lambda(cfg)
+	functionConfig, err := client.GetFunctionConfiguration(r.context, &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(props.FunctionName),
+	})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			*output = ReadResult[FunctionEnvironmentUpdateOutputs]{}
+			return nil
+		}
+		return err
+	}
+	live := map[string]string{}
+	if functionConfig.Environment != nil {
+		live = functionConfig.Environment.Variables
+	}
+	environment := map[string]string{}
+	for key := range props.Environment {
+		if value, ok := live[key]; ok {
+			environment[key] = value
+		}
+	}
+	props.Environment = environment
+	*output = ReadResult[FunctionEnvironmentUpdateOutputs]{ID: input.ID, Props: props}
 	return nil
 }
 
@@ -47,7 +138,7 @@ func (r *FunctionEnvironmentUpdate) updateEnvironment(input *FunctionEnvironment
 		cfg.Region = input.Region
 	}
 	
-	client := lambda.NewFromConfig(cfg)
+	client := r.clients.Lambda(cfg)
 
 	// Get the current function configuration to preserve other settings
 	functionConfig, err := client.GetFunctionConfiguration(r.context, &lambda.GetFunctionConfigurationInput{
@@ -1,9 +1,11 @@
 package resource
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/aws/aws-sdk-go-v2/service/route53/types"
 )
@@ -18,6 +20,7 @@ type HostedZoneLookupInputs struct {
 
 type HostedZoneLookupOutputs struct {
 	ZoneId string `json:"zoneId"`
+	Domain string `json:"domain,omitempty"`
 }
 
 func (r *HostedZoneLookup) Create(input *HostedZoneLookupInputs, output *CreateResult[HostedZoneLookupOutputs]) error {
@@ -28,7 +31,7 @@ func (r *HostedZoneLookup) Create(input *HostedZoneLookupInputs, output *CreateR
 
 	*output = CreateResult[HostedZoneLookupOutputs]{
 		ID:   zoneId,
-		Outs: HostedZoneLookupOutputs{ZoneId: zoneId},
+		Outs: HostedZoneLookupOutputs{ZoneId: zoneId, Domain: input.Domain},
 	}
 	return nil
 }
@@ -40,18 +43,58 @@ func (r *HostedZoneLookup) Update(input *UpdateInput[HostedZoneLookupInputs, Hos
 	}
 
 	*output = UpdateResult[HostedZoneLookupOutputs]{
-		Outs: HostedZoneLookupOutputs{ZoneId: zoneId},
+		Outs: HostedZoneLookupOutputs{ZoneId: zoneId, Domain: input.News.Domain},
 	}
 	return nil
 }
 
+func (r *HostedZoneLookup) Check(input *CheckInput[HostedZoneLookupInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("domain", input.News.Domain)
+	*output = checks.result()
+	return nil
+}
+
+func (r *HostedZoneLookup) Diff(input *DiffInput[HostedZoneLookupInputs, HostedZoneLookupOutputs], output *DiffResult) error {
+	if input.Olds.Domain == "" {
+		*output = DiffResult{}
+		return nil
+	}
+	var changed diffBuilder
+	changed.compare("domain", input.Olds.Domain, input.News.Domain)
+	// the ID is the zone that was found for the domain
+	*output = newDiff(changed, "domain")
+	return nil
+}
+
+func (r *HostedZoneLookup) Read(input *ReadInput[HostedZoneLookupOutputs], output *ReadResult[HostedZoneLookupOutputs]) error {
+	cfg, err := r.config()
+	if err != nil {
+		return err
+	}
+	client := r.clients.Route53(cfg)
+	_, err = client.GetHostedZone(r.context, &route53.GetHostedZoneInput{
+		Id: aws.String(input.ID),
+	})
+	if err != nil {
+		var notFound *types.NoSuchHostedZone
+		if errors.As(err, &notFound) {
+			*output = ReadResult[HostedZoneLookupOutputs]{}
+			return nil
+		}
+		return err
+	}
+	*output = ReadResult[HostedZoneLookupOutputs]{ID: input.ID, Props: input.Props}
+	return nil
+}
+
 func (r *HostedZoneLookup) lookup(domain string) (string, error) {
 	cfg, err := r.config()
 	if err != nil {
 		return "", err
 	}
 
-	client := route53.NewFromConfig(cfg)
+	client := r.clients.Route53(cfg)
 
 	var zones []types.HostedZone
 	var nextMarker *string
@@ -2,12 +2,8 @@ package resource
 
 import (
 	"errors"
-	"math/rand"
-	"strings"
-	"time"
+	"sort"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
 	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore/types"
 )
 
@@ -30,13 +26,12 @@ type KvKeysOutputs struct {
 }
 
 func (r *KvKeys) Create(input *KvKeysInputs, output *CreateResult[KvKeysOutputs]) error {
-	cfg, err := r.config()
+	store, err := r.store(input.Store)
 	if err != nil {
 		return err
 	}
-	client := cloudfrontkeyvaluestore.NewFromConfig(cfg)
 
-	if err := r.upload(client, input.Store, input.Namespace, input.Entries, nil); err != nil {
+	if err := store.write(kvKeysChanges(input.Namespace, input.Entries, nil)); err != nil {
 		return err
 	}
 
@@ -53,23 +48,23 @@ func (r *KvKeys) Create(input *KvKeysInputs, output *CreateResult[KvKeysOutputs]
 }
 
 func (r *KvKeys) Update(input *UpdateInput[KvKeysInputs, KvKeysOutputs], output *UpdateResult[KvKeysOutputs]) error {
-	cfg, err := r.config()
+	store, err := r.store(input.News.Store)
 	if err != nil {
 		return err
 	}
-	client := cloudfrontkeyvaluestore.NewFromConfig(cfg)
 
+	// none of the old entries are in the new store or namespace
 	oldEntries := input.Olds.Entries
-	if input.News.Store != input.Olds.Store {
+	if input.News.Store != input.Olds.Store || input.News.Namespace != input.Olds.Namespace {
 		oldEntries = nil
 	}
 
-	if err := r.upload(client, input.News.Store, input.News.Namespace, input.News.Entries, oldEntries); err != nil {
+	if err := store.write(kvKeysChanges(input.News.Namespace, input.News.Entries, oldEntries)); err != nil {
 		return err
 	}
 
 	if input.News.Purge {
-		if err := r.purge(client, input.News.Store, input.News.Namespace, input.News.Entries); err != nil {
+		if err := r.purge(store, input.News.Namespace, input.News.Entries); err != nil {
 			return err
 		}
 	}
@@ -90,213 +85,125 @@ func (r *KvKeys) Delete(input *DeleteInput[KvKeysOutputs], output *int) error {
 		return nil
 	}
 
-	cfg, err := r.config()
+	store, err := r.store(input.Outs.Store)
 	if err != nil {
 		return err
 	}
-	client := cloudfrontkeyvaluestore.NewFromConfig(cfg)
 
-	return r.purge(client, input.Outs.Store, input.Outs.Namespace, nil)
+	return r.purge(store, input.Outs.Namespace, nil)
 }
 
-func (r *KvKeys) upload(client *cloudfrontkeyvaluestore.Client, store string, namespace string, entries map[string]string, oldEntries map[string]string) error {
-	if len(entries) == 0 {
-		return nil
-	}
+func (r *KvKeys) Check(input *CheckInput[KvKeysInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("store", input.News.Store)
+	*output = checks.result()
+	return nil
+}
 
-	// Identify keys that need to be updated (new keys or changed values)
-	var keysToUpdate []struct {
-		key   string
-		value string
-	}
-	for k, v := range entries {
-		oldValue, exists := oldEntries[k]
-		if !exists || oldValue != v {
-			keysToUpdate = append(keysToUpdate, struct {
-				key   string
-				value string
-			}{k, v})
-		}
+func (r *KvKeys) Diff(input *DiffInput[KvKeysInputs, KvKeysOutputs], output *DiffResult) error {
+	var changed diffBuilder
+	changed.compare("store", input.Olds.Store, input.News.Store)
+	changed.compare("namespace", input.Olds.Namespace, input.News.Namespace)
+	changed.compare("purge", input.Olds.Purge, input.News.Purge)
+	if len(input.Olds.Entries) != 0 || len(input.News.Entries) != 0 {
+		changed.compare("entries", input.Olds.Entries, input.News.Entries)
 	}
+	*output = newDiff(changed)
+	return nil
+}
 
-	// If no keys need updating, return early
-	if len(keysToUpdate) == 0 {
+// Read picks up the entries that were changed or removed outside of a deploy,
+// so the next one uploads them again
+func (r *KvKeys) Read(input *ReadInput[KvKeysOutputs], output *ReadResult[KvKeysOutputs]) error {
+	props := input.Props
+	if props.Store == "" {
+		*output = ReadResult[KvKeysOutputs]{ID: input.ID, Props: props}
 		return nil
 	}
-
-	// Prepare put items
-	putItems := make([]types.PutKeyRequestListItem, 0, len(keysToUpdate))
-	for _, kv := range keysToUpdate {
-		putItems = append(putItems, types.PutKeyRequestListItem{
-			Key:   aws.String(namespace + ":" + kv.key),
-			Value: aws.String(kv.value),
-		})
+	store, err := r.store(props.Store)
+	if err != nil {
+		return err
 	}
 
-	// Update keys with retry support (passing nil for etag to fetch a new one)
-	return r.updateKeys(client, store, nil, putItems, nil)
-}
-
-func (r *KvKeys) purge(client *cloudfrontkeyvaluestore.Client, store string, namespace string, entries map[string]string) error {
-	// Collect all keys under the namespace that are not in entries
-	var keysToDelete []string
-	var nextToken *string = nil
-
-	// Use pagination to get all keys
-	for {
-		listResp, err := client.ListKeys(r.context, &cloudfrontkeyvaluestore.ListKeysInput{
-			KvsARN:    aws.String(store),
-			NextToken: nextToken,
-		})
-		if err != nil {
-			var notFoundErr *types.ResourceNotFoundException
-			if errors.As(err, &notFoundErr) {
-				return nil
-			}
-			return err
+	// a missing key and a missing store look the same to GetKey
+	_, err = store.etag()
+	if err != nil {
+		var notFoundErr *types.ResourceNotFoundException
+		if errors.As(err, &notFoundErr) {
+			*output = ReadResult[KvKeysOutputs]{}
+			return nil
 		}
+		return err
+	}
 
-		// Process keys from this page
-		for _, item := range listResp.Items {
-			// Check if the key has the namespace prefix
-			if strings.HasPrefix(*item.Key, namespace+":") {
-				// Extract the key without namespace prefix
-				keyWithoutNamespace := strings.TrimPrefix(*item.Key, namespace+":")
-				
-				// Skip keys that exist in entries
-				if entries != nil {
-					if _, exists := entries[keyWithoutNamespace]; exists {
-						continue
-					}
-				}
-				
-				keysToDelete = append(keysToDelete, *item.Key)
-			}
+	// only the tracked keys are read, the store can hold far more
+	entries := map[string]string{}
+	for key := range props.Entries {
+		value, _, ok, err := store.get(props.Namespace + ":" + key)
+		if err != nil && !errors.Is(err, errKvChanged) {
+			return err
 		}
-
-		// Check if there are more keys to retrieve
-		if listResp.NextToken == nil {
-			break
+		if ok {
+			entries[key] = value
 		}
-		nextToken = listResp.NextToken
-	}
-
-	if len(keysToDelete) == 0 {
-		return nil
 	}
+	props.Entries = entries
+	*output = ReadResult[KvKeysOutputs]{ID: input.ID, Props: props}
+	return nil
+}
 
-	// Prepare delete items
-	deleteItems := make([]types.DeleteKeyRequestListItem, 0, len(keysToDelete))
-	for _, key := range keysToDelete {
-		deleteItems = append(deleteItems, types.DeleteKeyRequestListItem{
-			Key: aws.String(key),
-		})
+func (r *KvKeys) store(arn string) (*kvStore, error) {
+	cfg, err := r.config()
+	if err != nil {
+		return nil, err
 	}
-
-	// Update keys with retry support (passing nil for etag to fetch a new one)
-	return r.updateKeys(client, store, nil, nil, deleteItems)
+	return newKvStore(r.context, r.clients.KeyValueStore(cfg), arn), nil
 }
 
-func (r *KvKeys) updateKeys(client *cloudfrontkeyvaluestore.Client, store string, etag *string, putItems []types.PutKeyRequestListItem, deleteItems []types.DeleteKeyRequestListItem) error {
-	// If etag is nil, fetch a new one
-	if etag == nil {
-		descResp, err := client.DescribeKeyValueStore(r.context, &cloudfrontkeyvaluestore.DescribeKeyValueStoreInput{
-			KvsARN: aws.String(store),
-		})
-		if err != nil {
-			return err
+// Removes every key in the namespace that isn't one of the entries or one of
+// their parts
+func (r *KvKeys) purge(store *kvStore, namespace string, entries map[string]string) error {
+	live, err := store.list(namespace + ":")
+	if err != nil {
+		var notFoundErr *types.ResourceNotFoundException
+		if errors.As(err, &notFoundErr) {
+			return nil
 		}
-		etag = descResp.ETag
+		return err
 	}
 
-	// Process in batches of 50
-	const batchSize = 50
-	
-	// Determine if we have items to process in this call
-	hasPuts := len(putItems) > 0
-	hasDeletes := len(deleteItems) > 0
-	
-	// If there are no items to process, we're done
-	if !hasPuts && !hasDeletes {
-		return nil
-	}
-	
-	// Get the puts and deletes for this batch
-	var putBatch []types.PutKeyRequestListItem
-	var deleteBatch []types.DeleteKeyRequestListItem
-	var remainingPuts []types.PutKeyRequestListItem
-	var remainingDeletes []types.DeleteKeyRequestListItem
-	
-	// Calculate how many items we can include in this batch
-	availableBatchSize := batchSize
-	
-	// Process puts up to batch size
-	if hasPuts {
-		if len(putItems) <= availableBatchSize {
-			putBatch = putItems
-			remainingPuts = nil
-		} else {
-			putBatch = putItems[:availableBatchSize]
-			remainingPuts = putItems[availableBatchSize:]
+	keep := map[string]bool{}
+	for key, value := range entries {
+		for _, stored := range kvKeys(namespace+":"+key, value) {
+			keep[stored] = true
 		}
-		
-		// Adjust available batch size for deletes
-		availableBatchSize -= len(putBatch)
 	}
-	
-	// Process deletes with remaining batch size
-	if hasDeletes && availableBatchSize > 0 {
-		if len(deleteItems) <= availableBatchSize {
-			deleteBatch = deleteItems
-			remainingDeletes = nil
-		} else {
-			deleteBatch = deleteItems[:availableBatchSize]
-			remainingDeletes = deleteItems[availableBatchSize:]
+	ops := []kvOp{}
+	for key := range live {
+		if !keep[key] {
+			ops = append(ops, kvOp{Key: key})
 		}
-	} else if hasDeletes {
-		// No space in this batch, all deletes go to next batch
-		remainingDeletes = deleteItems
-	}
-	
-	// Prepare update input
-	input := &cloudfrontkeyvaluestore.UpdateKeysInput{
-		KvsARN:  aws.String(store),
-		IfMatch: etag,
-	}
-	
-	// Add puts if we have any
-	if len(putBatch) > 0 {
-		input.Puts = putBatch
 	}
-	
-	// Add deletes if we have any
-	if len(deleteBatch) > 0 {
-		input.Deletes = deleteBatch
-	}
-	
-	// Update keys
-	resp, err := client.UpdateKeys(r.context, input)
-	
-	// Handle errors
-	if err != nil {
-		var preconditionFailedErr *types.ValidationException
-		if errors.As(err, &preconditionFailedErr) && 
-		   preconditionFailedErr.Message != nil && 
-		   strings.Contains(*preconditionFailedErr.Message, "Pre-Condition failed") {
-			
-			// Sleep for a random time between 100 and 500ms to reduce contention
-			time.Sleep(time.Duration(rand.Intn(400)+100) * time.Millisecond)
-			
-			// Retry with nil etag (to force fetching a new one) and the same batch
-			return r.updateKeys(client, store, nil, putItems, deleteItems)
+	return store.write(ops)
+}
+
+// The changes that upload the entries that are new or have a different value
+// than before
+func kvKeysChanges(namespace string, entries map[string]string, oldEntries map[string]string) []kvOp {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ops := []kvOp{}
+	for _, key := range keys {
+		value := entries[key]
+		oldValue, exists := oldEntries[key]
+		if exists && oldValue == value {
+			continue
 		}
-		return err
-	}
-	
-	// Process any remaining items
-	if len(remainingPuts) > 0 || len(remainingDeletes) > 0 {
-		return r.updateKeys(client, store, resp.ETag, remainingPuts, remainingDeletes)
+		ops = append(ops, kvPut(namespace+":"+key, value, kvPartCount(oldValue))...)
 	}
-	
-	return nil
-}
\ No newline at end of file
+	return ops
+}
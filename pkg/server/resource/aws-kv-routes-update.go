@@ -2,19 +2,7 @@ package resource
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"math/rand"
-	"strings"
-	"time"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
-	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore/types"
-)
-
-const (
-	chunkSize = 1000
 )
 
 type KvRoutesUpdate struct {
@@ -29,58 +17,28 @@ type KvRoutesUpdateInputs struct {
 }
 
 type KvRoutesUpdateOutputs struct {
-	Store     string   `json:"store,omitempty"`
-	Key       string   `json:"key,omitempty"`
-	Entry     string   `json:"entry,omitempty"`
-	Namespace string   `json:"namespace,omitempty"`
+	Store     string `json:"store,omitempty"`
+	Key       string `json:"key,omitempty"`
+	Entry     string `json:"entry,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
 }
 
 func (r *KvRoutesUpdate) Create(input *KvRoutesUpdateInputs, output *CreateResult[KvRoutesUpdateOutputs]) error {
-	// get client
-	cfg, err := r.config()
-	if err != nil {
-		return err
-	}
-	client := cloudfrontkeyvaluestore.NewFromConfig(cfg)
-
-	// get etag
-	etag, err := r.getEtag(client, input.Store)
+	store, err := r.store(input.Store)
 	if err != nil {
 		return err
 	}
 
-	// get routes
-	fullKey := input.Namespace + ":" + input.Key
-	existingRoutes, chunkNum, err := r.getRoutes(client, input.Store, fullKey)
-	if err != nil {
-		// check etag to see if this happened b/c routes were updated in the meantime
-		newEtag, err := r.getEtag(client, input.Store)
-		if err == nil && newEtag != etag {
-			// sleep for a random time between 100 and 500ms
-			time.Sleep(time.Duration(rand.Intn(400)+100) * time.Millisecond)
-			return r.Create(input, output)
+	err = r.modify(store, input.Namespace+":"+input.Key, func(routes []string) []string {
+		if !existsRoute(routes, input.Entry) {
+			routes = append(routes, input.Entry)
 		}
-		return err
-	}
-	
-	// append route if not exists
-	routes := existingRoutes
-	if !existsRoute(routes, input.Entry) {
-		routes = append(routes, input.Entry)
-	}
-
-	// set routes
-	err = r.setRoutes(client, input.Store, etag, fullKey, routes, chunkNum)
+		return routes
+	})
 	if err != nil {
-		var preconditionFailedErr *types.ValidationException
-		if errors.As(err, &preconditionFailedErr) && preconditionFailedErr.Message != nil && strings.Contains(*preconditionFailedErr.Message, "Pre-Condition failed") {
-			// sleep for a random time between 100 and 500ms
-			time.Sleep(time.Duration(rand.Intn(400)+100) * time.Millisecond)
-			return r.Create(input, output)
-		}
 		return err
 	}
-	
+
 	*output = CreateResult[KvRoutesUpdateOutputs]{
 		ID: fmt.Sprintf("%s:%s:%s", input.Store, input.Namespace, input.Key),
 		Outs: KvRoutesUpdateOutputs{
@@ -99,7 +57,7 @@ func (r *KvRoutesUpdate) Update(input *UpdateInput[KvRoutesUpdateInputs, KvRoute
 		input.News.Namespace != input.Olds.Namespace ||
 		input.News.Key != input.Olds.Key {
 		result := CreateResult[KvRoutesUpdateOutputs]{}
-		
+
 		// First, delete the old entry if it exists
 		deleteInput := DeleteInput[KvRoutesUpdateOutputs]{
 			ID: input.ID,
@@ -114,63 +72,35 @@ func (r *KvRoutesUpdate) Update(input *UpdateInput[KvRoutesUpdateInputs, KvRoute
 		if err := r.Delete(&deleteInput, &dummy); err != nil {
 			return err
 		}
-		
+
 		// Then create the new entry
 		if err := r.Create(&input.News, &result); err != nil {
 			return err
 		}
-		
+
 		*output = UpdateResult[KvRoutesUpdateOutputs]{
 			Outs: result.Outs,
 		}
 		return nil
 	}
-	
-	// get client
-	cfg, err := r.config()
-	if err != nil {
-		return err
-	}
-	client := cloudfrontkeyvaluestore.NewFromConfig(cfg)
 
-	// get etag
-	etag, err := r.getEtag(client, input.News.Store)
+	store, err := r.store(input.News.Store)
 	if err != nil {
 		return err
 	}
 
-	// get routes
-	fullKey := input.News.Namespace + ":" + input.News.Key
-	existingEntries, chunkNum, err := r.getRoutes(client, input.News.Store, fullKey)
-	if err != nil {
-		// check etag to see if this happened b/c routes were updated in the meantime
-		newEtag, err := r.getEtag(client, input.News.Store)
-		if err == nil && newEtag != etag {
-			// sleep for a random time between 100 and 500ms
-			time.Sleep(time.Duration(rand.Intn(400)+100) * time.Millisecond)
-			return r.Update(input, output)
-		}
-		return err
-	}
-	
 	// Remove the old entry and add new
-	entries := removeRoute(existingEntries, input.Olds.Entry)
-	if !existsRoute(entries, input.News.Entry) {
-		entries = append(entries, input.News.Entry)
-	}
-	
-	// Save routes
-	err = r.setRoutes(client, input.News.Store, etag, fullKey, entries, chunkNum)
-	if err != nil {
-		var preconditionFailedErr *types.ValidationException
-		if errors.As(err, &preconditionFailedErr) && preconditionFailedErr.Message != nil && strings.Contains(*preconditionFailedErr.Message, "Pre-Condition failed") {
-			// sleep for a random time between 100 and 500ms
-			time.Sleep(time.Duration(rand.Intn(400)+100) * time.Millisecond)
-			return r.Update(input, output)
+	err = r.modify(store, input.News.Namespace+":"+input.News.Key, func(routes []string) []string {
+		routes = removeRoute(routes, input.Olds.Entry)
+		if !existsRoute(routes, input.News.Entry) {
+			routes = append(routes, input.News.Entry)
 		}
+		return routes
+	})
+	if err != nil {
 		return err
 	}
-	
+
 	*output = UpdateResult[KvRoutesUpdateOutputs]{
 		Outs: KvRoutesUpdateOutputs{
 			Store:     input.News.Store,
@@ -183,239 +113,108 @@ func (r *KvRoutesUpdate) Update(input *UpdateInput[KvRoutesUpdateInputs, KvRoute
 }
 
 func (r *KvRoutesUpdate) Delete(input *DeleteInput[KvRoutesUpdateOutputs], output *int) error {
-	// get client
-	cfg, err := r.config()
-	if err != nil {
-		return err
-	}
-	client := cloudfrontkeyvaluestore.NewFromConfig(cfg)
-
-	// get etag
-	etag, err := r.getEtag(client, input.Outs.Store)
-	if err != nil {
-		return err
-	}
-
-	// get routes
-	fullKey := input.Outs.Namespace + ":" + input.Outs.Key
-	existingRoutes, chunkNum, err := r.getRoutes(client, input.Outs.Store, fullKey)
+	store, err := r.store(input.Outs.Store)
 	if err != nil {
-		// check etag to see if this happened b/c routes were updated in the meantime
-		newEtag, err := r.getEtag(client, input.Outs.Store)
-		if err == nil && newEtag != etag {
-			// sleep for a random time between 100 and 500ms
-			time.Sleep(time.Duration(rand.Intn(400)+100) * time.Millisecond)
-			return r.Delete(input, output)
-		}
 		return err
 	}
 
-	// Remove route
-	routes := removeRoute(existingRoutes, input.Outs.Entry)
-
-	// Route not found - DO NOT do optimizations like this b/c existingRoutes might not
-	// contain the latest data. Etag might have changed from when etag was first retrieved.
-	//if len(routes) == len(existingRoutes) {
-	//	return nil
-	//}
-
-	if len(routes) == 0 {
-		err = r.deleteKey(client, input.Outs.Store, etag, fullKey, chunkNum)
-	} else {
-		err = r.setRoutes(client, input.Outs.Store, etag, fullKey, routes, chunkNum)
-	}
-	if err != nil {
-		var preconditionFailedErr *types.ValidationException
-		if errors.As(err, &preconditionFailedErr) && preconditionFailedErr.Message != nil && strings.Contains(*preconditionFailedErr.Message, "Pre-Condition failed") {
-			// sleep for a random time between 100 and 500ms
-			time.Sleep(time.Duration(rand.Intn(400)+100) * time.Millisecond)
-			return r.Delete(input, output)
-		}
-		return err
-	}
+	// Always write even if the route is already gone, the routes that were
+	// read might be older than the ones the write is checked against
+	return r.modify(store, input.Outs.Namespace+":"+input.Outs.Key, func(routes []string) []string {
+		return removeRoute(routes, input.Outs.Entry)
+	})
+}
 
+func (r *KvRoutesUpdate) Check(input *CheckInput[KvRoutesUpdateInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("store", input.News.Store)
+	checks.require("key", input.News.Key)
+	checks.require("entry", input.News.Entry)
+	*output = checks.result()
 	return nil
 }
 
-func (r *KvRoutesUpdate) getEtag(client *cloudfrontkeyvaluestore.Client, storeARN string) (string, error) {
-	descResp, err := client.DescribeKeyValueStore(r.context, &cloudfrontkeyvaluestore.DescribeKeyValueStoreInput{
-		KvsARN: aws.String(storeARN),
-	})
-	if err != nil {
-		return "", err
-	}
-	return *descResp.ETag, nil
+func (r *KvRoutesUpdate) Diff(input *DiffInput[KvRoutesUpdateInputs, KvRoutesUpdateOutputs], output *DiffResult) error {
+	var changed diffBuilder
+	changed.compare("store", input.Olds.Store, input.News.Store)
+	changed.compare("namespace", input.Olds.Namespace, input.News.Namespace)
+	changed.compare("key", input.Olds.Key, input.News.Key)
+	changed.compare("entry", input.Olds.Entry, input.News.Entry)
+	// the ID is made up of the store, namespace, and key
+	*output = newDiff(changed, "store", "namespace", "key")
+	return nil
 }
 
-func (r *KvRoutesUpdate) getRoutes(client *cloudfrontkeyvaluestore.Client, storeARN, key string) ([]string, int, error) {
-	chunkNum := 1
-
-	getResp, err := client.GetKey(r.context, &cloudfrontkeyvaluestore.GetKeyInput{
-		KvsARN: aws.String(storeARN),
-		Key:    aws.String(key),
-	})
-	
-	if err != nil {
-		var notFoundErr *types.ResourceNotFoundException
-		if errors.As(err, &notFoundErr) {
-			// Key not found, return empty entries
-			return []string{}, chunkNum, nil
-		}
-		// other error, propagate
-		return nil, chunkNum, err
+// Read clears the entry if it was removed from the routes outside of a
+// deploy, so the next one adds it again
+func (r *KvRoutesUpdate) Read(input *ReadInput[KvRoutesUpdateOutputs], output *ReadResult[KvRoutesUpdateOutputs]) error {
+	props := input.Props
+	if props.Store == "" {
+		*output = ReadResult[KvRoutesUpdateOutputs]{ID: input.ID, Props: props}
+		return nil
 	}
-	
-	if getResp.Value == nil {
-		// no value
-		return []string{}, chunkNum, nil
+	store, err := r.store(props.Store)
+	if err != nil {
+		return err
 	}
-	
-	entriesData := *getResp.Value
-
-	// Check if the data is chunked by trying to parse a metadata object first
-	var metadata map[string]int
-	if err := json.Unmarshal([]byte(*getResp.Value), &metadata); err == nil {
-		if parts, ok := metadata["parts"]; ok && parts > 0 {
-			chunkNum = parts
-
-			// This is chunked data, we need to retrieve and concatenate all chunks
-			entriesData = ""
-			
-			// Retrieve all chunks
-			for i := 0; i < parts; i++ {
-				chunkResp, err := client.GetKey(r.context, &cloudfrontkeyvaluestore.GetKeyInput{
-					KvsARN: aws.String(storeARN),
-					Key:    aws.String(fmt.Sprintf("%s:%d", key, i)),
-				})
-				
-				if err != nil {
-					return nil, chunkNum, fmt.Errorf("failed to retrieve chunk %d: %w", i, err)
-				}
-				
-				if chunkResp.Value == nil {
-					return nil, chunkNum, fmt.Errorf("chunk %d value is missing", i)
-				}
-				
-				entriesData += *chunkResp.Value
-			}
-		}
+	routes, _, err := r.getRoutes(store, props.Namespace+":"+props.Key)
+	if err != nil {
+		return err
 	}
-	
-	// Parse routes array
-	var entries []string
-	if err := json.Unmarshal([]byte(entriesData), &entries); err != nil {
-		return nil, chunkNum, fmt.Errorf("failed to unmarshal existing entries: %w", err)
+	if !existsRoute(routes, props.Entry) {
+		props.Entry = ""
 	}
-	
-	return entries, chunkNum, nil
+	*output = ReadResult[KvRoutesUpdateOutputs]{ID: input.ID, Props: props}
+	return nil
 }
 
-func (r *KvRoutesUpdate) setRoutes(client *cloudfrontkeyvaluestore.Client, storeARN string, etag string, key string, entries []string, oldChunkNum int) error {
-	newChunkNum := 1
-	puts := []types.PutKeyRequestListItem{}
-	deletes := []types.DeleteKeyRequestListItem{}
-
-	// Build new entries
-	entriesJSON, err := json.Marshal(entries)
+func (r *KvRoutesUpdate) store(arn string) (*kvStore, error) {
+	cfg, err := r.config()
 	if err != nil {
-		return fmt.Errorf("failed to marshal entries: %w", err)
+		return nil, err
 	}
-	entriesStr := string(entriesJSON)
+	return newKvStore(r.context, r.clients.KeyValueStore(cfg), arn), nil
+}
 
-	// Check if the string is longer than chunkSize
-	if len(entriesStr) > chunkSize {
-		// Calculate number of chunks needed
-		newChunkNum= (len(entriesStr) + chunkSize - 1) / chunkSize // Ceiling division
-		
-		// Create a metadata entry to store the number of parts
-		metadataMap := map[string]int{"parts": newChunkNum}
-		metadataJSON, err := json.Marshal(metadataMap)
+// Reads the routes, changes them and writes them back. The write only goes
+// through if no one else changed the store since the read, otherwise it
+// starts over with backoff.
+func (r *KvRoutesUpdate) modify(store *kvStore, key string, change func([]string) []string) error {
+	return store.retry(func() error {
+		etag, err := store.etag()
 		if err != nil {
-			return fmt.Errorf("failed to marshal metadata: %w", err)
+			return err
 		}
-		
-		// Create multiple puts for each chunk
-		puts = append(puts, types.PutKeyRequestListItem{
-			Key:   aws.String(key),
-			Value: aws.String(string(metadataJSON)),
-		})
-		
-		// Split the string into chunks
-		for i := 0; i < newChunkNum; i++ {
-			start := i * chunkSize
-			end := start + chunkSize
-			if end > len(entriesStr) {
-				end = len(entriesStr)
-			}
-			
-			puts = append(puts, types.PutKeyRequestListItem{
-				Key:   aws.String(fmt.Sprintf("%s:%d", key, i)),
-				Value: aws.String(entriesStr[start:end]),
-			})
+		routes, parts, err := r.getRoutes(store, key)
+		if err != nil {
+			return err
 		}
-	} else {
-		// For smaller strings, use the original approach
-		puts = append(puts, types.PutKeyRequestListItem{
-			Key:   aws.String(key),
-			Value: aws.String(entriesStr),
-		})
-	}
-
-	// Delete extra chunks if previous chunk # is greater than new chunk #
-	if oldChunkNum > newChunkNum {
-		for i := newChunkNum; i < oldChunkNum; i++ {
-			deletes = append(deletes, types.DeleteKeyRequestListItem{
-				Key: aws.String(fmt.Sprintf("%s:%d", key, i)),
-			})
+		routes = change(routes)
+		if len(routes) == 0 {
+			return store.writeFrom(etag, kvDelete(key, parts))
 		}
-
-		if newChunkNum == 1 {
-			deletes = append(deletes, types.DeleteKeyRequestListItem{
-				Key: aws.String(fmt.Sprintf("%s:0", key)),
-			})
+		value, err := json.Marshal(routes)
+		if err != nil {
+			return fmt.Errorf("failed to marshal entries: %w", err)
 		}
-	}
-
-	// update
-	_, err = client.UpdateKeys(r.context, &cloudfrontkeyvaluestore.UpdateKeysInput{
-		KvsARN:  aws.String(storeARN),
-		IfMatch: aws.String(etag),
-		Puts:    puts,
-		Deletes: deletes,
+		return store.writeFrom(etag, kvPut(key, string(value), parts))
 	})
-	if err != nil {
-		return err
-	}
-
-	return nil
 }
 
-func (r *KvRoutesUpdate) deleteKey(client *cloudfrontkeyvaluestore.Client, storeARN, etag string, key string, oldChunkNum int) error {
-	deletes := []types.DeleteKeyRequestListItem{}
-	deletes = append(deletes, types.DeleteKeyRequestListItem{
-		Key: aws.String(key),
-	})
-	
-	// Add all chunk keys to delete
-	if oldChunkNum > 1 {
-		for i := 0; i < oldChunkNum; i++ {
-			deletes = append(deletes, types.DeleteKeyRequestListItem{
-				Key: aws.String(fmt.Sprintf("%s:%d", key, i)),
-			})
-		}
-	}
-	
-	// Not chunked or key not found, proceed with normal delete
-	_, err := client.UpdateKeys(r.context, &cloudfrontkeyvaluestore.UpdateKeysInput{
-		KvsARN:  aws.String(storeARN),
-		IfMatch: aws.String(etag),
-		Deletes: deletes,
-	})
+// Returns the routes under the key and how many parts they are stored in
+func (r *KvRoutesUpdate) getRoutes(store *kvStore, key string) ([]string, int, error) {
+	value, parts, ok, err := store.get(key)
 	if err != nil {
-		return err
+		return nil, parts, err
 	}
-
-	return nil
+	if !ok {
+		return []string{}, 0, nil
+	}
+	var entries []string
+	if err := json.Unmarshal([]byte(value), &entries); err != nil {
+		return nil, parts, fmt.Errorf("failed to unmarshal existing entries: %w", err)
+	}
+	return entries, parts, nil
 }
 
 func existsRoute(entries []string, entry string) bool {
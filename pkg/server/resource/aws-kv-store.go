@@ -0,0 +1,360 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore/types"
+)
+
+const (
+	// The most keys a single UpdateKeys call can change
+	KV_BATCH_KEYS = 50
+	// The most bytes of keys and values a single UpdateKeys call can send
+	KV_BATCH_BYTES = 3 * 1024 * 1024
+	// Values over this are split into parts, the store allows 1 KB
+	KV_VALUE_LIMIT = 1000
+	// How many times a write is retried when another deploy changed the store
+	// in the meantime
+	KV_ATTEMPTS = 8
+)
+
+// A change to a single key, a nil value deletes it
+type kvOp struct {
+	Key   string
+	Value *string
+}
+
+// Reads and writes a CloudFront KeyValueStore. Values over KV_VALUE_LIMIT are
+// stored as {"parts":N} under the key with the value split across key:0 to
+// key:N-1, the same way the router function reads them.
+type kvStore struct {
+	context context.Context
+	client  KeyValueStoreClient
+	arn     string
+}
+
+func newKvStore(ctx context.Context, client KeyValueStoreClient, arn string) *kvStore {
+	return &kvStore{ctx, client, arn}
+}
+
+func (s *kvStore) etag() (*string, error) {
+	result, err := s.client.DescribeKeyValueStore(s.context, &cloudfrontkeyvaluestore.DescribeKeyValueStoreInput{
+		KvsARN: aws.String(s.arn),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.ETag, nil
+}
+
+// Returns the value of a key with its parts put back together, and how many
+// parts it is stored in
+func (s *kvStore) get(key string) (string, int, bool, error) {
+	value, ok, err := s.getRaw(key)
+	if err != nil || !ok {
+		return "", 0, ok, err
+	}
+	parts := kvParts(value)
+	if parts == 0 {
+		return value, 0, true, nil
+	}
+	var b strings.Builder
+	for i := 0; i < parts; i++ {
+		part, ok, err := s.getRaw(kvPartKey(key, i))
+		if err != nil {
+			return "", parts, false, err
+		}
+		if !ok {
+			return "", parts, false, fmt.Errorf("part %d of %s is missing: %w", i, key, errKvChanged)
+		}
+		b.WriteString(part)
+	}
+	return b.String(), parts, true, nil
+}
+
+func (s *kvStore) getRaw(key string) (string, bool, error) {
+	result, err := s.client.GetKey(s.context, &cloudfrontkeyvaluestore.GetKeyInput{
+		KvsARN: aws.String(s.arn),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	if result.Value == nil {
+		return "", false, nil
+	}
+	return *result.Value, true, nil
+}
+
+// Returns every key that starts with the prefix as it is stored, parts and
+// all
+func (s *kvStore) list(prefix string) (map[string]string, error) {
+	result := map[string]string{}
+	var nextToken *string
+	for {
+		page, err := s.client.ListKeys(s.context, &cloudfrontkeyvaluestore.ListKeysInput{
+			KvsARN:    aws.String(s.arn),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			if strings.HasPrefix(aws.ToString(item.Key), prefix) {
+				result[aws.ToString(item.Key)] = aws.ToString(item.Value)
+			}
+		}
+		if page.NextToken == nil {
+			return result, nil
+		}
+		nextToken = page.NextToken
+	}
+}
+
+// Applies the changes in order, in as few UpdateKeys calls as the limits
+// allow. Each call is retried with a new ETag when it conflicts.
+func (s *kvStore) write(ops []kvOp) error {
+	return s.writeFrom(nil, ops)
+}
+
+// Applies the changes like write, but the first batch only goes through if
+// the store still has the ETag the changes were worked out from. A conflict
+// on it is returned so the caller can read the store again.
+func (s *kvStore) writeFrom(etag *string, ops []kvOp) error {
+	for i, batch := range kvBatches(ops) {
+		if i == 0 && etag != nil {
+			next, err := s.update(etag, batch)
+			if err != nil {
+				return err
+			}
+			etag = next
+			continue
+		}
+		err := s.retry(func() error {
+			if etag == nil {
+				next, err := s.etag()
+				if err != nil {
+					return err
+				}
+				etag = next
+			}
+			next, err := s.update(etag, batch)
+			if err != nil {
+				etag = nil
+				return err
+			}
+			etag = next
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sends a single batch that only goes through if the store still has the
+// ETag, returns the new one
+func (s *kvStore) update(etag *string, ops []kvOp) (*string, error) {
+	input := &cloudfrontkeyvaluestore.UpdateKeysInput{
+		KvsARN:  aws.String(s.arn),
+		IfMatch: etag,
+	}
+	for _, op := range ops {
+		if op.Value == nil {
+			input.Deletes = append(input.Deletes, types.DeleteKeyRequestListItem{Key: aws.String(op.Key)})
+			continue
+		}
+		input.Puts = append(input.Puts, types.PutKeyRequestListItem{Key: aws.String(op.Key), Value: op.Value})
+	}
+	result, err := s.client.UpdateKeys(s.context, input)
+	if err != nil {
+		return nil, err
+	}
+	return result.ETag, nil
+}
+
+// Runs fn again with exponential backoff for as long as it fails because
+// the store changed underneath it
+func (s *kvStore) retry(fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !isKvConflict(err) {
+			return err
+		}
+		if attempt == KV_ATTEMPTS-1 {
+			// not wrapped so an outer retry doesn't start over
+			return fmt.Errorf("the key value store kept changing during the deploy, gave up after %d attempts: %v", KV_ATTEMPTS, err)
+		}
+		select {
+		case <-s.context.Done():
+			return s.context.Err()
+		case <-time.After(backoff(attempt)):
+		}
+	}
+}
+
+// Returned when a read saw the store part way through someone else's write
+var errKvChanged = errors.New("the key value store changed while it was being read")
+
+func isKvConflict(err error) bool {
+	if errors.Is(err, errKvChanged) {
+		return true
+	}
+	var conflict *types.ConflictException
+	if errors.As(err, &conflict) {
+		return true
+	}
+	var validation *types.ValidationException
+	return errors.As(err, &validation) &&
+		validation.Message != nil &&
+		strings.Contains(*validation.Message, "Pre-Condition failed")
+}
+
+// The changes that store a value, splitting it into parts when it's too
+// large. Parts are written before the key that points to them and the ones
+// that are no longer used are removed after.
+func kvPut(key string, value string, oldParts int) []kvOp {
+	ops := []kvOp{}
+	chunks := splitKvValue(value)
+	parts := 0
+	if len(chunks) > 1 {
+		parts = len(chunks)
+		for i, chunk := range chunks {
+			ops = append(ops, kvOp{Key: kvPartKey(key, i), Value: aws.String(chunk)})
+		}
+		ops = append(ops, kvOp{Key: key, Value: aws.String(fmt.Sprintf(`{"parts":%d}`, parts))})
+	} else {
+		ops = append(ops, kvOp{Key: key, Value: aws.String(value)})
+	}
+	for i := parts; i < oldParts; i++ {
+		ops = append(ops, kvOp{Key: kvPartKey(key, i)})
+	}
+	return ops
+}
+
+// The changes that remove a key and its parts
+func kvDelete(key string, parts int) []kvOp {
+	ops := []kvOp{{Key: key}}
+	for i := 0; i < parts; i++ {
+		ops = append(ops, kvOp{Key: kvPartKey(key, i)})
+	}
+	return ops
+}
+
+// The keys a value is stored under
+func kvKeys(key string, value string) []string {
+	keys := []string{key}
+	if chunks := splitKvValue(value); len(chunks) > 1 {
+		for i := range chunks {
+			keys = append(keys, kvPartKey(key, i))
+		}
+	}
+	return keys
+}
+
+// The number of parts a value is split into when it's stored, 0 if it fits
+// in a single key
+func kvPartCount(value string) int {
+	if chunks := splitKvValue(value); len(chunks) > 1 {
+		return len(chunks)
+	}
+	return 0
+}
+
+func kvPartKey(key string, part int) string {
+	return fmt.Sprintf("%s:%d", key, part)
+}
+
+// The number of parts a stored value points to, 0 if it's not split
+func kvParts(value string) int {
+	if !strings.HasPrefix(value, `{"parts":`) {
+		return 0
+	}
+	var header struct {
+		Parts int `json:"parts"`
+	}
+	if err := json.Unmarshal([]byte(value), &header); err != nil {
+		return 0
+	}
+	return header.Parts
+}
+
+// Puts a split value back together from the raw keys of a store
+func kvAssemble(raw map[string]string, key string) (string, bool) {
+	value, ok := raw[key]
+	if !ok {
+		return "", false
+	}
+	parts := kvParts(value)
+	if parts == 0 {
+		return value, true
+	}
+	var b strings.Builder
+	for i := 0; i < parts; i++ {
+		part, ok := raw[kvPartKey(key, i)]
+		if !ok {
+			return "", false
+		}
+		b.WriteString(part)
+	}
+	return b.String(), true
+}
+
+// Splits a value into chunks of at most KV_VALUE_LIMIT bytes without
+// breaking up a UTF-8 character
+func splitKvValue(value string) []string {
+	if len(value) <= KV_VALUE_LIMIT {
+		return []string{value}
+	}
+	chunks := []string{}
+	for len(value) > 0 {
+		end := min(KV_VALUE_LIMIT, len(value))
+		for end < len(value) && end > 0 && !utf8.RuneStart(value[end]) {
+			end--
+		}
+		chunks = append(chunks, value[:end])
+		value = value[end:]
+	}
+	return chunks
+}
+
+// Groups the changes into batches that fit in a single UpdateKeys call. A
+// key is never changed twice in the same batch.
+func kvBatches(ops []kvOp) [][]kvOp {
+	batches := [][]kvOp{}
+	batch := []kvOp{}
+	size := 0
+	keys := map[string]bool{}
+	for _, op := range ops {
+		opSize := len(op.Key)
+		if op.Value != nil {
+			opSize += len(*op.Value)
+		}
+		if len(batch) == KV_BATCH_KEYS || size+opSize > KV_BATCH_BYTES || keys[op.Key] {
+			batches = append(batches, batch)
+			batch = []kvOp{}
+			size = 0
+			keys = map[string]bool{}
+		}
+		batch = append(batch, op)
+		size += opSize
+		keys[op.Key] = true
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
@@ -0,0 +1,131 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore/types"
+)
+
+func TestSplitKvValue(t *testing.T) {
+	if chunks := splitKvValue("small"); len(chunks) != 1 || chunks[0] != "small" {
+		t.Fatalf("expected a small value to stay whole, got %v", chunks)
+	}
+
+	// the multibyte characters straddle the limit
+	value := strings.Repeat("a", KV_VALUE_LIMIT-1) + strings.Repeat("é", KV_VALUE_LIMIT)
+	chunks := splitKvValue(value)
+	if strings.Join(chunks, "") != value {
+		t.Fatal("expected the chunks to join back into the value")
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > KV_VALUE_LIMIT {
+			t.Fatalf("chunk %d is %d bytes", i, len(chunk))
+		}
+		if !utf8.ValidString(chunk) {
+			t.Fatalf("chunk %d splits a character", i)
+		}
+	}
+}
+
+func TestKvPut(t *testing.T) {
+	value := strings.Repeat("x", KV_VALUE_LIMIT*2+1)
+	ops := kvPut("ns:key", value, 5)
+	raw := map[string]string{}
+	deleted := []string{}
+	for i, op := range ops {
+		if op.Value == nil {
+			deleted = append(deleted, op.Key)
+			continue
+		}
+		if op.Key == "ns:key" && i != 3 {
+			t.Fatalf("expected the header to be written after the parts, got it at %d", i)
+		}
+		raw[op.Key] = *op.Value
+	}
+	if raw["ns:key"] != `{"parts":3}` {
+		t.Fatalf("expected a header for 3 parts, got %s", raw["ns:key"])
+	}
+	if assembled, ok := kvAssemble(raw, "ns:key"); !ok || assembled != value {
+		t.Fatal("expected the parts to assemble back into the value")
+	}
+	if strings.Join(deleted, ",") != "ns:key:3,ns:key:4" {
+		t.Fatalf("expected the unused parts to be deleted, got %v", deleted)
+	}
+
+	ops = kvPut("ns:key", "small", 2)
+	if len(ops) != 3 || *ops[0].Value != "small" || ops[1].Value != nil || ops[2].Value != nil {
+		t.Fatalf("expected a single put and the old parts deleted, got %v", ops)
+	}
+}
+
+func TestKvKeysChanges(t *testing.T) {
+	large := strings.Repeat("x", KV_VALUE_LIMIT+1)
+	ops := kvKeysChanges("site", map[string]string{
+		"same":    "1",
+		"changed": "2",
+		"large":   "small now",
+	}, map[string]string{
+		"same":    "1",
+		"changed": "1",
+		"large":   large,
+	})
+	keys := []string{}
+	for _, op := range ops {
+		keys = append(keys, op.Key)
+	}
+	expected := "site:changed,site:large,site:large:0,site:large:1"
+	if strings.Join(keys, ",") != expected {
+		t.Fatalf("expected %s, got %s", expected, strings.Join(keys, ","))
+	}
+}
+
+func TestKvBatches(t *testing.T) {
+	ops := []kvOp{}
+	for i := 0; i < KV_BATCH_KEYS+1; i++ {
+		ops = append(ops, kvOp{Key: fmt.Sprintf("key%d", i), Value: aws.String("v")})
+	}
+	if batches := kvBatches(ops); len(batches) != 2 || len(batches[0]) != KV_BATCH_KEYS {
+		t.Fatalf("expected the keys to be split at %d", KV_BATCH_KEYS)
+	}
+
+	// the same key is never changed twice in one call
+	ops = []kvOp{{Key: "a", Value: aws.String("1")}, {Key: "a"}}
+	if batches := kvBatches(ops); len(batches) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(batches))
+	}
+
+	large := strings.Repeat("x", KV_VALUE_LIMIT)
+	ops = []kvOp{}
+	for i := 0; i < KV_BATCH_BYTES/KV_VALUE_LIMIT; i++ {
+		ops = append(ops, kvOp{Key: fmt.Sprintf("key%d", i), Value: aws.String(large)})
+	}
+	for _, batch := range kvBatches(ops) {
+		size := 0
+		for _, op := range batch {
+			size += len(op.Key) + len(*op.Value)
+		}
+		if size > KV_BATCH_BYTES {
+			t.Fatalf("batch is %d bytes", size)
+		}
+	}
+}
+
+func TestIsKvConflict(t *testing.T) {
+	if !isKvConflict(&types.ConflictException{}) {
+		t.Fatal("expected a conflict to be retried")
+	}
+	if !isKvConflict(&types.ValidationException{Message: aws.String("Pre-Condition failed")}) {
+		t.Fatal("expected a failed precondition to be retried")
+	}
+	if !isKvConflict(fmt.Errorf("part 1 of key is missing: %w", errKvChanged)) {
+		t.Fatal("expected a torn read to be retried")
+	}
+	if isKvConflict(errors.New("access denied")) {
+		t.Fatal("expected other errors to fail")
+	}
+}
@@ -19,14 +19,15 @@ type OriginAccessControlInputs struct {
 }
 
 type OriginAccessControlOutputs struct {
+	Name string `json:"name,omitempty"`
 }
 
-func (r *OriginAccessControl) Read(input *DeleteInput[OriginAccessControlOutputs], output *ReadResult[OriginAccessControlOutputs]) error {
+func (r *OriginAccessControl) Read(input *ReadInput[OriginAccessControlOutputs], output *ReadResult[OriginAccessControlOutputs]) error {
 	cfg, err := r.config()
 	if err != nil {
 		return err
 	}
-	cf := cloudfront.NewFromConfig(cfg)
+	cf := r.clients.CloudFront(cfg)
 	
 	resp, err := cf.GetOriginAccessControl(r.context, &cloudfront.GetOriginAccessControlInput{
 		Id: aws.String(input.ID),
@@ -41,17 +42,38 @@ func (r *OriginAccessControl) Read(input *DeleteInput[OriginAccessControlOutputs
 	}
 
 	*output = ReadResult[OriginAccessControlOutputs]{
-		ID: *resp.OriginAccessControl.Id,
-		Outs: OriginAccessControlOutputs{},
+		ID:    *resp.OriginAccessControl.Id,
+		Props: input.Props,
 	}
 	return nil
 }
+
+func (r *OriginAccessControl) Check(input *CheckInput[OriginAccessControlInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("name", input.News.Name)
+	*output = checks.result()
+	return nil
+}
+
+// The name is only used to generate the name of the origin access control
+// when it's created, so a new name replaces it
+func (r *OriginAccessControl) Diff(input *DiffInput[OriginAccessControlInputs, OriginAccessControlOutputs], output *DiffResult) error {
+	if input.Olds.Name == "" {
+		*output = DiffResult{}
+		return nil
+	}
+	var changed diffBuilder
+	changed.compare("name", input.Olds.Name, input.News.Name)
+	*output = newDiff(changed, "name")
+	return nil
+}
+
 func (r *OriginAccessControl) Create(input *OriginAccessControlInputs, output *CreateResult[OriginAccessControlOutputs]) error {
 	cfg, err := r.config()
 	if err != nil {
 		return err
 	}
-	cf := cloudfront.NewFromConfig(cfg)
+	cf := r.clients.CloudFront(cfg)
 	slog.Info("creating origin access control")
 	resp, err := cf.CreateOriginAccessControl(r.context, &cloudfront.CreateOriginAccessControlInput{
 		OriginAccessControlConfig: &types.OriginAccessControlConfig{
@@ -67,7 +89,7 @@ func (r *OriginAccessControl) Create(input *OriginAccessControlInputs, output *C
 	}
 	*output = CreateResult[OriginAccessControlOutputs]{
 		ID:   *resp.OriginAccessControl.Id,
-		Outs: OriginAccessControlOutputs{},
+		Outs: OriginAccessControlOutputs{Name: input.Name},
 	}
 	return nil
 }
@@ -77,7 +99,7 @@ func (r *OriginAccessControl) Delete(input *DeleteInput[OriginAccessControlOutpu
 	if err != nil {
 		return err
 	}
-	cf := cloudfront.NewFromConfig(cfg)
+	cf := r.clients.CloudFront(cfg)
 	resp, err := cf.GetOriginAccessControl(r.context, &cloudfront.GetOriginAccessControlInput{
 		Id: aws.String(input.ID),
 	})
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"errors"
 	"log/slog"
 	"time"
 
@@ -24,7 +25,7 @@ func (r *OriginAccessIdentity) Create(input *OriginAccessIdentityInputs, output
 	if err != nil {
 		return err
 	}
-	cf := cloudfront.NewFromConfig(cfg)
+	cf := r.clients.CloudFront(cfg)
 	slog.Info("creating origin access identity")
 	resp, err := cf.CreateCloudFrontOriginAccessIdentity(r.context, &cloudfront.CreateCloudFrontOriginAccessIdentityInput{
 		CloudFrontOriginAccessIdentityConfig: &types.CloudFrontOriginAccessIdentityConfig{
@@ -42,12 +43,39 @@ func (r *OriginAccessIdentity) Create(input *OriginAccessIdentityInputs, output
 	return nil
 }
 
+// There are no inputs, so there is never anything to change
+func (r *OriginAccessIdentity) Diff(input *DiffInput[OriginAccessIdentityInputs, OriginAccessIdentityOutputs], output *DiffResult) error {
+	*output = newDiff(nil)
+	return nil
+}
+
+func (r *OriginAccessIdentity) Read(input *ReadInput[OriginAccessIdentityOutputs], output *ReadResult[OriginAccessIdentityOutputs]) error {
+	cfg, err := r.config()
+	if err != nil {
+		return err
+	}
+	cf := r.clients.CloudFront(cfg)
+	_, err = cf.GetCloudFrontOriginAccessIdentity(r.context, &cloudfront.GetCloudFrontOriginAccessIdentityInput{
+		Id: aws.String(input.ID),
+	})
+	if err != nil {
+		var notFound *types.NoSuchCloudFrontOriginAccessIdentity
+		if errors.As(err, &notFound) {
+			*output = ReadResult[OriginAccessIdentityOutputs]{}
+			return nil
+		}
+		return err
+	}
+	*output = ReadResult[OriginAccessIdentityOutputs]{ID: input.ID, Props: input.Props}
+	return nil
+}
+
 func (r *OriginAccessIdentity) Delete(input *DeleteInput[OriginAccessIdentityOutputs], output *int) error {
 	cfg, err := r.config()
 	if err != nil {
 		return err
 	}
-	cf := cloudfront.NewFromConfig(cfg)
+	cf := r.clients.CloudFront(cfg)
 	resp, err := cf.GetCloudFrontOriginAccessIdentity(r.context, &cloudfront.GetCloudFrontOriginAccessIdentityInput{
 		Id: aws.String(input.ID),
 	})
@@ -19,6 +19,7 @@ type RdsRoleLookupInputs struct {
 }
 
 type RdsRoleLookupOutputs struct {
+	Name string `json:"name,omitempty"`
 }
 
 func (r *RdsRoleLookup) Create(input *RdsRoleLookupInputs, output *CreateResult[RdsRoleLookupOutputs]) error {
@@ -28,7 +29,7 @@ func (r *RdsRoleLookup) Create(input *RdsRoleLookupInputs, output *CreateResult[
 	}
 	*output = CreateResult[RdsRoleLookupOutputs]{
 		ID:   "lookup",
-		Outs: RdsRoleLookupOutputs{},
+		Outs: RdsRoleLookupOutputs{Name: input.Name},
 	}
 	return nil
 }
@@ -40,17 +41,62 @@ func (r *RdsRoleLookup) Update(input *UpdateInput[RdsRoleLookupInputs, RdsRoleLo
 	}
 
 	*output = UpdateResult[RdsRoleLookupOutputs]{
-		Outs: RdsRoleLookupOutputs{},
+		Outs: RdsRoleLookupOutputs{Name: input.News.Name},
 	}
 	return nil
 }
 
+func (r *RdsRoleLookup) Check(input *CheckInput[RdsRoleLookupInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("name", input.News.Name)
+	*output = checks.result()
+	return nil
+}
+
+func (r *RdsRoleLookup) Diff(input *DiffInput[RdsRoleLookupInputs, RdsRoleLookupOutputs], output *DiffResult) error {
+	if input.Olds.Name == "" {
+		*output = DiffResult{}
+		return nil
+	}
+	var changed diffBuilder
+	changed.compare("name", input.Olds.Name, input.News.Name)
+	*output = newDiff(changed)
+	return nil
+}
+
+// Read removes the lookup if the role is gone, so the next deploy waits for
+// it again
+func (r *RdsRoleLookup) Read(input *ReadInput[RdsRoleLookupOutputs], output *ReadResult[RdsRoleLookupOutputs]) error {
+	if input.Props.Name == "" {
+		*output = ReadResult[RdsRoleLookupOutputs]{ID: input.ID, Props: input.Props}
+		return nil
+	}
+	cfg, err := r.config()
+	if err != nil {
+		return err
+	}
+	client := r.clients.Iam(cfg)
+	_, err = client.GetRole(r.context, &iam.GetRoleInput{
+		RoleName: aws.String(input.Props.Name),
+	})
+	if err != nil {
+		var noSuchEntityErr *types.NoSuchEntityException
+		if errors.As(err, &noSuchEntityErr) {
+			*output = ReadResult[RdsRoleLookupOutputs]{}
+			return nil
+		}
+		return err
+	}
+	*output = ReadResult[RdsRoleLookupOutputs]{ID: input.ID, Props: input.Props}
+	return nil
+}
+
 func (r *RdsRoleLookup) handle(input *RdsRoleLookupInputs) (error) {
 	cfg, err := r.config()
 	if err != nil {
 		return err
 	}
-	client := iam.NewFromConfig(cfg)
+	client := r.clients.Iam(cfg)
 
 	start := time.Now()
 	timeout := 5 * time.Minute
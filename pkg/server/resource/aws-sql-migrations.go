@@ -0,0 +1,466 @@
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/sst/sst/v3/pkg/bus"
+)
+
+const SQL_MIGRATIONS_DEFAULT_TABLE = "sst_migrations"
+
+// Applies the .sql files in a directory, in the order of their names, to a
+// Postgres database through the Data API. Each file runs in its own
+// transaction and is recorded in a table so it only ever runs once.
+type SqlMigrations struct {
+	*AwsResource
+}
+
+type SqlMigrationsInputs struct {
+	ClusterArn   string `json:"clusterArn"`
+	SecretArn    string `json:"secretArn"`
+	DatabaseName string `json:"databaseName"`
+	// Relative to the root of the app
+	Directory string `json:"directory"`
+	// The table the applied migrations are recorded in
+	Table string `json:"table,omitempty"`
+	// Only report the migrations that would be applied
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+type SqlMigrationsOutputs struct {
+	ClusterArn   string         `json:"clusterArn,omitempty"`
+	SecretArn    string         `json:"secretArn,omitempty"`
+	DatabaseName string         `json:"databaseName,omitempty"`
+	Directory    string         `json:"directory,omitempty"`
+	Table        string         `json:"table,omitempty"`
+	DryRun       bool           `json:"dryRun,omitempty"`
+	Applied      []SqlMigration `json:"applied"`
+}
+
+type SqlMigration struct {
+	Name     string `json:"name"`
+	Checksum string `json:"checksum"`
+}
+
+type sqlMigrationFile struct {
+	SqlMigration
+	Statements []string
+}
+
+const (
+	SqlMigrationStatusPending = "pending"
+	SqlMigrationStatusApplied = "applied"
+)
+
+type SqlMigrationEvent struct {
+	DatabaseName string
+	Name         string
+	Status       string
+}
+
+func (r *SqlMigrations) Create(input *SqlMigrationsInputs, output *CreateResult[SqlMigrationsOutputs]) error {
+	outs, err := r.migrate(input)
+	if err != nil {
+		return err
+	}
+	*output = CreateResult[SqlMigrationsOutputs]{
+		ID:   input.DatabaseName + "/" + input.table(),
+		Outs: *outs,
+	}
+	return nil
+}
+
+func (r *SqlMigrations) Update(input *UpdateInput[SqlMigrationsInputs, SqlMigrationsOutputs], output *UpdateResult[SqlMigrationsOutputs]) error {
+	outs, err := r.migrate(&input.News)
+	if err != nil {
+		return err
+	}
+	*output = UpdateResult[SqlMigrationsOutputs]{
+		Outs: *outs,
+	}
+	return nil
+}
+
+// Migrations are never rolled back, removing the resource leaves the schema
+// as it is
+func (r *SqlMigrations) Delete(input *DeleteInput[SqlMigrationsOutputs], output *int) error {
+	return nil
+}
+
+func (r *SqlMigrations) Check(input *CheckInput[SqlMigrationsInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("clusterArn", input.News.ClusterArn)
+	checks.require("secretArn", input.News.SecretArn)
+	checks.require("databaseName", input.News.DatabaseName)
+	checks.require("directory", input.News.Directory)
+	if input.News.Table != "" && !tableNameRegex.MatchString(input.News.Table) {
+		checks.fail("table", fmt.Sprintf("%q needs to start with a letter or underscore and only contain letters, numbers, and underscores", input.News.Table))
+	}
+	if input.News.Directory != "" {
+		if _, err := r.files(&input.News); err != nil {
+			checks.fail("directory", err.Error())
+		}
+	}
+	*output = checks.result()
+	return nil
+}
+
+// The files are compared against the migrations that were applied in the
+// last deploy, so adding or editing one shows up as a change
+func (r *SqlMigrations) Diff(input *DiffInput[SqlMigrationsInputs, SqlMigrationsOutputs], output *DiffResult) error {
+	if input.Olds.DatabaseName == "" {
+		*output = DiffResult{}
+		return nil
+	}
+	var changed diffBuilder
+	changed.compare("clusterArn", input.Olds.ClusterArn, input.News.ClusterArn)
+	changed.compare("secretArn", input.Olds.SecretArn, input.News.SecretArn)
+	changed.compare("databaseName", input.Olds.DatabaseName, input.News.DatabaseName)
+	changed.compare("directory", input.Olds.Directory, input.News.Directory)
+	changed.compare("table", input.Olds.Table, input.News.Table)
+	changed.compare("dryRun", input.Olds.DryRun, input.News.DryRun)
+	files, err := r.files(&input.News)
+	if err != nil {
+		return err
+	}
+	current := []SqlMigration{}
+	for _, file := range files {
+		current = append(current, file.SqlMigration)
+	}
+	changed.compare("applied", input.Olds.Applied, current)
+	*output = newDiff(changed)
+	return nil
+}
+
+// Read picks up the migrations that were applied outside of SST, and removes
+// the resource if the database is gone
+func (r *SqlMigrations) Read(input *ReadInput[SqlMigrationsOutputs], output *ReadResult[SqlMigrationsOutputs]) error {
+	props := input.Props
+	if props.DatabaseName == "" {
+		*output = ReadResult[SqlMigrationsOutputs]{ID: input.ID, Props: props}
+		return nil
+	}
+	applied, err := r.applied(&SqlMigrationsInputs{
+		ClusterArn:   props.ClusterArn,
+		SecretArn:    props.SecretArn,
+		DatabaseName: props.DatabaseName,
+		Table:        props.Table,
+	})
+	if err != nil {
+		// the database does not exist
+		if strings.Contains(err.Error(), "SQLState: 3D000") {
+			*output = ReadResult[SqlMigrationsOutputs]{}
+			return nil
+		}
+		return err
+	}
+	props.Applied = applied
+	*output = ReadResult[SqlMigrationsOutputs]{ID: input.ID, Props: props}
+	return nil
+}
+
+func (r *SqlMigrations) migrate(input *SqlMigrationsInputs) (*SqlMigrationsOutputs, error) {
+	files, err := r.files(input)
+	if err != nil {
+		return nil, err
+	}
+	if !input.DryRun {
+		if err := r.execute(input, nil, fmt.Sprintf(`create table if not exists %s (
+			name text primary key,
+			checksum text not null,
+			applied_at timestamptz not null default now()
+		);`, input.table())); err != nil {
+			return nil, err
+		}
+	}
+	applied, err := r.applied(input)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := planSqlMigrations(files, applied)
+	if err != nil {
+		return nil, err
+	}
+
+	outs := &SqlMigrationsOutputs{
+		ClusterArn:   input.ClusterArn,
+		SecretArn:    input.SecretArn,
+		DatabaseName: input.DatabaseName,
+		Directory:    input.Directory,
+		Table:        input.Table,
+		DryRun:       input.DryRun,
+		Applied:      applied,
+	}
+	for _, file := range pending {
+		if input.DryRun {
+			bus.Publish(&SqlMigrationEvent{DatabaseName: input.DatabaseName, Name: file.Name, Status: SqlMigrationStatusPending})
+			continue
+		}
+		if err := r.apply(input, file); err != nil {
+			return nil, fmt.Errorf("failed to apply migration %s: %w", file.Name, err)
+		}
+		bus.Publish(&SqlMigrationEvent{DatabaseName: input.DatabaseName, Name: file.Name, Status: SqlMigrationStatusApplied})
+		outs.Applied = append(outs.Applied, file.SqlMigration)
+	}
+	return outs, nil
+}
+
+// Runs the statements of a migration and records it in the same transaction
+func (r *SqlMigrations) apply(input *SqlMigrationsInputs, file sqlMigrationFile) error {
+	cfg, err := r.config()
+	if err != nil {
+		return err
+	}
+	client := r.clients.RdsData(cfg)
+
+	tx, err := client.BeginTransaction(r.context, &rdsdata.BeginTransactionInput{
+		ResourceArn: &input.ClusterArn,
+		SecretArn:   &input.SecretArn,
+		Database:    &input.DatabaseName,
+	})
+	if err != nil {
+		return err
+	}
+	rollback := func() {
+		client.RollbackTransaction(r.context, &rdsdata.RollbackTransactionInput{
+			ResourceArn:   &input.ClusterArn,
+			SecretArn:     &input.SecretArn,
+			TransactionId: tx.TransactionId,
+		})
+	}
+	for _, sql := range file.Statements {
+		if err := r.execute(input, tx.TransactionId, sql); err != nil {
+			rollback()
+			return err
+		}
+	}
+	err = r.execute(input, tx.TransactionId, fmt.Sprintf("insert into %s (name, checksum) values (:name, :checksum);", input.table()),
+		types.SqlParameter{Name: stringPtr("name"), Value: &types.FieldMemberStringValue{Value: file.Name}},
+		types.SqlParameter{Name: stringPtr("checksum"), Value: &types.FieldMemberStringValue{Value: file.Checksum}},
+	)
+	if err != nil {
+		rollback()
+		return err
+	}
+	_, err = client.CommitTransaction(r.context, &rdsdata.CommitTransactionInput{
+		ResourceArn:   &input.ClusterArn,
+		SecretArn:     &input.SecretArn,
+		TransactionId: tx.TransactionId,
+	})
+	return err
+}
+
+// The migrations recorded in the table, nothing if the table doesn't exist
+func (r *SqlMigrations) applied(input *SqlMigrationsInputs) ([]SqlMigration, error) {
+	cfg, err := r.config()
+	if err != nil {
+		return nil, err
+	}
+	client := r.clients.RdsData(cfg)
+
+	result, err := client.ExecuteStatement(r.context, &rdsdata.ExecuteStatementInput{
+		ResourceArn: &input.ClusterArn,
+		SecretArn:   &input.SecretArn,
+		Database:    &input.DatabaseName,
+		Sql:         stringPtr(fmt.Sprintf("select name, checksum from %s order by name;", input.table())),
+	})
+	if err != nil {
+		// the table does not exist
+		if strings.Contains(err.Error(), "SQLState: 42P01") {
+			return []SqlMigration{}, nil
+		}
+		return nil, err
+	}
+	applied := []SqlMigration{}
+	for _, record := range result.Records {
+		if len(record) < 2 {
+			continue
+		}
+		name, _ := record[0].(*types.FieldMemberStringValue)
+		checksum, _ := record[1].(*types.FieldMemberStringValue)
+		if name == nil || checksum == nil {
+			continue
+		}
+		applied = append(applied, SqlMigration{Name: name.Value, Checksum: checksum.Value})
+	}
+	return applied, nil
+}
+
+func (r *SqlMigrations) execute(input *SqlMigrationsInputs, transactionId *string, sql string, parameters ...types.SqlParameter) error {
+	cfg, err := r.config()
+	if err != nil {
+		return err
+	}
+	client := r.clients.RdsData(cfg)
+
+	_, err = client.ExecuteStatement(r.context, &rdsdata.ExecuteStatementInput{
+		ResourceArn:   &input.ClusterArn,
+		SecretArn:     &input.SecretArn,
+		Database:      &input.DatabaseName,
+		TransactionId: transactionId,
+		Sql:           &sql,
+		Parameters:    parameters,
+	})
+	return err
+}
+
+func (r *SqlMigrations) files(input *SqlMigrationsInputs) ([]sqlMigrationFile, error) {
+	directory := input.Directory
+	if !filepath.IsAbs(directory) {
+		directory = filepath.Join(r.project.PathRoot(), directory)
+	}
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the migrations in %s: %w", input.Directory, err)
+	}
+	files := []sqlMigrationFile{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(directory, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		checksum := sha256.Sum256(data)
+		files = append(files, sqlMigrationFile{
+			SqlMigration: SqlMigration{
+				Name:     entry.Name(),
+				Checksum: hex.EncodeToString(checksum[:]),
+			},
+			Statements: splitSqlStatements(string(data)),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files, nil
+}
+
+func (input *SqlMigrationsInputs) table() string {
+	if input.Table == "" {
+		return SQL_MIGRATIONS_DEFAULT_TABLE
+	}
+	return input.Table
+}
+
+// Returns the files that still need to be applied. Files that were applied
+// can't be changed, removed, or have new files sorted before them.
+func planSqlMigrations(files []sqlMigrationFile, applied []SqlMigration) ([]sqlMigrationFile, error) {
+	byName := map[string]sqlMigrationFile{}
+	for _, file := range files {
+		byName[file.Name] = file
+	}
+	last := ""
+	done := map[string]bool{}
+	for _, migration := range applied {
+		file, ok := byName[migration.Name]
+		if !ok {
+			return nil, fmt.Errorf("migration %s was applied but is no longer in the directory", migration.Name)
+		}
+		if file.Checksum != migration.Checksum {
+			return nil, fmt.Errorf("migration %s was changed after it was applied, add a new migration instead", migration.Name)
+		}
+		done[migration.Name] = true
+		last = max(last, migration.Name)
+	}
+	pending := []sqlMigrationFile{}
+	for _, file := range files {
+		if done[file.Name] {
+			continue
+		}
+		if file.Name < last {
+			return nil, fmt.Errorf("migration %s sorts before %s, which was already applied, rename it so it comes after", file.Name, last)
+		}
+		pending = append(pending, file)
+	}
+	return pending, nil
+}
+
+// Splits a file into the statements in it since the Data API runs one at a
+// time. Semicolons in quotes, dollar quoted bodies, and comments are skipped.
+func splitSqlStatements(sql string) []string {
+	statements := []string{}
+	var current strings.Builder
+	// statements with nothing but comments in them are dropped
+	content := false
+	flush := func() {
+		if content {
+			statements = append(statements, strings.TrimSpace(current.String()))
+		}
+		current.Reset()
+		content = false
+	}
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end == -1 {
+				end = len(sql) - i
+			}
+			current.WriteString(sql[i : i+end])
+			i += end - 1
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				end = len(sql) - i - 4
+			}
+			current.WriteString(sql[i : i+end+4])
+			i += end + 3
+		case c == '\'' || c == '"':
+			content = true
+			end := strings.IndexByte(sql[i+1:], c)
+			if end == -1 {
+				end = len(sql) - i - 2
+			}
+			current.WriteString(sql[i : i+end+2])
+			i += end + 1
+		case c == '$':
+			content = true
+			tag := dollarQuoteTag(sql[i:])
+			if tag == "" {
+				current.WriteByte(c)
+				continue
+			}
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end == -1 {
+				end = len(sql) - i - 2*len(tag)
+			}
+			current.WriteString(sql[i : i+end+2*len(tag)])
+			i += end + 2*len(tag) - 1
+		case c == ';':
+			current.WriteByte(c)
+			flush()
+		default:
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				content = true
+			}
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+// Returns the tag that opens a dollar quoted string, like $$ or $body$
+func dollarQuoteTag(sql string) string {
+	for i := 1; i < len(sql); i++ {
+		c := sql[i]
+		if c == '$' {
+			return sql[:i+1]
+		}
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9') {
+			return ""
+		}
+	}
+	return ""
+}
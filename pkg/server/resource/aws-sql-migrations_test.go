@@ -0,0 +1,66 @@
+package resource
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitSqlStatements(t *testing.T) {
+	sql := `-- create the users table; with a comment
+create table users (id serial primary key, name text default 'a;b');
+/* a block; comment */
+create function touch() returns trigger as $body$
+begin
+  new.updated = now();
+  return new;
+end;
+$body$ language plpgsql;
+select $1, "odd;name" from users
+-- trailing comment`
+	statements := splitSqlStatements(sql)
+	if len(statements) != 3 {
+		t.Fatalf("expected 3 statements, got %d: %q", len(statements), statements)
+	}
+	if !strings.HasSuffix(statements[0], "default 'a;b');") {
+		t.Errorf("unexpected first statement %q", statements[0])
+	}
+	if !strings.Contains(statements[1], "return new;\nend;\n$body$ language plpgsql;") {
+		t.Errorf("unexpected second statement %q", statements[1])
+	}
+	if !strings.HasPrefix(statements[2], `select $1, "odd;name" from users`) {
+		t.Errorf("unexpected third statement %q", statements[2])
+	}
+	if statements := splitSqlStatements("-- nothing here\n/* or here */"); len(statements) != 0 {
+		t.Errorf("expected no statements, got %q", statements)
+	}
+}
+
+func TestPlanSqlMigrations(t *testing.T) {
+	file := func(name string, checksum string) sqlMigrationFile {
+		return sqlMigrationFile{SqlMigration: SqlMigration{Name: name, Checksum: checksum}}
+	}
+	files := []sqlMigrationFile{file("001.sql", "a"), file("002.sql", "b"), file("003.sql", "c")}
+
+	pending, err := planSqlMigrations(files, []SqlMigration{{"001.sql", "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, item := range pending {
+		names = append(names, item.Name)
+	}
+	if !slices.Equal(names, []string{"002.sql", "003.sql"}) {
+		t.Fatalf("unexpected pending migrations %v", names)
+	}
+
+	if _, err := planSqlMigrations(files, []SqlMigration{{"001.sql", "changed"}}); err == nil {
+		t.Fatal("expected a checksum mismatch to be refused")
+	}
+	if _, err := planSqlMigrations(files, []SqlMigration{{"000.sql", "x"}}); err == nil {
+		t.Fatal("expected a removed migration to be refused")
+	}
+	if _, err := planSqlMigrations(files, []SqlMigration{{"002.sql", "b"}}); err == nil {
+		t.Fatal("expected a migration sorted before an applied one to be refused")
+	}
+}
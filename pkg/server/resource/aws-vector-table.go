@@ -1,10 +1,13 @@
 package resource
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
 )
 
 type VectorTable struct {
@@ -12,15 +15,44 @@ type VectorTable struct {
 }
 
 type VectorTableInputs struct {
-	ClusterArn   string `json:"clusterArn"`
-	SecretArn    string `json:"secretArn"`
-	DatabaseName string `json:"databaseName"`
-	TableName    string `json:"tableName"`
-	Dimension    int    `json:"dimension"`
+	ClusterArn   string       `json:"clusterArn"`
+	SecretArn    string       `json:"secretArn"`
+	DatabaseName string       `json:"databaseName"`
+	TableName    string       `json:"tableName"`
+	Dimension    int          `json:"dimension"`
+	Index        *VectorIndex `json:"index,omitempty"`
+	// How a change in dimension is applied to the existing rows. Without it
+	// the change is refused, with "column" the current embeddings are moved
+	// to a column of their own and an empty one takes their place.
+	Migration string `json:"migration,omitempty"`
 }
 
 type VectorTableOutputs struct {
-	Dimension int `json:"dimension"`
+	ClusterArn   string       `json:"clusterArn,omitempty"`
+	SecretArn    string       `json:"secretArn,omitempty"`
+	DatabaseName string       `json:"databaseName,omitempty"`
+	TableName    string       `json:"tableName,omitempty"`
+	Dimension    int          `json:"dimension"`
+	Index        *VectorIndex `json:"index,omitempty"`
+	Migration    string       `json:"migration,omitempty"`
+	// Goes up by one every time the dimension or the index changes
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+}
+
+const (
+	VectorIndexHnsw    = "hnsw"
+	VectorIndexIvfflat = "ivfflat"
+
+	VectorMigrationColumn = "column"
+)
+
+// The index on the embeddings. M and EfConstruction are for HNSW, Lists is
+// for IVFFlat. Anything left out uses the pgvector default.
+type VectorIndex struct {
+	Type           string `json:"type"`
+	M              int    `json:"m,omitempty"`
+	EfConstruction int    `json:"efConstruction,omitempty"`
+	Lists          int    `json:"lists,omitempty"`
 }
 
 func (r *VectorTable) Create(input *VectorTableInputs, output *CreateResult[VectorTableOutputs]) error {
@@ -45,7 +77,7 @@ func (r *VectorTable) Create(input *VectorTableInputs, output *CreateResult[Vect
 
 	*output = CreateResult[VectorTableOutputs]{
 		ID:   input.TableName,
-		Outs: VectorTableOutputs{Dimension: input.Dimension},
+		Outs: input.outputs(1),
 	}
 	return nil
 }
@@ -60,33 +92,151 @@ func (r *VectorTable) Update(input *UpdateInput[VectorTableInputs, VectorTableOu
 	if err := r.enablePgtrgmExtension(&input.News); err != nil {
 		return err
 	}
-	if input.Olds.Dimension != input.News.Dimension {
-		if err := r.removeTable(&input.News); err != nil {
-			return err
-		}
-	}
 	if err := r.createTable(&input.News); err != nil {
 		return err
 	}
-	if err := r.createEmbeddingIndex(&input.News); err != nil {
-		return err
+
+	// tables created before schema versions were tracked are on the first one
+	version := max(input.Olds.SchemaVersion, 1)
+	switch {
+	case input.Olds.Dimension != input.News.Dimension:
+		if input.News.Migration != VectorMigrationColumn {
+			return errors.New(vectorMigrationPlan(input.News.TableName, input.Olds.Dimension, input.News.Dimension))
+		}
+		if err := r.migrateDimension(&input.News, input.Olds.Dimension); err != nil {
+			return err
+		}
+		version++
+	case !input.Olds.Index.equal(input.News.Index):
+		if err := r.replaceEmbeddingIndex(&input.News); err != nil {
+			return err
+		}
+		version++
+	default:
+		if err := r.createEmbeddingIndex(&input.News); err != nil {
+			return err
+		}
 	}
 	if err := r.createMetadataIndex(&input.News); err != nil {
 		return err
 	}
 
 	*output = UpdateResult[VectorTableOutputs]{
-		Outs: VectorTableOutputs{Dimension: input.News.Dimension},
+		Outs: input.News.outputs(version),
 	}
 	return nil
 }
 
+var tableNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func (r *VectorTable) Check(input *CheckInput[VectorTableInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("clusterArn", input.News.ClusterArn)
+	checks.require("secretArn", input.News.SecretArn)
+	checks.require("databaseName", input.News.DatabaseName)
+	checks.require("tableName", input.News.TableName)
+	if input.News.TableName != "" && !tableNameRegex.MatchString(input.News.TableName) {
+		checks.fail("tableName", fmt.Sprintf("%q needs to start with a letter or underscore and only contain letters, numbers, and underscores", input.News.TableName))
+	}
+	if input.News.Dimension <= 0 {
+		checks.fail("dimension", "dimension needs to be greater than 0")
+	}
+	// refuse at preview instead of part way through the deploy
+	if input.Olds.Dimension > 0 && input.Olds.Dimension != input.News.Dimension && input.News.Migration != VectorMigrationColumn {
+		checks.fail("dimension", vectorMigrationPlan(input.News.TableName, input.Olds.Dimension, input.News.Dimension))
+	}
+	if input.News.Migration != "" && input.News.Migration != VectorMigrationColumn {
+		checks.fail("migration", fmt.Sprintf("migration needs to be %q", VectorMigrationColumn))
+	}
+	if index := input.News.Index; index != nil {
+		switch index.Type {
+		case VectorIndexHnsw:
+			if index.Lists != 0 {
+				checks.fail("index.lists", "lists is only supported by ivfflat indexes")
+			}
+			if index.M != 0 && (index.M < 2 || index.M > 100) {
+				checks.fail("index.m", "m needs to be between 2 and 100")
+			}
+			if index.EfConstruction != 0 && index.EfConstruction < 2*max(index.M, 16) {
+				checks.fail("index.efConstruction", "efConstruction needs to be at least double m")
+			}
+		case VectorIndexIvfflat:
+			if index.M != 0 || index.EfConstruction != 0 {
+				checks.fail("index", "m and efConstruction are only supported by hnsw indexes")
+			}
+			if index.Lists < 0 || index.Lists > 32768 {
+				checks.fail("index.lists", "lists needs to be between 1 and 32768")
+			}
+		default:
+			checks.fail("index.type", fmt.Sprintf("index type needs to be %q or %q", VectorIndexHnsw, VectorIndexIvfflat))
+		}
+	}
+	*output = checks.result()
+	return nil
+}
+
+func (r *VectorTable) Diff(input *DiffInput[VectorTableInputs, VectorTableOutputs], output *DiffResult) error {
+	if input.Olds.TableName == "" {
+		*output = DiffResult{}
+		return nil
+	}
+	var changed diffBuilder
+	changed.compare("clusterArn", input.Olds.ClusterArn, input.News.ClusterArn)
+	changed.compare("secretArn", input.Olds.SecretArn, input.News.SecretArn)
+	changed.compare("databaseName", input.Olds.DatabaseName, input.News.DatabaseName)
+	changed.compare("tableName", input.Olds.TableName, input.News.TableName)
+	changed.compare("dimension", input.Olds.Dimension, input.News.Dimension)
+	changed.compare("index", input.Olds.Index, input.News.Index)
+	changed.compare("migration", input.Olds.Migration, input.News.Migration)
+	// the ID is the table name and the old table is never migrated
+	*output = newDiff(changed, "clusterArn", "databaseName", "tableName")
+	return nil
+}
+
+// Read removes the table from the state if it, or its database, was dropped
+func (r *VectorTable) Read(input *ReadInput[VectorTableOutputs], output *ReadResult[VectorTableOutputs]) error {
+	props := input.Props
+	if props.TableName == "" {
+		*output = ReadResult[VectorTableOutputs]{ID: input.ID, Props: props}
+		return nil
+	}
+	cfg, err := r.config()
+	if err != nil {
+		return err
+	}
+	client := r.clients.RdsData(cfg)
+	result, err := client.ExecuteStatement(r.context, &rdsdata.ExecuteStatementInput{
+		ResourceArn: &props.ClusterArn,
+		SecretArn:   &props.SecretArn,
+		Database:    &props.DatabaseName,
+		Sql:         stringPtr(fmt.Sprintf("select to_regclass('%s')::text;", props.TableName)),
+	})
+	if err != nil {
+		// the database does not exist
+		if strings.Contains(err.Error(), "SQLState: 3D000") {
+			*output = ReadResult[VectorTableOutputs]{}
+			return nil
+		}
+		return err
+	}
+	if len(result.Records) == 0 || len(result.Records[0]) == 0 {
+		*output = ReadResult[VectorTableOutputs]{}
+		return nil
+	}
+	if _, ok := result.Records[0][0].(*types.FieldMemberIsNull); ok {
+		*output = ReadResult[VectorTableOutputs]{}
+		return nil
+	}
+	*output = ReadResult[VectorTableOutputs]{ID: input.ID, Props: props}
+	return nil
+}
+
 func (r *VectorTable) createDatabase(input *VectorTableInputs) error {
 	cfg, err := r.config()
 	if err != nil {
 		return err
 	}
-	client := rdsdata.NewFromConfig(cfg)
+	client := r.clients.RdsData(cfg)
 
 	_, err = client.ExecuteStatement(r.context, &rdsdata.ExecuteStatementInput{
 		ResourceArn: &input.ClusterArn,
@@ -118,18 +268,39 @@ func (r *VectorTable) createTable(input *VectorTableInputs) error {
 	return r.executeSQL(input, sql)
 }
 
-func (r *VectorTable) removeTable(input *VectorTableInputs) error {
-	sql := fmt.Sprintf("drop table if exists %s;", input.TableName)
-	return r.executeSQL(input, sql)
+func (r *VectorTable) createEmbeddingIndex(input *VectorTableInputs) error {
+	return r.executeSQL(input, input.Index.create(input.TableName))
 }
 
-func (r *VectorTable) createEmbeddingIndex(input *VectorTableInputs) error {
-	sql := fmt.Sprintf("create index on %s using hnsw (embedding vector_cosine_ops);", input.TableName)
-	return r.executeSQL(input, sql)
+// The index is rebuilt in a transaction so queries never run without one
+func (r *VectorTable) replaceEmbeddingIndex(input *VectorTableInputs) error {
+	return r.executeTransaction(input, []string{
+		fmt.Sprintf("drop index if exists %s_embedding_idx;", input.TableName),
+		input.Index.create(input.TableName),
+	})
+}
+
+// Existing embeddings can't be converted to another dimension, so they are
+// kept in a column named after their dimension to re-embed from, and the
+// embedding column starts out empty. Rows without an embedding don't show
+// up in queries until they are put again.
+func (r *VectorTable) migrateDimension(input *VectorTableInputs, previous int) error {
+	err := r.executeTransaction(input, []string{
+		fmt.Sprintf("drop index if exists %s_embedding_idx;", input.TableName),
+		fmt.Sprintf("alter table %s rename column embedding to embedding_%d;", input.TableName, previous),
+		fmt.Sprintf("alter table %s add column embedding vector(%d);", input.TableName, input.Dimension),
+		input.Index.create(input.TableName),
+	})
+	// an earlier migration away from the same dimension left its column behind
+	if err != nil && strings.Contains(err.Error(), "SQLState: 42701") {
+		return fmt.Errorf("%s already has an embedding_%d column from an earlier migration, drop or rename it and deploy again, nothing was changed", input.TableName, previous)
+	}
+	return err
 }
 
 func (r *VectorTable) createMetadataIndex(input *VectorTableInputs) error {
-	sql := fmt.Sprintf("create index on %s using gin (metadata);", input.TableName)
+	// named so running it again on each update doesn't add another index
+	sql := fmt.Sprintf("create index if not exists %s_metadata_idx on %s using gin (metadata);", input.TableName, input.TableName)
 	return r.executeSQL(input, sql)
 }
 
@@ -138,7 +309,7 @@ func (r *VectorTable) executeSQL(input *VectorTableInputs, sql string) error {
 	if err != nil {
 		return err
 	}
-	client := rdsdata.NewFromConfig(cfg)
+	client := r.clients.RdsData(cfg)
 
 	_, err = client.ExecuteStatement(r.context, &rdsdata.ExecuteStatementInput{
 		ResourceArn: &input.ClusterArn,
@@ -154,6 +325,103 @@ func (r *VectorTable) executeSQL(input *VectorTableInputs, sql string) error {
 	return nil
 }
 
+func (r *VectorTable) executeTransaction(input *VectorTableInputs, statements []string) error {
+	cfg, err := r.config()
+	if err != nil {
+		return err
+	}
+	client := r.clients.RdsData(cfg)
+
+	tx, err := client.BeginTransaction(r.context, &rdsdata.BeginTransactionInput{
+		ResourceArn: &input.ClusterArn,
+		SecretArn:   &input.SecretArn,
+		Database:    &input.DatabaseName,
+	})
+	if err != nil {
+		return err
+	}
+	for _, sql := range statements {
+		_, err = client.ExecuteStatement(r.context, &rdsdata.ExecuteStatementInput{
+			ResourceArn:   &input.ClusterArn,
+			SecretArn:     &input.SecretArn,
+			Database:      &input.DatabaseName,
+			TransactionId: tx.TransactionId,
+			Sql:           stringPtr(sql),
+		})
+		if err != nil {
+			client.RollbackTransaction(r.context, &rdsdata.RollbackTransactionInput{
+				ResourceArn:   &input.ClusterArn,
+				SecretArn:     &input.SecretArn,
+				TransactionId: tx.TransactionId,
+			})
+			return err
+		}
+	}
+	_, err = client.CommitTransaction(r.context, &rdsdata.CommitTransactionInput{
+		ResourceArn:   &input.ClusterArn,
+		SecretArn:     &input.SecretArn,
+		TransactionId: tx.TransactionId,
+	})
+	return err
+}
+
+func (input *VectorTableInputs) outputs(version int) VectorTableOutputs {
+	return VectorTableOutputs{
+		ClusterArn:    input.ClusterArn,
+		SecretArn:     input.SecretArn,
+		DatabaseName:  input.DatabaseName,
+		TableName:     input.TableName,
+		Dimension:     input.Dimension,
+		Index:         input.Index,
+		Migration:     input.Migration,
+		SchemaVersion: version,
+	}
+}
+
+// The statement that creates the embedding index, tables without an index
+// config get the HNSW index they always had
+func (index *VectorIndex) create(tableName string) string {
+	if index == nil {
+		index = &VectorIndex{Type: VectorIndexHnsw}
+	}
+	options := []string{}
+	if index.M > 0 {
+		options = append(options, fmt.Sprintf("m = %d", index.M))
+	}
+	if index.EfConstruction > 0 {
+		options = append(options, fmt.Sprintf("ef_construction = %d", index.EfConstruction))
+	}
+	if index.Lists > 0 {
+		options = append(options, fmt.Sprintf("lists = %d", index.Lists))
+	}
+	sql := fmt.Sprintf("create index if not exists %s_embedding_idx on %s using %s (embedding vector_cosine_ops)", tableName, tableName, index.Type)
+	if len(options) > 0 {
+		sql += " with (" + strings.Join(options, ", ") + ")"
+	}
+	return sql + ";"
+}
+
+func (index *VectorIndex) equal(other *VectorIndex) bool {
+	if index == nil {
+		index = &VectorIndex{Type: VectorIndexHnsw}
+	}
+	if other == nil {
+		other = &VectorIndex{Type: VectorIndexHnsw}
+	}
+	return *index == *other
+}
+
+func vectorMigrationPlan(tableName string, from int, to int) string {
+	return strings.Join([]string{
+		fmt.Sprintf("The dimension of %s can't be changed from %d to %d in place, the existing embeddings don't fit the new dimension.", tableName, from, to),
+		fmt.Sprintf("Set migration to %q to apply it in a single transaction:", VectorMigrationColumn),
+		fmt.Sprintf("  1. The current embeddings are moved to the embedding_%d column", from),
+		fmt.Sprintf("  2. An empty embedding column with %d dimensions takes its place, with a new index", to),
+		"  3. Rows don't show up in queries until they are put again with new embeddings",
+		"Or set the dimension back to keep the table as it is.",
+	}, "\n")
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -0,0 +1,25 @@
+package resource
+
+import "testing"
+
+func TestVectorIndexCreate(t *testing.T) {
+	var index *VectorIndex
+	if sql := index.create("embeddings"); sql != "create index if not exists embeddings_embedding_idx on embeddings using hnsw (embedding vector_cosine_ops);" {
+		t.Fatalf("unexpected default index %q", sql)
+	}
+	index = &VectorIndex{Type: VectorIndexHnsw, M: 24, EfConstruction: 100}
+	if sql := index.create("embeddings"); sql != "create index if not exists embeddings_embedding_idx on embeddings using hnsw (embedding vector_cosine_ops) with (m = 24, ef_construction = 100);" {
+		t.Fatalf("unexpected hnsw index %q", sql)
+	}
+	index = &VectorIndex{Type: VectorIndexIvfflat, Lists: 100}
+	if sql := index.create("embeddings"); sql != "create index if not exists embeddings_embedding_idx on embeddings using ivfflat (embedding vector_cosine_ops) with (lists = 100);" {
+		t.Fatalf("unexpected ivfflat index %q", sql)
+	}
+	if !index.equal(&VectorIndex{Type: VectorIndexIvfflat, Lists: 100}) || index.equal(nil) {
+		t.Fatal("unexpected index comparison")
+	}
+	var missing *VectorIndex
+	if !missing.equal(&VectorIndex{Type: VectorIndexHnsw}) {
+		t.Fatal("expected no index config to be the default hnsw index")
+	}
+}
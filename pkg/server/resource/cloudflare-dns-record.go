@@ -4,18 +4,24 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 
+	cloudflare "github.com/cloudflare/cloudflare-go"
 	"github.com/sst/sst/v3/pkg/project"
+	"github.com/sst/sst/v3/pkg/project/provider"
 )
 
 // Base resource for Cloudflare providers
 type CloudflareResource struct {
-	context context.Context
-	project *project.Project
+	context  context.Context
+	project  *project.Project
+	throttle *throttle
+	// The base URL of the API
+	url string
 }
 
 type CloudflareDnsRecord struct {
@@ -39,7 +45,13 @@ type CloudflareDnsRecordInputs struct {
 }
 
 type CloudflareDnsRecordOutputs struct {
-	RecordId string `json:"recordId"`
+	RecordId string  `json:"recordId"`
+	ZoneId   string  `json:"zoneId,omitempty"`
+	Type     string  `json:"type,omitempty"`
+	Name     string  `json:"name,omitempty"`
+	Value    *string `json:"value,omitempty"`
+	Proxied  *bool   `json:"proxied,omitempty"`
+	Data     *Data   `json:"data,omitempty"`
 }
 
 func (r *CloudflareDnsRecord) Create(input *CloudflareDnsRecordInputs, output *CreateResult[CloudflareDnsRecordOutputs]) error {
@@ -50,7 +62,7 @@ func (r *CloudflareDnsRecord) Create(input *CloudflareDnsRecordInputs, output *C
 
 	*output = CreateResult[CloudflareDnsRecordOutputs]{
 		ID:   recordId,
-		Outs: CloudflareDnsRecordOutputs{RecordId: recordId},
+		Outs: r.outputs(input, recordId),
 	}
 	return nil
 }
@@ -62,14 +74,83 @@ func (r *CloudflareDnsRecord) Update(input *UpdateInput[CloudflareDnsRecordInput
 	}
 
 	*output = UpdateResult[CloudflareDnsRecordOutputs]{
-		Outs: CloudflareDnsRecordOutputs{RecordId: recordId},
+		Outs: r.outputs(&input.News, recordId),
+	}
+	return nil
+}
+
+func (r *CloudflareDnsRecord) Check(input *CheckInput[CloudflareDnsRecordInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("zoneId", input.News.ZoneId)
+	checks.require("type", input.News.Type)
+	checks.require("name", input.News.Name)
+	if input.News.Value == nil && input.News.Data == nil {
+		checks.fail("value", "value or data is required")
+	}
+	*output = checks.result()
+	return nil
+}
+
+func (r *CloudflareDnsRecord) Diff(input *DiffInput[CloudflareDnsRecordInputs, CloudflareDnsRecordOutputs], output *DiffResult) error {
+	if input.Olds.ZoneId == "" {
+		*output = DiffResult{}
+		return nil
+	}
+	var changed diffBuilder
+	changed.compare("zoneId", input.Olds.ZoneId, input.News.ZoneId)
+	changed.compare("type", input.Olds.Type, input.News.Type)
+	changed.compare("name", input.Olds.Name, input.News.Name)
+	changed.compare("value", input.Olds.Value, input.News.Value)
+	changed.compare("proxied", input.Olds.Proxied, input.News.Proxied)
+	changed.compare("data", input.Olds.Data, input.News.Data)
+	*output = newDiff(changed, "zoneId", "type", "name")
+	return nil
+}
+
+// Read picks up changes made to the record outside of a deploy. Records that
+// already existed when they were created aren't tracked, so they aren't read.
+func (r *CloudflareDnsRecord) Read(input *ReadInput[CloudflareDnsRecordOutputs], output *ReadResult[CloudflareDnsRecordOutputs]) error {
+	props := input.Props
+	match, ok := r.project.Provider("cloudflare")
+	if !ok || input.ID == "existing-record" || props.ZoneId == "" {
+		*output = ReadResult[CloudflareDnsRecordOutputs]{ID: input.ID, Props: props}
+		return nil
+	}
+	api := match.(*provider.CloudflareProvider).Api()
+	record, err := api.GetDNSRecord(r.context, cloudflare.ZoneIdentifier(props.ZoneId), input.ID)
+	if err != nil {
+		var notFound *cloudflare.NotFoundError
+		if errors.As(err, &notFound) {
+			*output = ReadResult[CloudflareDnsRecordOutputs]{}
+			return nil
+		}
+		return err
+	}
+	if props.Value != nil {
+		props.Value = &record.Content
+	}
+	if props.Proxied != nil {
+		props.Proxied = record.Proxied
 	}
+	*output = ReadResult[CloudflareDnsRecordOutputs]{ID: input.ID, Props: props}
 	return nil
 }
 
+func (r *CloudflareDnsRecord) outputs(input *CloudflareDnsRecordInputs, recordId string) CloudflareDnsRecordOutputs {
+	return CloudflareDnsRecordOutputs{
+		RecordId: recordId,
+		ZoneId:   input.ZoneId,
+		Type:     input.Type,
+		Name:     input.Name,
+		Value:    input.Value,
+		Proxied:  input.Proxied,
+		Data:     input.Data,
+	}
+}
+
 func (r *CloudflareDnsRecord) createOrUpdateRecord(input *CloudflareDnsRecordInputs) (string, error) {
 	// Construct the URL for the DNS record
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records", input.ZoneId)
+	url := fmt.Sprintf("%s/zones/%s/dns_records", r.url, input.ZoneId)
 	
 	// Create the payload based on record type
 	var payloadBytes []byte
@@ -120,7 +201,7 @@ func (r *CloudflareDnsRecord) createOrUpdateRecord(input *CloudflareDnsRecordInp
 	req.Header.Set("Authorization", "Bearer "+input.ApiToken)
 	
 	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := r.throttle.do(r.context, client, req)
 	if err != nil {
 		return "", err
 	}
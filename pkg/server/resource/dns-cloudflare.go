@@ -0,0 +1,111 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/sst/sst/v3/pkg/project/provider"
+)
+
+type cloudflareDnsAdapter struct {
+	api  *cloudflare.API
+	zone *cloudflare.ResourceContainer
+}
+
+// Uses the cloudflare provider of the app, or CLOUDFLARE_API_TOKEN if the app
+// doesn't have one
+func newCloudflareDnsAdapter(r *CloudflareResource, zoneId string) (*cloudflareDnsAdapter, error) {
+	var api *cloudflare.API
+	if match, ok := r.project.Provider("cloudflare"); ok {
+		api = match.(*provider.CloudflareProvider).Api()
+	}
+	if api == nil {
+		token := os.Getenv("CLOUDFLARE_API_TOKEN")
+		if token == "" {
+			return nil, fmt.Errorf("the cloudflare provider or CLOUDFLARE_API_TOKEN is needed to manage cloudflare dns records")
+		}
+		created, err := cloudflare.NewWithAPIToken(token, cloudflare.BaseURL(r.url))
+		if err != nil {
+			return nil, err
+		}
+		api = created
+	}
+	return &cloudflareDnsAdapter{api, cloudflare.ZoneIdentifier(zoneId)}, nil
+}
+
+func (a *cloudflareDnsAdapter) records(ctx context.Context, name string, recordType string) ([]cloudflare.DNSRecord, error) {
+	records, _, err := a.api.ListDNSRecords(ctx, a.zone, cloudflare.ListDNSRecordsParams{
+		Name: name,
+		Type: recordType,
+	})
+	return records, err
+}
+
+func (a *cloudflareDnsAdapter) Read(ctx context.Context, name string, recordType string) (*dnsRecordSet, error) {
+	records, err := a.records(ctx, name, recordType)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	set := &dnsRecordSet{
+		Name:    name,
+		Type:    recordType,
+		Values:  []string{},
+		TTL:     records[0].TTL,
+		Proxied: records[0].Proxied,
+	}
+	for _, record := range records {
+		value := record.Content
+		if record.Priority != nil {
+			value = fmt.Sprintf("%d %s", *record.Priority, value)
+		}
+		set.Values = append(set.Values, value)
+	}
+	return set, nil
+}
+
+func (a *cloudflareDnsAdapter) Create(ctx context.Context, set *dnsRecordSet) error {
+	ttl := set.TTL
+	// proxied records always use the automatic ttl
+	if set.Proxied != nil && *set.Proxied {
+		ttl = 1
+	}
+	for _, value := range set.Values {
+		params := cloudflare.CreateDNSRecordParams{
+			Type:    set.Type,
+			Name:    set.Name,
+			Content: value,
+			TTL:     ttl,
+			Proxied: set.Proxied,
+		}
+		if set.Type == "MX" {
+			params.Priority, params.Content = splitDnsPriority(value)
+		}
+		if _, err := a.api.CreateDNSRecord(ctx, a.zone, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cloudflare has a record for each value, so they are all replaced
+func (a *cloudflareDnsAdapter) Update(ctx context.Context, set *dnsRecordSet) error {
+	if err := a.Delete(ctx, set); err != nil {
+		return err
+	}
+	return a.Create(ctx, set)
+}
+
+func (a *cloudflareDnsAdapter) Delete(ctx context.Context, set *dnsRecordSet) error {
+	records, err := a.records(ctx, set.Name, set.Type)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := a.api.DeleteDNSRecord(ctx, a.zone, record.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
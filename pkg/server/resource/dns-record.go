@@ -0,0 +1,350 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// The records with the same name and type in a zone, managed as one
+type dnsRecordSet struct {
+	Name    string
+	Type    string
+	Values  []string
+	TTL     int
+	Proxied *bool
+}
+
+// A DNS provider that records can be managed with. Each adapter is bound to
+// a single zone and returns nil from Read when there are no records with the
+// name and type.
+type dnsAdapter interface {
+	Read(ctx context.Context, name string, recordType string) (*dnsRecordSet, error)
+	Create(ctx context.Context, set *dnsRecordSet) error
+	Update(ctx context.Context, set *dnsRecordSet) error
+	Delete(ctx context.Context, set *dnsRecordSet) error
+}
+
+const DNS_DEFAULT_TTL = 60
+
+// Records that SST creates get a TXT record next to them with the app and
+// stage that own them. Records without it, or owned by another stage, are
+// never changed or deleted.
+type dnsOwner struct {
+	adapter dnsAdapter
+	owner   string
+}
+
+func newDnsOwner(adapter dnsAdapter, app string, stage string) *dnsOwner {
+	return &dnsOwner{adapter, fmt.Sprintf("sst:%s:%s", app, stage)}
+}
+
+func (o *dnsOwner) marker(set *dnsRecordSet) *dnsRecordSet {
+	return &dnsRecordSet{
+		Name:   "_sst-owner." + strings.ToLower(set.Type) + "." + set.Name,
+		Type:   "TXT",
+		Values: []string{o.owner},
+		TTL:    set.TTL,
+	}
+}
+
+// owned returns if the records are owned by this stage and if they have a
+// marker at all
+func (o *dnsOwner) owned(ctx context.Context, set *dnsRecordSet) (bool, bool, error) {
+	marker, err := o.adapter.Read(ctx, o.marker(set).Name, "TXT")
+	if err != nil {
+		return false, false, err
+	}
+	if marker == nil {
+		return false, false, nil
+	}
+	return slices.Contains(marker.Values, o.owner), true, nil
+}
+
+func (o *dnsOwner) Create(ctx context.Context, set *dnsRecordSet) error {
+	existing, err := o.adapter.Read(ctx, set.Name, set.Type)
+	if err != nil {
+		return err
+	}
+	owned, marked, err := o.owned(ctx, set)
+	if err != nil {
+		return err
+	}
+	if (existing != nil || marked) && !owned {
+		return fmt.Errorf("the %s record for %s already exists and was not created by this stage, remove it or import it first", set.Type, set.Name)
+	}
+	if !marked {
+		if err := o.adapter.Create(ctx, o.marker(set)); err != nil {
+			return err
+		}
+	}
+	// left behind by a create that failed part way
+	if existing != nil {
+		return o.adapter.Update(ctx, set)
+	}
+	return o.adapter.Create(ctx, set)
+}
+
+func (o *dnsOwner) Update(ctx context.Context, set *dnsRecordSet) error {
+	owned, _, err := o.owned(ctx, set)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		return fmt.Errorf("the %s record for %s was not created by this stage and won't be changed", set.Type, set.Name)
+	}
+	existing, err := o.adapter.Read(ctx, set.Name, set.Type)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return o.adapter.Create(ctx, set)
+	}
+	return o.adapter.Update(ctx, set)
+}
+
+func (o *dnsOwner) Delete(ctx context.Context, set *dnsRecordSet) error {
+	owned, _, err := o.owned(ctx, set)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		slog.Info("skipping dns record not owned by this stage", "name", set.Name, "type", set.Type)
+		return nil
+	}
+	existing, err := o.adapter.Read(ctx, set.Name, set.Type)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if err := o.adapter.Delete(ctx, existing); err != nil {
+			return err
+		}
+	}
+	marker, err := o.adapter.Read(ctx, o.marker(set).Name, "TXT")
+	if err != nil || marker == nil {
+		return err
+	}
+	return o.adapter.Delete(ctx, marker)
+}
+
+// Read returns the records as they are now, or nil if they are gone or no
+// longer owned by this stage
+func (o *dnsOwner) Read(ctx context.Context, set *dnsRecordSet) (*dnsRecordSet, error) {
+	owned, _, err := o.owned(ctx, set)
+	if err != nil || !owned {
+		return nil, err
+	}
+	return o.adapter.Read(ctx, set.Name, set.Type)
+}
+
+// Manages a DNS record with any of the supported providers
+type DnsRecord struct {
+	aws        *AwsResource
+	cloudflare *CloudflareResource
+	vercel     *VercelResource
+}
+
+type DnsRecordInputs struct {
+	// One of route53, cloudflare, vercel, or rfc2136
+	Provider string `json:"provider"`
+	// The hosted zone ID for Route 53 and the zone ID for Cloudflare. The domain
+	// for Vercel and the zone name for RFC 2136.
+	Zone    string   `json:"zone"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Values  []string `json:"values"`
+	TTL     int      `json:"ttl,omitempty"`
+	Proxied *bool    `json:"proxied,omitempty"`
+	TeamId  string   `json:"teamId,omitempty"`
+	// The address of the RFC 2136 server and the TSIG key to sign updates with
+	Server        string `json:"server,omitempty"`
+	TsigKey       string `json:"tsigKey,omitempty"`
+	TsigSecret    string `json:"tsigSecret,omitempty"`
+	TsigAlgorithm string `json:"tsigAlgorithm,omitempty"`
+}
+
+type DnsRecordOutputs DnsRecordInputs
+
+var dnsProviders = []string{"route53", "cloudflare", "vercel", "rfc2136"}
+
+func (r *DnsRecord) Create(input *DnsRecordInputs, output *CreateResult[DnsRecordOutputs]) error {
+	owner, err := r.owner(input)
+	if err != nil {
+		return err
+	}
+	if err := owner.Create(r.aws.context, input.set()); err != nil {
+		return err
+	}
+	*output = CreateResult[DnsRecordOutputs]{
+		ID:   input.Zone + "/" + input.set().Name + "/" + strings.ToUpper(input.Type),
+		Outs: DnsRecordOutputs(*input),
+	}
+	return nil
+}
+
+func (r *DnsRecord) Update(input *UpdateInput[DnsRecordInputs, DnsRecordOutputs], output *UpdateResult[DnsRecordOutputs]) error {
+	owner, err := r.owner(&input.News)
+	if err != nil {
+		return err
+	}
+	if err := owner.Update(r.aws.context, input.News.set()); err != nil {
+		return err
+	}
+	*output = UpdateResult[DnsRecordOutputs]{
+		Outs: DnsRecordOutputs(input.News),
+	}
+	return nil
+}
+
+func (r *DnsRecord) Delete(input *DeleteInput[DnsRecordOutputs], output *int) error {
+	outs := DnsRecordInputs(input.Outs)
+	owner, err := r.owner(&outs)
+	if err != nil {
+		return err
+	}
+	return owner.Delete(r.aws.context, outs.set())
+}
+
+func (r *DnsRecord) Read(input *ReadInput[DnsRecordOutputs], output *ReadResult[DnsRecordOutputs]) error {
+	props := DnsRecordInputs(input.Props)
+	owner, err := r.owner(&props)
+	if err != nil {
+		return err
+	}
+	set, err := owner.Read(r.aws.context, props.set())
+	if err != nil {
+		return err
+	}
+	if set == nil {
+		*output = ReadResult[DnsRecordOutputs]{}
+		return nil
+	}
+	props.Values = set.Values
+	// a ttl left to the default stays unset, and proxied records always read
+	// back the automatic ttl, so neither shows up as a change
+	if !dnsProxied(props.Proxied) && (props.TTL != 0 || set.TTL != DNS_DEFAULT_TTL) {
+		props.TTL = set.TTL
+	}
+	if props.Proxied != nil {
+		props.Proxied = set.Proxied
+	}
+	*output = ReadResult[DnsRecordOutputs]{ID: input.ID, Props: DnsRecordOutputs(props)}
+	return nil
+}
+
+func (r *DnsRecord) Check(input *CheckInput[DnsRecordInputs], output *CheckResult) error {
+	var checks checkBuilder
+	news := input.News
+	if !slices.Contains(dnsProviders, news.Provider) {
+		checks.fail("provider", fmt.Sprintf("provider needs to be one of %s", strings.Join(dnsProviders, ", ")))
+	}
+	checks.require("zone", news.Zone)
+	checks.require("name", news.Name)
+	checks.require("type", news.Type)
+	if len(news.Values) == 0 {
+		checks.fail("values", "values needs at least one value")
+	}
+	if news.TTL < 0 {
+		checks.fail("ttl", "ttl can't be negative")
+	}
+	if news.Proxied != nil && news.Provider != "cloudflare" {
+		checks.fail("proxied", "proxied is only supported by cloudflare")
+	}
+	if news.Provider == "rfc2136" {
+		checks.require("server", news.Server)
+		if news.TsigKey != "" {
+			checks.require("tsigSecret", news.TsigSecret)
+		}
+	}
+	*output = checks.result()
+	return nil
+}
+
+func (r *DnsRecord) Diff(input *DiffInput[DnsRecordInputs, DnsRecordOutputs], output *DiffResult) error {
+	var changed diffBuilder
+	changed.compare("provider", input.Olds.Provider, input.News.Provider)
+	changed.compare("zone", input.Olds.Zone, input.News.Zone)
+	changed.compare("name", input.Olds.Name, input.News.Name)
+	changed.compare("type", input.Olds.Type, input.News.Type)
+	changed.compare("values", input.Olds.Values, input.News.Values)
+	// cloudflare ignores the ttl of proxied records
+	if !dnsProxied(input.Olds.Proxied) || !dnsProxied(input.News.Proxied) {
+		changed.compare("ttl", input.Olds.TTL, input.News.TTL)
+	}
+	changed.compare("proxied", input.Olds.Proxied, input.News.Proxied)
+	changed.compare("teamId", input.Olds.TeamId, input.News.TeamId)
+	changed.compare("server", input.Olds.Server, input.News.Server)
+	changed.compare("tsigKey", input.Olds.TsigKey, input.News.TsigKey)
+	changed.compare("tsigSecret", input.Olds.TsigSecret, input.News.TsigSecret)
+	changed.compare("tsigAlgorithm", input.Olds.TsigAlgorithm, input.News.TsigAlgorithm)
+	// the ID is made up of the zone, name, and type
+	*output = newDiff(changed, "provider", "zone", "name", "type", "server", "teamId")
+	// the new record can't be created while the old one is still there
+	output.DeleteBeforeReplace = len(output.Replaces) > 0
+	return nil
+}
+
+func (r *DnsRecord) owner(input *DnsRecordInputs) (*dnsOwner, error) {
+	adapter, err := r.adapter(input)
+	if err != nil {
+		return nil, err
+	}
+	app := r.aws.project.App()
+	return newDnsOwner(adapter, app.Name, app.Stage), nil
+}
+
+func (r *DnsRecord) adapter(input *DnsRecordInputs) (dnsAdapter, error) {
+	switch input.Provider {
+	case "route53":
+		cfg, err := r.aws.config()
+		if err != nil {
+			return nil, err
+		}
+		return newRoute53DnsAdapter(r.aws.clients.Route53(cfg), input.Zone), nil
+	case "cloudflare":
+		return newCloudflareDnsAdapter(r.cloudflare, input.Zone)
+	case "vercel":
+		return newVercelDnsAdapter(r.vercel, input.Zone, input.TeamId)
+	case "rfc2136":
+		return newRfc2136DnsAdapter(input.Zone, input.Server, input.TsigKey, input.TsigSecret, input.TsigAlgorithm), nil
+	}
+	return nil, fmt.Errorf("unknown dns provider %q", input.Provider)
+}
+
+func (input *DnsRecordInputs) set() *dnsRecordSet {
+	ttl := input.TTL
+	if ttl == 0 {
+		ttl = DNS_DEFAULT_TTL
+	}
+	return &dnsRecordSet{
+		Name:    normalizeDnsName(input.Name),
+		Type:    strings.ToUpper(input.Type),
+		Values:  input.Values,
+		TTL:     ttl,
+		Proxied: input.Proxied,
+	}
+}
+
+func dnsProxied(proxied *bool) bool {
+	return proxied != nil && *proxied
+}
+
+func normalizeDnsName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// Splits the priority from the value of an MX record, like 10 mail.example.com
+func splitDnsPriority(value string) (*uint16, string) {
+	priority, rest, ok := strings.Cut(value, " ")
+	if !ok {
+		return nil, value
+	}
+	var parsed uint16
+	if _, err := fmt.Sscanf(priority, "%d", &parsed); err != nil {
+		return nil, value
+	}
+	return &parsed, strings.TrimSpace(rest)
+}
@@ -0,0 +1,87 @@
+package resource
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeDnsAdapter struct {
+	records map[string]*dnsRecordSet
+}
+
+func (a *fakeDnsAdapter) Read(ctx context.Context, name string, recordType string) (*dnsRecordSet, error) {
+	return a.records[name+"/"+recordType], nil
+}
+
+func (a *fakeDnsAdapter) Create(ctx context.Context, set *dnsRecordSet) error {
+	a.records[set.Name+"/"+set.Type] = set
+	return nil
+}
+
+func (a *fakeDnsAdapter) Update(ctx context.Context, set *dnsRecordSet) error {
+	return a.Create(ctx, set)
+}
+
+func (a *fakeDnsAdapter) Delete(ctx context.Context, set *dnsRecordSet) error {
+	delete(a.records, set.Name+"/"+set.Type)
+	return nil
+}
+
+func TestDnsOwner(t *testing.T) {
+	ctx := context.Background()
+	adapter := &fakeDnsAdapter{records: map[string]*dnsRecordSet{}}
+	owner := newDnsOwner(adapter, "app", "dev")
+	other := newDnsOwner(adapter, "app", "production")
+	set := &dnsRecordSet{Name: "api.example.com", Type: "A", Values: []string{"1.2.3.4"}, TTL: 60}
+
+	if err := owner.Create(ctx, set); err != nil {
+		t.Fatal(err)
+	}
+	if adapter.records["_sst-owner.a.api.example.com/TXT"] == nil {
+		t.Fatal("expected an owner marker")
+	}
+	if err := other.Create(ctx, set); err == nil {
+		t.Fatal("expected another stage to not take over the record")
+	}
+	if err := other.Update(ctx, set); err == nil {
+		t.Fatal("expected another stage to not update the record")
+	}
+	if err := other.Delete(ctx, set); err != nil || len(adapter.records) != 2 {
+		t.Fatal("expected another stage to skip deleting the record")
+	}
+	if read, _ := other.Read(ctx, set); read != nil {
+		t.Fatal("expected another stage to not read the record")
+	}
+
+	updated := &dnsRecordSet{Name: set.Name, Type: set.Type, Values: []string{"5.6.7.8"}, TTL: 60}
+	if err := owner.Update(ctx, updated); err != nil {
+		t.Fatal(err)
+	}
+	read, err := owner.Read(ctx, set)
+	if err != nil || read == nil || read.Values[0] != "5.6.7.8" {
+		t.Fatalf("unexpected record %+v", read)
+	}
+	if err := owner.Delete(ctx, set); err != nil {
+		t.Fatal(err)
+	}
+	if len(adapter.records) != 0 {
+		t.Fatalf("expected the record and marker to be deleted, got %v", adapter.records)
+	}
+
+	// records that were there before sst are never taken over
+	adapter.records["www.example.com/CNAME"] = &dnsRecordSet{Name: "www.example.com", Type: "CNAME", Values: []string{"example.com"}}
+	if err := owner.Create(ctx, &dnsRecordSet{Name: "www.example.com", Type: "CNAME", Values: []string{"other.com"}}); err == nil {
+		t.Fatal("expected an unowned record to not be overwritten")
+	}
+}
+
+func TestSplitDnsPriority(t *testing.T) {
+	priority, value := splitDnsPriority("10 mail.example.com")
+	if priority == nil || *priority != 10 || value != "mail.example.com" {
+		t.Fatalf("unexpected %v %q", priority, value)
+	}
+	priority, value = splitDnsPriority("mail.example.com")
+	if priority != nil || value != "mail.example.com" {
+		t.Fatalf("unexpected %v %q", priority, value)
+	}
+}
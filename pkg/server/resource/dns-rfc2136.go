@@ -0,0 +1,147 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Manages records with dynamic updates on any server that supports RFC 2136,
+// like BIND, Knot, or PowerDNS
+type rfc2136DnsAdapter struct {
+	zone          string
+	server        string
+	tsigKey       string
+	tsigSecret    string
+	tsigAlgorithm string
+}
+
+func newRfc2136DnsAdapter(zone string, server string, tsigKey string, tsigSecret string, tsigAlgorithm string) *rfc2136DnsAdapter {
+	if !strings.Contains(server, ":") {
+		server = server + ":53"
+	}
+	if tsigAlgorithm == "" {
+		tsigAlgorithm = dns.HmacSHA256
+	}
+	return &rfc2136DnsAdapter{
+		zone:          dns.Fqdn(normalizeDnsName(zone)),
+		server:        server,
+		tsigKey:       dns.Fqdn(tsigKey),
+		tsigSecret:    tsigSecret,
+		tsigAlgorithm: dns.Fqdn(tsigAlgorithm),
+	}
+}
+
+func (a *rfc2136DnsAdapter) exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	client := &dns.Client{Net: "tcp"}
+	if a.tsigSecret != "" {
+		client.TsigSecret = map[string]string{a.tsigKey: a.tsigSecret}
+		msg.SetTsig(a.tsigKey, a.tsigAlgorithm, 300, 0)
+	}
+	result, _, err := client.ExchangeContext(ctx, msg, a.server)
+	if err != nil {
+		return nil, err
+	}
+	if result.Rcode != dns.RcodeSuccess && result.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("dns server %s returned %s", a.server, dns.RcodeToString[result.Rcode])
+	}
+	return result, nil
+}
+
+func (a *rfc2136DnsAdapter) Read(ctx context.Context, name string, recordType string) (*dnsRecordSet, error) {
+	rrtype, ok := dns.StringToType[recordType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported dns record type %s", recordType)
+	}
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), rrtype)
+	msg.RecursionDesired = false
+	result, err := a.exchange(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	set := &dnsRecordSet{
+		Name:   name,
+		Type:   recordType,
+		Values: []string{},
+	}
+	for _, rr := range result.Answer {
+		if rr.Header().Rrtype != rrtype {
+			continue
+		}
+		set.TTL = int(rr.Header().Ttl)
+		set.Values = append(set.Values, rrValue(rr))
+	}
+	if len(set.Values) == 0 {
+		return nil, nil
+	}
+	return set, nil
+}
+
+func (a *rfc2136DnsAdapter) Create(ctx context.Context, set *dnsRecordSet) error {
+	rrs, err := a.records(set)
+	if err != nil {
+		return err
+	}
+	msg := new(dns.Msg)
+	msg.SetUpdate(a.zone)
+	msg.RRsetNotUsed(rrs[:1])
+	msg.Insert(rrs)
+	_, err = a.exchange(ctx, msg)
+	return err
+}
+
+func (a *rfc2136DnsAdapter) Update(ctx context.Context, set *dnsRecordSet) error {
+	rrs, err := a.records(set)
+	if err != nil {
+		return err
+	}
+	msg := new(dns.Msg)
+	msg.SetUpdate(a.zone)
+	msg.RemoveRRset(rrs[:1])
+	msg.Insert(rrs)
+	_, err = a.exchange(ctx, msg)
+	return err
+}
+
+func (a *rfc2136DnsAdapter) Delete(ctx context.Context, set *dnsRecordSet) error {
+	rrs, err := a.records(set)
+	if err != nil {
+		return err
+	}
+	msg := new(dns.Msg)
+	msg.SetUpdate(a.zone)
+	msg.RemoveRRset(rrs[:1])
+	_, err = a.exchange(ctx, msg)
+	return err
+}
+
+func (a *rfc2136DnsAdapter) records(set *dnsRecordSet) ([]dns.RR, error) {
+	if len(set.Values) == 0 {
+		return nil, fmt.Errorf("the %s record for %s has no values", set.Type, set.Name)
+	}
+	result := []dns.RR{}
+	for _, value := range set.Values {
+		if set.Type == "TXT" {
+			value = fmt.Sprintf("%q", value)
+		}
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", dns.Fqdn(set.Name), set.TTL, set.Type, value))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rr)
+	}
+	return result, nil
+}
+
+// The value of a record as it would be written in a zone file, without the
+// quotes around TXT values or the trailing dot of names
+func rrValue(rr dns.RR) string {
+	if txt, ok := rr.(*dns.TXT); ok {
+		return strings.Join(txt.Txt, "")
+	}
+	value := strings.TrimSpace(strings.TrimPrefix(rr.String(), rr.Header().String()))
+	return strings.TrimSuffix(value, ".")
+}
@@ -0,0 +1,134 @@
+package resource
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// A stand-in for a DNS server that accepts signed dynamic updates
+type fakeDnsServer struct {
+	sync.Mutex
+	records []dns.RR
+}
+
+func (s *fakeDnsServer) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	s.Lock()
+	defer s.Unlock()
+	reply := new(dns.Msg)
+	reply.SetReply(req)
+	if req.IsTsig() != nil {
+		if w.TsigStatus() != nil {
+			reply.Rcode = dns.RcodeNotAuth
+			w.WriteMsg(reply)
+			return
+		}
+		reply.SetTsig(req.IsTsig().Hdr.Name, dns.HmacSHA256, 300, time.Now().Unix())
+	}
+	if req.Opcode == dns.OpcodeQuery {
+		question := req.Question[0]
+		for _, rr := range s.records {
+			if rr.Header().Name == question.Name && rr.Header().Rrtype == question.Qtype {
+				reply.Answer = append(reply.Answer, rr)
+			}
+		}
+		w.WriteMsg(reply)
+		return
+	}
+	if req.IsTsig() == nil {
+		reply.Rcode = dns.RcodeRefused
+		w.WriteMsg(reply)
+		return
+	}
+	for _, rr := range req.Answer {
+		if rr.Header().Class == dns.ClassNONE && s.find(rr.Header()) {
+			reply.Rcode = dns.RcodeYXRrset
+			w.WriteMsg(reply)
+			return
+		}
+	}
+	for _, rr := range req.Ns {
+		header := rr.Header()
+		if header.Class == dns.ClassANY {
+			kept := []dns.RR{}
+			for _, existing := range s.records {
+				if existing.Header().Name != header.Name || existing.Header().Rrtype != header.Rrtype {
+					kept = append(kept, existing)
+				}
+			}
+			s.records = kept
+			continue
+		}
+		s.records = append(s.records, rr)
+	}
+	w.WriteMsg(reply)
+}
+
+func (s *fakeDnsServer) find(header *dns.RR_Header) bool {
+	for _, rr := range s.records {
+		if rr.Header().Name == header.Name && rr.Header().Rrtype == header.Rrtype {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRfc2136DnsAdapter(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := "c2VjcmV0LWtleS1mb3ItdGVzdHM="
+	server := &dns.Server{
+		Listener:   listener,
+		Handler:    &fakeDnsServer{},
+		TsigSecret: map[string]string{"sst.": secret},
+		// the default only accepts queries
+		MsgAcceptFunc: func(dh dns.Header) dns.MsgAcceptAction {
+			return dns.MsgAccept
+		},
+	}
+	go server.ActivateAndServe()
+	defer server.Shutdown()
+
+	ctx := context.Background()
+	adapter := newRfc2136DnsAdapter("example.com", listener.Addr().String(), "sst", secret, "")
+	set := &dnsRecordSet{Name: "api.example.com", Type: "TXT", Values: []string{"hello world"}, TTL: 60}
+
+	if err := adapter.Create(ctx, set); err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.Create(ctx, set); err == nil {
+		t.Fatal("expected create to fail when the records exist")
+	}
+	read, err := adapter.Read(ctx, set.Name, set.Type)
+	if err != nil || read == nil || read.Values[0] != "hello world" || read.TTL != 60 {
+		t.Fatalf("unexpected records %+v, %v", read, err)
+	}
+
+	set.Values = []string{"one", "two"}
+	if err := adapter.Update(ctx, set); err != nil {
+		t.Fatal(err)
+	}
+	read, err = adapter.Read(ctx, set.Name, set.Type)
+	if err != nil || read == nil || len(read.Values) != 2 {
+		t.Fatalf("unexpected records %+v, %v", read, err)
+	}
+
+	if err := adapter.Delete(ctx, set); err != nil {
+		t.Fatal(err)
+	}
+	read, err = adapter.Read(ctx, set.Name, set.Type)
+	if err != nil || read != nil {
+		t.Fatalf("expected the records to be deleted, got %+v, %v", read, err)
+	}
+
+	unsigned := newRfc2136DnsAdapter("example.com", listener.Addr().String(), "", "", "")
+	if err := unsigned.Create(ctx, set); err == nil {
+		t.Fatal("expected unsigned updates to be refused")
+	}
+}
@@ -0,0 +1,107 @@
+package resource
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// How long to wait for a record change to reach the Route 53 name servers
+const DNS_CHANGE_TIMEOUT = 5 * time.Minute
+
+type route53DnsAdapter struct {
+	client Route53Client
+	zoneId string
+}
+
+func newRoute53DnsAdapter(client Route53Client, zoneId string) *route53DnsAdapter {
+	return &route53DnsAdapter{
+		client: client,
+		zoneId: strings.TrimPrefix(zoneId, "/hostedzone/"),
+	}
+}
+
+func (a *route53DnsAdapter) Read(ctx context.Context, name string, recordType string) (*dnsRecordSet, error) {
+	result, err := a.client.ListResourceRecordSets(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(a.zoneId),
+		StartRecordName: aws.String(name),
+		StartRecordType: types.RRType(recordType),
+		MaxItems:        aws.Int32(1),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.ResourceRecordSets) == 0 {
+		return nil, nil
+	}
+	match := result.ResourceRecordSets[0]
+	// route 53 escapes the wildcard in the names it returns
+	matchName := normalizeDnsName(strings.ReplaceAll(aws.ToString(match.Name), `\052`, "*"))
+	if matchName != name || string(match.Type) != recordType {
+		return nil, nil
+	}
+	set := &dnsRecordSet{
+		Name:   name,
+		Type:   recordType,
+		Values: []string{},
+		TTL:    int(aws.ToInt64(match.TTL)),
+	}
+	for _, record := range match.ResourceRecords {
+		value := aws.ToString(record.Value)
+		if recordType == "TXT" {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+		}
+		set.Values = append(set.Values, value)
+	}
+	return set, nil
+}
+
+func (a *route53DnsAdapter) Create(ctx context.Context, set *dnsRecordSet) error {
+	return a.change(ctx, types.ChangeActionCreate, set)
+}
+
+func (a *route53DnsAdapter) Update(ctx context.Context, set *dnsRecordSet) error {
+	return a.change(ctx, types.ChangeActionUpsert, set)
+}
+
+func (a *route53DnsAdapter) Delete(ctx context.Context, set *dnsRecordSet) error {
+	return a.change(ctx, types.ChangeActionDelete, set)
+}
+
+func (a *route53DnsAdapter) change(ctx context.Context, action types.ChangeAction, set *dnsRecordSet) error {
+	records := []types.ResourceRecord{}
+	for _, value := range set.Values {
+		if set.Type == "TXT" {
+			value = strconv.Quote(value)
+		}
+		records = append(records, types.ResourceRecord{Value: aws.String(value)})
+	}
+	result, err := a.client.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(a.zoneId),
+		ChangeBatch: &types.ChangeBatch{
+			Changes: []types.Change{
+				{
+					Action: action,
+					ResourceRecordSet: &types.ResourceRecordSet{
+						Name:            aws.String(set.Name),
+						Type:            types.RRType(set.Type),
+						TTL:             aws.Int64(int64(set.TTL)),
+						ResourceRecords: records,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	waiter := route53.NewResourceRecordSetsChangedWaiter(a.client)
+	return waiter.Wait(ctx, &route53.GetChangeInput{Id: result.ChangeInfo.Id}, DNS_CHANGE_TIMEOUT)
+}
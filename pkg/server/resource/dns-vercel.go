@@ -0,0 +1,176 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type vercelDnsAdapter struct {
+	resource *VercelResource
+	token    string
+	domain   string
+	teamId   string
+}
+
+type vercelDnsRecord struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	TTL        int    `json:"ttl"`
+	MxPriority *int   `json:"mxPriority"`
+}
+
+func newVercelDnsAdapter(r *VercelResource, domain string, teamId string) (*vercelDnsAdapter, error) {
+	token := os.Getenv("VERCEL_API_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("VERCEL_API_TOKEN is needed to manage vercel dns records")
+	}
+	return &vercelDnsAdapter{r, token, normalizeDnsName(domain), teamId}, nil
+}
+
+// Vercel names are relative to the domain, with an empty name for the apex
+func (a *vercelDnsAdapter) relative(name string) string {
+	if name == a.domain {
+		return ""
+	}
+	return strings.TrimSuffix(name, "."+a.domain)
+}
+
+func (a *vercelDnsAdapter) request(ctx context.Context, method string, path string, query url.Values, body interface{}) ([]byte, error) {
+	if a.teamId != "" {
+		query.Set("teamId", a.teamId)
+	}
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, a.resource.url+path+"?"+query.Encode(), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	resp, err := a.resource.throttle.do(ctx, &http.Client{}, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("vercel api error, status: %d, response: %s", resp.StatusCode, string(data))
+	}
+	return data, nil
+}
+
+func (a *vercelDnsAdapter) records(ctx context.Context, name string, recordType string) ([]vercelDnsRecord, error) {
+	result := []vercelDnsRecord{}
+	relative := a.relative(name)
+	until := ""
+	for {
+		query := url.Values{}
+		if until != "" {
+			query.Set("until", until)
+		}
+		data, err := a.request(ctx, "GET", "/v4/domains/"+a.domain+"/records", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Records    []vercelDnsRecord `json:"records"`
+			Pagination *struct {
+				Next *int64 `json:"next"`
+			} `json:"pagination"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, err
+		}
+		for _, record := range page.Records {
+			if record.Name == relative && record.Type == recordType {
+				result = append(result, record)
+			}
+		}
+		if page.Pagination == nil || page.Pagination.Next == nil {
+			return result, nil
+		}
+		until = fmt.Sprint(*page.Pagination.Next)
+	}
+}
+
+func (a *vercelDnsAdapter) Read(ctx context.Context, name string, recordType string) (*dnsRecordSet, error) {
+	records, err := a.records(ctx, name, recordType)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	set := &dnsRecordSet{
+		Name:   name,
+		Type:   recordType,
+		Values: []string{},
+		TTL:    records[0].TTL,
+	}
+	for _, record := range records {
+		value := record.Value
+		if record.MxPriority != nil {
+			value = fmt.Sprintf("%d %s", *record.MxPriority, value)
+		}
+		set.Values = append(set.Values, value)
+	}
+	return set, nil
+}
+
+func (a *vercelDnsAdapter) Create(ctx context.Context, set *dnsRecordSet) error {
+	for _, value := range set.Values {
+		body := map[string]interface{}{
+			"name":  a.relative(set.Name),
+			"type":  set.Type,
+			"value": value,
+			"ttl":   set.TTL,
+		}
+		if set.Type == "MX" {
+			priority, rest := splitDnsPriority(value)
+			body["value"] = rest
+			if priority != nil {
+				body["mxPriority"] = *priority
+			}
+		}
+		if _, err := a.request(ctx, "POST", "/v2/domains/"+a.domain+"/records", url.Values{}, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Vercel has a record for each value, so they are all replaced
+func (a *vercelDnsAdapter) Update(ctx context.Context, set *dnsRecordSet) error {
+	if err := a.Delete(ctx, set); err != nil {
+		return err
+	}
+	return a.Create(ctx, set)
+}
+
+func (a *vercelDnsAdapter) Delete(ctx context.Context, set *dnsRecordSet) error {
+	records, err := a.records(ctx, set.Name, set.Type)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if _, err := a.request(ctx, "DELETE", "/v2/domains/"+a.domain+"/records/"+record.Id, url.Values{}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
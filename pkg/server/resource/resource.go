@@ -4,19 +4,55 @@ import (
 	"context"
 	"fmt"
 	"net/rpc"
+	"reflect"
+	"slices"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sst/sst/v3/pkg/flag"
 	"github.com/sst/sst/v3/pkg/project"
-	"github.com/sst/sst/v3/pkg/project/provider"
 )
 
+// Read refreshes the outputs of a resource from the cloud. An empty ID in the
+// result means the resource no longer exists.
 type ReadInput[T any] struct {
-	ID   string `json:"id"`
+	ID    string `json:"id"`
+	Props T      `json:"props"`
 }
 
 type ReadResult[T any] struct {
+	ID    string `json:"id"`
+	Props T      `json:"props"`
+}
+
+// Diff compares the outputs of the last deploy with the new inputs
+type DiffInput[N any, O any] struct {
 	ID   string `json:"id"`
-	Outs T      `json:"outs"`
+	Olds O      `json:"olds"`
+	News N      `json:"news"`
+}
+
+// Changes is left unset when the outputs don't have enough to compare, like
+// ones from an older version, and the engine compares the inputs instead
+type DiffResult struct {
+	Changes             *bool    `json:"changes,omitempty"`
+	Replaces            []string `json:"replaces,omitempty"`
+	DeleteBeforeReplace bool     `json:"deleteBeforeReplace,omitempty"`
+}
+
+// Check validates the inputs before anything is deployed
+type CheckInput[N any] struct {
+	Olds N `json:"olds"`
+	News N `json:"news"`
+}
+
+type CheckResult struct {
+	Failures []CheckFailure `json:"failures,omitempty"`
+}
+
+type CheckFailure struct {
+	Property string `json:"property"`
+	Reason   string `json:"reason"`
 }
 
 type CreateResult[T any] struct {
@@ -39,28 +75,85 @@ type UpdateInput[N any, O any] struct {
 	Olds O      `json:"olds"`
 }
 
+// newDiff returns the result for the properties that changed, replacing the
+// resource if any of them are in replaces
+func newDiff(changed []string, replaces ...string) DiffResult {
+	result := DiffResult{Changes: aws.Bool(len(changed) > 0)}
+	for _, property := range changed {
+		if slices.Contains(replaces, property) {
+			result.Replaces = append(result.Replaces, property)
+		}
+	}
+	return result
+}
+
+type diffBuilder []string
+
+func (d *diffBuilder) compare(property string, old, next interface{}) {
+	if !reflect.DeepEqual(old, next) {
+		*d = append(*d, property)
+	}
+}
+
+type checkBuilder []CheckFailure
+
+func (c *checkBuilder) require(property string, value string) {
+	if strings.TrimSpace(value) == "" {
+		c.fail(property, fmt.Sprintf("%s is required", property))
+	}
+}
+
+func (c *checkBuilder) fail(property string, reason string) {
+	*c = append(*c, CheckFailure{Property: property, Reason: reason})
+}
+
+func (c checkBuilder) result() CheckResult {
+	return CheckResult{Failures: c}
+}
+
 type AwsResource struct {
-	context context.Context
-	project *project.Project
+	context  context.Context
+	project  *project.Project
+	throttle *throttle
+	clients  *AwsClients
 }
 
 func (a *AwsResource) config() (aws.Config, error) {
-	result, ok := a.project.Provider("aws")
-	if !ok {
-		return aws.Config{}, fmt.Errorf("no aws provider found")
+	return a.configWith(a.throttle)
+}
+
+// configWith limits the calls made with the config by the given throttle
+// instead of the one shared by every AWS resource
+func (a *AwsResource) configWith(t *throttle) (aws.Config, error) {
+	cfg, err := a.clients.Config()
+	if err != nil {
+		return aws.Config{}, err
 	}
-	casted := result.(*provider.AwsProvider)
-	return casted.Config(), nil
+	t.apply(&cfg)
+	return cfg, nil
 }
 
 func Register(ctx context.Context, p *project.Project, r *rpc.Server) error {
-	awsResource := &AwsResource{ctx, p}
-	cloudflareResource := &CloudflareResource{ctx, p}
-	vercelResource := &VercelResource{ctx, p}
+	return RegisterWith(ctx, p, r, Clients{})
+}
+
+// RegisterWith registers the resources with the clients swapped out for the
+// ones that are set
+func RegisterWith(ctx context.Context, p *project.Project, r *rpc.Server, clients Clients) error {
+	awsResource := &AwsResource{ctx, p, newThrottle(flag.SST_RESOURCE_CONCURRENCY_AWS, 10), nil}
+	awsResource.clients = clients.Aws.withDefaults(awsResource)
+	if clients.CloudflareUrl == "" {
+		clients.CloudflareUrl = CLOUDFLARE_API_URL
+	}
+	if clients.VercelUrl == "" {
+		clients.VercelUrl = VERCEL_API_URL
+	}
+	cloudflareResource := &CloudflareResource{ctx, p, newThrottle(flag.SST_RESOURCE_CONCURRENCY_CLOUDFLARE, 4), clients.CloudflareUrl}
+	vercelResource := &VercelResource{ctx, p, newThrottle(flag.SST_RESOURCE_CONCURRENCY_VERCEL, 4), clients.VercelUrl}
 	r.RegisterName("Resource.Run", NewRun())
 	
 	// AWS Resources
-	r.RegisterName("Resource.Aws.BucketFiles", &BucketFiles{awsResource})
+	r.RegisterName("Resource.Aws.BucketFiles", &BucketFiles{awsResource, newThrottle(flag.SST_RESOURCE_CONCURRENCY_UPLOAD, 16)})
 	r.RegisterName("Resource.Aws.DistributionDeploymentWaiter", &DistributionDeploymentWaiter{awsResource})
 	r.RegisterName("Resource.Aws.DistributionInvalidation", &DistributionInvalidation{awsResource})
 	r.RegisterName("Resource.Aws.FunctionCodeUpdater", &FunctionCodeUpdater{awsResource})
@@ -71,6 +164,7 @@ func Register(ctx context.Context, p *project.Project, r *rpc.Server) error {
 	r.RegisterName("Resource.Aws.OriginAccessIdentity", &OriginAccessIdentity{awsResource})
 	r.RegisterName("Resource.Aws.OriginAccessControl", &OriginAccessControl{awsResource})
 	r.RegisterName("Resource.Aws.RdsRoleLookup", &RdsRoleLookup{awsResource})
+	r.RegisterName("Resource.Aws.SqlMigrations", &SqlMigrations{awsResource})
 	r.RegisterName("Resource.Aws.VectorTable", &VectorTable{awsResource})
 
 	// Cloudflare Resources
@@ -79,5 +173,8 @@ func Register(ctx context.Context, p *project.Project, r *rpc.Server) error {
 	// Vercel Resources
 	r.RegisterName("Resource.Vercel.DnsRecord", &VercelDnsRecord{vercelResource})
 
+	// Resources that work across providers
+	r.RegisterName("Resource.Dns.Record", &DnsRecord{awsResource, cloudflareResource, vercelResource})
+
 	return nil
 }
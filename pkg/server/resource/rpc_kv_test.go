@@ -0,0 +1,120 @@
+package resource_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore/types"
+	"github.com/sst/sst/v3/pkg/server/resource"
+	"github.com/sst/sst/v3/pkg/server/servertest"
+)
+
+const STORE = "arn:aws:cloudfront::123456789012:key-value-store/test"
+
+func TestRpcKvKeys(t *testing.T) {
+	s := servertest.New(t)
+	s.KeyValueStore.CreateStore(STORE)
+	s.KeyValueStore.Put(STORE, "other:x", "kept")
+
+	large := strings.Repeat("a", 2500)
+	inputs := resource.KvKeysInputs{
+		Store:     STORE,
+		Namespace: "site",
+		Entries:   map[string]string{"small": "1", "large": large},
+		Purge:     true,
+	}
+	created := call[resource.CreateResult[resource.KvKeysOutputs]](t, s, "Resource.Aws.KvKeys.Create", inputs)
+	keys := s.KeyValueStore.Keys(STORE)
+	if keys["site:small"] != "1" || keys["site:large"] != `{"parts":3}` {
+		t.Fatalf("expected the large value to be split, got %v", keys)
+	}
+	for key, value := range keys {
+		if len(value) > servertest.KV_MAX_VALUE_SIZE {
+			t.Fatalf("%s is over the value limit", key)
+		}
+	}
+
+	// the large value shrinks, so its parts are removed, and the write is
+	// retried after someone else changes the store
+	s.KeyValueStore.FailNext("UpdateKeys", &types.ConflictException{Message: aws.String("Pre-Condition failed")})
+	next := inputs
+	next.Entries = map[string]string{"large": "2"}
+	updated := call[resource.UpdateResult[resource.KvKeysOutputs]](t, s, "Resource.Aws.KvKeys.Update", resource.UpdateInput[resource.KvKeysInputs, resource.KvKeysOutputs]{
+		ID: created.ID, News: next, Olds: created.Outs,
+	})
+	keys = s.KeyValueStore.Keys(STORE)
+	if len(keys) != 2 || keys["site:large"] != "2" || keys["other:x"] != "kept" {
+		t.Fatalf("expected only the new entry and the other namespace, got %v", keys)
+	}
+
+	s.KeyValueStore.Put(STORE, "site:large", "changed")
+	read := call[resource.ReadResult[resource.KvKeysOutputs]](t, s, "Resource.Aws.KvKeys.Read", resource.ReadInput[resource.KvKeysOutputs]{
+		ID: created.ID, Props: updated.Outs,
+	})
+	if read.Props.Entries["large"] != "changed" {
+		t.Fatalf("expected the change to be read, got %v", read.Props.Entries)
+	}
+
+	// more entries than fit in one write
+	many := map[string]string{}
+	for i := 0; i < 120; i++ {
+		many[fmt.Sprint(i)] = "v"
+	}
+	next.Entries = many
+	s.Recorder.Reset()
+	updated = call[resource.UpdateResult[resource.KvKeysOutputs]](t, s, "Resource.Aws.KvKeys.Update", resource.UpdateInput[resource.KvKeysInputs, resource.KvKeysOutputs]{
+		ID: created.ID, News: next, Olds: read.Props,
+	})
+	if count := len(s.KeyValueStore.Keys(STORE)); count != 121 {
+		t.Fatalf("expected 120 entries and the other namespace, got %d", count)
+	}
+	if count := s.Recorder.Count("KeyValueStore.UpdateKeys"); count < 3 {
+		t.Fatalf("expected the entries to be written in batches, got %d writes", count)
+	}
+
+	call[int](t, s, "Resource.Aws.KvKeys.Delete", resource.DeleteInput[resource.KvKeysOutputs]{ID: created.ID, Outs: updated.Outs})
+	if keys := s.KeyValueStore.Keys(STORE); len(keys) != 1 {
+		t.Fatalf("expected only the other namespace to be left, got %v", keys)
+	}
+}
+
+func TestRpcKvRoutesUpdate(t *testing.T) {
+	s := servertest.New(t)
+	s.KeyValueStore.CreateStore(STORE)
+	route := func(entry string) resource.KvRoutesUpdateInputs {
+		return resource.KvRoutesUpdateInputs{Store: STORE, Namespace: "router", Key: "routes", Entry: entry}
+	}
+	routes := func() []string {
+		var result []string
+		json.Unmarshal([]byte(s.KeyValueStore.Keys(STORE)["router:routes"]), &result)
+		return result
+	}
+
+	first := call[resource.CreateResult[resource.KvRoutesUpdateOutputs]](t, s, "Resource.Aws.KvRoutesUpdate.Create", route("url,/a"))
+	s.KeyValueStore.FailNext("UpdateKeys", &types.ConflictException{Message: aws.String("Pre-Condition failed")})
+	second := call[resource.CreateResult[resource.KvRoutesUpdateOutputs]](t, s, "Resource.Aws.KvRoutesUpdate.Create", route("url,/b"))
+	if result := routes(); len(result) != 2 {
+		t.Fatalf("expected both routes after the conflict, got %v", result)
+	}
+
+	// enough routes that they don't fit in one value
+	created := []resource.KvRoutesUpdateOutputs{first.Outs, second.Outs}
+	for i := 0; i < 60; i++ {
+		result := call[resource.CreateResult[resource.KvRoutesUpdateOutputs]](t, s, "Resource.Aws.KvRoutesUpdate.Create", route(fmt.Sprintf("url,/%s/%d", strings.Repeat("x", 20), i)))
+		created = append(created, result.Outs)
+	}
+	keys := s.KeyValueStore.Keys(STORE)
+	if !strings.HasPrefix(keys["router:routes"], `{"parts":`) {
+		t.Fatalf("expected the routes to be split, got %s", keys["router:routes"])
+	}
+
+	for _, outs := range created {
+		call[int](t, s, "Resource.Aws.KvRoutesUpdate.Delete", resource.DeleteInput[resource.KvRoutesUpdateOutputs]{ID: first.ID, Outs: outs})
+	}
+	if keys := s.KeyValueStore.Keys(STORE); len(keys) != 0 {
+		t.Fatalf("expected the store to be empty, got %v", keys)
+	}
+}
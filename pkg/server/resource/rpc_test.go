@@ -0,0 +1,568 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	r53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/sst/sst/v3/pkg/server/resource"
+	"github.com/sst/sst/v3/pkg/server/servertest"
+)
+
+// Makes the call and fails the test if it errors
+func call[T any](t *testing.T, s *servertest.Server, method string, args interface{}) T {
+	t.Helper()
+	var result T
+	if err := s.Call(method, args, &result); err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	return result
+}
+
+func TestRpcBucketFiles(t *testing.T) {
+	s := servertest.New(t)
+	s.S3.CreateBucket("assets")
+	dir := t.TempDir()
+	file := func(key string, contents string) resource.BucketFile {
+		source := filepath.Join(dir, key)
+		os.WriteFile(source, []byte(contents), 0644)
+		return resource.BucketFile{Source: source, Key: key, ContentType: "text/plain", Hash: aws.String(contents)}
+	}
+
+	inputs := resource.BucketFilesInputs{
+		BucketName: "assets",
+		Files:      []resource.BucketFile{file("a.txt", "a"), file("b.txt", "b")},
+		Purge:      true,
+	}
+	created := call[resource.CreateResult[resource.BucketFilesOutputs]](t, s, "Resource.Aws.BucketFiles.Create", inputs)
+	if keys := s.S3.Keys("assets"); !slices.Equal(keys, []string{"a.txt", "b.txt"}) {
+		t.Fatalf("expected both files to be uploaded, got %v", keys)
+	}
+	if object, _ := s.S3.Object("assets", "a.txt"); string(object.Body) != "a" || object.ContentType != "text/plain" {
+		t.Fatalf("unexpected object %+v", object)
+	}
+
+	// only the changed file is uploaded again and the removed one is purged
+	s.Recorder.Reset()
+	next := inputs
+	next.Files = []resource.BucketFile{file("b.txt", "b2")}
+	updated := call[resource.UpdateResult[resource.BucketFilesOutputs]](t, s, "Resource.Aws.BucketFiles.Update", resource.UpdateInput[resource.BucketFilesInputs, resource.BucketFilesOutputs]{
+		ID: created.ID, News: next, Olds: created.Outs,
+	})
+	if count := s.Recorder.Count("S3.PutObject"); count != 1 {
+		t.Fatalf("expected 1 upload, got %d", count)
+	}
+	if keys := s.S3.Keys("assets"); !slices.Equal(keys, []string{"b.txt"}) {
+		t.Fatalf("expected a.txt to be purged, got %v", keys)
+	}
+
+	s.S3.DeleteObject("assets", "b.txt")
+	read := call[resource.ReadResult[resource.BucketFilesOutputs]](t, s, "Resource.Aws.BucketFiles.Read", resource.ReadInput[resource.BucketFilesOutputs]{
+		ID: created.ID, Props: updated.Outs,
+	})
+	if len(read.Props.Files) != 0 {
+		t.Fatalf("expected the deleted file to be dropped, got %v", read.Props.Files)
+	}
+
+	s.S3.CreateBucket("assets")
+	call[int](t, s, "Resource.Aws.BucketFiles.Delete", resource.DeleteInput[resource.BucketFilesOutputs]{ID: created.ID, Outs: updated.Outs})
+	expected := []string{"Resource.Aws.BucketFiles.Update", "Resource.Aws.BucketFiles.Read", "Resource.Aws.BucketFiles.Delete"}
+	if names := s.Recorder.Names("Resource."); !slices.Equal(names, expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestRpcDistributionInvalidation(t *testing.T) {
+	s := servertest.New(t)
+	s.CloudFront.SetDistribution("E1", "Deployed")
+
+	// more wildcards than fit in one invalidation
+	paths := []string{}
+	for i := 0; i < resource.WILDCARD_LIMIT+1; i++ {
+		paths = append(paths, "/"+string(rune('a'+i))+"/*")
+	}
+	inputs := resource.DistributionInvalidationInputs{DistributionId: "E1", Paths: paths, Version: "1"}
+	s.CloudFront.FailNext("CreateInvalidation", nil, errors.New("throttled"))
+	if err := s.Call("Resource.Aws.DistributionInvalidation.Create", inputs, nil); err == nil || !strings.Contains(err.Error(), "1 of 2 batches") {
+		t.Fatalf("expected the failed batch to fail the deploy, got %v", err)
+	}
+
+	// the retry only submits the batch that failed
+	created := call[resource.CreateResult[resource.DistributionInvalidationOutputs]](t, s, "Resource.Aws.DistributionInvalidation.Create", inputs)
+	batches := s.CloudFront.Invalidations("E1")
+	if len(batches) != 2 {
+		t.Fatalf("expected 2 submitted batches, got %d", len(batches))
+	}
+	if len(batches[1]) != 1 || batches[1][0] != paths[len(paths)-1] {
+		t.Fatalf("expected the retry to submit the missing batch, got %v", batches[1])
+	}
+	if len(created.Outs.Invalidations) != 2 {
+		t.Fatalf("expected 2 invalidations, got %+v", created.Outs)
+	}
+	// the progress is cleared once every batch is in, so the next deploy of
+	// the same version starts over
+	call[resource.CreateResult[resource.DistributionInvalidationOutputs]](t, s, "Resource.Aws.DistributionInvalidation.Create", inputs)
+	if batches := s.CloudFront.Invalidations("E1"); len(batches) != 4 {
+		t.Fatalf("expected every batch to be submitted again, got %d", len(batches))
+	}
+
+	// collapsing the directory into a wildcard would take a second batch
+	paths = paths[:resource.WILDCARD_LIMIT]
+	for i := 0; i < resource.INVALIDATION_COLLAPSE_THRESHOLD; i++ {
+		paths = append(paths, fmt.Sprintf("/images/%d.png", i))
+	}
+	updated := call[resource.UpdateResult[resource.DistributionInvalidationOutputs]](t, s, "Resource.Aws.DistributionInvalidation.Update", resource.UpdateInput[resource.DistributionInvalidationInputs, resource.DistributionInvalidationOutputs]{
+		ID: created.ID, News: resource.DistributionInvalidationInputs{DistributionId: "E1", Paths: paths, Version: "2"}, Olds: created.Outs,
+	})
+	if len(updated.Outs.Invalidations) != 1 {
+		t.Fatalf("expected a single invalidation, got %+v", updated.Outs)
+	}
+	batches = s.CloudFront.Invalidations("E1")
+	if last := batches[len(batches)-1]; len(last) != len(paths) {
+		t.Fatalf("expected the files to be invalidated one by one, got %v", last)
+	}
+}
+
+func TestRpcDistributionDeploymentWaiter(t *testing.T) {
+	s := servertest.New(t)
+	s.CloudFront.SetDistribution("E1", "Deployed")
+	created := call[resource.CreateResult[resource.DistributionDeploymentWaiterOutputs]](t, s, "Resource.Aws.DistributionDeploymentWaiter.Create", resource.DistributionDeploymentWaiterInputs{
+		DistributionId: "E1", Wait: true,
+	})
+	if !created.Outs.IsDone || s.Recorder.Count("CloudFront.GetDistribution") != 1 {
+		t.Fatalf("expected a single check of the deployed distribution, got %+v", created.Outs)
+	}
+
+	err := s.Call("Resource.Aws.DistributionDeploymentWaiter.Create", resource.DistributionDeploymentWaiterInputs{DistributionId: "missing", Wait: true}, nil)
+	if err == nil {
+		t.Fatal("expected a missing distribution to fail")
+	}
+}
+
+func TestRpcFunctionCodeUpdater(t *testing.T) {
+	s := servertest.New(t)
+	s.Lambda.CreateFunction("fn", nil)
+
+	inputs := resource.FunctionCodeUpdaterInputs{FunctionName: "fn", S3Bucket: "code", S3Key: "v1.zip", Region: "us-east-1", Alias: "live"}
+	created := call[resource.CreateResult[resource.FunctionCodeUpdaterOutputs]](t, s, "Resource.Aws.FunctionCodeUpdater.Create", inputs)
+	if alias, _ := s.Lambda.Alias("fn", "live"); created.Outs.Version != "1" || alias.Version != "1" {
+		t.Fatalf("expected the alias to point to version 1, got %+v", alias)
+	}
+	if created.Outs.AliasArn != "arn:aws:lambda:us-east-1:123456789012:function:fn:live" {
+		t.Fatalf("expected the alias ARN in the outputs, got %q", created.Outs.AliasArn)
+	}
+
+	s.Recorder.Reset()
+	next := inputs
+	next.S3Key = "v2.zip"
+	next.Deployment = &resource.FunctionDeployment{Strategy: resource.FunctionDeploymentCanary, Percent: 10}
+	updated := call[resource.UpdateResult[resource.FunctionCodeUpdaterOutputs]](t, s, "Resource.Aws.FunctionCodeUpdater.Update", resource.UpdateInput[resource.FunctionCodeUpdaterInputs, resource.FunctionCodeUpdaterOutputs]{
+		ID: created.ID, News: next, Olds: created.Outs,
+	})
+	alias, _ := s.Lambda.Alias("fn", "live")
+	if updated.Outs.Version != "2" || alias.Version != "2" || len(alias.Weights) != 0 {
+		t.Fatalf("expected all the traffic on version 2, got %+v", alias)
+	}
+	// the canary step and then the rest of the traffic
+	if count := s.Recorder.Count("Lambda.UpdateAlias"); count != 2 {
+		t.Fatalf("expected 2 alias updates, got %d", count)
+	}
+
+	// an alarm going off during the canary moves the traffic back
+	s.CloudWatch.SetAlarm("errors", cwtypes.StateValueAlarm)
+	broken := next
+	broken.S3Key = "v3.zip"
+	broken.Deployment = &resource.FunctionDeployment{Strategy: resource.FunctionDeploymentCanary, Percent: 10, Alarms: []string{"errors"}}
+	err := s.Call("Resource.Aws.FunctionCodeUpdater.Update", resource.UpdateInput[resource.FunctionCodeUpdaterInputs, resource.FunctionCodeUpdaterOutputs]{
+		ID: created.ID, News: broken, Olds: updated.Outs,
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "errors alarm") {
+		t.Fatalf("expected the deploy to be rolled back, got %v", err)
+	}
+	if alias, _ := s.Lambda.Alias("fn", "live"); alias.Version != "2" || len(alias.Weights) != 0 {
+		t.Fatalf("expected all the traffic back on version 2, got %+v", alias)
+	}
+	// calls that don't go through the alias get the previous code too
+	if code, _ := s.Lambda.Invoke("fn", ""); code.S3Key != "v2.zip" {
+		t.Fatalf("expected an unqualified invoke to run v2.zip after the rollback, got %+v", code)
+	}
+
+	s.Lambda.DeleteFunction("fn")
+	read := call[resource.ReadResult[resource.FunctionCodeUpdaterOutputs]](t, s, "Resource.Aws.FunctionCodeUpdater.Read", resource.ReadInput[resource.FunctionCodeUpdaterOutputs]{
+		ID: created.ID, Props: updated.Outs,
+	})
+	if read.ID != "" {
+		t.Fatal("expected a deleted function to be removed from the state")
+	}
+}
+
+func TestRpcFunctionEnvironmentUpdate(t *testing.T) {
+	s := servertest.New(t)
+	s.Lambda.CreateFunction("fn", map[string]string{"EXISTING": "1"})
+
+	inputs := resource.FunctionEnvironmentUpdateInputs{FunctionName: "fn", Environment: map[string]string{"SST_KEY": "a"}}
+	created := call[resource.CreateResult[resource.FunctionEnvironmentUpdateOutputs]](t, s, "Resource.Aws.FunctionEnvironmentUpdate.Create", inputs)
+	function, _ := s.Lambda.Function("fn")
+	if function.Environment["EXISTING"] != "1" || function.Environment["SST_KEY"] != "a" {
+		t.Fatalf("expected the variables to be merged, got %v", function.Environment)
+	}
+
+	read := call[resource.ReadResult[resource.FunctionEnvironmentUpdateOutputs]](t, s, "Resource.Aws.FunctionEnvironmentUpdate.Read", resource.ReadInput[resource.FunctionEnvironmentUpdateOutputs]{
+		ID: created.ID, Props: created.Outs,
+	})
+	if len(read.Props.Environment) != 1 || read.Props.Environment["SST_KEY"] != "a" {
+		t.Fatalf("expected only the managed variables to be read, got %v", read.Props.Environment)
+	}
+
+	check := call[resource.CheckResult](t, s, "Resource.Aws.FunctionEnvironmentUpdate.Check", resource.CheckInput[resource.FunctionEnvironmentUpdateInputs]{
+		News: resource.FunctionEnvironmentUpdateInputs{FunctionName: "fn", Environment: map[string]string{"1BAD": "x"}},
+	})
+	if len(check.Failures) != 1 {
+		t.Fatalf("expected the invalid key to fail, got %v", check.Failures)
+	}
+}
+
+func TestRpcOriginAccess(t *testing.T) {
+	s := servertest.New(t)
+	for _, kind := range []string{"OriginAccessIdentity", "OriginAccessControl"} {
+		created := call[resource.CreateResult[struct{}]](t, s, "Resource.Aws."+kind+".Create", map[string]string{"name": "site"})
+		read := call[resource.ReadResult[struct{}]](t, s, "Resource.Aws."+kind+".Read", resource.ReadInput[struct{}]{ID: created.ID})
+		if read.ID != created.ID {
+			t.Fatalf("%s: expected to read %s, got %s", kind, created.ID, read.ID)
+		}
+		call[int](t, s, "Resource.Aws."+kind+".Delete", resource.DeleteInput[struct{}]{ID: created.ID})
+		read = call[resource.ReadResult[struct{}]](t, s, "Resource.Aws."+kind+".Read", resource.ReadInput[struct{}]{ID: created.ID})
+		if read.ID != "" {
+			t.Fatalf("%s: expected it to be gone after the delete", kind)
+		}
+	}
+	if len(s.CloudFront.OriginAccessIdentities()) != 0 || len(s.CloudFront.OriginAccessControls()) != 0 {
+		t.Fatal("expected nothing to be left behind")
+	}
+}
+
+func TestRpcRun(t *testing.T) {
+	s := servertest.New(t)
+	dir := t.TempDir()
+	call[resource.CreateResult[resource.RunOutputs]](t, s, "Resource.Run.Create", resource.RunInputs{
+		Command: "echo $NAME > out.txt",
+		Cwd:     dir,
+		Env:     map[string]string{"NAME": "sst"},
+	})
+	if data, _ := os.ReadFile(filepath.Join(dir, "out.txt")); string(data) != "sst\n" {
+		t.Fatalf("expected the command to run in the directory, got %q", data)
+	}
+	if err := s.Call("Resource.Run.Create", resource.RunInputs{Command: "exit 1", Cwd: dir}, nil); err == nil {
+		t.Fatal("expected a failing command to fail")
+	}
+}
+
+func TestRpcVectorTable(t *testing.T) {
+	s := servertest.New(t)
+	inputs := resource.VectorTableInputs{ClusterArn: "cluster", SecretArn: "secret", DatabaseName: "db", TableName: "embeddings", Dimension: 3}
+	created := call[resource.CreateResult[resource.VectorTableOutputs]](t, s, "Resource.Aws.VectorTable.Create", inputs)
+	if relations := s.RdsData.Relations("db"); !slices.Contains(relations, "embeddings_embedding_idx") {
+		t.Fatalf("expected the embedding index, got %v", relations)
+	}
+
+	// nothing changed, so the index that's already there is kept
+	updated := call[resource.UpdateResult[resource.VectorTableOutputs]](t, s, "Resource.Aws.VectorTable.Update", resource.UpdateInput[resource.VectorTableInputs, resource.VectorTableOutputs]{
+		ID: created.ID, News: inputs, Olds: created.Outs,
+	})
+	if updated.Outs.SchemaVersion != 1 {
+		t.Fatalf("expected the schema version to stay at 1, got %d", updated.Outs.SchemaVersion)
+	}
+	if !slices.ContainsFunc(s.RdsData.Statements(), func(sql string) bool {
+		return strings.HasPrefix(sql, "create index if not exists embeddings_embedding_idx")
+	}) {
+		t.Fatal("expected the embedding index to only be created if it doesn't exist")
+	}
+	if relations := s.RdsData.Relations("db"); !slices.Contains(relations, "embeddings_metadata_idx") || slices.Contains(relations, "embeddings_idx1") {
+		t.Fatalf("expected a single metadata index, got %v", relations)
+	}
+
+	// 3 to 4 and back to 3 keeps each set of embeddings in its own column
+	outs := updated.Outs
+	for _, dimension := range []int{4, 3} {
+		next := inputs
+		next.Dimension = dimension
+		next.Migration = resource.VectorMigrationColumn
+		outs = call[resource.UpdateResult[resource.VectorTableOutputs]](t, s, "Resource.Aws.VectorTable.Update", resource.UpdateInput[resource.VectorTableInputs, resource.VectorTableOutputs]{
+			ID: created.ID, News: next, Olds: outs,
+		}).Outs
+	}
+	expected := []string{"embedding", "embedding_3", "embedding_4", "id", "metadata"}
+	if columns := s.RdsData.Columns("db", "embeddings"); !slices.Equal(columns, expected) {
+		t.Fatalf("expected %v, got %v", expected, columns)
+	}
+
+	// going to 4 again would overwrite the embedding_3 column
+	next := inputs
+	next.Dimension = 4
+	next.Migration = resource.VectorMigrationColumn
+	err := s.Call("Resource.Aws.VectorTable.Update", resource.UpdateInput[resource.VectorTableInputs, resource.VectorTableOutputs]{
+		ID: created.ID, News: next, Olds: outs,
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "already has an embedding_3 column") {
+		t.Fatalf("expected the existing column to be reported, got %v", err)
+	}
+	if columns := s.RdsData.Columns("db", "embeddings"); !slices.Equal(columns, expected) {
+		t.Fatalf("expected the migration to be rolled back, got %v", columns)
+	}
+	if relations := s.RdsData.Relations("db"); !slices.Contains(relations, "embeddings_embedding_idx") {
+		t.Fatalf("expected the embedding index to be kept, got %v", relations)
+	}
+}
+
+func TestRpcCheckAndDiff(t *testing.T) {
+	s := servertest.New(t)
+	migrations := t.TempDir()
+	os.WriteFile(filepath.Join(migrations, "0001_init.sql"), []byte("CREATE TABLE a (id int);"), 0644)
+
+	checks := []struct {
+		method   string
+		news     interface{}
+		property string
+	}{
+		{"Resource.Aws.HostedZoneLookup.Check", resource.HostedZoneLookupInputs{}, "domain"},
+		{"Resource.Aws.RdsRoleLookup.Check", resource.RdsRoleLookupInputs{}, "name"},
+		{"Resource.Aws.VectorTable.Check", resource.VectorTableInputs{ClusterArn: "arn", SecretArn: "arn", DatabaseName: "db", TableName: "1bad", Dimension: 3}, "tableName"},
+		{"Resource.Aws.SqlMigrations.Check", resource.SqlMigrationsInputs{ClusterArn: "arn", SecretArn: "arn", DatabaseName: "db", Directory: filepath.Join(migrations, "missing")}, "directory"},
+		{"Resource.Cloudflare.DnsRecord.Check", resource.CloudflareDnsRecordInputs{ZoneId: "zone", Type: "A", Name: "example.com"}, "value"},
+		{"Resource.Vercel.DnsRecord.Check", resource.VercelDnsRecordInputs{Domain: "example.com", Type: "A"}, "value"},
+		{"Resource.Dns.Record.Check", resource.DnsRecordInputs{Provider: "route53", Zone: "Z1", Name: "example.com", Type: "A"}, "values"},
+	}
+	for _, check := range checks {
+		result := call[resource.CheckResult](t, s, check.method, map[string]interface{}{"news": check.news})
+		if len(result.Failures) != 1 || result.Failures[0].Property != check.property {
+			t.Fatalf("%s: expected %s to fail, got %v", check.method, check.property, result.Failures)
+		}
+	}
+
+	diffs := []struct {
+		method   string
+		olds     interface{}
+		news     interface{}
+		replaces []string
+	}{
+		{"Resource.Aws.HostedZoneLookup.Diff", resource.HostedZoneLookupOutputs{Domain: "a.com"}, resource.HostedZoneLookupInputs{Domain: "b.com"}, []string{"domain"}},
+		{"Resource.Aws.VectorTable.Diff", resource.VectorTableOutputs{ClusterArn: "arn", TableName: "a", Dimension: 3}, resource.VectorTableInputs{ClusterArn: "arn", TableName: "a", Dimension: 3, Index: &resource.VectorIndex{Type: resource.VectorIndexHnsw}}, nil},
+		{"Resource.Aws.SqlMigrations.Diff", resource.SqlMigrationsOutputs{DatabaseName: "db", Directory: migrations}, resource.SqlMigrationsInputs{DatabaseName: "db", Directory: migrations}, nil},
+		{"Resource.Vercel.DnsRecord.Diff", resource.VercelDnsRecordOutputs{Domain: "example.com", Type: "A", Value: "1.1.1.1"}, resource.VercelDnsRecordInputs{Domain: "example.com", Type: "CNAME", Value: "a.com"}, []string{"type"}},
+		{"Resource.Cloudflare.DnsRecord.Diff", resource.CloudflareDnsRecordOutputs{ZoneId: "zone", Type: "A", Name: "a", Value: aws.String("1.1.1.1")}, resource.CloudflareDnsRecordInputs{ZoneId: "zone", Type: "A", Name: "a", Value: aws.String("2.2.2.2")}, nil},
+		{"Resource.Aws.OriginAccessControl.Diff", resource.OriginAccessControlOutputs{Name: "a"}, resource.OriginAccessControlInputs{Name: "b"}, []string{"name"}},
+		{"Resource.Dns.Record.Diff", resource.DnsRecordOutputs{Provider: "route53", Zone: "Z1", Name: "a", Type: "A", Values: []string{"1.1.1.1"}}, resource.DnsRecordInputs{Provider: "route53", Zone: "Z2", Name: "a", Type: "A", Values: []string{"1.1.1.1"}}, []string{"zone"}},
+	}
+	for _, diff := range diffs {
+		result := call[resource.DiffResult](t, s, diff.method, map[string]interface{}{"olds": diff.olds, "news": diff.news})
+		if result.Changes == nil || !*result.Changes || !slices.Equal(result.Replaces, diff.replaces) {
+			t.Fatalf("%s: expected a change replacing %v, got %+v", diff.method, diff.replaces, result)
+		}
+	}
+}
+
+func TestRpcHostedZoneLookup(t *testing.T) {
+	s := servertest.New(t)
+	s.Route53.CreateZone("Z1", "example.com")
+	s.Route53.CreateZone("Z2", "sub.example.com")
+
+	// the closest zone to the domain wins
+	created := call[resource.CreateResult[resource.HostedZoneLookupOutputs]](t, s, "Resource.Aws.HostedZoneLookup.Create", resource.HostedZoneLookupInputs{Domain: "api.sub.example.com"})
+	if created.ID != "Z2" || created.Outs.ZoneId != "Z2" {
+		t.Fatalf("expected zone Z2, got %+v", created)
+	}
+	if err := s.Call("Resource.Aws.HostedZoneLookup.Create", resource.HostedZoneLookupInputs{Domain: "example.org"}, nil); err == nil {
+		t.Fatal("expected a domain without a zone to fail")
+	}
+
+	read := call[resource.ReadResult[resource.HostedZoneLookupOutputs]](t, s, "Resource.Aws.HostedZoneLookup.Read", resource.ReadInput[resource.HostedZoneLookupOutputs]{
+		ID: "Z3", Props: resource.HostedZoneLookupOutputs{ZoneId: "Z3", Domain: "example.net"},
+	})
+	if read.ID != "" {
+		t.Fatal("expected a deleted zone to be removed from the state")
+	}
+}
+
+func TestRpcRdsRoleLookup(t *testing.T) {
+	s := servertest.New(t)
+	s.Iam.CreateRole("AWSServiceRoleForRDS")
+
+	created := call[resource.CreateResult[resource.RdsRoleLookupOutputs]](t, s, "Resource.Aws.RdsRoleLookup.Create", resource.RdsRoleLookupInputs{Name: "AWSServiceRoleForRDS"})
+	if created.Outs.Name != "AWSServiceRoleForRDS" {
+		t.Fatalf("unexpected outputs %+v", created.Outs)
+	}
+
+	s.Iam.DeleteRole("AWSServiceRoleForRDS")
+	read := call[resource.ReadResult[resource.RdsRoleLookupOutputs]](t, s, "Resource.Aws.RdsRoleLookup.Read", resource.ReadInput[resource.RdsRoleLookupOutputs]{
+		ID: created.ID, Props: created.Outs,
+	})
+	if read.ID != "" {
+		t.Fatal("expected a deleted role to be removed from the state")
+	}
+}
+
+func TestRpcSqlMigrations(t *testing.T) {
+	s := servertest.New(t)
+	s.RdsData.CreateDatabase("db")
+	migrations := filepath.Join(s.Root, "migrations")
+	os.MkdirAll(migrations, 0755)
+	os.WriteFile(filepath.Join(migrations, "0001_users.sql"), []byte("CREATE TABLE users (id serial primary key, email text);"), 0644)
+
+	// the directory is relative to the root of the project
+	inputs := resource.SqlMigrationsInputs{ClusterArn: "cluster", SecretArn: "secret", DatabaseName: "db", Directory: "migrations"}
+	created := call[resource.CreateResult[resource.SqlMigrationsOutputs]](t, s, "Resource.Aws.SqlMigrations.Create", inputs)
+	if len(created.Outs.Applied) != 1 || created.Outs.Applied[0].Name != "0001_users.sql" {
+		t.Fatalf("expected the first migration to be applied, got %+v", created.Outs.Applied)
+	}
+
+	// a failed migration is rolled back along with its record
+	os.WriteFile(filepath.Join(migrations, "0002_posts.sql"), []byte("CREATE TABLE posts (id int);\nALTER TABLE missing ADD COLUMN title text;"), 0644)
+	err := s.Call("Resource.Aws.SqlMigrations.Update", resource.UpdateInput[resource.SqlMigrationsInputs, resource.SqlMigrationsOutputs]{
+		ID: created.ID, News: inputs, Olds: created.Outs,
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "0002_posts.sql") {
+		t.Fatalf("expected the second migration to fail, got %v", err)
+	}
+	if relations := s.RdsData.Relations("db"); slices.Contains(relations, "posts") {
+		t.Fatalf("expected the posts table to be rolled back, got %v", relations)
+	}
+
+	read := call[resource.ReadResult[resource.SqlMigrationsOutputs]](t, s, "Resource.Aws.SqlMigrations.Read", resource.ReadInput[resource.SqlMigrationsOutputs]{
+		ID: created.ID, Props: created.Outs,
+	})
+	if len(read.Props.Applied) != 1 || read.Props.Applied[0] != created.Outs.Applied[0] {
+		t.Fatalf("expected only the first migration to be recorded, got %+v", read.Props.Applied)
+	}
+}
+
+func TestRpcCloudflareDnsRecord(t *testing.T) {
+	s := servertest.New(t)
+	s.Cloudflare.CreateZone("zone")
+
+	inputs := resource.CloudflareDnsRecordInputs{ZoneId: "zone", Type: "CNAME", Name: "www.example.com", Value: aws.String("example.com"), ApiToken: "token"}
+	created := call[resource.CreateResult[resource.CloudflareDnsRecordOutputs]](t, s, "Resource.Cloudflare.DnsRecord.Create", inputs)
+	if records := s.Cloudflare.Records("zone"); len(records) != 1 || records[0].ID != created.ID {
+		t.Fatalf("expected the record to be created, got %+v", records)
+	}
+
+	// a record that's already there isn't tracked
+	again := call[resource.CreateResult[resource.CloudflareDnsRecordOutputs]](t, s, "Resource.Cloudflare.DnsRecord.Create", inputs)
+	if again.ID != "existing-record" {
+		t.Fatalf("expected the existing record to be reported, got %q", again.ID)
+	}
+}
+
+func TestRpcVercelDnsRecord(t *testing.T) {
+	s := servertest.New(t)
+	t.Setenv("VERCEL_API_TOKEN", "token")
+	s.Vercel.CreateDomain("example.com")
+
+	inputs := resource.VercelDnsRecordInputs{Domain: "example.com", Type: "CNAME", Name: "www", Value: "cname.vercel-dns.com", ApiToken: "token"}
+	created := call[resource.CreateResult[resource.VercelDnsRecordOutputs]](t, s, "Resource.Vercel.DnsRecord.Create", inputs)
+	if records := s.Vercel.Records("example.com"); len(records) != 1 || records[0].Id != created.ID {
+		t.Fatalf("expected the record to be created, got %+v", records)
+	}
+	again := call[resource.CreateResult[resource.VercelDnsRecordOutputs]](t, s, "Resource.Vercel.DnsRecord.Create", inputs)
+	if again.ID != "existing-record" {
+		t.Fatalf("expected the existing record to be reported, got %q", again.ID)
+	}
+
+	read := call[resource.ReadResult[resource.VercelDnsRecordOutputs]](t, s, "Resource.Vercel.DnsRecord.Read", resource.ReadInput[resource.VercelDnsRecordOutputs]{
+		ID: created.ID, Props: created.Outs,
+	})
+	if read.ID != created.ID {
+		t.Fatalf("expected the record to be found, got %+v", read)
+	}
+	read = call[resource.ReadResult[resource.VercelDnsRecordOutputs]](t, s, "Resource.Vercel.DnsRecord.Read", resource.ReadInput[resource.VercelDnsRecordOutputs]{
+		ID: "rec_missing", Props: created.Outs,
+	})
+	if read.ID != "" {
+		t.Fatal("expected a deleted record to be removed from the state")
+	}
+}
+
+func TestRpcDnsRecord(t *testing.T) {
+	s := servertest.New(t)
+	t.Setenv("CLOUDFLARE_API_TOKEN", "token")
+	t.Setenv("VERCEL_API_TOKEN", "token")
+	s.Route53.CreateZone("Z1", "example.com")
+	s.Cloudflare.CreateZone("zone")
+	s.Vercel.CreateDomain("example.com")
+
+	inputs := resource.DnsRecordInputs{Provider: "route53", Zone: "Z1", Name: "www.example.com", Type: "A", Values: []string{"1.1.1.1"}}
+	created := call[resource.CreateResult[resource.DnsRecordOutputs]](t, s, "Resource.Dns.Record.Create", inputs)
+	if values := s.Route53.Records("Z1", "www.example.com", "A"); !slices.Equal(values, []string{"1.1.1.1"}) {
+		t.Fatalf("expected the record to be created, got %v", values)
+	}
+	// the marker names the app and stage of the project
+	if values := s.Route53.Records("Z1", "_sst-owner.a.www.example.com", "TXT"); !slices.Equal(values, []string{`"sst:app:test"`}) {
+		t.Fatalf("expected the owner marker, got %v", values)
+	}
+	// a refresh doesn't turn the default ttl into a change
+	refreshed := call[resource.ReadResult[resource.DnsRecordOutputs]](t, s, "Resource.Dns.Record.Read", resource.ReadInput[resource.DnsRecordOutputs]{ID: created.ID, Props: created.Outs})
+	diff := call[resource.DiffResult](t, s, "Resource.Dns.Record.Diff", resource.DiffInput[resource.DnsRecordInputs, resource.DnsRecordOutputs]{ID: created.ID, Olds: refreshed.Props, News: inputs})
+	if diff.Changes == nil || *diff.Changes {
+		t.Fatalf("expected no changes after a refresh, got %+v", diff)
+	}
+
+	next := inputs
+	next.Values = []string{"2.2.2.2"}
+	updated := call[resource.UpdateResult[resource.DnsRecordOutputs]](t, s, "Resource.Dns.Record.Update", resource.UpdateInput[resource.DnsRecordInputs, resource.DnsRecordOutputs]{
+		ID: created.ID, News: next, Olds: created.Outs,
+	})
+	if values := s.Route53.Records("Z1", "www.example.com", "A"); !slices.Equal(values, []string{"2.2.2.2"}) {
+		t.Fatalf("expected the record to be updated, got %v", values)
+	}
+	call[int](t, s, "Resource.Dns.Record.Delete", resource.DeleteInput[resource.DnsRecordOutputs]{ID: created.ID, Outs: updated.Outs})
+	if s.Route53.Records("Z1", "www.example.com", "A") != nil || s.Route53.Records("Z1", "_sst-owner.a.www.example.com", "TXT") != nil {
+		t.Fatal("expected the record and its marker to be deleted")
+	}
+
+	// the other providers keep a record per value next to the marker
+	cloudflare := resource.DnsRecordInputs{Provider: "cloudflare", Zone: "zone", Name: "www.example.com", Type: "A", Values: []string{"1.1.1.1", "2.2.2.2"}}
+	call[resource.CreateResult[resource.DnsRecordOutputs]](t, s, "Resource.Dns.Record.Create", cloudflare)
+	if records := s.Cloudflare.Records("zone"); len(records) != 3 {
+		t.Fatalf("expected 2 records and a marker, got %+v", records)
+	}
+	// proxied records read back the automatic ttl
+	proxied := resource.DnsRecordInputs{Provider: "cloudflare", Zone: "zone", Name: "proxied.example.com", Type: "A", Values: []string{"1.1.1.1"}, TTL: 300, Proxied: aws.Bool(true)}
+	created = call[resource.CreateResult[resource.DnsRecordOutputs]](t, s, "Resource.Dns.Record.Create", proxied)
+	refreshed = call[resource.ReadResult[resource.DnsRecordOutputs]](t, s, "Resource.Dns.Record.Read", resource.ReadInput[resource.DnsRecordOutputs]{ID: created.ID, Props: created.Outs})
+	diff = call[resource.DiffResult](t, s, "Resource.Dns.Record.Diff", resource.DiffInput[resource.DnsRecordInputs, resource.DnsRecordOutputs]{ID: created.ID, Olds: refreshed.Props, News: proxied})
+	if diff.Changes == nil || *diff.Changes {
+		t.Fatalf("expected no changes after refreshing a proxied record, got %+v", diff)
+	}
+	vercel := resource.DnsRecordInputs{Provider: "vercel", Zone: "example.com", Name: "www.example.com", Type: "A", Values: []string{"1.1.1.1", "2.2.2.2"}}
+	call[resource.CreateResult[resource.DnsRecordOutputs]](t, s, "Resource.Dns.Record.Create", vercel)
+	if records := s.Vercel.Records("example.com"); len(records) != 3 {
+		t.Fatalf("expected 2 records and a marker, got %+v", records)
+	}
+
+	// a record that's already there without a marker isn't taken over
+	s.Route53.ChangeResourceRecordSets(context.Background(), &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String("Z1"),
+		ChangeBatch: &r53types.ChangeBatch{Changes: []r53types.Change{{
+			Action: r53types.ChangeActionCreate,
+			ResourceRecordSet: &r53types.ResourceRecordSet{
+				Name: aws.String("old.example.com."), Type: r53types.RRTypeA, TTL: aws.Int64(300),
+				ResourceRecords: []r53types.ResourceRecord{{Value: aws.String("3.3.3.3")}},
+			},
+		}}},
+	})
+	err := s.Call("Resource.Dns.Record.Create", resource.DnsRecordInputs{Provider: "route53", Zone: "Z1", Name: "old.example.com", Type: "A", Values: []string{"4.4.4.4"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "not created by this stage") {
+		t.Fatalf("expected an unowned record to be refused, got %v", err)
+	}
+	if values := s.Route53.Records("Z1", "old.example.com", "A"); !slices.Equal(values, []string{"3.3.3.3"}) {
+		t.Fatalf("expected the unowned record to be left alone, got %v", values)
+	}
+}
@@ -67,6 +67,26 @@ func (r *Run) Update(input *UpdateInput[RunInputs, RunOutputs], output *UpdateRe
 	return nil
 }
 
+func (r *Run) Check(input *CheckInput[RunInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("command", input.News.Command)
+	*output = checks.result()
+	return nil
+}
+
+// The command isn't kept in the outputs, so the inputs are compared to decide
+// if it runs again
+func (r *Run) Diff(input *DiffInput[RunInputs, RunOutputs], output *DiffResult) error {
+	*output = DiffResult{}
+	return nil
+}
+
+// A command can't be read back, so it reads its own outputs
+func (r *Run) Read(input *ReadInput[RunOutputs], output *ReadResult[RunOutputs]) error {
+	*output = ReadResult[RunOutputs]{ID: input.ID, Props: input.Props}
+	return nil
+}
+
 func (r *Run) executeCommand(input *RunInputs) error {
 	r.lock.Acquire(context.Background(), 1)
 	defer r.lock.Release(1)
@@ -0,0 +1,123 @@
+package resource
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	THROTTLE_ATTEMPTS    = 10
+	THROTTLE_BASE_DELAY  = 500 * time.Millisecond
+	THROTTLE_MAX_BACKOFF = 20 * time.Second
+)
+
+// Limits the number of concurrent API calls made to a provider and retries
+// the ones that are rate limited
+type throttle struct {
+	size int
+	lock *semaphore.Weighted
+}
+
+func newThrottle(value string, fallback int) *throttle {
+	size := fallback
+	if value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err == nil && parsed > 0 {
+			size = parsed
+		}
+	}
+	return &throttle{
+		size: size,
+		lock: semaphore.NewWeighted(int64(size)),
+	}
+}
+
+// Applies the limit to every call made with the config. The AWS SDK already
+// retries throttling errors with jittered backoff so it only needs more room.
+func (t *throttle) apply(cfg *aws.Config) {
+	cfg.Retryer = func() aws.Retryer {
+		return retry.NewStandard(func(o *retry.StandardOptions) {
+			o.MaxAttempts = THROTTLE_ATTEMPTS
+			o.MaxBackoff = THROTTLE_MAX_BACKOFF
+			// the client side retry quota gives up too early when a large
+			// stage is being throttled
+			o.RateLimiter = ratelimit.None
+		})
+	}
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		// added after the retry middleware so a slot isn't held while
+		// waiting to retry
+		return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("SSTThrottle", func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			if err := t.lock.Acquire(ctx, 1); err != nil {
+				return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+			}
+			defer t.lock.Release(1)
+			return next.HandleFinalize(ctx, in)
+		}), middleware.After)
+	})
+}
+
+// Sends the request and retries it when the provider responds with a 429
+func (t *throttle) do(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		if err := t.lock.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		t.lock.Release(1)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || attempt == THROTTLE_ATTEMPTS-1 {
+			return resp, nil
+		}
+		delay := retryAfter(resp.Header.Get("Retry-After"))
+		if delay == 0 {
+			delay = backoff(attempt)
+		}
+		resp.Body.Close()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Exponential backoff with full jitter
+func backoff(attempt int) time.Duration {
+	ceiling := THROTTLE_BASE_DELAY << attempt
+	if ceiling <= 0 || ceiling > THROTTLE_MAX_BACKOFF {
+		ceiling = THROTTLE_MAX_BACKOFF
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return min(time.Duration(seconds)*time.Second, THROTTLE_MAX_BACKOFF)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return min(max(time.Until(at), 0), THROTTLE_MAX_BACKOFF)
+	}
+	return 0
+}
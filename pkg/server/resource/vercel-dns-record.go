@@ -7,14 +7,19 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
 
 	"github.com/sst/sst/v3/pkg/project"
 )
 
 // Base resource for Vercel providers
 type VercelResource struct {
-	context context.Context
-	project *project.Project
+	context  context.Context
+	project  *project.Project
+	throttle *throttle
+	// The base URL of the API
+	url string
 }
 
 type VercelDnsRecord struct {
@@ -32,6 +37,11 @@ type VercelDnsRecordInputs struct {
 
 type VercelDnsRecordOutputs struct {
 	RecordId string `json:"recordId"`
+	Domain   string `json:"domain,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Value    string `json:"value,omitempty"`
+	TeamId   string `json:"teamId,omitempty"`
 }
 
 
@@ -43,7 +53,7 @@ func (r *VercelDnsRecord) Create(input *VercelDnsRecordInputs, output *CreateRes
 
 	*output = CreateResult[VercelDnsRecordOutputs]{
 		ID:   recordId,
-		Outs: VercelDnsRecordOutputs{RecordId: recordId},
+		Outs: r.outputs(input, recordId),
 	}
 	return nil
 }
@@ -55,14 +65,117 @@ func (r *VercelDnsRecord) Update(input *UpdateInput[VercelDnsRecordInputs, Verce
 	}
 
 	*output = UpdateResult[VercelDnsRecordOutputs]{
-		Outs: VercelDnsRecordOutputs{RecordId: recordId},
+		Outs: r.outputs(&input.News, recordId),
 	}
 	return nil
 }
 
+func (r *VercelDnsRecord) Check(input *CheckInput[VercelDnsRecordInputs], output *CheckResult) error {
+	var checks checkBuilder
+	checks.require("domain", input.News.Domain)
+	checks.require("type", input.News.Type)
+	checks.require("value", input.News.Value)
+	*output = checks.result()
+	return nil
+}
+
+func (r *VercelDnsRecord) Diff(input *DiffInput[VercelDnsRecordInputs, VercelDnsRecordOutputs], output *DiffResult) error {
+	if input.Olds.Domain == "" {
+		*output = DiffResult{}
+		return nil
+	}
+	var changed diffBuilder
+	changed.compare("domain", input.Olds.Domain, input.News.Domain)
+	changed.compare("type", input.Olds.Type, input.News.Type)
+	changed.compare("name", input.Olds.Name, input.News.Name)
+	changed.compare("value", input.Olds.Value, input.News.Value)
+	changed.compare("teamId", input.Olds.TeamId, input.News.TeamId)
+	*output = newDiff(changed, "domain", "type", "name", "teamId")
+	return nil
+}
+
+// Read removes the record from the state if it was deleted outside of a
+// deploy. Records that already existed when they were created aren't tracked,
+// so they aren't read.
+func (r *VercelDnsRecord) Read(input *ReadInput[VercelDnsRecordOutputs], output *ReadResult[VercelDnsRecordOutputs]) error {
+	props := input.Props
+	token := os.Getenv("VERCEL_API_TOKEN")
+	if token == "" || input.ID == "existing-record" || props.Domain == "" {
+		*output = ReadResult[VercelDnsRecordOutputs]{ID: input.ID, Props: props}
+		return nil
+	}
+	until := ""
+	for {
+		query := url.Values{}
+		if props.TeamId != "" {
+			query.Set("teamId", props.TeamId)
+		}
+		if until != "" {
+			query.Set("until", until)
+		}
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/v4/domains/%s/records?%s", r.url, props.Domain, query.Encode()), nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := r.throttle.do(r.context, &http.Client{}, req)
+		if err != nil {
+			return err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			*output = ReadResult[VercelDnsRecordOutputs]{}
+			return nil
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("failed to read DNS records, status: %d, response: %s", resp.StatusCode, string(body))
+		}
+		var result struct {
+			Records []struct {
+				Id    string `json:"id"`
+				Value string `json:"value"`
+			} `json:"records"`
+			Pagination *struct {
+				Next *int64 `json:"next"`
+			} `json:"pagination"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return err
+		}
+		for _, record := range result.Records {
+			if record.Id == input.ID {
+				props.Value = record.Value
+				*output = ReadResult[VercelDnsRecordOutputs]{ID: input.ID, Props: props}
+				return nil
+			}
+		}
+		if result.Pagination == nil || result.Pagination.Next == nil {
+			break
+		}
+		until = fmt.Sprint(*result.Pagination.Next)
+	}
+	*output = ReadResult[VercelDnsRecordOutputs]{}
+	return nil
+}
+
+func (r *VercelDnsRecord) outputs(input *VercelDnsRecordInputs, recordId string) VercelDnsRecordOutputs {
+	return VercelDnsRecordOutputs{
+		RecordId: recordId,
+		Domain:   input.Domain,
+		Type:     input.Type,
+		Name:     input.Name,
+		Value:    input.Value,
+		TeamId:   input.TeamId,
+	}
+}
+
 func (r *VercelDnsRecord) createOrUpdateRecord(input *VercelDnsRecordInputs) (string, error) {
 	// Construct the URL with teamId if available
-	url := fmt.Sprintf("https://api.vercel.com/v4/domains/%s/records", input.Domain)
+	url := fmt.Sprintf("%s/v4/domains/%s/records", r.url, input.Domain)
 	if input.TeamId != "" {
 		url = fmt.Sprintf("%s?teamId=%s", url, input.TeamId)
 	}
@@ -94,7 +207,7 @@ func (r *VercelDnsRecord) createOrUpdateRecord(input *VercelDnsRecordInputs) (st
 	req.Header.Set("Authorization", "Bearer "+input.ApiToken)
 	
 	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := r.throttle.do(r.context, client, req)
 	if err != nil {
 		return "", err
 	}
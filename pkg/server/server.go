@@ -39,15 +39,20 @@ func New() (*Server, error) {
 		Mux:  http.NewServeMux(),
 		Rpc:  rpc.NewServer(),
 	}
-	result.Mux.HandleFunc("/rpc", func(w http.ResponseWriter, r *http.Request) {
+	result.Mux.HandleFunc("/rpc", RpcHandler(result.Rpc))
+	return result, nil
+}
+
+// RpcHandler serves the JSON-RPC calls the components make, one per request
+func RpcHandler(server *rpc.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
 		slog.Info("rpc request", "method", r.Method, "url", r.URL.String())
-		result.Rpc.ServeCodec(jsonrpc.NewServerCodec(&HttpConn{Reader: r.Body, Writer: w}))
-	})
-	return result, nil
+		server.ServeCodec(jsonrpc.NewServerCodec(&HttpConn{Reader: r.Body, Writer: w}))
+	}
 }
 
 func (s *Server) Start(ctx context.Context, p *project.Project) error {
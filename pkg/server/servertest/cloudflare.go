@@ -0,0 +1,172 @@
+package servertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+)
+
+// FakeCloudflare serves the DNS record endpoints of the Cloudflare API from
+// memory. Point the resources at URL.
+type FakeCloudflare struct {
+	fake
+	URL     string
+	zones   map[string][]cloudflare.DNSRecord
+	records int
+}
+
+func NewFakeCloudflare(recorder *Recorder) *FakeCloudflare {
+	return &FakeCloudflare{
+		fake:  fake{service: "Cloudflare", recorder: recorder},
+		zones: map[string][]cloudflare.DNSRecord{},
+	}
+}
+
+func (f *FakeCloudflare) start() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /zones/{zone}/dns_records", f.listRecords)
+	mux.HandleFunc("POST /zones/{zone}/dns_records", f.createRecord)
+	mux.HandleFunc("GET /zones/{zone}/dns_records/{id}", f.getRecord)
+	mux.HandleFunc("DELETE /zones/{zone}/dns_records/{id}", f.deleteRecord)
+	server := httptest.NewServer(mux)
+	f.URL = server.URL
+	return server
+}
+
+func (f *FakeCloudflare) CreateZone(id string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.zones[id] = []cloudflare.DNSRecord{}
+}
+
+// Records returns a copy of the records in a zone
+func (f *FakeCloudflare) Records(zoneId string) []cloudflare.DNSRecord {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]cloudflare.DNSRecord{}, f.zones[zoneId]...)
+}
+
+type cloudflareResponse struct {
+	Success    bool                      `json:"success"`
+	Errors     []cloudflare.ResponseInfo `json:"errors"`
+	Messages   []cloudflare.ResponseInfo `json:"messages"`
+	Result     interface{}               `json:"result"`
+	ResultInfo *cloudflare.ResultInfo    `json:"result_info,omitempty"`
+}
+
+func (f *FakeCloudflare) respond(w http.ResponseWriter, status int, result interface{}, errs ...cloudflare.ResponseInfo) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	response := cloudflareResponse{
+		Success:  len(errs) == 0,
+		Errors:   append([]cloudflare.ResponseInfo{}, errs...),
+		Messages: []cloudflare.ResponseInfo{},
+		Result:   result,
+	}
+	if records, ok := result.([]cloudflare.DNSRecord); ok {
+		response.ResultInfo = &cloudflare.ResultInfo{Page: 1, PerPage: len(records), TotalPages: 1, Count: len(records), Total: len(records)}
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// Starts a request, holding the lock until the returned func is called.
+// Returns false if the request was already answered.
+func (f *FakeCloudflare) handle(w http.ResponseWriter, r *http.Request, operation string) (func(), []cloudflare.DNSRecord, bool) {
+	done, err := f.begin(operation)
+	if err != nil {
+		// not a 5xx, which the client would retry
+		f.respond(w, http.StatusBadRequest, nil, cloudflare.ResponseInfo{Code: 10000, Message: err.Error()})
+		return nil, nil, false
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		done()
+		f.respond(w, http.StatusForbidden, nil, cloudflare.ResponseInfo{Code: 10000, Message: "Authentication error"})
+		return nil, nil, false
+	}
+	records, ok := f.zones[r.PathValue("zone")]
+	if !ok {
+		done()
+		f.respond(w, http.StatusNotFound, nil, cloudflare.ResponseInfo{Code: 7003, Message: "Could not route to /zones/" + r.PathValue("zone")})
+		return nil, nil, false
+	}
+	return done, records, true
+}
+
+func (f *FakeCloudflare) listRecords(w http.ResponseWriter, r *http.Request) {
+	done, records, ok := f.handle(w, r, "ListDNSRecords")
+	if !ok {
+		return
+	}
+	defer done()
+	name := strings.ToLower(r.URL.Query().Get("name"))
+	recordType := r.URL.Query().Get("type")
+	result := []cloudflare.DNSRecord{}
+	for _, record := range records {
+		if (name == "" || record.Name == name) && (recordType == "" || record.Type == recordType) {
+			result = append(result, record)
+		}
+	}
+	f.respond(w, http.StatusOK, result)
+}
+
+// Takes the records the SST resources send, rejecting an exact duplicate
+// like Cloudflare does
+func (f *FakeCloudflare) createRecord(w http.ResponseWriter, r *http.Request) {
+	done, records, ok := f.handle(w, r, "CreateDNSRecord")
+	if !ok {
+		return
+	}
+	defer done()
+	var record cloudflare.DNSRecord
+	if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+		f.respond(w, http.StatusBadRequest, nil, cloudflare.ResponseInfo{Code: 9207, Message: "Request body is invalid."})
+		return
+	}
+	record.Name = strings.ToLower(strings.TrimSuffix(record.Name, "."))
+	for _, existing := range records {
+		if existing.Name == record.Name && existing.Type == record.Type && existing.Content == record.Content {
+			f.respond(w, http.StatusBadRequest, nil, cloudflare.ResponseInfo{Code: 81057, Message: "Record already exists."})
+			return
+		}
+	}
+	f.records++
+	record.ID = fmt.Sprintf("record-%d", f.records)
+	record.ZoneID = r.PathValue("zone")
+	f.zones[record.ZoneID] = append(records, record)
+	f.respond(w, http.StatusOK, record)
+}
+
+func (f *FakeCloudflare) getRecord(w http.ResponseWriter, r *http.Request) {
+	done, records, ok := f.handle(w, r, "GetDNSRecord")
+	if !ok {
+		return
+	}
+	defer done()
+	for _, record := range records {
+		if record.ID == r.PathValue("id") {
+			f.respond(w, http.StatusOK, record)
+			return
+		}
+	}
+	f.respond(w, http.StatusNotFound, nil, cloudflare.ResponseInfo{Code: 81044, Message: "Record does not exist."})
+}
+
+func (f *FakeCloudflare) deleteRecord(w http.ResponseWriter, r *http.Request) {
+	done, records, ok := f.handle(w, r, "DeleteDNSRecord")
+	if !ok {
+		return
+	}
+	defer done()
+	for i, record := range records {
+		if record.ID == r.PathValue("id") {
+			f.zones[r.PathValue("zone")] = append(records[:i:i], records[i+1:]...)
+			f.respond(w, http.StatusOK, map[string]string{"id": record.ID})
+			return
+		}
+	}
+	f.respond(w, http.StatusNotFound, nil, cloudflare.ResponseInfo{Code: 81044, Message: "Record does not exist."})
+}
@@ -0,0 +1,217 @@
+package servertest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+)
+
+// FakeCloudFront keeps distributions, their invalidations and origin access
+// identities and controls in memory. Invalidations complete right away.
+type FakeCloudFront struct {
+	fake
+	distributions map[string]string
+	invalidations map[string][][]string
+	identities    map[string]string
+	controls      map[string]string
+	next          int
+}
+
+func NewFakeCloudFront(recorder *Recorder) *FakeCloudFront {
+	return &FakeCloudFront{
+		fake:          fake{service: "CloudFront", recorder: recorder},
+		distributions: map[string]string{},
+		invalidations: map[string][][]string{},
+		identities:    map[string]string{},
+		controls:      map[string]string{},
+	}
+}
+
+// SetDistribution adds a distribution or changes its status, like
+// "InProgress" or "Deployed"
+func (f *FakeCloudFront) SetDistribution(id string, status string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.distributions[id] = status
+}
+
+// Invalidations returns the paths of each invalidation of a distribution
+func (f *FakeCloudFront) Invalidations(distributionId string) [][]string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([][]string{}, f.invalidations[distributionId]...)
+}
+
+// OriginAccessIdentities returns the IDs of the identities that exist
+func (f *FakeCloudFront) OriginAccessIdentities() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return mapKeys(f.identities)
+}
+
+// OriginAccessControls returns the IDs of the controls that exist
+func (f *FakeCloudFront) OriginAccessControls() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return mapKeys(f.controls)
+}
+
+func (f *FakeCloudFront) id(prefix string) string {
+	f.next++
+	return fmt.Sprintf("%s%d", prefix, f.next)
+}
+
+func (f *FakeCloudFront) GetDistribution(ctx context.Context, params *cloudfront.GetDistributionInput, optFns ...func(*cloudfront.Options)) (*cloudfront.GetDistributionOutput, error) {
+	done, err := f.begin("GetDistribution")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	status, ok := f.distributions[aws.ToString(params.Id)]
+	if !ok {
+		return nil, &types.NoSuchDistribution{}
+	}
+	return &cloudfront.GetDistributionOutput{
+		Distribution: &types.Distribution{Id: params.Id, Status: aws.String(status)},
+	}, nil
+}
+
+func (f *FakeCloudFront) CreateInvalidation(ctx context.Context, params *cloudfront.CreateInvalidationInput, optFns ...func(*cloudfront.Options)) (*cloudfront.CreateInvalidationOutput, error) {
+	done, err := f.begin("CreateInvalidation")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	distributionId := aws.ToString(params.DistributionId)
+	if _, ok := f.distributions[distributionId]; !ok {
+		return nil, &types.NoSuchDistribution{}
+	}
+	paths := params.InvalidationBatch.Paths
+	if int(aws.ToInt32(paths.Quantity)) != len(paths.Items) {
+		return nil, &types.InconsistentQuantities{}
+	}
+	f.invalidations[distributionId] = append(f.invalidations[distributionId], append([]string{}, paths.Items...))
+	return &cloudfront.CreateInvalidationOutput{
+		Invalidation: &types.Invalidation{
+			Id:     aws.String(f.id("I")),
+			Status: aws.String("Completed"),
+		},
+	}, nil
+}
+
+func (f *FakeCloudFront) GetInvalidation(ctx context.Context, params *cloudfront.GetInvalidationInput, optFns ...func(*cloudfront.Options)) (*cloudfront.GetInvalidationOutput, error) {
+	done, err := f.begin("GetInvalidation")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return &cloudfront.GetInvalidationOutput{
+		Invalidation: &types.Invalidation{
+			Id:     params.Id,
+			Status: aws.String("Completed"),
+		},
+	}, nil
+}
+
+func (f *FakeCloudFront) CreateCloudFrontOriginAccessIdentity(ctx context.Context, params *cloudfront.CreateCloudFrontOriginAccessIdentityInput, optFns ...func(*cloudfront.Options)) (*cloudfront.CreateCloudFrontOriginAccessIdentityOutput, error) {
+	done, err := f.begin("CreateCloudFrontOriginAccessIdentity")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	id := f.id("E")
+	f.identities[id] = f.id("etag-")
+	return &cloudfront.CreateCloudFrontOriginAccessIdentityOutput{
+		CloudFrontOriginAccessIdentity: &types.CloudFrontOriginAccessIdentity{Id: aws.String(id)},
+		ETag:                           aws.String(f.identities[id]),
+	}, nil
+}
+
+func (f *FakeCloudFront) GetCloudFrontOriginAccessIdentity(ctx context.Context, params *cloudfront.GetCloudFrontOriginAccessIdentityInput, optFns ...func(*cloudfront.Options)) (*cloudfront.GetCloudFrontOriginAccessIdentityOutput, error) {
+	done, err := f.begin("GetCloudFrontOriginAccessIdentity")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	etag, ok := f.identities[aws.ToString(params.Id)]
+	if !ok {
+		return nil, &types.NoSuchCloudFrontOriginAccessIdentity{}
+	}
+	return &cloudfront.GetCloudFrontOriginAccessIdentityOutput{
+		CloudFrontOriginAccessIdentity: &types.CloudFrontOriginAccessIdentity{Id: params.Id},
+		ETag:                           aws.String(etag),
+	}, nil
+}
+
+func (f *FakeCloudFront) DeleteCloudFrontOriginAccessIdentity(ctx context.Context, params *cloudfront.DeleteCloudFrontOriginAccessIdentityInput, optFns ...func(*cloudfront.Options)) (*cloudfront.DeleteCloudFrontOriginAccessIdentityOutput, error) {
+	done, err := f.begin("DeleteCloudFrontOriginAccessIdentity")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	id := aws.ToString(params.Id)
+	etag, ok := f.identities[id]
+	if !ok {
+		return nil, &types.NoSuchCloudFrontOriginAccessIdentity{}
+	}
+	if aws.ToString(params.IfMatch) != etag {
+		return nil, &types.PreconditionFailed{}
+	}
+	delete(f.identities, id)
+	return &cloudfront.DeleteCloudFrontOriginAccessIdentityOutput{}, nil
+}
+
+func (f *FakeCloudFront) CreateOriginAccessControl(ctx context.Context, params *cloudfront.CreateOriginAccessControlInput, optFns ...func(*cloudfront.Options)) (*cloudfront.CreateOriginAccessControlOutput, error) {
+	done, err := f.begin("CreateOriginAccessControl")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	config := params.OriginAccessControlConfig
+	if name := aws.ToString(config.Name); name == "" || len(name) > 64 {
+		return nil, &types.InvalidArgument{Message: aws.String("name needs to be between 1 and 64 characters")}
+	}
+	id := f.id("O")
+	f.controls[id] = f.id("etag-")
+	return &cloudfront.CreateOriginAccessControlOutput{
+		OriginAccessControl: &types.OriginAccessControl{Id: aws.String(id), OriginAccessControlConfig: config},
+		ETag:                aws.String(f.controls[id]),
+	}, nil
+}
+
+func (f *FakeCloudFront) GetOriginAccessControl(ctx context.Context, params *cloudfront.GetOriginAccessControlInput, optFns ...func(*cloudfront.Options)) (*cloudfront.GetOriginAccessControlOutput, error) {
+	done, err := f.begin("GetOriginAccessControl")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	etag, ok := f.controls[aws.ToString(params.Id)]
+	if !ok {
+		return nil, &types.NoSuchOriginAccessControl{}
+	}
+	return &cloudfront.GetOriginAccessControlOutput{
+		OriginAccessControl: &types.OriginAccessControl{Id: params.Id},
+		ETag:                aws.String(etag),
+	}, nil
+}
+
+func (f *FakeCloudFront) DeleteOriginAccessControl(ctx context.Context, params *cloudfront.DeleteOriginAccessControlInput, optFns ...func(*cloudfront.Options)) (*cloudfront.DeleteOriginAccessControlOutput, error) {
+	done, err := f.begin("DeleteOriginAccessControl")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	id := aws.ToString(params.Id)
+	etag, ok := f.controls[id]
+	if !ok {
+		return nil, &types.NoSuchOriginAccessControl{}
+	}
+	if aws.ToString(params.IfMatch) != etag {
+		return nil, &types.PreconditionFailed{}
+	}
+	delete(f.controls, id)
+	return &cloudfront.DeleteOriginAccessControlOutput{}, nil
+}
@@ -0,0 +1,54 @@
+package servertest
+
+import (
+	"context"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// FakeCloudWatch keeps the state of metric alarms in memory
+type FakeCloudWatch struct {
+	fake
+	alarms map[string]types.StateValue
+}
+
+func NewFakeCloudWatch(recorder *Recorder) *FakeCloudWatch {
+	return &FakeCloudWatch{
+		fake:   fake{service: "CloudWatch", recorder: recorder},
+		alarms: map[string]types.StateValue{},
+	}
+}
+
+// SetAlarm creates an alarm or changes its state, like a metric crossing
+// its threshold would
+func (f *FakeCloudWatch) SetAlarm(name string, state types.StateValue) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.alarms[name] = state
+}
+
+func (f *FakeCloudWatch) DescribeAlarms(ctx context.Context, params *cloudwatch.DescribeAlarmsInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.DescribeAlarmsOutput, error) {
+	done, err := f.begin("DescribeAlarms")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	result := &cloudwatch.DescribeAlarmsOutput{}
+	for _, name := range mapKeys(f.alarms) {
+		state := f.alarms[name]
+		if len(params.AlarmNames) > 0 && !slices.Contains(params.AlarmNames, name) {
+			continue
+		}
+		if params.StateValue != "" && params.StateValue != state {
+			continue
+		}
+		result.MetricAlarms = append(result.MetricAlarms, types.MetricAlarm{
+			AlarmName:  aws.String(name),
+			StateValue: state,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package servertest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// FakeIam keeps the names of roles in memory
+type FakeIam struct {
+	fake
+	roles map[string]bool
+}
+
+func NewFakeIam(recorder *Recorder) *FakeIam {
+	return &FakeIam{
+		fake:  fake{service: "Iam", recorder: recorder},
+		roles: map[string]bool{},
+	}
+}
+
+func (f *FakeIam) CreateRole(name string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.roles[name] = true
+}
+
+// DeleteRole removes a role like it was deleted outside of a deploy
+func (f *FakeIam) DeleteRole(name string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.roles, name)
+}
+
+func (f *FakeIam) GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	done, err := f.begin("GetRole")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	name := aws.ToString(params.RoleName)
+	if !f.roles[name] {
+		return nil, &types.NoSuchEntityException{Message: aws.String(fmt.Sprintf("The role with name %s cannot be found.", name))}
+	}
+	return &iam.GetRoleOutput{Role: &types.Role{RoleName: params.RoleName}}, nil
+}
@@ -0,0 +1,199 @@
+package servertest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore/types"
+)
+
+// The limits a KeyValueStore enforces on UpdateKeys
+const (
+	KV_MAX_KEYS       = 50
+	KV_MAX_KEY_SIZE   = 512
+	KV_MAX_VALUE_SIZE = 1024
+	KV_MAX_PAYLOAD    = 3 * 1024 * 1024
+	// How many keys ListKeys returns at once
+	KV_PAGE_SIZE = 50
+)
+
+type fakeStore struct {
+	version int
+	keys    map[string]string
+}
+
+// FakeKeyValueStore keeps stores in memory and rejects writes with a stale
+// ETag or over the limits, the same way CloudFront does
+type FakeKeyValueStore struct {
+	fake
+	stores map[string]*fakeStore
+}
+
+func NewFakeKeyValueStore(recorder *Recorder) *FakeKeyValueStore {
+	return &FakeKeyValueStore{
+		fake:   fake{service: "KeyValueStore", recorder: recorder},
+		stores: map[string]*fakeStore{},
+	}
+}
+
+func (f *FakeKeyValueStore) CreateStore(arn string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.stores[arn] = &fakeStore{keys: map[string]string{}}
+}
+
+// Keys returns a copy of every key in a store
+func (f *FakeKeyValueStore) Keys(arn string) map[string]string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if store, ok := f.stores[arn]; ok {
+		return maps.Clone(store.keys)
+	}
+	return nil
+}
+
+// Put changes a key like another deploy or the console would, which also
+// changes the ETag
+func (f *FakeKeyValueStore) Put(arn string, key string, value string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	store := f.stores[arn]
+	store.keys[key] = value
+	store.version++
+}
+
+func (f *FakeKeyValueStore) store(arn *string) (*fakeStore, error) {
+	store, ok := f.stores[aws.ToString(arn)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("Key value store not found")}
+	}
+	return store, nil
+}
+
+func (s *fakeStore) etag() *string {
+	return aws.String(fmt.Sprintf("etag-%d", s.version))
+}
+
+func (f *FakeKeyValueStore) DescribeKeyValueStore(ctx context.Context, params *cloudfrontkeyvaluestore.DescribeKeyValueStoreInput, optFns ...func(*cloudfrontkeyvaluestore.Options)) (*cloudfrontkeyvaluestore.DescribeKeyValueStoreOutput, error) {
+	done, err := f.begin("DescribeKeyValueStore")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	store, err := f.store(params.KvsARN)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudfrontkeyvaluestore.DescribeKeyValueStoreOutput{
+		KvsARN:    params.KvsARN,
+		ETag:      store.etag(),
+		ItemCount: aws.Int32(int32(len(store.keys))),
+	}, nil
+}
+
+func (f *FakeKeyValueStore) GetKey(ctx context.Context, params *cloudfrontkeyvaluestore.GetKeyInput, optFns ...func(*cloudfrontkeyvaluestore.Options)) (*cloudfrontkeyvaluestore.GetKeyOutput, error) {
+	done, err := f.begin("GetKey")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	store, err := f.store(params.KvsARN)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := store.keys[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("Key not found")}
+	}
+	return &cloudfrontkeyvaluestore.GetKeyOutput{Key: params.Key, Value: aws.String(value)}, nil
+}
+
+func (f *FakeKeyValueStore) ListKeys(ctx context.Context, params *cloudfrontkeyvaluestore.ListKeysInput, optFns ...func(*cloudfrontkeyvaluestore.Options)) (*cloudfrontkeyvaluestore.ListKeysOutput, error) {
+	done, err := f.begin("ListKeys")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	store, err := f.store(params.KvsARN)
+	if err != nil {
+		return nil, err
+	}
+	keys := mapKeys(store.keys)
+	start := 0
+	if params.NextToken != nil {
+		start, err = strconv.Atoi(*params.NextToken)
+		if err != nil {
+			return nil, &types.ValidationException{Message: aws.String("invalid next token")}
+		}
+	}
+	end := min(start+KV_PAGE_SIZE, len(keys))
+	result := &cloudfrontkeyvaluestore.ListKeysOutput{}
+	for _, key := range keys[start:end] {
+		result.Items = append(result.Items, types.ListKeysResponseListItem{
+			Key:   aws.String(key),
+			Value: aws.String(store.keys[key]),
+		})
+	}
+	if end < len(keys) {
+		result.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return result, nil
+}
+
+func (f *FakeKeyValueStore) UpdateKeys(ctx context.Context, params *cloudfrontkeyvaluestore.UpdateKeysInput, optFns ...func(*cloudfrontkeyvaluestore.Options)) (*cloudfrontkeyvaluestore.UpdateKeysOutput, error) {
+	done, err := f.begin("UpdateKeys")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	store, err := f.store(params.KvsARN)
+	if err != nil {
+		return nil, err
+	}
+	if aws.ToString(params.IfMatch) != *store.etag() {
+		return nil, &types.ConflictException{Message: aws.String("Pre-Condition failed")}
+	}
+	if count := len(params.Puts) + len(params.Deletes); count > KV_MAX_KEYS {
+		return nil, &types.ValidationException{Message: aws.String(fmt.Sprintf("can't change more than %d keys at once, got %d", KV_MAX_KEYS, count))}
+	}
+	size := 0
+	seen := map[string]bool{}
+	for _, put := range params.Puts {
+		key, value := aws.ToString(put.Key), aws.ToString(put.Value)
+		if len(key) > KV_MAX_KEY_SIZE || len(value) > KV_MAX_VALUE_SIZE {
+			return nil, &types.ValidationException{Message: aws.String(fmt.Sprintf("%s is over the size limit", key))}
+		}
+		if seen[key] {
+			return nil, &types.ValidationException{Message: aws.String(fmt.Sprintf("%s is changed more than once", key))}
+		}
+		seen[key] = true
+		size += len(key) + len(value)
+	}
+	for _, item := range params.Deletes {
+		key := aws.ToString(item.Key)
+		if seen[key] {
+			return nil, &types.ValidationException{Message: aws.String(fmt.Sprintf("%s is changed more than once", key))}
+		}
+		seen[key] = true
+		size += len(key)
+	}
+	if size > KV_MAX_PAYLOAD {
+		return nil, &types.ValidationException{Message: aws.String("the changes are over the payload limit")}
+	}
+
+	for _, put := range params.Puts {
+		store.keys[aws.ToString(put.Key)] = aws.ToString(put.Value)
+	}
+	for _, item := range params.Deletes {
+		delete(store.keys, aws.ToString(item.Key))
+	}
+	store.version++
+	return &cloudfrontkeyvaluestore.UpdateKeysOutput{
+		ETag:      store.etag(),
+		ItemCount: aws.Int32(int32(len(store.keys))),
+	}, nil
+}
@@ -0,0 +1,298 @@
+package servertest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// The code a function or one of its versions runs
+type FakeCode struct {
+	S3Bucket string
+	S3Key    string
+	ImageUri string
+}
+
+type FakeFunction struct {
+	FakeCode
+	Environment map[string]string
+	// The published versions, in order
+	Versions []string
+}
+
+type FakeAlias struct {
+	Version string
+	// The share of the traffic going to other versions
+	Weights map[string]float64
+}
+
+type fakeFunction struct {
+	FakeFunction
+	// The code each published version runs
+	published map[string]FakeCode
+	aliases   map[string]FakeAlias
+}
+
+// FakeLambda keeps functions, their versions and aliases in memory. Code and
+// configuration updates finish right away.
+type FakeLambda struct {
+	fake
+	functions map[string]*fakeFunction
+}
+
+func NewFakeLambda(recorder *Recorder) *FakeLambda {
+	return &FakeLambda{
+		fake:      fake{service: "Lambda", recorder: recorder},
+		functions: map[string]*fakeFunction{},
+	}
+}
+
+func (f *FakeLambda) CreateFunction(name string, environment map[string]string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.functions[name] = &fakeFunction{
+		FakeFunction: FakeFunction{Environment: maps.Clone(environment)},
+		published:    map[string]FakeCode{},
+		aliases:      map[string]FakeAlias{},
+	}
+}
+
+// DeleteFunction removes a function like it was deleted outside of a deploy
+func (f *FakeLambda) DeleteFunction(name string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.functions, name)
+}
+
+// Function returns a copy of a function
+func (f *FakeLambda) Function(name string) (FakeFunction, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	function, ok := f.functions[name]
+	if !ok {
+		return FakeFunction{}, false
+	}
+	result := function.FakeFunction
+	result.Environment = maps.Clone(result.Environment)
+	result.Versions = append([]string{}, result.Versions...)
+	return result, true
+}
+
+// Alias returns where an alias of a function points
+func (f *FakeLambda) Alias(function string, name string) (FakeAlias, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	fn, ok := f.functions[function]
+	if !ok {
+		return FakeAlias{}, false
+	}
+	alias, ok := fn.aliases[name]
+	return alias, ok
+}
+
+// Invoke returns the code that runs when the function is invoked with the
+// qualifier, which can be empty, a version or an alias. An alias runs its
+// primary version.
+func (f *FakeLambda) Invoke(function string, qualifier string) (FakeCode, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	fn, ok := f.functions[function]
+	if !ok {
+		return FakeCode{}, false
+	}
+	if qualifier == "" || qualifier == "$LATEST" {
+		return fn.FakeCode, true
+	}
+	if alias, ok := fn.aliases[qualifier]; ok {
+		qualifier = alias.Version
+	}
+	code, ok := fn.published[qualifier]
+	return code, ok
+}
+
+func (f *FakeLambda) function(name *string) (*fakeFunction, error) {
+	function, ok := f.functions[aws.ToString(name)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String(fmt.Sprintf("Function not found: %s", aws.ToString(name)))}
+	}
+	return function, nil
+}
+
+func (f *FakeLambda) configuration(name *string, function *fakeFunction) *types.FunctionConfiguration {
+	return &types.FunctionConfiguration{
+		FunctionName:     name,
+		Environment:      &types.EnvironmentResponse{Variables: maps.Clone(function.Environment)},
+		LastUpdateStatus: types.LastUpdateStatusSuccessful,
+		State:            types.StateActive,
+	}
+}
+
+func (f *FakeLambda) GetFunction(ctx context.Context, params *lambda.GetFunctionInput, optFns ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+	done, err := f.begin("GetFunction")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	function, err := f.function(params.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	return &lambda.GetFunctionOutput{Configuration: f.configuration(params.FunctionName, function)}, nil
+}
+
+func (f *FakeLambda) GetFunctionConfiguration(ctx context.Context, params *lambda.GetFunctionConfigurationInput, optFns ...func(*lambda.Options)) (*lambda.GetFunctionConfigurationOutput, error) {
+	done, err := f.begin("GetFunctionConfiguration")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	function, err := f.function(params.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	configuration := f.configuration(params.FunctionName, function)
+	return &lambda.GetFunctionConfigurationOutput{
+		FunctionName:     configuration.FunctionName,
+		Environment:      configuration.Environment,
+		LastUpdateStatus: configuration.LastUpdateStatus,
+		State:            configuration.State,
+	}, nil
+}
+
+func (f *FakeLambda) UpdateFunctionCode(ctx context.Context, params *lambda.UpdateFunctionCodeInput, optFns ...func(*lambda.Options)) (*lambda.UpdateFunctionCodeOutput, error) {
+	done, err := f.begin("UpdateFunctionCode")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	function, err := f.function(params.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	function.S3Bucket = aws.ToString(params.S3Bucket)
+	function.S3Key = aws.ToString(params.S3Key)
+	function.ImageUri = aws.ToString(params.ImageUri)
+	return &lambda.UpdateFunctionCodeOutput{
+		FunctionName:     params.FunctionName,
+		Version:          aws.String("$LATEST"),
+		LastUpdateStatus: types.LastUpdateStatusSuccessful,
+	}, nil
+}
+
+func (f *FakeLambda) UpdateFunctionConfiguration(ctx context.Context, params *lambda.UpdateFunctionConfigurationInput, optFns ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error) {
+	done, err := f.begin("UpdateFunctionConfiguration")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	function, err := f.function(params.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	if params.Environment != nil {
+		function.Environment = maps.Clone(params.Environment.Variables)
+	}
+	return &lambda.UpdateFunctionConfigurationOutput{
+		FunctionName:     params.FunctionName,
+		LastUpdateStatus: types.LastUpdateStatusSuccessful,
+	}, nil
+}
+
+// Publishes a new version only when the code changed since the last one,
+// like Lambda does
+func (f *FakeLambda) PublishVersion(ctx context.Context, params *lambda.PublishVersionInput, optFns ...func(*lambda.Options)) (*lambda.PublishVersionOutput, error) {
+	done, err := f.begin("PublishVersion")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	function, err := f.function(params.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	if len(function.Versions) == 0 || function.published[function.Versions[len(function.Versions)-1]] != function.FakeCode {
+		version := fmt.Sprint(len(function.Versions) + 1)
+		function.Versions = append(function.Versions, version)
+		function.published[version] = function.FakeCode
+	}
+	return &lambda.PublishVersionOutput{
+		FunctionName: params.FunctionName,
+		Version:      aws.String(function.Versions[len(function.Versions)-1]),
+	}, nil
+}
+
+func (f *FakeLambda) GetAlias(ctx context.Context, params *lambda.GetAliasInput, optFns ...func(*lambda.Options)) (*lambda.GetAliasOutput, error) {
+	done, err := f.begin("GetAlias")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	function, err := f.function(params.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	alias, ok := function.aliases[aws.ToString(params.Name)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String(fmt.Sprintf("Alias not found: %s", aws.ToString(params.Name)))}
+	}
+	return &lambda.GetAliasOutput{
+		Name:            params.Name,
+		AliasArn:        aliasArn(params.FunctionName, params.Name),
+		FunctionVersion: aws.String(alias.Version),
+		RoutingConfig:   &types.AliasRoutingConfiguration{AdditionalVersionWeights: maps.Clone(alias.Weights)},
+	}, nil
+}
+
+func (f *FakeLambda) CreateAlias(ctx context.Context, params *lambda.CreateAliasInput, optFns ...func(*lambda.Options)) (*lambda.CreateAliasOutput, error) {
+	done, err := f.begin("CreateAlias")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	function, err := f.function(params.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	name := aws.ToString(params.Name)
+	if _, ok := function.aliases[name]; ok {
+		return nil, &types.ResourceConflictException{Message: aws.String(fmt.Sprintf("Alias already exists: %s", name))}
+	}
+	function.aliases[name] = FakeAlias{Version: aws.ToString(params.FunctionVersion), Weights: map[string]float64{}}
+	return &lambda.CreateAliasOutput{Name: params.Name, AliasArn: aliasArn(params.FunctionName, params.Name), FunctionVersion: params.FunctionVersion}, nil
+}
+
+func (f *FakeLambda) UpdateAlias(ctx context.Context, params *lambda.UpdateAliasInput, optFns ...func(*lambda.Options)) (*lambda.UpdateAliasOutput, error) {
+	done, err := f.begin("UpdateAlias")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	function, err := f.function(params.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	name := aws.ToString(params.Name)
+	alias, ok := function.aliases[name]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String(fmt.Sprintf("Alias not found: %s", name))}
+	}
+	if params.FunctionVersion != nil {
+		alias.Version = aws.ToString(params.FunctionVersion)
+	}
+	if params.RoutingConfig != nil {
+		alias.Weights = maps.Clone(params.RoutingConfig.AdditionalVersionWeights)
+		if alias.Weights == nil {
+			alias.Weights = map[string]float64{}
+		}
+	}
+	function.aliases[name] = alias
+	return &lambda.UpdateAliasOutput{Name: params.Name, AliasArn: aliasArn(params.FunctionName, params.Name), FunctionVersion: aws.String(alias.Version)}, nil
+}
+
+func aliasArn(function *string, alias *string) *string {
+	return aws.String(fmt.Sprintf("arn:aws:lambda:us-east-1:123456789012:function:%s:%s", aws.ToString(function), aws.ToString(alias)))
+}
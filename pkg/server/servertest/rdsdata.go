@@ -0,0 +1,338 @@
+package servertest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+type fakeDatabase struct {
+	extensions map[string]bool
+	// The columns of each table
+	tables map[string]map[string]bool
+	// The tables and indexes, which share a namespace in Postgres
+	relations map[string]bool
+	// The rows inserted into each table
+	rows map[string][]map[string]string
+}
+
+func (d *fakeDatabase) clone() *fakeDatabase {
+	result := &fakeDatabase{
+		extensions: maps.Clone(d.extensions),
+		tables:     map[string]map[string]bool{},
+		relations:  maps.Clone(d.relations),
+		rows:       map[string][]map[string]string{},
+	}
+	for name, columns := range d.tables {
+		result.tables[name] = maps.Clone(columns)
+	}
+	for name, rows := range d.rows {
+		for _, row := range rows {
+			result.rows[name] = append(result.rows[name], maps.Clone(row))
+		}
+	}
+	return result
+}
+
+// FakeRdsData understands just enough of the SQL the resources run to keep
+// track of databases, tables, columns, indexes, and the rows that are
+// inserted with parameters. It fails with the same
+// SQLState codes Postgres does, anything else it doesn't understand
+// succeeds. Statements in a transaction are undone on rollback.
+type FakeRdsData struct {
+	fake
+	databases    map[string]*fakeDatabase
+	statements   []string
+	transactions map[string]map[string]*fakeDatabase
+}
+
+func NewFakeRdsData(recorder *Recorder) *FakeRdsData {
+	return &FakeRdsData{
+		fake:         fake{service: "RdsData", recorder: recorder},
+		databases:    map[string]*fakeDatabase{},
+		transactions: map[string]map[string]*fakeDatabase{},
+	}
+}
+
+// CreateDatabase creates an empty database like the cluster was set up with
+func (f *FakeRdsData) CreateDatabase(name string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.execute("", "create database "+name, nil)
+}
+
+// Statements returns the SQL that was run, in order
+func (f *FakeRdsData) Statements() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.statements...)
+}
+
+// Columns returns the columns of a table
+func (f *FakeRdsData) Columns(database string, table string) []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if db, ok := f.databases[database]; ok {
+		return mapKeys(db.tables[table])
+	}
+	return nil
+}
+
+// Rows returns the rows inserted into a table
+func (f *FakeRdsData) Rows(database string, table string) []map[string]string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	result := []map[string]string{}
+	if db, ok := f.databases[database]; ok {
+		for _, row := range db.rows[table] {
+			result = append(result, maps.Clone(row))
+		}
+	}
+	return result
+}
+
+// Relations returns the tables and indexes in a database
+func (f *FakeRdsData) Relations(database string) []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if db, ok := f.databases[database]; ok {
+		return mapKeys(db.relations)
+	}
+	return nil
+}
+
+func sqlError(state string, message string) error {
+	return &types.BadRequestException{Message: aws.String(fmt.Sprintf("ERROR: %s; SQLState: %s", message, state))}
+}
+
+var (
+	sqlCreateDatabase = regexp.MustCompile(`(?i)^create database (\w+)$`)
+	sqlCreateTable    = regexp.MustCompile(`(?is)^create table (if not exists )?(\w+) \((.*)\)$`)
+	sqlCreateIndex    = regexp.MustCompile(`(?i)^create index (if not exists )?(\w+ )?on (\w+)`)
+	sqlDropIndex      = regexp.MustCompile(`(?i)^drop index (if exists )?(\w+)$`)
+	sqlCreateExt      = regexp.MustCompile(`(?i)^create extension (if not exists )?(\w+)$`)
+	sqlRenameColumn   = regexp.MustCompile(`(?i)^alter table (\w+) rename column (\w+) to (\w+)$`)
+	sqlAddColumn      = regexp.MustCompile(`(?i)^alter table (\w+) add column (\w+)`)
+	sqlRegclass       = regexp.MustCompile(`(?i)^select to_regclass\('(\w+)'\)`)
+	sqlInsert         = regexp.MustCompile(`(?i)^insert into (\w+) \(([^)]*)\) values \(([^)]*)\)$`)
+	sqlSelect         = regexp.MustCompile(`(?i)^select ([\w, ]+) from (\w+)(?: order by (\w+))?$`)
+)
+
+func splitSqlList(list string) []string {
+	result := []string{}
+	for _, item := range strings.Split(list, ",") {
+		result = append(result, strings.TrimSpace(item))
+	}
+	return result
+}
+
+// Applies a statement to the database, the lock is held
+func (f *FakeRdsData) execute(database string, sql string, parameters []types.SqlParameter) (*rdsdata.ExecuteStatementOutput, error) {
+	sql = strings.TrimSuffix(strings.TrimSpace(sql), ";")
+	f.statements = append(f.statements, sql)
+	result := &rdsdata.ExecuteStatementOutput{}
+
+	if match := sqlCreateDatabase.FindStringSubmatch(sql); match != nil {
+		if _, ok := f.databases[match[1]]; ok {
+			return nil, sqlError("42P04", fmt.Sprintf("database %q already exists", match[1]))
+		}
+		f.databases[match[1]] = &fakeDatabase{
+			extensions: map[string]bool{},
+			tables:     map[string]map[string]bool{},
+			relations:  map[string]bool{},
+			rows:       map[string][]map[string]string{},
+		}
+		return result, nil
+	}
+
+	db, ok := f.databases[database]
+	if !ok {
+		return nil, sqlError("3D000", fmt.Sprintf("database %q does not exist", database))
+	}
+	switch {
+	case sqlCreateExt.MatchString(sql):
+		match := sqlCreateExt.FindStringSubmatch(sql)
+		if db.extensions[match[2]] && match[1] == "" {
+			return nil, sqlError("42710", fmt.Sprintf("extension %q already exists", match[2]))
+		}
+		db.extensions[match[2]] = true
+	case sqlCreateTable.MatchString(sql):
+		match := sqlCreateTable.FindStringSubmatch(sql)
+		if db.relations[match[2]] {
+			if match[1] != "" {
+				return result, nil
+			}
+			return nil, sqlError("42P07", fmt.Sprintf("relation %q already exists", match[2]))
+		}
+		columns := map[string]bool{}
+		for _, column := range strings.Split(match[3], ",") {
+			if fields := strings.Fields(column); len(fields) > 0 {
+				columns[fields[0]] = true
+			}
+		}
+		db.tables[match[2]] = columns
+		db.relations[match[2]] = true
+		db.rows[match[2]] = nil
+	case sqlCreateIndex.MatchString(sql):
+		match := sqlCreateIndex.FindStringSubmatch(sql)
+		if _, ok := db.tables[match[3]]; !ok {
+			return nil, sqlError("42P01", fmt.Sprintf("relation %q does not exist", match[3]))
+		}
+		name := strings.TrimSpace(match[2])
+		if name == "" {
+			// Postgres picks a name that isn't taken
+			name = match[3] + "_idx"
+			for i := 1; db.relations[name]; i++ {
+				name = fmt.Sprintf("%s_idx%d", match[3], i)
+			}
+		}
+		if db.relations[name] {
+			if match[1] != "" {
+				return result, nil
+			}
+			return nil, sqlError("42P07", fmt.Sprintf("relation %q already exists", name))
+		}
+		db.relations[name] = true
+	case sqlDropIndex.MatchString(sql):
+		match := sqlDropIndex.FindStringSubmatch(sql)
+		if !db.relations[match[2]] && match[1] == "" {
+			return nil, sqlError("42704", fmt.Sprintf("index %q does not exist", match[2]))
+		}
+		delete(db.relations, match[2])
+	case sqlRenameColumn.MatchString(sql):
+		match := sqlRenameColumn.FindStringSubmatch(sql)
+		columns, ok := db.tables[match[1]]
+		if !ok {
+			return nil, sqlError("42P01", fmt.Sprintf("relation %q does not exist", match[1]))
+		}
+		if !columns[match[2]] {
+			return nil, sqlError("42703", fmt.Sprintf("column %q does not exist", match[2]))
+		}
+		if columns[match[3]] {
+			return nil, sqlError("42701", fmt.Sprintf("column %q of relation %q already exists", match[3], match[1]))
+		}
+		delete(columns, match[2])
+		columns[match[3]] = true
+	case sqlAddColumn.MatchString(sql):
+		match := sqlAddColumn.FindStringSubmatch(sql)
+		columns, ok := db.tables[match[1]]
+		if !ok {
+			return nil, sqlError("42P01", fmt.Sprintf("relation %q does not exist", match[1]))
+		}
+		if columns[match[2]] {
+			return nil, sqlError("42701", fmt.Sprintf("column %q of relation %q already exists", match[2], match[1]))
+		}
+		columns[match[2]] = true
+	case sqlRegclass.MatchString(sql):
+		match := sqlRegclass.FindStringSubmatch(sql)
+		var field types.Field = &types.FieldMemberIsNull{Value: true}
+		if db.relations[match[1]] {
+			field = &types.FieldMemberStringValue{Value: match[1]}
+		}
+		result.Records = [][]types.Field{{field}}
+	case sqlInsert.MatchString(sql):
+		match := sqlInsert.FindStringSubmatch(sql)
+		if _, ok := db.tables[match[1]]; !ok {
+			return nil, sqlError("42P01", fmt.Sprintf("relation %q does not exist", match[1]))
+		}
+		columns, values := splitSqlList(match[2]), splitSqlList(match[3])
+		row := map[string]string{}
+		for i, column := range columns {
+			value := strings.Trim(values[i], "'")
+			for _, parameter := range parameters {
+				if ":"+aws.ToString(parameter.Name) == values[i] {
+					if field, ok := parameter.Value.(*types.FieldMemberStringValue); ok {
+						value = field.Value
+					}
+				}
+			}
+			row[column] = value
+		}
+		db.rows[match[1]] = append(db.rows[match[1]], row)
+	case sqlSelect.MatchString(sql):
+		match := sqlSelect.FindStringSubmatch(sql)
+		if _, ok := db.tables[match[2]]; !ok {
+			return nil, sqlError("42P01", fmt.Sprintf("relation %q does not exist", match[2]))
+		}
+		rows := append([]map[string]string{}, db.rows[match[2]]...)
+		if match[3] != "" {
+			sort.SliceStable(rows, func(i, j int) bool {
+				return rows[i][match[3]] < rows[j][match[3]]
+			})
+		}
+		for _, row := range rows {
+			record := []types.Field{}
+			for _, column := range splitSqlList(match[1]) {
+				record = append(record, &types.FieldMemberStringValue{Value: row[column]})
+			}
+			result.Records = append(result.Records, record)
+		}
+	}
+	return result, nil
+}
+
+func (f *FakeRdsData) ExecuteStatement(ctx context.Context, params *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+	done, err := f.begin("ExecuteStatement")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if id := aws.ToString(params.TransactionId); id != "" {
+		if f.transactions[id] == nil {
+			return nil, &types.BadRequestException{Message: aws.String(fmt.Sprintf("Transaction %s is not found", id))}
+		}
+	}
+	return f.execute(aws.ToString(params.Database), aws.ToString(params.Sql), params.Parameters)
+}
+
+func (f *FakeRdsData) BeginTransaction(ctx context.Context, params *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+	done, err := f.begin("BeginTransaction")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	snapshot := map[string]*fakeDatabase{}
+	for name, db := range f.databases {
+		snapshot[name] = db.clone()
+	}
+	id := fmt.Sprintf("tx-%d", len(f.transactions)+1)
+	f.transactions[id] = snapshot
+	return &rdsdata.BeginTransactionOutput{TransactionId: aws.String(id)}, nil
+}
+
+func (f *FakeRdsData) CommitTransaction(ctx context.Context, params *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+	done, err := f.begin("CommitTransaction")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	id := aws.ToString(params.TransactionId)
+	if f.transactions[id] == nil {
+		return nil, &types.BadRequestException{Message: aws.String(fmt.Sprintf("Transaction %s is not found", id))}
+	}
+	f.transactions[id] = nil
+	return &rdsdata.CommitTransactionOutput{TransactionStatus: aws.String("Transaction Committed")}, nil
+}
+
+func (f *FakeRdsData) RollbackTransaction(ctx context.Context, params *rdsdata.RollbackTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error) {
+	done, err := f.begin("RollbackTransaction")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	id := aws.ToString(params.TransactionId)
+	snapshot := f.transactions[id]
+	if snapshot == nil {
+		return nil, &types.BadRequestException{Message: aws.String(fmt.Sprintf("Transaction %s is not found", id))}
+	}
+	f.databases = snapshot
+	f.transactions[id] = nil
+	return &rdsdata.RollbackTransactionOutput{TransactionStatus: aws.String("Rollback Complete")}, nil
+}
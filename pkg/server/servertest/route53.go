@@ -0,0 +1,183 @@
+package servertest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+type fakeZone struct {
+	name string
+	// The record sets by name and type
+	records map[string]types.ResourceRecordSet
+}
+
+// FakeRoute53 keeps hosted zones and their records in memory. Changes are in
+// sync right away.
+type FakeRoute53 struct {
+	fake
+	zones   map[string]*fakeZone
+	changes int
+}
+
+func NewFakeRoute53(recorder *Recorder) *FakeRoute53 {
+	return &FakeRoute53{
+		fake:  fake{service: "Route53", recorder: recorder},
+		zones: map[string]*fakeZone{},
+	}
+}
+
+// Route 53 names are fully qualified and escape the wildcard
+func route53Name(name string) string {
+	return strings.ReplaceAll(strings.TrimSuffix(strings.ToLower(name), ".")+".", "*", `\052`)
+}
+
+func route53Key(name string, recordType types.RRType) string {
+	return route53Name(name) + " " + string(recordType)
+}
+
+func (f *FakeRoute53) CreateZone(id string, name string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.zones[id] = &fakeZone{name: route53Name(name), records: map[string]types.ResourceRecordSet{}}
+}
+
+// Records returns the values of a record set, nil if there isn't one
+func (f *FakeRoute53) Records(zoneId string, name string, recordType string) []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	zone, ok := f.zones[zoneId]
+	if !ok {
+		return nil
+	}
+	set, ok := zone.records[route53Key(name, types.RRType(recordType))]
+	if !ok {
+		return nil
+	}
+	values := []string{}
+	for _, record := range set.ResourceRecords {
+		values = append(values, aws.ToString(record.Value))
+	}
+	return values
+}
+
+func (f *FakeRoute53) zone(id *string) (*fakeZone, error) {
+	zone, ok := f.zones[strings.TrimPrefix(aws.ToString(id), "/hostedzone/")]
+	if !ok {
+		return nil, &types.NoSuchHostedZone{Message: aws.String(fmt.Sprintf("No hosted zone found with ID: %s", aws.ToString(id)))}
+	}
+	return zone, nil
+}
+
+func (f *FakeRoute53) ListHostedZones(ctx context.Context, params *route53.ListHostedZonesInput, optFns ...func(*route53.Options)) (*route53.ListHostedZonesOutput, error) {
+	done, err := f.begin("ListHostedZones")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	result := &route53.ListHostedZonesOutput{}
+	for _, id := range mapKeys(f.zones) {
+		result.HostedZones = append(result.HostedZones, types.HostedZone{
+			Id:   aws.String("/hostedzone/" + id),
+			Name: aws.String(f.zones[id].name),
+		})
+	}
+	return result, nil
+}
+
+func (f *FakeRoute53) GetHostedZone(ctx context.Context, params *route53.GetHostedZoneInput, optFns ...func(*route53.Options)) (*route53.GetHostedZoneOutput, error) {
+	done, err := f.begin("GetHostedZone")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	zone, err := f.zone(params.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &route53.GetHostedZoneOutput{
+		HostedZone: &types.HostedZone{Id: params.Id, Name: aws.String(zone.name)},
+	}, nil
+}
+
+// Lists the record sets from the start name and type on, in order
+func (f *FakeRoute53) ListResourceRecordSets(ctx context.Context, params *route53.ListResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ListResourceRecordSetsOutput, error) {
+	done, err := f.begin("ListResourceRecordSets")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	zone, err := f.zone(params.HostedZoneId)
+	if err != nil {
+		return nil, err
+	}
+	keys := mapKeys(zone.records)
+	start := sort.SearchStrings(keys, route53Key(aws.ToString(params.StartRecordName), params.StartRecordType))
+	limit := len(keys)
+	if params.MaxItems != nil {
+		limit = start + int(*params.MaxItems)
+	}
+	result := &route53.ListResourceRecordSetsOutput{}
+	for _, key := range keys[start:min(limit, len(keys))] {
+		result.ResourceRecordSets = append(result.ResourceRecordSets, zone.records[key])
+	}
+	return result, nil
+}
+
+// Applies every change or none of them, like Route 53 does
+func (f *FakeRoute53) ChangeResourceRecordSets(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	done, err := f.begin("ChangeResourceRecordSets")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	zone, err := f.zone(params.HostedZoneId)
+	if err != nil {
+		return nil, err
+	}
+	records := map[string]types.ResourceRecordSet{}
+	for key, set := range zone.records {
+		records[key] = set
+	}
+	for _, change := range params.ChangeBatch.Changes {
+		set := *change.ResourceRecordSet
+		set.Name = aws.String(route53Name(aws.ToString(set.Name)))
+		key := route53Key(aws.ToString(set.Name), set.Type)
+		_, exists := records[key]
+		switch change.Action {
+		case types.ChangeActionCreate:
+			if exists {
+				return nil, &types.InvalidChangeBatch{Message: aws.String(fmt.Sprintf("Tried to create resource record set [name='%s', type='%s'] but it already exists", aws.ToString(set.Name), set.Type))}
+			}
+			records[key] = set
+		case types.ChangeActionUpsert:
+			records[key] = set
+		case types.ChangeActionDelete:
+			if !exists {
+				return nil, &types.InvalidChangeBatch{Message: aws.String(fmt.Sprintf("Tried to delete resource record set [name='%s', type='%s'] but it was not found", aws.ToString(set.Name), set.Type))}
+			}
+			delete(records, key)
+		}
+	}
+	zone.records = records
+	f.changes++
+	return &route53.ChangeResourceRecordSetsOutput{
+		ChangeInfo: &types.ChangeInfo{Id: aws.String(fmt.Sprintf("/change/C%d", f.changes)), Status: types.ChangeStatusInsync},
+	}, nil
+}
+
+func (f *FakeRoute53) GetChange(ctx context.Context, params *route53.GetChangeInput, optFns ...func(*route53.Options)) (*route53.GetChangeOutput, error) {
+	done, err := f.begin("GetChange")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return &route53.GetChangeOutput{
+		ChangeInfo: &types.ChangeInfo{Id: params.Id, Status: types.ChangeStatusInsync},
+	}, nil
+}
@@ -0,0 +1,237 @@
+package servertest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type FakeObject struct {
+	Body         []byte
+	ContentType  string
+	CacheControl string
+}
+
+type fakeUpload struct {
+	bucket string
+	key    string
+	object FakeObject
+	parts  map[int32][]byte
+}
+
+// FakeS3 keeps the buckets and their objects in memory
+type FakeS3 struct {
+	fake
+	buckets map[string]map[string]FakeObject
+	uploads map[string]*fakeUpload
+	next    int
+}
+
+func NewFakeS3(recorder *Recorder) *FakeS3 {
+	return &FakeS3{
+		fake:    fake{service: "S3", recorder: recorder},
+		buckets: map[string]map[string]FakeObject{},
+		uploads: map[string]*fakeUpload{},
+	}
+}
+
+func (f *FakeS3) CreateBucket(name string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.buckets[name] == nil {
+		f.buckets[name] = map[string]FakeObject{}
+	}
+}
+
+// Object returns a copy of an object in a bucket
+func (f *FakeS3) Object(bucket string, key string) (FakeObject, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	object, ok := f.buckets[bucket][key]
+	return object, ok
+}
+
+// DeleteObject removes an object like it was deleted outside of a deploy
+func (f *FakeS3) DeleteObject(bucket string, key string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.buckets[bucket], key)
+}
+
+// Keys returns the sorted keys of the objects in a bucket
+func (f *FakeS3) Keys(bucket string) []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.keys(bucket)
+}
+
+func (f *FakeS3) keys(bucket string) []string {
+	keys := []string{}
+	for key := range f.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (f *FakeS3) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	done, err := f.begin("HeadBucket")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if f.buckets[aws.ToString(params.Bucket)] == nil {
+		return nil, &types.NotFound{}
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (f *FakeS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	done, err := f.begin("HeadObject")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	object, ok := f.buckets[aws.ToString(params.Bucket)][aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NotFound{}
+	}
+	return &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(object.Body))),
+		ContentType:   aws.String(object.ContentType),
+	}, nil
+}
+
+func (f *FakeS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	done, err := f.begin("PutObject")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	bucket := aws.ToString(params.Bucket)
+	if f.buckets[bucket] == nil {
+		return nil, &types.NoSuchBucket{}
+	}
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.buckets[bucket][aws.ToString(params.Key)] = FakeObject{
+		Body:         body,
+		ContentType:  aws.ToString(params.ContentType),
+		CacheControl: aws.ToString(params.CacheControl),
+	}
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *FakeS3) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	done, err := f.begin("CreateMultipartUpload")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	bucket := aws.ToString(params.Bucket)
+	if f.buckets[bucket] == nil {
+		return nil, &types.NoSuchBucket{}
+	}
+	f.next++
+	id := fmt.Sprintf("upload-%d", f.next)
+	f.uploads[id] = &fakeUpload{
+		bucket: bucket,
+		key:    aws.ToString(params.Key),
+		object: FakeObject{
+			ContentType:  aws.ToString(params.ContentType),
+			CacheControl: aws.ToString(params.CacheControl),
+		},
+		parts: map[int32][]byte{},
+	}
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String(id)}, nil
+}
+
+func (f *FakeS3) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	done, err := f.begin("UploadPart")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	upload, ok := f.uploads[aws.ToString(params.UploadId)]
+	if !ok {
+		return nil, &types.NoSuchUpload{}
+	}
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	number := aws.ToInt32(params.PartNumber)
+	upload.parts[number] = body
+	return &s3.UploadPartOutput{ETag: aws.String(fmt.Sprintf("part-%d", number))}, nil
+}
+
+func (f *FakeS3) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	done, err := f.begin("CompleteMultipartUpload")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	id := aws.ToString(params.UploadId)
+	upload, ok := f.uploads[id]
+	if !ok {
+		return nil, &types.NoSuchUpload{}
+	}
+	object := upload.object
+	for _, part := range params.MultipartUpload.Parts {
+		body, ok := upload.parts[aws.ToInt32(part.PartNumber)]
+		if !ok {
+			return nil, fmt.Errorf("part %d was never uploaded", aws.ToInt32(part.PartNumber))
+		}
+		object.Body = append(object.Body, body...)
+	}
+	f.buckets[upload.bucket][upload.key] = object
+	delete(f.uploads, id)
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (f *FakeS3) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	done, err := f.begin("AbortMultipartUpload")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	delete(f.uploads, aws.ToString(params.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (f *FakeS3) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	done, err := f.begin("DeleteObjects")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	bucket := aws.ToString(params.Bucket)
+	if f.buckets[bucket] == nil {
+		return nil, &types.NoSuchBucket{}
+	}
+	if len(params.Delete.Objects) > 1000 {
+		return nil, fmt.Errorf("can't delete more than 1000 objects at once, got %d", len(params.Delete.Objects))
+	}
+	result := &s3.DeleteObjectsOutput{}
+	for _, object := range params.Delete.Objects {
+		delete(f.buckets[bucket], aws.ToString(object.Key))
+		if !aws.ToBool(params.Delete.Quiet) {
+			result.Deleted = append(result.Deleted, types.DeletedObject{Key: object.Key})
+		}
+	}
+	return result, nil
+}
+
+// Uploads that were started and never completed or aborted
+func (f *FakeS3) PendingUploads() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.uploads)
+}
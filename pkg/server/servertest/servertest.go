@@ -0,0 +1,254 @@
+// Package servertest boots the resource RPC server in process with in memory
+// fakes of the AWS, Cloudflare, and Vercel APIs it talks to, so the
+// Resource.* methods can be tested without an account.
+//
+// The server runs for a project named "app" on the "test" stage, rooted in
+// a temporary directory with a local home in another one. No providers are
+// loaded, so the Cloudflare and Vercel resources use the API tokens in the
+// environment.
+package servertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sst/sst/v3/pkg/project"
+	"github.com/sst/sst/v3/pkg/project/provider"
+	"github.com/sst/sst/v3/pkg/server"
+	"github.com/sst/sst/v3/pkg/server/resource"
+)
+
+type Server struct {
+	// Point SST_SERVER at this to have components call the fakes
+	URL string
+	// The root of the project, relative paths in inputs start here
+	Root string
+
+	S3            *FakeS3
+	CloudFront    *FakeCloudFront
+	Lambda        *FakeLambda
+	KeyValueStore *FakeKeyValueStore
+	CloudWatch    *FakeCloudWatch
+	RdsData       *FakeRdsData
+	Route53       *FakeRoute53
+	Iam           *FakeIam
+	Cloudflare    *FakeCloudflare
+	Vercel        *FakeVercel
+	Recorder      *Recorder
+
+	lock sync.Mutex
+	id   int
+}
+
+// New starts a server that is shut down when the test is done
+func New(t testing.TB) *Server {
+	t.Helper()
+	recorder := &Recorder{}
+	s := &Server{
+		Root:          t.TempDir(),
+		S3:            NewFakeS3(recorder),
+		CloudFront:    NewFakeCloudFront(recorder),
+		Lambda:        NewFakeLambda(recorder),
+		KeyValueStore: NewFakeKeyValueStore(recorder),
+		CloudWatch:    NewFakeCloudWatch(recorder),
+		RdsData:       NewFakeRdsData(recorder),
+		Route53:       NewFakeRoute53(recorder),
+		Iam:           NewFakeIam(recorder),
+		Cloudflare:    NewFakeCloudflare(recorder),
+		Vercel:        NewFakeVercel(recorder),
+		Recorder:      recorder,
+	}
+
+	t.Cleanup(s.Cloudflare.start().Close)
+	t.Cleanup(s.Vercel.start().Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	rpcServer := rpc.NewServer()
+	p := project.NewWith(s.Root, &project.App{Name: "app", Stage: "test"}, provider.NewLocalHomeIn(t.TempDir()))
+	err := resource.RegisterWith(ctx, p, rpcServer, resource.Clients{
+		Aws: resource.AwsClients{
+			Config: func() (aws.Config, error) {
+				return aws.Config{Region: "us-east-1"}, nil
+			},
+			S3:            func(cfg aws.Config) resource.S3Client { return s.S3 },
+			CloudFront:    func(cfg aws.Config) resource.CloudFrontClient { return s.CloudFront },
+			Lambda:        func(cfg aws.Config) resource.LambdaClient { return s.Lambda },
+			KeyValueStore: func(cfg aws.Config) resource.KeyValueStoreClient { return s.KeyValueStore },
+			CloudWatch:    func(cfg aws.Config) resource.CloudWatchClient { return s.CloudWatch },
+			RdsData:       func(cfg aws.Config) resource.RdsDataClient { return s.RdsData },
+			Route53:       func(cfg aws.Config) resource.Route53Client { return s.Route53 },
+			Iam:           func(cfg aws.Config) resource.IamClient { return s.Iam },
+		},
+		CloudflareUrl: s.Cloudflare.URL,
+		VercelUrl:     s.Vercel.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener := httptest.NewServer(server.RpcHandler(rpcServer))
+	t.Cleanup(listener.Close)
+	s.URL = listener.URL
+	return s
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *string         `json:"error"`
+}
+
+// Call makes the same JSON-RPC call a component does, like
+// Call("Resource.Aws.KvKeys.Create", inputs, &result), and records it
+func (s *Server) Call(method string, args interface{}, reply interface{}) error {
+	s.lock.Lock()
+	s.id++
+	id := s.id
+	s.lock.Unlock()
+
+	err := s.call(id, method, args, reply)
+	s.Recorder.record(method, err)
+	return err
+}
+
+func (s *Server) call(id int, method string, args interface{}, reply interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"id":     id,
+		"method": method,
+		"params": []interface{}{args},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(s.URL+"/rpc", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("rpc call failed with %s", resp.Status)
+	}
+	var result rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if result.Error != nil {
+		return errors.New(*result.Error)
+	}
+	if reply == nil {
+		return nil
+	}
+	return json.Unmarshal(result.Result, reply)
+}
+
+// A call made to the server or to one of the fakes
+type Call struct {
+	// Resource.Aws.KvKeys.Create for the server, KeyValueStore.UpdateKeys for
+	// a fake
+	Name string
+	Err  error
+}
+
+// Recorder keeps the calls in the order they were made so a test can check
+// the sequence a deploy went through
+type Recorder struct {
+	lock  sync.Mutex
+	calls []Call
+}
+
+func (r *Recorder) record(name string, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.calls = append(r.calls, Call{Name: name, Err: err})
+}
+
+func (r *Recorder) Calls() []Call {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Call{}, r.calls...)
+}
+
+// Names returns the names of the calls that start with the prefix, like
+// "Resource." for the ones made to the server
+func (r *Recorder) Names(prefix string) []string {
+	result := []string{}
+	for _, call := range r.Calls() {
+		if strings.HasPrefix(call.Name, prefix) {
+			result = append(result, call.Name)
+		}
+	}
+	return result
+}
+
+// Count returns how many times the call was made
+func (r *Recorder) Count(name string) int {
+	count := 0
+	for _, call := range r.Calls() {
+		if call.Name == name {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.calls = nil
+}
+
+// The failures a fake returns instead of handling the call
+type fake struct {
+	service  string
+	recorder *Recorder
+	lock     sync.Mutex
+	failures map[string][]error
+}
+
+// FailNext makes the next calls to the operation fail with the errors, one
+// call per error
+func (f *fake) FailNext(operation string, errs ...error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.failures == nil {
+		f.failures = map[string][]error{}
+	}
+	f.failures[operation] = append(f.failures[operation], errs...)
+}
+
+// Records the call and returns the failure queued up for it. The lock is
+// held until the returned func is called.
+func (f *fake) begin(operation string) (func(), error) {
+	f.lock.Lock()
+	var err error
+	if queued := f.failures[operation]; len(queued) > 0 {
+		err = queued[0]
+		f.failures[operation] = queued[1:]
+	}
+	f.recorder.record(f.service+"."+operation, err)
+	if err != nil {
+		f.lock.Unlock()
+		return nil, err
+	}
+	return f.lock.Unlock, nil
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
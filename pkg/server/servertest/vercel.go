@@ -0,0 +1,145 @@
+package servertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+type FakeVercelRecord struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	TTL        int    `json:"ttl"`
+	MxPriority *int   `json:"mxPriority,omitempty"`
+}
+
+// FakeVercel serves the DNS record endpoints of the Vercel API from memory.
+// Point the resources at URL.
+type FakeVercel struct {
+	fake
+	URL     string
+	domains map[string][]FakeVercelRecord
+	records int
+}
+
+func NewFakeVercel(recorder *Recorder) *FakeVercel {
+	return &FakeVercel{
+		fake:    fake{service: "Vercel", recorder: recorder},
+		domains: map[string][]FakeVercelRecord{},
+	}
+}
+
+func (f *FakeVercel) start() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4/domains/{domain}/records", f.listRecords)
+	// the SST resources create records with both versions
+	mux.HandleFunc("POST /v2/domains/{domain}/records", f.createRecord)
+	mux.HandleFunc("POST /v4/domains/{domain}/records", f.createRecord)
+	mux.HandleFunc("DELETE /v2/domains/{domain}/records/{id}", f.deleteRecord)
+	server := httptest.NewServer(mux)
+	f.URL = server.URL
+	return server
+}
+
+func (f *FakeVercel) CreateDomain(domain string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.domains[domain] = []FakeVercelRecord{}
+}
+
+// Records returns a copy of the records of a domain
+func (f *FakeVercel) Records(domain string) []FakeVercelRecord {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]FakeVercelRecord{}, f.domains[domain]...)
+}
+
+func (f *FakeVercel) respond(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (f *FakeVercel) fail(w http.ResponseWriter, status int, code string, message string) {
+	f.respond(w, status, map[string]interface{}{
+		"error": map[string]string{"code": code, "message": message},
+	})
+}
+
+// Starts a request, holding the lock until the returned func is called.
+// Returns false if the request was already answered.
+func (f *FakeVercel) handle(w http.ResponseWriter, r *http.Request, operation string) (func(), []FakeVercelRecord, bool) {
+	done, err := f.begin(operation)
+	if err != nil {
+		f.fail(w, http.StatusBadRequest, "bad_request", err.Error())
+		return nil, nil, false
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		done()
+		f.fail(w, http.StatusForbidden, "forbidden", "Not authorized")
+		return nil, nil, false
+	}
+	records, ok := f.domains[r.PathValue("domain")]
+	if !ok {
+		done()
+		f.fail(w, http.StatusNotFound, "not_found", "Domain not found")
+		return nil, nil, false
+	}
+	return done, records, true
+}
+
+func (f *FakeVercel) listRecords(w http.ResponseWriter, r *http.Request) {
+	done, records, ok := f.handle(w, r, "ListRecords")
+	if !ok {
+		return
+	}
+	defer done()
+	f.respond(w, http.StatusOK, map[string]interface{}{
+		"records":    records,
+		"pagination": map[string]interface{}{"next": nil},
+	})
+}
+
+// Rejects a record with the same name, type, and value like Vercel does
+func (f *FakeVercel) createRecord(w http.ResponseWriter, r *http.Request) {
+	done, records, ok := f.handle(w, r, "CreateRecord")
+	if !ok {
+		return
+	}
+	defer done()
+	var record FakeVercelRecord
+	if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+		f.fail(w, http.StatusBadRequest, "bad_request", "Invalid request body")
+		return
+	}
+	for _, existing := range records {
+		if existing.Name == record.Name && existing.Type == record.Type && existing.Value == record.Value {
+			f.fail(w, http.StatusBadRequest, "record_exists", "A record with the same name, type, and value already exists")
+			return
+		}
+	}
+	f.records++
+	record.Id = fmt.Sprintf("rec_%d", f.records)
+	f.domains[r.PathValue("domain")] = append(records, record)
+	f.respond(w, http.StatusOK, map[string]string{"uid": record.Id})
+}
+
+func (f *FakeVercel) deleteRecord(w http.ResponseWriter, r *http.Request) {
+	done, records, ok := f.handle(w, r, "DeleteRecord")
+	if !ok {
+		return
+	}
+	defer done()
+	for i, record := range records {
+		if record.Id == r.PathValue("id") {
+			f.domains[r.PathValue("domain")] = append(records[:i:i], records[i+1:]...)
+			f.respond(w, http.StatusOK, map[string]interface{}{})
+			return
+		}
+	}
+	f.fail(w, http.StatusNotFound, "not_found", "Record not found")
+}
@@ -0,0 +1,275 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// The component tree of a stage along with the dependencies between its
+// resources
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+type GraphNode struct {
+	URN    resource.URN `json:"urn"`
+	Name   string       `json:"name"`
+	Type   string       `json:"type"`
+	Parent resource.URN `json:"parent,omitempty"`
+	Failed bool         `json:"failed,omitempty"`
+}
+
+const (
+	GraphEdgeParent     = "parent"
+	GraphEdgeDependency = "dependency"
+)
+
+type GraphEdge struct {
+	From       resource.URN `json:"from"`
+	To         resource.URN `json:"to"`
+	Kind       string       `json:"kind"`
+	Properties []string     `json:"properties,omitempty"`
+}
+
+type GraphOptions struct {
+	// Only include the resources of these types and their children. A type
+	// ending in * matches every type that starts with it.
+	Types []string
+	// Only include the resource with this name and its children
+	Root string
+	// The resources that failed in the last update
+	Failed map[resource.URN]bool
+}
+
+func NewGraph(resources []apitype.ResourceV3, opts GraphOptions) *Graph {
+	children := map[resource.URN][]resource.URN{}
+	for _, item := range resources {
+		if item.Parent != "" {
+			children[item.Parent] = append(children[item.Parent], item.URN)
+		}
+	}
+
+	// every resource is included unless there are filters, in which case
+	// the matches and everything under them are
+	included := map[resource.URN]bool{}
+	var include func(urn resource.URN)
+	include = func(urn resource.URN) {
+		if included[urn] {
+			return
+		}
+		included[urn] = true
+		for _, child := range children[urn] {
+			include(child)
+		}
+	}
+	for _, item := range resources {
+		if strings.HasPrefix(string(item.Type), "pulumi:providers:") {
+			continue
+		}
+		if opts.Root != "" && item.URN.Name() != opts.Root {
+			continue
+		}
+		if len(opts.Types) > 0 && !matchType(opts.Types, string(item.Type)) {
+			continue
+		}
+		include(item.URN)
+	}
+
+	graph := &Graph{
+		Nodes: []GraphNode{},
+		Edges: []GraphEdge{},
+	}
+	for _, item := range resources {
+		if !included[item.URN] || strings.HasPrefix(string(item.Type), "pulumi:providers:") {
+			continue
+		}
+		node := GraphNode{
+			URN:    item.URN,
+			Name:   item.URN.Name(),
+			Type:   string(item.Type),
+			Failed: opts.Failed[item.URN],
+		}
+		if included[item.Parent] {
+			node.Parent = item.Parent
+			graph.Edges = append(graph.Edges, GraphEdge{
+				From: item.Parent,
+				To:   item.URN,
+				Kind: GraphEdgeParent,
+			})
+		}
+		graph.Nodes = append(graph.Nodes, node)
+
+		properties := map[resource.URN][]string{}
+		for key, dependencies := range item.PropertyDependencies {
+			for _, dependency := range dependencies {
+				properties[dependency] = append(properties[dependency], string(key))
+			}
+		}
+		seen := map[resource.URN]bool{}
+		for _, dependency := range append(append([]resource.URN{}, item.Dependencies...), keys(properties)...) {
+			if seen[dependency] || !included[dependency] || dependency == item.Parent {
+				continue
+			}
+			seen[dependency] = true
+			sort.Strings(properties[dependency])
+			graph.Edges = append(graph.Edges, GraphEdge{
+				From:       item.URN,
+				To:         dependency,
+				Kind:       GraphEdgeDependency,
+				Properties: properties[dependency],
+			})
+		}
+	}
+	return graph
+}
+
+// DOT renders the graph for Graphviz with the components as clusters
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph sst {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, fontname=\"Helvetica\"];\n")
+	children := g.children()
+	nodes := g.nodes()
+	var write func(urn resource.URN, indent string)
+	write = func(urn resource.URN, indent string) {
+		node := nodes[urn]
+		attrs := "label=" + strconv.Quote(node.Name+"\n"+node.Type)
+		if node.Failed {
+			attrs += ", color=red, fontcolor=red"
+		}
+		if len(children[urn]) == 0 {
+			fmt.Fprintf(&b, "%s%s [%s];\n", indent, strconv.Quote(string(urn)), attrs)
+			return
+		}
+		fmt.Fprintf(&b, "%ssubgraph %s {\n", indent, strconv.Quote("cluster_"+string(urn)))
+		fmt.Fprintf(&b, "%s  label=%s;\n", indent, strconv.Quote(node.Name+"\n"+node.Type))
+		if node.Failed {
+			fmt.Fprintf(&b, "%s  color=red;\n", indent)
+		}
+		fmt.Fprintf(&b, "%s  %s [%s, style=dashed];\n", indent, strconv.Quote(string(urn)), attrs)
+		for _, child := range children[urn] {
+			write(child, indent+"  ")
+		}
+		fmt.Fprintf(&b, "%s}\n", indent)
+	}
+	for _, node := range g.Nodes {
+		if node.Parent == "" {
+			write(node.URN, "  ")
+		}
+	}
+	for _, edge := range g.Edges {
+		if edge.Kind != GraphEdgeDependency {
+			continue
+		}
+		attrs := ""
+		if len(edge.Properties) > 0 {
+			attrs = " [label=" + strconv.Quote(strings.Join(edge.Properties, ", ")) + "]"
+		}
+		fmt.Fprintf(&b, "  %s -> %s%s;\n", strconv.Quote(string(edge.From)), strconv.Quote(string(edge.To)), attrs)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a flowchart with the components as subgraphs
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	ids := map[resource.URN]string{}
+	for index, node := range g.Nodes {
+		ids[node.URN] = "n" + strconv.Itoa(index)
+	}
+	label := func(node GraphNode) string {
+		return strconv.Quote(strings.ReplaceAll(node.Name+"<br/>"+node.Type, "\"", "#quot;"))
+	}
+	children := g.children()
+	nodes := g.nodes()
+	var write func(urn resource.URN, indent string)
+	write = func(urn resource.URN, indent string) {
+		node := nodes[urn]
+		if len(children[urn]) == 0 {
+			fmt.Fprintf(&b, "%s%s[%s]\n", indent, ids[urn], label(node))
+			return
+		}
+		fmt.Fprintf(&b, "%ssubgraph %s_group[%s]\n", indent, ids[urn], label(node))
+		fmt.Fprintf(&b, "%s  %s[%s]\n", indent, ids[urn], label(node))
+		for _, child := range children[urn] {
+			write(child, indent+"  ")
+		}
+		fmt.Fprintf(&b, "%send\n", indent)
+	}
+	for _, node := range g.Nodes {
+		if node.Parent == "" {
+			write(node.URN, "  ")
+		}
+	}
+	for _, edge := range g.Edges {
+		if edge.Kind != GraphEdgeDependency {
+			continue
+		}
+		if len(edge.Properties) > 0 {
+			fmt.Fprintf(&b, "  %s -->|%s| %s\n", ids[edge.From], strconv.Quote(strings.Join(edge.Properties, ", ")), ids[edge.To])
+			continue
+		}
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[edge.From], ids[edge.To])
+	}
+	failed := []string{}
+	for _, node := range g.Nodes {
+		if node.Failed {
+			failed = append(failed, ids[node.URN])
+		}
+	}
+	if len(failed) > 0 {
+		b.WriteString("  classDef failed stroke:#e5484d,color:#e5484d\n")
+		fmt.Fprintf(&b, "  class %s failed\n", strings.Join(failed, ","))
+	}
+	return b.String()
+}
+
+func (g *Graph) children() map[resource.URN][]resource.URN {
+	result := map[resource.URN][]resource.URN{}
+	for _, node := range g.Nodes {
+		if node.Parent != "" {
+			result[node.Parent] = append(result[node.Parent], node.URN)
+		}
+	}
+	return result
+}
+
+func (g *Graph) nodes() map[resource.URN]GraphNode {
+	result := map[resource.URN]GraphNode{}
+	for _, node := range g.Nodes {
+		result[node.URN] = node
+	}
+	return result
+}
+
+func matchType(types []string, value string) bool {
+	for _, item := range types {
+		if prefix, ok := strings.CutSuffix(item, "*"); ok && strings.HasPrefix(value, prefix) {
+			return true
+		}
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func keys(m map[resource.URN][]string) []resource.URN {
+	result := []resource.URN{}
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result
+}
@@ -0,0 +1,64 @@
+package state
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func urn(kind, name string) resource.URN {
+	return resource.URN("urn:pulumi:dev::app::" + kind + "::" + name)
+}
+
+var resources = []apitype.ResourceV3{
+	{URN: urn("pulumi:pulumi:Stack", "app-dev"), Type: "pulumi:pulumi:Stack"},
+	{URN: urn("pulumi:providers:aws", "default"), Type: "pulumi:providers:aws"},
+	{URN: urn("sst:aws:Bucket", "Uploads"), Type: "sst:aws:Bucket", Parent: urn("pulumi:pulumi:Stack", "app-dev")},
+	{URN: urn("aws:s3/bucketV2:BucketV2", "UploadsBucket"), Type: "aws:s3/bucketV2:BucketV2", Parent: urn("sst:aws:Bucket", "Uploads")},
+	{URN: urn("sst:aws:Function", "Api"), Type: "sst:aws:Function", Parent: urn("pulumi:pulumi:Stack", "app-dev")},
+	{
+		URN:          urn("aws:lambda/function:Function", "ApiFunction"),
+		Type:         "aws:lambda/function:Function",
+		Parent:       urn("sst:aws:Function", "Api"),
+		Dependencies: []resource.URN{urn("aws:s3/bucketV2:BucketV2", "UploadsBucket")},
+		PropertyDependencies: map[resource.PropertyKey][]resource.URN{
+			"environment": {urn("aws:s3/bucketV2:BucketV2", "UploadsBucket")},
+		},
+	},
+}
+
+func TestNewGraph(t *testing.T) {
+	graph := NewGraph(resources, GraphOptions{
+		Failed: map[resource.URN]bool{urn("aws:lambda/function:Function", "ApiFunction"): true},
+	})
+	if len(graph.Nodes) != 5 {
+		t.Fatalf("Expected 5 nodes without the provider, got %d", len(graph.Nodes))
+	}
+	dependencies := 0
+	for _, edge := range graph.Edges {
+		if edge.Kind == GraphEdgeDependency {
+			dependencies++
+			if len(edge.Properties) != 1 || edge.Properties[0] != "environment" {
+				t.Errorf("Expected the environment property, got %v", edge.Properties)
+			}
+		}
+	}
+	if dependencies != 1 {
+		t.Errorf("Expected 1 dependency, got %d", dependencies)
+	}
+	if !strings.Contains(graph.Mermaid(), "class n4 failed") {
+		t.Errorf("Expected the function to be failed:\n%s", graph.Mermaid())
+	}
+
+	graph = NewGraph(resources, GraphOptions{Root: "Api"})
+	if len(graph.Nodes) != 2 || len(graph.Edges) != 1 {
+		t.Errorf("Expected only the Api subtree, got %v", graph.Nodes)
+	}
+
+	graph = NewGraph(resources, GraphOptions{Types: []string{"aws:s3*"}})
+	if len(graph.Nodes) != 1 || graph.Nodes[0].Name != "UploadsBucket" {
+		t.Errorf("Expected only the bucket, got %v", graph.Nodes)
+	}
+}
@@ -0,0 +1,24 @@
+package tunnel
+
+import "strings"
+
+// ProxyEnv returns the environment variables that point proxy aware tools at
+// the SOCKS5 proxy listening on addr. The socks5h scheme makes clients send
+// hostnames through the proxy so they are resolved inside the VPC.
+func ProxyEnv(addr string) []string {
+	url := "socks5h://" + addr
+	result := []string{
+		"SST_TUNNEL_PROXY=" + url,
+	}
+	for _, key := range []string{"ALL_PROXY", "HTTP_PROXY", "HTTPS_PROXY"} {
+		result = append(result,
+			key+"="+url,
+			strings.ToLower(key)+"="+url,
+		)
+	}
+	result = append(result,
+		"NO_PROXY=localhost,127.0.0.1,::1",
+		"no_proxy=localhost,127.0.0.1,::1",
+	)
+	return result
+}
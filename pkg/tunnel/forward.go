@@ -0,0 +1,107 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Forward struct {
+	Name      string
+	LocalPort int
+	Host      string
+	Port      int
+}
+
+func (f Forward) LocalAddress() string {
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(f.LocalPort))
+}
+
+func (f Forward) RemoteAddress() string {
+	return net.JoinHostPort(f.Host, strconv.Itoa(f.Port))
+}
+
+// ParseForward parses a forward in the form of `<local-port>:<host>:<port>`.
+// The local port can be left out to listen on the same port as the remote.
+func ParseForward(name string, spec string) (Forward, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) == 2 {
+		parts = append([]string{parts[1]}, parts...)
+	}
+	if len(parts) != 3 || parts[1] == "" {
+		return Forward{}, fmt.Errorf("invalid forward %q, expected <local-port>:<host>:<port>", spec)
+	}
+	local, err := strconv.Atoi(parts[0])
+	if err != nil || local < 0 || local > 65535 {
+		return Forward{}, fmt.Errorf("invalid local port in forward %q", spec)
+	}
+	remote, err := strconv.Atoi(parts[2])
+	if err != nil || remote <= 0 || remote > 65535 {
+		return Forward{}, fmt.Errorf("invalid port in forward %q", spec)
+	}
+	if name == "" {
+		name = parts[1]
+	}
+	return Forward{
+		Name:      name,
+		LocalPort: local,
+		Host:      parts[1],
+		Port:      remote,
+	}, nil
+}
+
+// ServeForward listens on the local port and pipes every connection to the
+// remote host through dial until the context is cancelled.
+func ServeForward(ctx context.Context, forward Forward, dial DialFunc, onConn func(forward Forward, addr string)) error {
+	listener, err := net.Listen("tcp", forward.LocalAddress())
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if onConn != nil {
+			onConn(forward, local.RemoteAddr().String())
+		}
+		go func() {
+			defer local.Close()
+			remote, err := dial(ctx, "tcp", forward.RemoteAddress())
+			if err != nil {
+				slog.Error("failed to dial forward", "name", forward.Name, "addr", forward.RemoteAddress(), "err", err)
+				return
+			}
+			defer remote.Close()
+			pipe(local, remote)
+		}()
+	}
+}
+
+func pipe(a net.Conn, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(a, b)
+		a.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(b, a)
+		b.Close()
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,30 @@
+package tunnel
+
+import (
+	"reflect"
+	"testing"
+)
+
+var forwards = map[string]Forward{
+	"5432:db.internal:5432": {Name: "db.internal", LocalPort: 5432, Host: "db.internal", Port: 5432},
+	"15432:10.0.4.12:5432":  {Name: "10.0.4.12", LocalPort: 15432, Host: "10.0.4.12", Port: 5432},
+	"db.internal:6379":      {Name: "db.internal", LocalPort: 6379, Host: "db.internal", Port: 6379},
+}
+
+func TestParseForward(t *testing.T) {
+	for input, expected := range forwards {
+		result, err := ParseForward("", input)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", input, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	}
+	for _, input := range []string{"", "5432", "abc:db:5432", "5432::5432", "5432:db:0", "1:2:3:4"} {
+		if _, err := ParseForward("", input); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package tunnel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/global"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var knownHostsLock sync.Mutex
+
+// The user the known hosts file belongs to. Under sudo this is the user that
+// invoked it, which sudo sets and the caller can't override.
+type knownHostsOwner struct {
+	uid  int
+	gid  int
+	home string
+}
+
+func sudoOwner() *knownHostsOwner {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+	uid, uidErr := strconv.Atoi(os.Getenv("SUDO_UID"))
+	gid, gidErr := strconv.Atoi(os.Getenv("SUDO_GID"))
+	if uidErr != nil || gidErr != nil || uid == 0 {
+		return nil
+	}
+	match, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil || match.HomeDir == "" {
+		return nil
+	}
+	return &knownHostsOwner{uid: uid, gid: gid, home: match.HomeDir}
+}
+
+// KnownHostsPath is where the host keys of the bastions are stored. Under sudo
+// it's worked out from the home directory of the invoking user instead of the
+// environment, which that user controls.
+func KnownHostsPath() string {
+	if owner := sudoOwner(); owner != nil {
+		if runtime.GOOS == "darwin" {
+			return filepath.Join(owner.home, "Library", "Application Support", "sst", "tunnel_known_hosts")
+		}
+		return filepath.Join(owner.home, ".config", "sst", "tunnel_known_hosts")
+	}
+	return filepath.Join(global.ConfigDir(), "tunnel_known_hosts")
+}
+
+// hostKeyCallback trusts the key of a bastion on first use and persists it to
+// the known hosts file so later connections are verified against it.
+func hostKeyCallback(path string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		knownHostsLock.Lock()
+		defer knownHostsLock.Unlock()
+		owner := sudoOwner()
+		known, err := readKnownHosts(path, owner)
+		if err != nil {
+			return err
+		}
+		host := knownhosts.Normalize(hostname)
+		if existing, ok := known[host]; ok {
+			for _, item := range existing {
+				if bytes.Equal(item.Marshal(), key.Marshal()) {
+					return nil
+				}
+			}
+			return util.NewReadableError(nil, fmt.Sprintf("The host key for the bastion at %s has changed. If the bastion was recreated, remove its entry from %s", hostname, path))
+		}
+		slog.Info("trusting new host key", "host", hostname, "fingerprint", ssh.FingerprintSHA256(key))
+		return appendKnownHost(path, owner, host, key)
+	}
+}
+
+// Returns the keys in the known hosts file by host
+func readKnownHosts(path string, owner *knownHostsOwner) (map[string][]ssh.PublicKey, error) {
+	result := map[string][]ssh.PublicKey{}
+	file, err := openKnownHosts(path, os.O_RDONLY, owner)
+	if errors.Is(err, fs.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	for len(data) > 0 {
+		marker, hosts, key, _, rest, err := ssh.ParseKnownHosts(data)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		data = rest
+		if marker != "" {
+			continue
+		}
+		for _, host := range hosts {
+			result[host] = append(result[host], key)
+		}
+	}
+	return result, nil
+}
+
+func appendKnownHost(path string, owner *knownHostsOwner, host string, key ssh.PublicKey) error {
+	file, err := openKnownHosts(path, os.O_WRONLY|os.O_APPEND, owner)
+	if errors.Is(err, fs.ErrNotExist) {
+		file, err = createKnownHosts(path, owner)
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	line := knownhosts.Line([]string{host}, key)
+	_, err = file.Write(append([]byte(line), '\n'))
+	return err
+}
+
+// Opens the known hosts file without following links. Under sudo it also has
+// to be a regular file owned by the invoking user in their home directory, so
+// it can't be pointed at a file they couldn't write to themselves.
+func openKnownHosts(path string, flag int, owner *knownHostsOwner) (*os.File, error) {
+	if err := checkKnownHostsDir(path, owner); err != nil {
+		return nil, err
+	}
+	file, err := openNoFollow(path, flag, 0)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	if owner != nil {
+		if uid, ok := fileOwner(info); ok && uid != owner.uid {
+			file.Close()
+			return nil, fmt.Errorf("%s is not owned by the user running the tunnel", path)
+		}
+	}
+	return file, nil
+}
+
+func createKnownHosts(path string, owner *knownHostsOwner) (*os.File, error) {
+	file, err := openNoFollow(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// only hand back the file this created, through the open handle
+	if owner != nil {
+		if err := file.Chown(owner.uid, owner.gid); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+func checkKnownHostsDir(path string, owner *knownHostsOwner) error {
+	if owner == nil {
+		return nil
+	}
+	home, err := filepath.EvalSymlinks(owner.home)
+	if err != nil {
+		return err
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(dir, home+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside of %s", dir, home)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package tunnel
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func testHostKey(t *testing.T) ssh.PublicKey {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	remote := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 22}
+	key := testHostKey(t)
+	callback := hostKeyCallback(path)
+
+	if err := callback("10.0.0.1:22", remote, key); err != nil {
+		t.Fatalf("expected the first key to be trusted: %v", err)
+	}
+	if err := callback("10.0.0.1:22", remote, key); err != nil {
+		t.Fatalf("expected the saved key to match: %v", err)
+	}
+	if err := callback("10.0.0.1:22", remote, testHostKey(t)); err == nil {
+		t.Fatal("expected a changed key to be rejected")
+	}
+	if err := callback("10.0.0.2:22", remote, testHostKey(t)); err != nil {
+		t.Fatalf("expected another host to be trusted: %v", err)
+	}
+}
+
+func TestHostKeyCallbackSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	os.WriteFile(target, []byte("untouched\n"), 0644)
+	path := filepath.Join(dir, "known_hosts")
+	os.Symlink(target, path)
+
+	remote := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 22}
+	if err := hostKeyCallback(path)("10.0.0.1:22", remote, testHostKey(t)); err == nil {
+		t.Fatal("expected a linked known hosts file to be refused")
+	}
+	if data, _ := os.ReadFile(target); string(data) != "untouched\n" {
+		t.Fatalf("expected the link target to be untouched, got %q", data)
+	}
+}
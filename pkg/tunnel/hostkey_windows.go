@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package tunnel
+
+import "os"
+
+func openNoFollow(path string, flag int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(path, flag, perm)
+}
+
+func fileOwner(info os.FileInfo) (int, bool) {
+	return 0, false
+}
@@ -2,35 +2,26 @@ package tunnel
 
 import (
 	"context"
-	"fmt"
 	"net"
 
 	"github.com/armon/go-socks5"
-	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
-	"golang.org/x/crypto/ssh"
 )
 
-func StartProxy(ctx context.Context, username string, host string, key []byte) error {
-	signer, err := ssh.ParsePrivateKey(key)
-	if err != nil {
-		return err
-	}
-	config := &ssh.ClientConfig{
-		User: username,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	}
-	sshClient, err := ssh.Dial("tcp", host, config)
-	if err != nil {
-		return err
-	}
-	defer sshClient.Close()
+const PROXY_ADDRESS = "127.0.0.1:1080"
+
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// ServeProxy runs a SOCKS5 proxy on the listener that forwards every
+// connection through dial until the context is cancelled.
+func ServeProxy(ctx context.Context, listener net.Listener, dial DialFunc, onDial func(network, addr string)) error {
 	server, err := socks5.New(&socks5.Config{
+		// resolve hostnames on the bastion so private DNS works
+		Resolver: remoteResolver{},
 		Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			fmt.Println(ui.TEXT_INFO_BOLD.Render(("| "), ui.TEXT_NORMAL.Render("Tunneling", network, addr)))
-			return sshClient.Dial(network, addr)
+			if onDial != nil {
+				onDial(network, addr)
+			}
+			return dial(ctx, network, addr)
 		},
 	})
 	if err != nil {
@@ -38,12 +29,19 @@ func StartProxy(ctx context.Context, username string, host string, key []byte) e
 	}
 	errChan := make(chan error, 1)
 	go func() {
-		errChan <- server.ListenAndServe("tcp", fmt.Sprintf("%s:%d", "127.0.0.1", 1080))
+		errChan <- server.Serve(listener)
 	}()
 	select {
 	case err := <-errChan:
 		return err
 	case <-ctx.Done():
+		listener.Close()
 		return nil
 	}
 }
+
+type remoteResolver struct{}
+
+func (remoteResolver) Resolve(ctx context.Context, name string) (context.Context, net.IP, error) {
+	return ctx, nil, nil
+}
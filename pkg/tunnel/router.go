@@ -0,0 +1,262 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sst/sst/v3/pkg/bus"
+	"golang.org/x/crypto/ssh"
+)
+
+type Bastion struct {
+	Name       string   `json:"name"`
+	Host       string   `json:"host"`
+	Username   string   `json:"username"`
+	PrivateKey string   `json:"privateKey"`
+	Subnets    []string `json:"subnets"`
+}
+
+type StatusEvent struct {
+	Name    string
+	Host    string
+	Status  string
+	Error   string
+	Attempt int
+}
+
+const (
+	StatusConnected    = "connected"
+	StatusDisconnected = "disconnected"
+)
+
+const keepaliveInterval = 15 * time.Second
+const maxBackoff = 30 * time.Second
+
+// Router keeps an SSH connection open to every bastion and sends each dial
+// over the bastion whose subnets contain the destination.
+type Router struct {
+	KnownHosts  string
+	connections []*connection
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+type connection struct {
+	bastion  Bastion
+	networks []*net.IPNet
+	callback ssh.HostKeyCallback
+	mu       sync.RWMutex
+	client   *ssh.Client
+}
+
+func NewRouter(bastions ...Bastion) (*Router, error) {
+	result := &Router{
+		KnownHosts: KnownHostsPath(),
+	}
+	for _, bastion := range bastions {
+		conn := &connection{bastion: bastion}
+		for _, subnet := range bastion.Subnets {
+			_, network, err := net.ParseCIDR(subnet)
+			if err != nil {
+				return nil, fmt.Errorf("invalid subnet %q for %s: %w", subnet, bastion.Name, err)
+			}
+			conn.networks = append(conn.networks, network)
+		}
+		result.connections = append(result.connections, conn)
+	}
+	return result, nil
+}
+
+// Connect opens the initial connection to every bastion and keeps them alive
+// in the background, reconnecting with backoff when a session drops, until
+// the context is cancelled or the router is closed.
+func (r *Router) Connect(ctx context.Context) error {
+	if len(r.connections) == 0 {
+		return fmt.Errorf("no bastions to connect to")
+	}
+	ctx, r.cancel = context.WithCancel(ctx)
+	for _, conn := range r.connections {
+		conn.callback = hostKeyCallback(r.KnownHosts)
+		client, err := conn.dial()
+		if err != nil {
+			r.Close()
+			return err
+		}
+		conn.set(client)
+		conn.publish(StatusConnected, nil, 0)
+	}
+	for _, conn := range r.connections {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			conn.maintain(ctx)
+		}()
+	}
+	return nil
+}
+
+func (r *Router) Dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn := r.route(addr)
+	client := conn.get()
+	if client == nil {
+		return nil, fmt.Errorf("tunnel to %s is not connected", conn.bastion.Host)
+	}
+	return client.Dial(network, addr)
+}
+
+// Close stops reconnecting and waits for that to finish before closing the
+// connections, so no bastion is dialed again once it returns.
+func (r *Router) Close() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+	for _, conn := range r.connections {
+		if client := conn.get(); client != nil {
+			client.Close()
+		}
+	}
+}
+
+func (r *Router) route(addr string) *connection {
+	if len(r.connections) == 1 {
+		return r.connections[0]
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return r.connections[0]
+	}
+	ips := []net.IP{}
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		// hostnames are dialed as is so they resolve on the bastion, but
+		// private endpoints like RDS publicly resolve to their VPC address
+		// which is enough to pick the right bastion
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		resolved, _ := net.DefaultResolver.LookupIP(ctx, "ip", host)
+		ips = append(ips, resolved...)
+	}
+	for _, ip := range ips {
+		for _, conn := range r.connections {
+			for _, network := range conn.networks {
+				if network.Contains(ip) {
+					return conn
+				}
+			}
+		}
+	}
+	return r.connections[0]
+}
+
+func (c *connection) dial() (*ssh.Client, error) {
+	signer, err := ssh.ParsePrivateKey([]byte(c.bastion.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+	config := &ssh.ClientConfig{
+		User: c.bastion.Username,
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeys(signer),
+		},
+		HostKeyCallback: c.callback,
+		Timeout:         10 * time.Second,
+	}
+	return ssh.Dial("tcp", c.bastion.Host, config)
+}
+
+func (c *connection) get() *ssh.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client
+}
+
+func (c *connection) set(client *ssh.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.client = client
+}
+
+func (c *connection) maintain(ctx context.Context) {
+	for {
+		client := c.get()
+		done := make(chan error, 1)
+		go func() {
+			done <- client.Wait()
+		}()
+		go keepalive(ctx, client)
+		select {
+		case <-ctx.Done():
+			client.Close()
+			return
+		case err := <-done:
+			slog.Error("tunnel disconnected", "host", c.bastion.Host, "err", err)
+			c.set(nil)
+			c.publish(StatusDisconnected, err, 0)
+		}
+		client = c.reconnect(ctx)
+		if client == nil {
+			return
+		}
+		c.set(client)
+	}
+}
+
+func (c *connection) reconnect(ctx context.Context) *ssh.Client {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		client, err := c.dial()
+		if err == nil {
+			c.publish(StatusConnected, nil, attempt)
+			return client
+		}
+		slog.Error("tunnel reconnect failed", "host", c.bastion.Host, "attempt", attempt, "err", err)
+		c.publish(StatusDisconnected, err, attempt)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (c *connection) publish(status string, err error, attempt int) {
+	evt := &StatusEvent{
+		Name:    c.bastion.Name,
+		Host:    c.bastion.Host,
+		Status:  status,
+		Attempt: attempt,
+	}
+	if err != nil {
+		evt.Error = err.Error()
+	}
+	bus.Publish(evt)
+}
+
+// keepalive closes the client when the bastion stops answering so that
+// half-open connections are detected and reconnected.
+func keepalive(ctx context.Context, client *ssh.Client) {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			if err != nil {
+				client.Close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// serveBastion accepts ssh sessions on a local port and hands back each
+// server side connection so the test can drop it.
+func serveBastion(t *testing.T) (string, <-chan net.Conn) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	conns := make(chan net.Conn, 8)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				conns <- conn
+				go ssh.DiscardRequests(reqs)
+				for ch := range chans {
+					ch.Reject(ssh.Prohibited, "")
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), conns
+}
+
+func testPrivateKey(t *testing.T) string {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(private, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(block))
+}
+
+func TestRouterCloseStopsReconnecting(t *testing.T) {
+	addr, conns := serveBastion(t)
+	router, err := NewRouter(Bastion{Name: "bastion", Host: addr, Username: "ec2-user", PrivateKey: testPrivateKey(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.KnownHosts = filepath.Join(t.TempDir(), "known_hosts")
+	// the parent context outlives the router like the one of the cli does
+	if err := router.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// drop the session so the router starts reconnecting
+	(<-conns).Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for router.connections[0].get() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the dropped session to be noticed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		router.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the reconnect loop to exit when the router is closed")
+	}
+	select {
+	case <-conns:
+		t.Fatal("expected no reconnect after the router is closed")
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
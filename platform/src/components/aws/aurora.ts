@@ -15,6 +15,7 @@ import { Vpc } from "./vpc.js";
 import { RandomPassword } from "@pulumi/random";
 import { DevCommand } from "../experimental/dev-command.js";
 import { RdsRoleLookup } from "./providers/rds-role-lookup.js";
+import { SqlMigrations } from "./providers/sql-migrations.js";
 import { DurationHours, toSeconds } from "../duration.js";
 import { permission } from "./permission.js";
 
@@ -264,6 +265,52 @@ export interface AuroraArgs {
    * ```
    */
   dataApi?: Input<boolean>;
+  /**
+   * Apply the `.sql` files in a directory to the database as part of the deploy.
+   *
+   * The files are applied in the order of their names, each in its own transaction.
+   * The ones that were applied are recorded in a table, so each file only runs once.
+   * The deploy fails if a file that was applied is changed or removed.
+   *
+   * This needs the `postgres` engine and `dataApi` to be enabled.
+   *
+   * @example
+   * ```js
+   * {
+   *   migrations: "migrations"
+   * }
+   * ```
+   *
+   * Set `dryRun` to list the migrations that would be applied, without applying them.
+   *
+   * ```js
+   * {
+   *   migrations: {
+   *     directory: "migrations",
+   *     dryRun: true
+   *   }
+   * }
+   * ```
+   */
+  migrations?: Input<
+    | string
+    | {
+        /**
+         * The directory with the migration files, relative to the root of your app.
+         */
+        directory: Input<string>;
+        /**
+         * The table the applied migrations are recorded in.
+         * @default `"sst_migrations"`
+         */
+        table?: Input<string>;
+        /**
+         * List the migrations that would be applied, without applying them.
+         * @default `false`
+         */
+        dryRun?: Input<boolean>;
+      }
+  >;
   /**
    * Enable [RDS Proxy](https://docs.aws.amazon.com/AmazonRDS/latest/UserGuide/rds-proxy.html)
    * for the database.
@@ -696,6 +743,7 @@ export class Aurora extends Component implements Link.Linkable {
     const cluster = createCluster();
     const instance = createInstances();
     createProxyTarget();
+    createMigrations();
 
     this.cluster = cluster;
     this.instance = instance;
@@ -1119,6 +1167,39 @@ Listening on "${dev.host}:${dev.port}"...`,
       });
     }
 
+    function createMigrations() {
+      if (!args.migrations) return;
+
+      const migrations = all([args.migrations, engine, dataApi]).apply(
+        ([migrations, engine, dataApi]) => {
+          if (engine !== "postgres")
+            throw new VisibleError(
+              `Migrations are only supported for the "postgres" engine in the "${name}" Aurora database.`,
+            );
+          if (!dataApi)
+            throw new VisibleError(
+              `Enable "dataApi" to apply migrations to the "${name}" Aurora database.`,
+            );
+          return typeof migrations === "string"
+            ? { directory: migrations }
+            : migrations;
+        },
+      );
+
+      new SqlMigrations(
+        `${name}Migrations`,
+        {
+          clusterArn: cluster.arn,
+          secretArn: secret.arn,
+          databaseName: dbName,
+          directory: migrations.directory,
+          table: migrations.table,
+          dryRun: migrations.dryRun,
+        },
+        { parent: self, dependsOn: [instance] },
+      );
+    }
+
     function createProxyTarget() {
       proxy.apply((proxy) => {
         if (!proxy) return;
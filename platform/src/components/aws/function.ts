@@ -43,6 +43,7 @@ import { RandomBytes } from "@pulumi/random";
 import { lazy } from "../../util/lazy.js";
 import { Efs } from "./efs.js";
 import { FunctionEnvironmentUpdate } from "./providers/function-environment-update.js";
+import { FunctionCodeUpdater } from "./providers/function-code-updater.js";
 import { warnOnce } from "../../util/warn.js";
 import {
   normalizeRouteArgs,
@@ -1188,6 +1189,61 @@ export interface FunctionArgs {
    * ```
    */
   versioning?: Input<boolean>;
+  /**
+   * Publish each deploy as a new version and point an alias to it. Traffic is moved
+   * from the previous version to the new one using the given `strategy`.
+   *
+   * With `canary`, `percent` of the traffic is sent to the new version and the rest
+   * after `interval`. With `linear`, `percent` more is sent every `interval`. If any
+   * of the `alarms` go off in the meantime, the alias is moved back to the previous
+   * version, the function is set back to the previous code, and the deploy fails.
+   *
+   * The function URL, the `arn`, and the linked `name` all go through the alias, so
+   * anything that's triggered by or invokes the function follows the traffic shift.
+   *
+   * :::note
+   * The deploy waits while the traffic is being moved.
+   * :::
+   *
+   * Deployments are skipped in `sst dev`.
+   *
+   * @default No alias is created
+   * @example
+   * ```js
+   * {
+   *   deployment: {
+   *     strategy: "canary",
+   *     percent: 10,
+   *     interval: "5 minutes",
+   *     alarms: ["MyFunctionErrors"]
+   *   }
+   * }
+   * ```
+   */
+  deployment?: Input<{
+    /**
+     * The name of the alias that's pointed to the new version.
+     * @default `"live"`
+     */
+    alias?: Input<string>;
+    /**
+     * How traffic is moved to the new version.
+     */
+    strategy: Input<"allAtOnce" | "canary" | "linear">;
+    /**
+     * The percent of traffic that's moved at each step.
+     */
+    percent?: Input<number>;
+    /**
+     * How long to wait between steps.
+     * @default `"0 seconds"`
+     */
+    interval?: Input<Duration>;
+    /**
+     * The names of the CloudWatch alarms to watch between steps.
+     */
+    alarms?: Input<Input<string>[]>;
+  }>;
   /**
    * A list of Lambda layer ARNs to add to the function.
    *
@@ -1507,6 +1563,7 @@ export class Function extends Component implements Link.Linkable {
   private role: iam.Role;
   private logGroup: Output<cloudwatch.LogGroup | undefined>;
   private urlEndpoint: Output<string | undefined>;
+  private aliasArn: Output<string | undefined>;
   private eventInvokeConfig?: lambda.FunctionEventInvokeConfig;
 
   private static readonly encryptionKey = lazy(
@@ -1560,6 +1617,7 @@ export class Function extends Component implements Link.Linkable {
     const logGroup = createLogGroup();
     const zipAsset = createZipAsset();
     const fn = createFunction();
+    const codeUpdater = createCodeUpdater();
     const urlEndpoint = createUrl();
     createProvisioned();
     const eventInvokeConfig = createEventInvokeConfig();
@@ -1570,6 +1628,7 @@ export class Function extends Component implements Link.Linkable {
     this.role = role;
     this.logGroup = logGroup;
     this.urlEndpoint = urlEndpoint;
+    this.aliasArn = codeUpdater.apply((updater) => updater?.aliasArn);
     this.eventInvokeConfig = eventInvokeConfig;
 
     const buildInput = output({
@@ -2381,6 +2440,39 @@ export class Function extends Component implements Link.Linkable {
       );
     }
 
+    function createCodeUpdater() {
+      return all([args.deployment, dev, isContainer, zipAsset]).apply(
+        ([deployment, dev, isContainer, zipAsset]) => {
+          if (!deployment || dev) return;
+
+          return new FunctionCodeUpdater(
+            `${name}CodeUpdater`,
+            {
+              functionName: fn.name,
+              region,
+              ...(isContainer
+                ? { imageUri: fn.imageUri.apply((uri) => uri!) }
+                : {
+                    s3Bucket: zipAsset!.bucket,
+                    s3Key: zipAsset!.key,
+                  }),
+              functionLastModified: fn.lastModified,
+              alias: deployment.alias ?? "live",
+              deployment: {
+                strategy: deployment.strategy,
+                percent: deployment.percent,
+                interval: deployment.interval
+                  ? toSeconds(deployment.interval)
+                  : undefined,
+                alarms: deployment.alarms,
+              },
+            },
+            { parent },
+          );
+        },
+      );
+    }
+
     function createUrl() {
       return url.apply((url) => {
         if (url === undefined) return output(undefined);
@@ -2390,6 +2482,9 @@ export class Function extends Component implements Link.Linkable {
           `${name}Url`,
           {
             functionName: fn.name,
+            // with a deployment, the URL goes through the alias so it only
+            // gets the new version as its traffic is moved over
+            qualifier: codeUpdater.apply((updater) => updater?.alias),
             authorizationType: url.authorization === "iam" ? "AWS_IAM" : "NONE",
             invokeMode: streaming.apply((streaming) =>
               streaming ? "RESPONSE_STREAM" : "BUFFERED",
@@ -2530,9 +2625,25 @@ export class Function extends Component implements Link.Linkable {
 
   /**
    * The ARN of the Lambda function.
+   *
+   * With `deployment`, this is the ARN of the alias, so anything that's
+   * triggered through it only gets the new version as the traffic is moved over.
    */
   public get arn() {
-    return this.function.arn;
+    return all([this.function.arn, this.aliasArn]).apply(
+      ([arn, aliasArn]) => aliasArn ?? arn,
+    );
+  }
+
+  /** @internal */
+  public get invokeArn() {
+    return all([
+      this.function.invokeArn,
+      this.function.arn,
+      this.aliasArn,
+    ]).apply(([invokeArn, arn, aliasArn]) =>
+      aliasArn ? invokeArn.replace(arn, aliasArn) : invokeArn,
+    );
   }
 
   /**
@@ -2615,13 +2726,16 @@ export class Function extends Component implements Link.Linkable {
   public getSSTLink() {
     return {
       properties: {
-        name: this.name,
+        // qualified with the alias when there's a deployment
+        name: all([this.name, this.aliasArn]).apply(([name, aliasArn]) =>
+          aliasArn ? `${name}:${aliasArn.split(":").pop()}` : name,
+        ),
         url: this.urlEndpoint,
       },
       include: [
         permission({
           actions: ["lambda:InvokeFunction"],
-          resources: [this.function.arn],
+          resources: [this.function.arn, this.arn],
         }),
       ],
     };
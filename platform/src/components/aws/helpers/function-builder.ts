@@ -55,7 +55,7 @@ export function functionBuilder(
       return {
         getFunction: () => fn,
         arn: fn.arn,
-        invokeArn: fn.nodes.function.invokeArn,
+        invokeArn: fn.invokeArn,
       };
     }
 
@@ -95,7 +95,7 @@ export function functionBuilder(
       return {
         getFunction: () => fn,
         arn: fn.arn,
-        invokeArn: fn.nodes.function.invokeArn,
+        invokeArn: fn.invokeArn,
       };
     }
     throw new Error(`Invalid function definition for the "${name}" Function`);
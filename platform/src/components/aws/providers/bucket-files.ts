@@ -1,5 +1,10 @@
+import fs from "fs";
+import path from "path";
+import zlib from "zlib";
+import crypto from "crypto";
 import { CustomResourceOptions, Input, dynamic } from "@pulumi/pulumi";
 import { rpc } from "../../rpc/rpc.js";
+import { VisibleError } from "../../error.js";
 
 export interface BucketFile {
   source: string;
@@ -7,6 +12,107 @@ export interface BucketFile {
   cacheControl?: string;
   contentType: string;
   hash?: string;
+  contentEncoding?: string;
+  metadata?: Record<string, string>;
+  storageClass?: string;
+  kmsKeyId?: string;
+  tags?: Record<string, string>;
+}
+
+const PRECOMPRESS_EXTENSIONS = {
+  gzip: ".gz",
+  br: ".br",
+};
+
+// CloudFront doesn't compress files smaller than this either
+const PRECOMPRESS_MIN_SIZE = 1000;
+// a variant has to save at least a tenth of the file to be worth routing
+const PRECOMPRESS_MAX_RATIO = 0.9;
+
+/**
+ * Compresses the file with each of the encodings and returns the variants to upload
+ * next to it. The compressed files are kept in the work directory by the hash of the
+ * file, so a file that hasn't changed isn't compressed again on the next deploy.
+ *
+ * Small files and files that don't compress well, like images, get no variants, so
+ * they don't need a routing entry of their own.
+ */
+export async function precompressBucketFile(
+  file: BucketFile,
+  encodings: ("gzip" | "br")[],
+): Promise<BucketFile[]> {
+  if (encodings.length === 0 || file.contentEncoding) return [];
+  const content = await fs.promises.readFile(file.source);
+  if (content.length < PRECOMPRESS_MIN_SIZE) return [];
+  const sourceHash = crypto.createHash("sha256").update(content).digest("hex");
+  const dir = path.join($cli.paths.work, "artifacts", "precompressed");
+  await fs.promises.mkdir(dir, { recursive: true });
+  const variants = await Promise.all(
+    encodings.map(async (encoding) => {
+      const extension = PRECOMPRESS_EXTENSIONS[encoding];
+      const hash = crypto
+        .createHash("sha256")
+        .update(sourceHash + extension)
+        .digest("hex");
+      const source = path.join(dir, hash + extension);
+      if (!fs.existsSync(source)) {
+        const compressed =
+          encoding === "br"
+            ? zlib.brotliCompressSync(content, {
+              params: { [zlib.constants.BROTLI_PARAM_QUALITY]: 11 },
+            })
+            : zlib.gzipSync(content, { level: 9 });
+        // written under another name first so a cancelled deploy doesn't leave a
+        // partial file behind
+        await fs.promises.writeFile(source + ".tmp", compressed);
+        await fs.promises.rename(source + ".tmp", source);
+      }
+      const { size } = await fs.promises.stat(source);
+      if (size > content.length * PRECOMPRESS_MAX_RATIO) return;
+      return {
+        ...file,
+        source,
+        key: file.key + extension,
+        hash,
+        contentEncoding: encoding,
+      };
+    }),
+  );
+  return variants.filter(
+    (variant): variant is BucketFile => variant !== undefined,
+  );
+}
+
+/**
+ * Fails if a precompressed variant has the same key as a file in the build output,
+ * since one of them would overwrite the other in the bucket. The files can include the
+ * variants.
+ */
+export function checkPrecompressedKeys(
+  files: BucketFile[],
+  variants: BucketFile[],
+) {
+  const generated = new Set(variants);
+  const keys = new Set(
+    files.filter((file) => !generated.has(file)).map((file) => file.key),
+  );
+  for (const variant of variants) {
+    if (!keys.has(variant.key)) continue;
+    throw new VisibleError(
+      `The precompressed variant "${variant.key}" has the same name as a file in the build output. Remove the file or add it to the \`ignore\` of the file options that precompress it.`,
+    );
+  }
+}
+
+/**
+ * The value of the routing entry of a file with precompressed variants. It lists the
+ * encodings of the variants after `s3`, in the order the router prefers them.
+ */
+export function bucketFileRoute(variants: BucketFile[]) {
+  const preferred = (["br", "gzip"] as const).filter((encoding) =>
+    variants.some((variant) => variant.contentEncoding === encoding),
+  );
+  return ["s3", ...preferred].join(",");
 }
 
 export interface BucketFilesInputs {
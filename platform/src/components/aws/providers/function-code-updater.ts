@@ -0,0 +1,67 @@
+import {
+  CustomResourceOptions,
+  Input,
+  Output,
+  dynamic,
+} from "@pulumi/pulumi";
+import { rpc } from "../../rpc/rpc.js";
+
+export interface FunctionCodeUpdaterInputs {
+  /**
+   * The name of the function to update.
+   */
+  functionName: Input<string>;
+  /**
+   * The region of the function to update.
+   */
+  region: Input<string>;
+  s3Bucket?: Input<string>;
+  s3Key?: Input<string>;
+  imageUri?: Input<string>;
+  functionLastModified?: Input<string>;
+  /**
+   * Publish the new code as a version and point this alias to it.
+   */
+  alias?: Input<string>;
+  /**
+   * How traffic is moved to the new version. Without it, all traffic is moved
+   * at once.
+   */
+  deployment?: Input<{
+    strategy: Input<"allAtOnce" | "canary" | "linear">;
+    /**
+     * The percent of traffic that's moved at each step.
+     */
+    percent?: Input<number>;
+    /**
+     * How long to wait between steps, in seconds.
+     */
+    interval?: Input<number>;
+    /**
+     * The names of the CloudWatch alarms to watch between steps. If any of
+     * them go off, the alias is moved back to the previous version.
+     */
+    alarms?: Input<Input<string>[]>;
+  }>;
+}
+
+export interface FunctionCodeUpdater {
+  version: Output<string>;
+  alias: Output<string | undefined>;
+  aliasArn: Output<string | undefined>;
+}
+
+export class FunctionCodeUpdater extends dynamic.Resource {
+  constructor(
+    name: string,
+    args: FunctionCodeUpdaterInputs,
+    opts?: CustomResourceOptions,
+  ) {
+    super(
+      new rpc.Provider("Aws.FunctionCodeUpdater"),
+      `${name}.sst.aws.FunctionCodeUpdater`,
+      { ...args, version: undefined, aliasArn: undefined },
+      opts,
+    );
+  }
+}
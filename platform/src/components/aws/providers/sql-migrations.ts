@@ -0,0 +1,35 @@
+import { CustomResourceOptions, Input, dynamic } from "@pulumi/pulumi";
+import { rpc } from "../../rpc/rpc.js";
+
+export interface SqlMigrationsInputs {
+  clusterArn: Input<string>;
+  secretArn: Input<string>;
+  databaseName: Input<string>;
+  /**
+   * The directory with the `.sql` files, relative to the root of the app.
+   */
+  directory: Input<string>;
+  /**
+   * The table the applied migrations are recorded in.
+   */
+  table?: Input<string | undefined>;
+  /**
+   * Only report the migrations that would be applied.
+   */
+  dryRun?: Input<boolean | undefined>;
+}
+
+export class SqlMigrations extends dynamic.Resource {
+  constructor(
+    name: string,
+    args: SqlMigrationsInputs,
+    opts?: CustomResourceOptions,
+  ) {
+    super(
+      new rpc.Provider("Aws.SqlMigrations"),
+      `${name}.sst.aws.SqlMigrations`,
+      args,
+      opts,
+    );
+  }
+}
@@ -7,6 +7,13 @@ export interface PostgresTableInputs {
   databaseName: Input<string>;
   tableName: Input<string>;
   dimension: Input<number>;
+  index?: Input<{
+    type: Input<"hnsw" | "ivfflat">;
+    m?: Input<number>;
+    efConstruction?: Input<number>;
+    lists?: Input<number>;
+  }>;
+  migration?: Input<"column">;
 }
 
 export class VectorTable extends dynamic.Resource {
@@ -620,6 +620,23 @@ export interface RouterArgs {
       Input<string | InlineUrlRouteArgs | InlineRouterBucketRouteArgs>
     >
   >;
+  /**
+   * Serve the precompressed files of the sites routed through this router. This adds a
+   * viewer response function that sets `Vary: Accept-Encoding` on the compressed
+   * responses.
+   *
+   * A site that uses `precompress` in its `fileOptions` can only be routed through a
+   * router that has this enabled.
+   *
+   * @default `false`
+   * @example
+   * ```js
+   * {
+   *   precompress: true
+   * }
+   * ```
+   */
+  precompress?: Input<boolean>;
   /**
    * Configure CloudFront Functions to customize the behavior of HTTP requests and responses at the edge.
    */
@@ -1003,6 +1020,7 @@ export class Router extends Component implements Link.Linkable {
   private kvStoreArn?: Output<string>;
   private kvNamespace?: Output<string>;
   private hasInlineRoutes: Output<boolean>;
+  private precompress: Output<boolean>;
 
   constructor(
     name: string,
@@ -1021,6 +1039,7 @@ export class Router extends Component implements Link.Linkable {
       this.kvStoreArn = ref.kvStoreArn;
       this.kvNamespace = ref.kvNamespace;
       this.hasInlineRoutes = ref.hasInlineRoutes;
+      this.precompress = ref.precompress;
       registerOutputs();
       return;
     }
@@ -1041,6 +1060,7 @@ export class Router extends Component implements Link.Linkable {
     this.kvStoreArn = kvStoreArn;
     this.kvNamespace = kvNamespace;
     this.hasInlineRoutes = output(hasInlineRoutes);
+    this.precompress = output(args.precompress ?? false);
     registerOutputs();
 
     function reference() {
@@ -1060,6 +1080,7 @@ export class Router extends Component implements Link.Linkable {
           kvStoreArn: tags?.["sst:ref:kv"],
           kvNamespace: tags?.["sst:ref:kv-namespace"],
           hasInlineRoutes: tags?.["sst:ref:kv"] === undefined,
+          precompress: tags?.["sst:ref:precompress"] === "true",
         };
       });
 
@@ -1068,6 +1089,7 @@ export class Router extends Component implements Link.Linkable {
         kvStoreArn: tags.kvStoreArn,
         kvNamespace: tags.kvNamespace,
         hasInlineRoutes: tags.hasInlineRoutes,
+        precompress: tags.precompress,
       };
     }
 
@@ -1490,18 +1512,8 @@ async function handler(event) {
   async function getRoutes() {
     let routes = [];
     try {
-      const v = await cf.kvs().get(routerNS + ":routes");
+      const v = await getKvValue(routerNS + ":routes");
       routes = JSON.parse(v);
-
-      // handle chunked routes
-      if (routes.parts) {
-        const chunkPromises = [];
-        for (let i = 0; i < routes.parts; i++) {
-          chunkPromises.push(cf.kvs().get(routerNS + ":routes:" + i));
-        }
-        const chunks = await Promise.all(chunkPromises);
-        routes = JSON.parse(chunks.join(""));
-      }
     } catch (e) {}
     return routes;
   }
@@ -1539,7 +1551,7 @@ async function handler(event) {
     // Load metadata
     if (match) {
       try {
-        const v = await cf.kvs().get(match.routeNs + ":metadata");
+        const v = await getKvValue(match.routeNs + ":metadata");
         return { type: match.type, routeNs: match.routeNs, metadata: JSON.parse(v) };
       } catch (e) {}
     }
@@ -1565,28 +1577,31 @@ async function handler(event) {
       }
 
       function createResponseFunction() {
-        return output(args.edge).apply((edge) => {
-          const userConfig = edge?.viewerResponse;
-          const userInjection = userConfig?.injection;
-          const kvStoreArn = userConfig?.kvStore;
+        return all([args.edge, args.precompress]).apply(
+          ([edge, precompress]) => {
+            const userConfig = edge?.viewerResponse;
+            const userInjection = userConfig?.injection;
+            const kvStoreArn = userConfig?.kvStore;
 
-          if (!userInjection) return;
+            if (!userInjection && !precompress) return;
 
-          return new cloudfront.Function(
-            `${name}CloudfrontFunctionResponse`,
-            {
-              runtime: "cloudfront-js-2.0",
-              keyValueStoreAssociations: kvStoreArn ? [kvStoreArn] : [],
-              code: `
+            return new cloudfront.Function(
+              `${name}CloudfrontFunctionResponse`,
+              {
+                runtime: "cloudfront-js-2.0",
+                keyValueStoreAssociations: kvStoreArn ? [kvStoreArn] : [],
+                code: `
 import cf from "cloudfront";
 async function handler(event) {
-  ${userInjection}
+  ${precompress ? CF_VARY_ENCODING_INJECTION : ""}
+  ${userInjection ?? ""}
   return event.response;
 }`,
-            },
-            { parent: self },
-          );
-        });
+              },
+              { parent: self },
+            );
+          },
+        );
       }
 
       function createDistribution() {
@@ -1637,11 +1652,15 @@ async function handler(event) {
                     : []),
                 ]),
               },
-              tags: {
-                "sst:ref:kv": kvStoreArn,
-                "sst:ref:kv-namespace": kvNamespace,
-                "sst:ref:version": _refVersion.toString(),
-              },
+              tags: all([kvStoreArn, args.precompress]).apply(
+                ([kvStoreArn, precompress]) => ({
+                  "sst:ref:kv": kvStoreArn,
+                  "sst:ref:kv-namespace": kvNamespace,
+                  "sst:ref:version": _refVersion.toString(),
+                  // read by the sites routed through a reference to this router
+                  ...(precompress ? { "sst:ref:precompress": "true" } : {}),
+                }),
+              ),
             },
             { parent: self },
           ),
@@ -1684,6 +1703,11 @@ async function handler(event) {
     return this.hasInlineRoutes;
   }
 
+  /** @internal */
+  public get _precompress() {
+    return this.precompress;
+  }
+
   /**
    * The underlying [resources](/docs/components/#nodes) this component creates.
    */
@@ -1944,7 +1968,30 @@ if (event.request.headers.host.value.includes('cloudfront.net')) {
   };
 }`;
 
+export const CF_VARY_ENCODING_INJECTION = `
+  if (event.response.headers["content-encoding"]) {
+    const vary = event.response.headers["vary"];
+    if (!vary) {
+      event.response.headers["vary"] = { value: "Accept-Encoding" };
+    } else if (!vary.value.toLowerCase().includes("accept-encoding")) {
+      vary.value += ", Accept-Encoding";
+    }
+  }`;
+
 export const CF_ROUTER_INJECTION = `
+async function getKvValue(key) {
+  const v = await cf.kvs().get(key);
+  // values over 1 KB are stored as {"parts":N} with the value split across key:0 to key:N-1
+  if (!v.startsWith('{"parts":')) return v;
+  const parts = JSON.parse(v).parts;
+  const chunkPromises = [];
+  for (let i = 0; i < parts; i++) {
+    chunkPromises.push(cf.kvs().get(key + ":" + i));
+  }
+  const chunks = await Promise.all(chunkPromises);
+  return chunks.join("");
+}
+
 async function routeSite(kvNamespace, metadata) {
   const baselessUri = metadata.base
     ? event.request.uri.replace(metadata.base, "")
@@ -1957,9 +2004,10 @@ async function routeSite(kvNamespace, metadata) {
     const postfixes = u.endsWith("/")
       ? ["index.html"]
       : ["", ".html", "/index.html"];
-    const v = await Promise.any(postfixes.map(p => cf.kvs().get(kvNamespace + ":" + u + p).then(v => p)));
+    const v = await Promise.any(postfixes.map(p => cf.kvs().get(kvNamespace + ":" + u + p).then(v => ({ postfix: p, value: v }))));
     // files are stored in a subdirectory, add it to the request uri
-    event.request.uri = metadata.s3.dir + event.request.uri + v;
+    event.request.uri = metadata.s3.dir + event.request.uri + v.postfix;
+    setEncodedUri(v.value);
     setS3Origin(metadata.s3.domain);
     return;
   } catch (e) {}
@@ -2017,6 +2065,28 @@ async function routeSite(kvNamespace, metadata) {
     setUrlOrigin(findNearestServer(metadata.servers), metadata.origin);
   }
 
+  function setEncodedUri(value) {
+    ${
+      // Files with precompressed variants are stored as "s3,br,gzip", with the
+      // encodings in the order they are preferred. The variant is uploaded next
+      // to the file, so the request is sent to it when the browser accepts it.
+      // The response gets "Vary: Accept-Encoding" from CF_VARY_ENCODING_INJECTION.
+      ""
+    }
+    const encodings = value.split(",").slice(1);
+    const header = event.request.headers["accept-encoding"];
+    if (!encodings.length || !header) return;
+    const accepted = header.value
+      .split(",")
+      .map((item) => item.trim().split(/\\s*;\\s*q=/))
+      .filter((item) => item.length === 1 || parseFloat(item[1]) > 0)
+      .map((item) => item[0]);
+    const encoding = encodings.find((e) => accepted.includes(e));
+    if (encoding) {
+      event.request.uri += encoding === "br" ? ".br" : ".gz";
+    }
+  }
+
   function setNextjsGeoHeaders() {
     ${
       // Inject the CloudFront viewer country, region, latitude, and longitude headers into
@@ -2294,6 +2364,7 @@ export function normalizeRouteArgs(
         ),
         routerKvNamespace: v.instance._kvNamespace!,
         routerKvStoreArn: v.instance._kvStoreArn!,
+        routerPrecompress: v.instance._precompress,
       };
     });
   });
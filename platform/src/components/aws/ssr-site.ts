@@ -15,7 +15,13 @@ import {
 import { Cdn, CdnArgs } from "./cdn.js";
 import { Function, FunctionArgs } from "./function.js";
 import { Bucket, BucketArgs } from "./bucket.js";
-import { BucketFile, BucketFiles } from "./providers/bucket-files.js";
+import {
+  BucketFile,
+  BucketFiles,
+  bucketFileRoute,
+  checkPrecompressedKeys,
+  precompressBucketFile,
+} from "./providers/bucket-files.js";
 import { logicalName } from "../naming.js";
 import { Input } from "../input.js";
 import {
@@ -35,6 +41,7 @@ import { Link } from "../link.js";
 import { URL_UNAVAILABLE } from "./linkable.js";
 import {
   CF_ROUTER_INJECTION,
+  CF_VARY_ENCODING_INJECTION,
   CF_BLOCK_CLOUDFRONT_URL_INJECTION,
   KV_SITE_METADATA,
   RouterRouteArgsDeprecated,
@@ -588,6 +595,7 @@ export abstract class SsrSite extends Component implements Link.Linkable {
     );
     const servers = createServers();
     const imageOptimizer = createImageOptimizer();
+    const assetFiles = buildAssetFiles();
     const assetsUploaded = uploadAssets();
     const kvNamespace = buildKvNamespace();
 
@@ -675,7 +683,7 @@ async function handler(event) {
   // Load metadata
   let metadata;
   try {
-    const v = await cf.kvs().get(kvNamespace + ":metadata");
+    const v = await getKvValue(kvNamespace + ":metadata");
     metadata = JSON.parse(v);
   } catch (e) {}
 
@@ -689,12 +697,15 @@ async function handler(event) {
     }
 
     function createResponseFunction() {
-      return edge.apply((edge) => {
+      return all([edge, args.assets]).apply(([edge, assets]) => {
         const userConfig = edge?.viewerResponse;
         const userInjection = userConfig?.injection;
         const kvStoreArn = userConfig?.kvStore;
+        const precompress = (assets?.fileOptions ?? []).some(
+          (fileOption) => fileOption.precompress?.length,
+        );
 
-        if (!userInjection) return;
+        if (!userInjection && !precompress) return;
 
         return new cloudfront.Function(
           `${name}CloudfrontFunctionResponse`,
@@ -704,7 +715,8 @@ async function handler(event) {
             code: `
 import cf from "cloudfront";
 async function handler(event) {
-  ${userInjection}
+  ${precompress ? CF_VARY_ENCODING_INJECTION : ""}
+  ${userInjection ?? ""}
   return event.response;
 }`,
           },
@@ -1147,7 +1159,7 @@ async function handler(event) {
       ].join("\n");
     }
 
-    function uploadAssets() {
+    function buildAssetFiles() {
       return all([args.assets, route, plan, outputPath]).apply(
         async ([assets, route, plan, outputPath]) => {
           // Define content headers
@@ -1155,6 +1167,10 @@ async function handler(event) {
           const nonVersionedFilesTTL = 86400; // 1 day
 
           const bucketFiles: BucketFile[] = [];
+          const variants: BucketFile[] = [];
+          // the routing entries of the files with precompressed variants, the
+          // ISR cache is only read by the servers so it isn't routed
+          const routes: Record<string, string> = {};
 
           // Handle each copy source
           for (const copy of [
@@ -1200,44 +1216,76 @@ async function handler(event) {
               }).filter((file) => !filesUploaded.includes(file));
 
               bucketFiles.push(
-                ...(await Promise.all(
-                  files.map(async (file) => {
-                    const source = path.resolve(outputPath, copy.from, file);
-                    const content = await fs.promises.readFile(source, "utf-8");
-                    const hash = crypto
-                      .createHash("sha256")
-                      .update(content)
-                      .digest("hex");
-                    return {
-                      source,
-                      key: path.posix.join(
-                        copy.to,
-                        route?.pathPrefix?.replace(/^\//, "") ?? "",
-                        file,
-                      ),
-                      hash,
-                      cacheControl: fileOption.cacheControl,
-                      contentType:
-                        fileOption.contentType ?? getContentType(file, "UTF-8"),
-                    };
-                  }),
-                )),
+                ...(
+                  await Promise.all(
+                    files.map(async (file) => {
+                      const source = path.resolve(outputPath, copy.from, file);
+                      const content = await fs.promises.readFile(source, "utf-8");
+                      const hash = crypto
+                        .createHash("sha256")
+                        .update(content)
+                        .digest("hex");
+                      const bucketFile: BucketFile = {
+                        source,
+                        key: path.posix.join(
+                          copy.to,
+                          route?.pathPrefix?.replace(/^\//, "") ?? "",
+                          file,
+                        ),
+                        hash,
+                        cacheControl: fileOption.cacheControl,
+                        contentType:
+                          fileOption.contentType ?? getContentType(file, "UTF-8"),
+                        contentEncoding: fileOption.contentEncoding,
+                        metadata: fileOption.metadata,
+                        storageClass: fileOption.storageClass,
+                        kmsKeyId: fileOption.kmsKeyId,
+                        tags: fileOption.tags,
+                      };
+                      const fileVariants = await precompressBucketFile(
+                        bucketFile,
+                        fileOption.precompress ?? [],
+                      );
+                      variants.push(...fileVariants);
+                      if (
+                        fileVariants.length > 0 &&
+                        plan.assets.some((asset) => asset === copy)
+                      ) {
+                        routes[path.posix.join("/", file)] =
+                          bucketFileRoute(fileVariants);
+                      }
+                      return [bucketFile, ...fileVariants];
+                    }),
+                  )
+                ).flat(),
               );
               filesUploaded.push(...files);
             }
           }
 
-          return new BucketFiles(
+          checkPrecompressedKeys(bucketFiles, variants);
+          if (route && !route.routerPrecompress && variants.length > 0)
+            throw new VisibleError(
+              `The "${name}" site precompresses files, but the Router it's routed through doesn't have \`precompress\` enabled. Set \`precompress: true\` on the Router.`,
+            );
+          return { files: bucketFiles, routes };
+        },
+      );
+    }
+
+    function uploadAssets() {
+      return assetFiles.apply(
+        (assetFiles) =>
+          new BucketFiles(
             `${name}AssetFiles`,
             {
               bucketName: bucket.name,
-              files: bucketFiles,
+              files: assetFiles.files,
               purge,
               region: getRegionOutput(undefined, { parent: self }).name,
             },
             { parent: self },
-          );
-        },
+          ),
       );
     }
 
@@ -1258,8 +1306,17 @@ async function handler(event) {
         plan,
         bucket.nodes.bucket.bucketRegionalDomainName,
         timeout,
+        assetFiles,
       ]).apply(
-        ([servers, imageOptimizer, outputPath, plan, bucketDomain, timeout]) =>
+        ([
+          servers,
+          imageOptimizer,
+          outputPath,
+          plan,
+          bucketDomain,
+          timeout,
+          assetFiles,
+        ]) =>
           all([
             servers.map((s) => ({ region: s.region, url: s.server!.url })),
             imageOptimizer?.url,
@@ -1298,6 +1355,9 @@ async function handler(event) {
                 });
               });
             });
+            // files with precompressed variants are routed on their own, at any
+            // depth, so the router knows which encodings it can serve
+            Object.assign(kvEntries, assetFiles.routes);
 
             kvEntries["metadata"] = JSON.stringify({
               base: plan.base,
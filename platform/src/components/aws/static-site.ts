@@ -15,7 +15,13 @@ import { Component, Prettify, Transform, transform } from "../component.js";
 import { Link } from "../link.js";
 import { Input } from "../input.js";
 import { globSync } from "glob";
-import { BucketFile, BucketFiles } from "./providers/bucket-files.js";
+import {
+  BucketFile,
+  BucketFiles,
+  bucketFileRoute,
+  checkPrecompressedKeys,
+  precompressBucketFile,
+} from "./providers/bucket-files.js";
 import { getContentType, BaseSiteDev } from "../base/base-site.js";
 import {
   BaseStaticSiteArgs,
@@ -29,6 +35,7 @@ import { KvKeys } from "./providers/kv-keys.js";
 import {
   CF_BLOCK_CLOUDFRONT_URL_INJECTION,
   CF_ROUTER_INJECTION,
+  CF_VARY_ENCODING_INJECTION,
   KV_SITE_METADATA,
   normalizeRouteArgs,
   RouterRouteArgs,
@@ -775,6 +782,7 @@ export class StaticSite extends Component implements Link.Linkable {
     const outputPath = buildApp(self, name, args.build, sitePath, environment);
     const bucket = createBucket();
     const { bucketName, bucketDomain } = getBucketDetails();
+    const assetFiles = buildAssetFiles();
     const assetsUploaded = uploadAssets();
     const kvNamespace = buildKvNamespace();
 
@@ -917,10 +925,13 @@ export class StaticSite extends Component implements Link.Linkable {
       };
     }
 
-    function uploadAssets() {
+    function buildAssetFiles() {
       return all([outputPath, assets, route]).apply(
         async ([outputPath, assets, route]) => {
           const bucketFiles: BucketFile[] = [];
+          const variants: BucketFile[] = [];
+          // the routing entries of the files with precompressed variants
+          const routes: Record<string, string> = {};
 
           // Build fileOptions
           const fileOptions = assets?.fileOptions ?? [
@@ -950,43 +961,72 @@ export class StaticSite extends Component implements Link.Linkable {
             }).filter((file) => !filesProcessed.includes(file));
 
             bucketFiles.push(
-              ...(await Promise.all(
-                files.map(async (file) => {
-                  const source = path.resolve(outputPath, file);
-                  const content = await fs.promises.readFile(source, "utf-8");
-                  const hash = crypto
-                    .createHash("sha256")
-                    .update(content)
-                    .digest("hex");
-                  return {
-                    source,
-                    key: path.posix.join(
-                      assets.path ?? "",
-                      route?.pathPrefix?.replace(/^\//, "") ?? "",
-                      file,
-                    ),
-                    hash,
-                    cacheControl: fileOption.cacheControl,
-                    contentType:
-                      fileOption.contentType ?? getContentType(file, "UTF-8"),
-                  };
-                }),
-              )),
+              ...(
+                await Promise.all(
+                  files.map(async (file) => {
+                    const source = path.resolve(outputPath, file);
+                    const content = await fs.promises.readFile(source, "utf-8");
+                    const hash = crypto
+                      .createHash("sha256")
+                      .update(content)
+                      .digest("hex");
+                    const bucketFile: BucketFile = {
+                      source,
+                      key: path.posix.join(
+                        assets.path ?? "",
+                        route?.pathPrefix?.replace(/^\//, "") ?? "",
+                        file,
+                      ),
+                      hash,
+                      cacheControl: fileOption.cacheControl,
+                      contentType:
+                        fileOption.contentType ?? getContentType(file, "UTF-8"),
+                      contentEncoding: fileOption.contentEncoding,
+                      metadata: fileOption.metadata,
+                      storageClass: fileOption.storageClass,
+                      kmsKeyId: fileOption.kmsKeyId,
+                      tags: fileOption.tags,
+                    };
+                    const fileVariants = await precompressBucketFile(
+                      bucketFile,
+                      fileOption.precompress ?? [],
+                    );
+                    if (fileVariants.length > 0) {
+                      variants.push(...fileVariants);
+                      routes[path.posix.join("/", file)] =
+                        bucketFileRoute(fileVariants);
+                    }
+                    return [bucketFile, ...fileVariants];
+                  }),
+                )
+              ).flat(),
             );
             filesProcessed.push(...files);
           }
 
-          return new BucketFiles(
+          checkPrecompressedKeys(bucketFiles, variants);
+          if (route && !route.routerPrecompress && variants.length > 0)
+            throw new VisibleError(
+              `The "${name}" site precompresses files, but the Router it's routed through doesn't have \`precompress\` enabled. Set \`precompress: true\` on the Router.`,
+            );
+          return { files: bucketFiles, routes };
+        },
+      );
+    }
+
+    function uploadAssets() {
+      return all([assetFiles, assets]).apply(
+        ([assetFiles, assets]) =>
+          new BucketFiles(
             `${name}AssetFiles`,
             {
               bucketName,
-              files: bucketFiles,
+              files: assetFiles.files,
               purge: assets.purge,
               region: getRegionOutput(undefined, { parent: self }).name,
             },
             { parent: self },
-          );
-        },
+          ),
       );
     }
 
@@ -1006,30 +1046,45 @@ export class StaticSite extends Component implements Link.Linkable {
         bucketDomain,
         errorPage,
         route,
-      ]).apply(async ([outputPath, assets, bucketDomain, errorPage, route]) => {
-        const kvEntries: Record<string, string> = {};
-        const dirs: string[] = [];
+        assetFiles,
+      ]).apply(
+        async ([
+          outputPath,
+          assets,
+          bucketDomain,
+          errorPage,
+          route,
+          assetFiles,
+        ]) => {
+          const kvEntries: Record<string, string> = {};
+          const dirs: string[] = [];
 
-        fs.readdirSync(outputPath, { withFileTypes: true }).forEach((item) => {
-          if (item.isDirectory()) {
-            dirs.push(path.posix.join("/", item.name));
-            return;
-          }
-          kvEntries[path.posix.join("/", item.name)] = "s3";
-        });
+          fs.readdirSync(outputPath, { withFileTypes: true }).forEach(
+            (item) => {
+              if (item.isDirectory()) {
+                dirs.push(path.posix.join("/", item.name));
+                return;
+              }
+              kvEntries[path.posix.join("/", item.name)] = "s3";
+            },
+          );
+          // files with precompressed variants are routed on their own, at any depth,
+          // so the router knows which encodings it can serve
+          Object.assign(kvEntries, assetFiles.routes);
 
-        kvEntries["metadata"] = JSON.stringify({
-          base: route?.pathPrefix === "/" ? undefined : route?.pathPrefix,
-          custom404: errorPage,
-          s3: {
-            domain: bucketDomain,
-            dir: assets.path ? "/" + assets.path : "",
-            routes: [...assets.routes, ...dirs],
-          },
-        } satisfies KV_SITE_METADATA);
+          kvEntries["metadata"] = JSON.stringify({
+            base: route?.pathPrefix === "/" ? undefined : route?.pathPrefix,
+            custom404: errorPage,
+            s3: {
+              domain: bucketDomain,
+              dir: assets.path ? "/" + assets.path : "",
+              routes: [...assets.routes, ...dirs],
+            },
+          } satisfies KV_SITE_METADATA);
 
-        return kvEntries;
-      });
+          return kvEntries;
+        },
+      );
 
       return new KvKeys(
         `${name}KvKeys`,
@@ -1096,7 +1151,7 @@ async function handler(event) {
   // Load metadata
   let metadata;
   try {
-    const v = await cf.kvs().get(kvNamespace + ":metadata");
+    const v = await getKvValue(kvNamespace + ":metadata");
     metadata = JSON.parse(v);
   } catch (e) {}
 
@@ -1110,12 +1165,15 @@ async function handler(event) {
     }
 
     function createResponseFunction() {
-      return output(args.edge).apply((edge) => {
+      return all([args.edge, assets]).apply(([edge, assets]) => {
         const userConfig = edge?.viewerResponse;
         const userInjection = userConfig?.injection;
         const kvStoreArn = userConfig?.kvStore ?? userConfig?.kvStores?.[0];
+        const precompress = (assets.fileOptions ?? []).some(
+          (fileOption) => fileOption.precompress?.length,
+        );
 
-        if (!userInjection) return;
+        if (!userInjection && !precompress) return;
 
         return new cloudfront.Function(
           `${name}CloudfrontFunctionResponse`,
@@ -1125,7 +1183,8 @@ async function handler(event) {
             code: `
 import cf from "cloudfront";
 async function handler(event) {
-  ${userInjection}
+  ${precompress ? CF_VARY_ENCODING_INJECTION : ""}
+  ${userInjection ?? ""}
   return event.response;
 }`,
           },
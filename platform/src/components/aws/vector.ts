@@ -17,7 +17,8 @@ export interface VectorArgs {
    * [dimensionality reduction](https://platform.openai.com/docs/api-reference/embeddings/create#embeddings-create-dimensions) automatically when generating embeddings.
    *
    * :::caution
-   * Changing the dimension will cause the data to be cleared.
+   * Changing the dimension fails the deploy unless `migration` is set, the existing
+   * embeddings don't fit the new dimension.
    * :::
    *
    * @example
@@ -28,6 +29,59 @@ export interface VectorArgs {
    * ```
    */
   dimension: Input<number>;
+  /**
+   * How a change in `dimension` is applied to the existing data.
+   *
+   * With `"column"`, the current embeddings are moved to a column named after their
+   * dimension, like `embedding_1536`, and an empty embedding column takes their place.
+   * Rows don't show up in queries until they are put again with new embeddings.
+   *
+   * If that column is already there from an earlier migration, the deploy fails
+   * without changing anything. Drop or rename the column and deploy again.
+   *
+   * @example
+   * ```js
+   * {
+   *   migration: "column"
+   * }
+   * ```
+   */
+  migration?: Input<"column">;
+  /**
+   * Configure the index on the embeddings. Options that are left out use the
+   * [pgvector](https://github.com/pgvector/pgvector#indexing) defaults.
+   *
+   * Changing the index rebuilds it.
+   *
+   * @default `{ type: "hnsw" }`
+   * @example
+   * ```js
+   * {
+   *   index: {
+   *     type: "ivfflat",
+   *     lists: 100
+   *   }
+   * }
+   * ```
+   */
+  index?: Input<{
+    /**
+     * The type of index.
+     */
+    type: Input<"hnsw" | "ivfflat">;
+    /**
+     * The max number of connections per layer of an `hnsw` index.
+     */
+    m?: Input<number>;
+    /**
+     * The size of the candidate list when building an `hnsw` index.
+     */
+    efConstruction?: Input<number>;
+    /**
+     * The number of lists in an `ivfflat` index.
+     */
+    lists?: Input<number>;
+  }>;
   /**
    * [Transform](/docs/components#transform) how this component creates its underlying
    * resources.
@@ -135,6 +189,8 @@ export class Vector extends Component implements Link.Linkable {
           databaseName: postgres.database,
           tableName,
           dimension: args.dimension,
+          index: args.index,
+          migration: args.migration,
         },
         { parent, dependsOn: postgres.nodes.instance },
       );
@@ -51,6 +51,51 @@ export interface BaseSiteFileOptions {
    * The `Content-Type` header to apply to the matched files.
    */
   contentType?: string;
+  /**
+   * The `Content-Encoding` header to apply to the matched files. Use this if the files
+   * in your build output are already compressed.
+   */
+  contentEncoding?: string;
+  /**
+   * Also upload compressed variants of the matched files. Each variant is uploaded next to
+   * the file with a `.gz` or `.br` extension and the matching `Content-Encoding` header.
+   * The router serves the variant to the browsers that accept it, and adds
+   * `Vary: Accept-Encoding` to the response. When the site is routed through a
+   * `Router`, it needs to have `precompress` enabled.
+   *
+   * Files under 1 KB and files that don't get at least 10% smaller, like images, are
+   * left as is. Each of the other files adds an entry to the KV store used for
+   * routing, so match the text files only. The variants are only compressed again
+   * when the file changes.
+   *
+   * @example
+   * ```js
+   * {
+   *   files: ["**\/*.js", "**\/*.css"],
+   *   precompress: ["br", "gzip"]
+   * }
+   * ```
+   */
+  precompress?: ("gzip" | "br")[];
+  /**
+   * The object metadata to set on the matched files. These are returned as
+   * `x-amz-meta-*` headers.
+   */
+  metadata?: Record<string, string>;
+  /**
+   * The S3 storage class of the matched files, like `INTELLIGENT_TIERING`.
+   * @default `"STANDARD"`
+   */
+  storageClass?: string;
+  /**
+   * The ARN or ID of the KMS key to encrypt the matched files with.
+   * @default The default encryption of the bucket
+   */
+  kmsKeyId?: string;
+  /**
+   * The object tags to set on the matched files. S3 allows up to 10 tags per file.
+   */
+  tags?: Record<string, string>;
 }
 
 export function getContentType(filename: string, textEncoding: string) {
@@ -0,0 +1,42 @@
+import { CustomResourceOptions, Input, dynamic } from "@pulumi/pulumi";
+import { rpc } from "../rpc/rpc.js";
+
+export interface DnsRecordInputs {
+  /**
+   * One of `route53`, `cloudflare`, `vercel`, or `rfc2136`.
+   */
+  provider: Input<"route53" | "cloudflare" | "vercel" | "rfc2136">;
+  /**
+   * The hosted zone ID for Route 53 and the zone ID for Cloudflare. The domain
+   * for Vercel and the zone name for RFC 2136.
+   */
+  zone: Input<string>;
+  name: Input<string>;
+  type: Input<string>;
+  values: Input<Input<string>[]>;
+  ttl?: Input<number>;
+  proxied?: Input<boolean>;
+  teamId?: Input<string>;
+  server?: Input<string>;
+  tsigKey?: Input<string>;
+  tsigSecret?: Input<string>;
+  tsigAlgorithm?: Input<string>;
+}
+
+/**
+ * A DNS record on any of the supported providers. A TXT record is created
+ * next to it with the app and stage, and records without one are never
+ * changed or removed.
+ */
+export class DnsRecord extends dynamic.Resource {
+  constructor(
+    name: string,
+    args: DnsRecordInputs,
+    opts?: CustomResourceOptions,
+  ) {
+    super(new rpc.Provider("Dns.Record"), `${name}.sst.DnsRecord`, args, {
+      ...opts,
+      additionalSecretOutputs: ["tsigSecret"],
+    });
+  }
+}
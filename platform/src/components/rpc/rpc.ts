@@ -99,5 +99,47 @@ export module rpc {
         throw ex;
       });
     }
+
+    async diff(id: string, olds: any, news: any): Promise<dynamic.DiffResult> {
+      // values that are not known yet can't be compared, let the engine
+      // compare the inputs instead
+      if (hasUnknowns(news)) return {};
+      return call(this.name("Diff"), {
+        id,
+        olds: withoutProvider(olds),
+        news: withoutProvider(news),
+      }).catch((ex) => {
+        if (ex instanceof MethodNotFoundError) return {};
+        throw ex;
+      });
+    }
+
+    async check(olds: any, news: any): Promise<dynamic.CheckResult> {
+      if (hasUnknowns(news)) return { inputs: news };
+      const result = await call(this.name("Check"), {
+        olds: withoutProvider(olds),
+        news: withoutProvider(news),
+      }).catch((ex) => {
+        if (ex instanceof MethodNotFoundError) return {};
+        throw ex;
+      });
+      return { inputs: news, failures: result.failures };
+    }
+  }
+
+  const unknown = "04da6b54-80e4-46f7-96ec-b56ff0331ba9";
+
+  function hasUnknowns(value: any): boolean {
+    if (value === unknown) return true;
+    if (Array.isArray(value)) return value.some(hasUnknowns);
+    if (value && typeof value === "object")
+      return Object.values(value).some(hasUnknowns);
+    return false;
+  }
+
+  function withoutProvider(value: any) {
+    if (!value || typeof value !== "object") return value;
+    const { __provider, ...rest } = value;
+    return rest;
   }
 }
@@ -270,6 +270,155 @@ export interface App {
    * `sst dev`, it'll still get removed. To avoid this, check out the `removal` prop.
    */
   protect?: boolean;
+
+  /**
+   * Configure the tunnel to the bastion host of your VPC.
+   */
+  tunnel?: {
+    /**
+     * Named port forwards to start with `sst tunnel forward`. Each forward listens on a
+     * local port and connects to a host in the VPC over SSH, without needing `sudo`.
+     *
+     * The value is in the form of `<local-port>:<host>:<port>`.
+     *
+     * @example
+     * ```ts
+     * {
+     *   tunnel: {
+     *     forwards: {
+     *       database: "5432:mydb.cluster-xyz.us-east-1.rds.amazonaws.com:5432"
+     *     }
+     *   }
+     * }
+     * ```
+     *
+     * These are also started automatically in the _Forwards_ tab in `sst dev`.
+     */
+    forwards?: Record<string, string>;
+  };
+
+  /**
+   * Back some of your linked resources with local emulators in `sst dev`, instead of
+   * the deployed ones. This is useful for working offline or running integration tests
+   * without touching your AWS account.
+   *
+   * - `bucket`: An S3 compatible server that stores objects in `.sst/emulator/bucket`.
+   * - `dynamo`: A DynamoDB Local container with the same tables and indexes.
+   * - `queue`: An ElasticMQ container that's compatible with SQS.
+   * - `postgres`: A Postgres container.
+   *
+   * The containers need Docker to be running.
+   *
+   * @example
+   * ```ts
+   * {
+   *   emulate: input.stage !== "production" ? ["bucket", "dynamo"] : []
+   * }
+   * ```
+   *
+   * The links of these resources are rewritten to point to the emulators. For the AWS
+   * SDK, the `AWS_ENDPOINT_URL_S3`, `AWS_ENDPOINT_URL_DYNAMODB`, and `AWS_ENDPOINT_URL_SQS`
+   * environment variables are set. The rest of your app is untouched.
+   */
+  emulate?: ("bucket" | "dynamo" | "queue" | "postgres")[];
+  /**
+   * The maximum number of resource operations that are run at the same time when you
+   * `deploy`, `diff`, `refresh`, or `remove` your app.
+   *
+   * By default, there's no limit and resources are deployed as concurrently as their
+   * dependencies allow. Lowering this helps when a large stage runs into the rate limits
+   * of your provider, or when your CI runner is small.
+   *
+   * @example
+   * ```ts
+   * {
+   *   parallel: 16
+   * }
+   * ```
+   *
+   * This can be overridden with the `--parallel` flag.
+   *
+   * ```bash
+   * sst deploy --parallel 4
+   * ```
+   *
+   * The API calls SST makes for its own resources, like uploading the files of a site, are
+   * limited separately per provider. Use the `SST_RESOURCE_CONCURRENCY_AWS`,
+   * `SST_RESOURCE_CONCURRENCY_CLOUDFLARE`, and `SST_RESOURCE_CONCURRENCY_VERCEL` environment
+   * variables to change these. Rate limited calls are retried with backoff. Uploads of files
+   * to S3 have their own limit that can be changed with `SST_RESOURCE_CONCURRENCY_UPLOAD`.
+   */
+  parallel?: number;
+  /**
+   * Groups of stages that are deployed together with `sst deploy --group`. This is
+   * useful if you deploy the same app to multiple regions or accounts.
+   *
+   * Each stage in a group can override the args of the providers in your app. These are
+   * merged into the providers you've configured, so the provider needs to be in your
+   * `providers`.
+   *
+   * @example
+   * ```ts
+   * {
+   *   groups: {
+   *     "prod-all": {
+   *       concurrency: 2,
+   *       mode: "best-effort",
+   *       stages: [
+   *         { name: "us", stage: "production-us", providers: { aws: { region: "us-east-1" } } },
+   *         { name: "eu", stage: "production-eu", providers: { aws: { region: "eu-west-1" } } },
+   *         { stage: "production-au", providers: { aws: { profile: "au-account" } } }
+   *       ]
+   *     }
+   *   }
+   * }
+   * ```
+   *
+   * The stages are deployed in the order they are listed. Set the `concurrency` to
+   * deploy more than one at a time. Their output is then prefixed with the `name` of the
+   * stage, which defaults to the stage. A stage can only be listed more than once if the
+   * `concurrency` is 1, since the deploys would otherwise run against the same state.
+   *
+   * Each stage writes its logs and `outputs.json` to `.sst/group/<group>/<name>`.
+   *
+   * By default, a group is deployed `fail-fast`, where no more stages are started once
+   * one fails. Use `best-effort` to deploy all of them regardless. Either way, a summary
+   * of every stage is printed at the end.
+   */
+  groups?: Record<
+    string,
+    {
+      /**
+       * The stages in the group.
+       */
+      stages: {
+        /**
+         * A name for this entry in the output. Needed if a stage is in the group more
+         * than once.
+         * @default The stage
+         */
+        name?: string;
+        /**
+         * The stage to deploy.
+         */
+        stage: string;
+        /**
+         * The args to override for each provider.
+         */
+        providers?: Record<string, Record<string, any>>;
+      }[];
+      /**
+       * The number of stages to deploy at the same time.
+       * @default `1`
+       */
+      concurrency?: number;
+      /**
+       * Whether to stop starting new stages once one fails.
+       * @default `"fail-fast"`
+       */
+      mode?: "fail-fast" | "best-effort";
+    }
+  >;
 }
 
 export interface AppInput {